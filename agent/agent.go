@@ -10,6 +10,7 @@ import (
 	"github.com/odpf/meteor/plugins"
 	"github.com/odpf/meteor/recipe"
 	"github.com/odpf/meteor/registry"
+	"github.com/odpf/meteor/utils"
 	"github.com/odpf/salt/log"
 	"github.com/pkg/errors"
 )
@@ -27,7 +28,8 @@ type Agent struct {
 	monitor          Monitor
 	logger           log.Logger
 	retrier          *retrier
-	stopOnSinkError  bool
+	sinkErrorPolicy  SinkErrorPolicy
+	delivery         Delivery
 	timerFn          TimerFn
 }
 
@@ -43,12 +45,26 @@ func NewAgent(config Config) *Agent {
 		timerFn = startDuration
 	}
 
+	sinkErrorPolicy := config.SinkErrorPolicy
+	if sinkErrorPolicy == "" {
+		sinkErrorPolicy = SinkErrorPolicyContinue
+		if config.StopOnSinkError {
+			sinkErrorPolicy = SinkErrorPolicyStop
+		}
+	}
+
+	delivery := config.Delivery
+	if delivery == "" {
+		delivery = DeliveryBestEffort
+	}
+
 	retrier := newRetrier(config.MaxRetries, config.RetryInitialInterval)
 	return &Agent{
 		extractorFactory: config.ExtractorFactory,
 		processorFactory: config.ProcessorFactory,
 		sinkFactory:      config.SinkFactory,
-		stopOnSinkError:  config.StopOnSinkError,
+		sinkErrorPolicy:  sinkErrorPolicy,
+		delivery:         delivery,
 		monitor:          mt,
 		logger:           config.Logger,
 		retrier:          retrier,
@@ -65,8 +81,20 @@ func (r *Agent) Validate(rcp recipe.Recipe) (errs []error) {
 			errs = append(errs, r.enrichInvalidConfigError(err, rcp.Source.Name, plugins.PluginTypeExtractor))
 		}
 	}
+	if err := validateSourceLimits(rcp.Source); err != nil {
+		errs = append(errs, errors.Wrapf(err, "invalid extractor \"%s\"", rcp.Source.Name))
+	}
 
 	for _, s := range rcp.Sinks {
+		if err := validateSinkErrorPolicy(s.OnError); err != nil {
+			errs = append(errs, errors.Wrapf(err, "invalid sink \"%s\"", s.Name))
+		}
+		if err := validateLimits(s); err != nil {
+			errs = append(errs, errors.Wrapf(err, "invalid sink \"%s\"", s.Name))
+		}
+		if err := validateRetryPolicy(s.Retry); err != nil {
+			errs = append(errs, errors.Wrapf(err, "invalid sink \"%s\"", s.Name))
+		}
 		sink, err := r.sinkFactory.Get(s.Name)
 		if err != nil {
 			errs = append(errs, err)
@@ -116,10 +144,14 @@ func (r *Agent) RunMultiple(ctx context.Context, recipes []recipe.Recipe) []Run
 func (r *Agent) Run(ctx context.Context, recipe recipe.Recipe) (run Run) {
 	run.Recipe = recipe
 	r.logger.Info("running recipe", "recipe", run.Recipe.Name)
+	ctx = plugins.ContextWithRunInfo(ctx, plugins.RunInfo{
+		RecipeName: recipe.Name,
+		StartedAt:  time.Now(),
+	})
 
 	var (
 		getDuration = r.timerFn()
-		stream      = newStream()
+		stream      = newStream(recipe.Source.BufferSize)
 		recordCount = 0
 	)
 
@@ -134,15 +166,22 @@ func (r *Agent) Run(ctx context.Context, recipe recipe.Recipe) (run Run) {
 		return
 	}
 
+	var committers []plugins.CommittingProcessor
 	for _, pr := range recipe.Processors {
-		if err := r.setupProcessor(ctx, pr, stream); err != nil {
+		proc, err := r.setupProcessor(ctx, pr, stream)
+		if err != nil {
 			run.Error = errors.Wrap(err, "failed to setup processor")
 			return
 		}
+		if cp, ok := proc.(plugins.CommittingProcessor); ok {
+			committers = append(committers, cp)
+		}
 	}
 
-	for _, sr := range recipe.Sinks {
-		err := r.setupSink(ctx, sr, stream, recipe)
+	run.Sinks = make([]SinkReport, len(recipe.Sinks))
+	for i, sr := range recipe.Sinks {
+		run.Sinks[i].Name = sr.Name
+		err := r.setupSink(ctx, sr, stream, recipe, &run.Sinks[i])
 		if err != nil {
 			run.Error = errors.Wrap(err, "failed to setup sink")
 			return
@@ -176,6 +215,10 @@ func (r *Agent) Run(ctx context.Context, recipe recipe.Recipe) (run Run) {
 		if err != nil {
 			run.Error = errors.Wrap(err, "failed to run extractor")
 		}
+		// release records held by buffered processors once extraction is done
+		if err := stream.flush(); err != nil && run.Error == nil {
+			run.Error = errors.Wrap(err, "failed to flush processors")
+		}
 	}()
 
 	// start listening.
@@ -185,6 +228,25 @@ func (r *Agent) Run(ctx context.Context, recipe recipe.Recipe) (run Run) {
 	}
 
 	// code will reach here stream.Listen() is done.
+	for _, report := range run.Sinks {
+		if len(report.FailedURNs) > 0 {
+			r.logger.Warn("sink failed to deliver records", "recipe", recipe.Name, "sink", report.Name,
+				"failed", len(report.FailedURNs), "records", report.RecordCount)
+		}
+	}
+	if run.Error == nil && r.delivery == DeliveryAllOrNothing {
+		run.Error = deliveryError(run.Sinks)
+	}
+	// processors keeping state across runs only move forward once the run succeeded
+	if run.Error == nil {
+		for _, cp := range committers {
+			if err := cp.Commit(ctx); err != nil {
+				run.Error = errors.Wrap(err, "failed to commit processor")
+				break
+			}
+		}
+	}
+
 	run.RecordCount = recordCount
 	success := run.Error == nil
 	run.Success = success
@@ -192,6 +254,10 @@ func (r *Agent) Run(ctx context.Context, recipe recipe.Recipe) (run Run) {
 }
 
 func (r *Agent) setupExtractor(ctx context.Context, sr recipe.PluginRecipe, str *stream) (runFn func() error, err error) {
+	if err = validateSourceLimits(sr); err != nil {
+		err = errors.Wrapf(err, "invalid extractor \"%s\"", sr.Name)
+		return
+	}
 	extractor, err := r.extractorFactory.Get(sr.Name)
 	if err != nil {
 		err = errors.Wrapf(err, "could not find extractor \"%s\"", sr.Name)
@@ -202,8 +268,19 @@ func (r *Agent) setupExtractor(ctx context.Context, sr recipe.PluginRecipe, str
 		return
 	}
 
+	emit := str.push
+	if limiter := utils.NewRateLimiter(sr.RateLimit); limiter != nil {
+		emit = func(record models.Record) {
+			// the run is cancelled if waiting fails, the stream is closed by then
+			if err := limiter.Wait(ctx); err != nil {
+				return
+			}
+			str.push(record)
+		}
+	}
+
 	runFn = func() (err error) {
-		if err = extractor.Extract(ctx, str.push); err != nil {
+		if err = extractor.Extract(ctx, emit); err != nil {
 			err = errors.Wrapf(err, "error running extractor \"%s\"", sr.Name)
 		}
 		return
@@ -212,13 +289,28 @@ func (r *Agent) setupExtractor(ctx context.Context, sr recipe.PluginRecipe, str
 	return
 }
 
-func (r *Agent) setupProcessor(ctx context.Context, pr recipe.PluginRecipe, str *stream) (err error) {
-	var proc plugins.Processor
+func (r *Agent) setupProcessor(ctx context.Context, pr recipe.PluginRecipe, str *stream) (proc plugins.Processor, err error) {
 	if proc, err = r.processorFactory.Get(pr.Name); err != nil {
-		return errors.Wrapf(err, "could not find processor \"%s\"", pr.Name)
+		return nil, errors.Wrapf(err, "could not find processor \"%s\"", pr.Name)
 	}
 	if err = proc.Init(ctx, pr.Config); err != nil {
-		return errors.Wrapf(err, "could not initiate processor \"%s\"", pr.Name)
+		return nil, errors.Wrapf(err, "could not initiate processor \"%s\"", pr.Name)
+	}
+
+	if bp, ok := proc.(plugins.BufferedProcessor); ok {
+		str.setBufferedMiddleware(func(src models.Record) (err error) {
+			if _, err = bp.Process(ctx, src); err != nil {
+				err = errors.Wrapf(err, "error running processor \"%s\"", pr.Name)
+			}
+			return
+		}, func(emit func(models.Record)) (err error) {
+			if err = bp.Flush(ctx, emit); err != nil {
+				err = errors.Wrapf(err, "error flushing processor \"%s\"", pr.Name)
+			}
+			return
+		})
+
+		return
 	}
 
 	str.setMiddleware(func(src models.Record) (dst models.Record, err error) {
@@ -234,9 +326,24 @@ func (r *Agent) setupProcessor(ctx context.Context, pr recipe.PluginRecipe, str
 	return
 }
 
-func (r *Agent) setupSink(ctx context.Context, sr recipe.PluginRecipe, stream *stream, recipe recipe.Recipe) (err error) {
+// setupSink subscribes the sink to the stream, the records it is sent and fails to deliver are added to the report
+func (r *Agent) setupSink(ctx context.Context, sr recipe.PluginRecipe, stream *stream, recipe recipe.Recipe, report *SinkReport) (err error) {
 	var sink plugins.Syncer
 
+	if err = validateSinkErrorPolicy(sr.OnError); err != nil {
+		return errors.Wrapf(err, "invalid sink \"%s\"", sr.Name)
+	}
+	if err = validateLimits(sr); err != nil {
+		return errors.Wrapf(err, "invalid sink \"%s\"", sr.Name)
+	}
+	if err = validateRetryPolicy(sr.Retry); err != nil {
+		return errors.Wrapf(err, "invalid sink \"%s\"", sr.Name)
+	}
+	policy := r.sinkErrorPolicy
+	if sr.OnError != "" {
+		policy = SinkErrorPolicy(sr.OnError)
+	}
+
 	if sink, err = r.sinkFactory.Get(sr.Name); err != nil {
 		return errors.Wrapf(err, "could not find sink \"%s\"", sr.Name)
 	}
@@ -249,9 +356,28 @@ func (r *Agent) setupSink(ctx context.Context, sr recipe.PluginRecipe, stream *s
 			"sink", sr.Name,
 			"error", e.Error())
 	}
+	batchSize := sr.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultBatchSize
+	}
+	retrier := r.retrier.withPolicy(sr.Retry)
+	limiter := utils.NewRateLimiter(sr.RateLimit)
+	// batches can be sunk at the same time when max_in_flight is set
+	var reportMu sync.Mutex
 	stream.subscribe(func(records []models.Record) error {
-		err := r.retrier.retry(func() error {
-			err := sink.Sink(ctx, records)
+		// records the sink failed to deliver, only those are sent again when the sink reports them
+		pending := records
+		err := retrier.retry(ctx, func() error {
+			if err := limiter.Wait(ctx); err != nil {
+				return err
+			}
+			err := sink.Sink(ctx, pending)
+			var partialErr plugins.PartialSinkError
+			if errors.As(err, &partialErr) {
+				if failed := selectRecords(pending, partialErr.FailedURNs); len(failed) > 0 {
+					pending = failed
+				}
+			}
 			return err
 		}, retryNotification)
 
@@ -266,17 +392,22 @@ func (r *Agent) setupSink(ctx context.Context, sr recipe.PluginRecipe, stream *s
 		}
 
 		r.monitor.RecordPlugin(recipe.Name, sr.Name, "sink", success)
+		reportMu.Lock()
+		report.record(records, pending, err)
+		reportMu.Unlock()
 
-		if !r.stopOnSinkError {
+		if policy != SinkErrorPolicyStop {
 			err = nil
 		}
 		// TODO: create a new error to signal stopping stream.
 		// returning nil so stream wont stop.
 		return err
-	}, defaultBatchSize)
+	}, subscription{
+		batchSize:   batchSize,
+		match:       newRecordMatcher(sr.When),
+		maxInFlight: sr.MaxInFlight,
+	})
 
-	//TODO: the sink closes even though some records remain unpublished
-	//TODO: once fixed, file sink's Close needs to close *File
 	stream.onClose(func() {
 		if err = sink.Close(); err != nil {
 			r.logger.Warn("error closing sink", "sink", sr.Name, "error", err)
@@ -309,6 +440,31 @@ func (r *Agent) enrichInvalidConfigError(err error, pluginName string, pluginTyp
 	return err
 }
 
+// validateLimits checks the rate limit and the max in flight of a plugin, neither can be negative.
+func validateLimits(pr recipe.PluginRecipe) error {
+	if pr.RateLimit < 0 {
+		return fmt.Errorf("invalid rate_limit %v, should not be negative", pr.RateLimit)
+	}
+	if pr.MaxInFlight < 0 {
+		return fmt.Errorf("invalid max_in_flight %d, should not be negative", pr.MaxInFlight)
+	}
+
+	return nil
+}
+
+// validateSourceLimits checks the limits of a source, max_in_flight only applies to sinks
+// and the buffer size cannot be negative.
+func validateSourceLimits(pr recipe.PluginRecipe) error {
+	if pr.MaxInFlight != 0 {
+		return fmt.Errorf("max_in_flight only applies to sinks, use buffer_size to limit the records emitted ahead of sinks")
+	}
+	if pr.BufferSize < 0 {
+		return fmt.Errorf("invalid buffer_size %d, should not be negative", pr.BufferSize)
+	}
+
+	return validateLimits(pr)
+}
+
 // startDuration starts a timer.
 func startDuration() func() int {
 	start := time.Now()
@@ -3,6 +3,7 @@ package agent_test
 import (
 	"context"
 	"errors"
+	"sync"
 	"testing"
 	"time"
 
@@ -17,11 +18,12 @@ import (
 	"github.com/stretchr/testify/mock"
 
 	commonv1beta1 "github.com/odpf/meteor/models/odpf/assets/common/v1beta1"
+	facetsv1beta1 "github.com/odpf/meteor/models/odpf/assets/facets/v1beta1"
 	assetsv1beta1 "github.com/odpf/meteor/models/odpf/assets/v1beta1"
 )
 
 var (
-	mockCtx = mock.AnythingOfType("*context.emptyCtx")
+	mockCtx = mock.AnythingOfType("*context.valueCtx")
 	ctx     = context.TODO()
 )
 
@@ -552,11 +554,575 @@ func TestAgentRun(t *testing.T) {
 		assert.Error(t, run.Error)
 	})
 
+	t.Run("should return error when sink with on_error stop fails", func(t *testing.T) {
+		data := []models.Record{
+			models.NewRecord(&assetsv1beta1.Table{
+				Resource: &commonv1beta1.Resource{Urn: "table-a"},
+			}),
+		}
+
+		extr := mocks.NewExtractor()
+		extr.SetEmit(data)
+		extr.On("Init", mockCtx, validRecipe.Source.Config).Return(nil).Once()
+		extr.On("Extract", mockCtx, mock.AnythingOfType("plugins.Emit")).Return(nil)
+		ef := registry.NewExtractorFactory()
+		if err := ef.Register("test-extractor", newExtractor(extr)); err != nil {
+			t.Fatal(err)
+		}
+
+		sink := mocks.NewSink()
+		sink.On("Init", mockCtx, validRecipe.Sinks[0].Config).Return(nil).Once()
+		sink.On("Sink", mockCtx, data).Return(errors.New("some error"))
+		sink.On("Close").Return(nil)
+		defer sink.AssertExpectations(t)
+		sf := registry.NewSinkFactory()
+		if err := sf.Register("test-sink", newSink(sink)); err != nil {
+			t.Fatal(err)
+		}
+
+		monitor := newMockMonitor()
+		monitor.On("RecordRun", mock.AnythingOfType("agent.Run")).Once()
+		monitor.On("RecordPlugin", mock.AnythingOfType("string"), mock.AnythingOfType("string"), mock.AnythingOfType("string"), mock.AnythingOfType("bool"))
+		defer monitor.AssertExpectations(t)
+
+		r := agent.NewAgent(agent.Config{
+			ExtractorFactory: ef,
+			ProcessorFactory: registry.NewProcessorFactory(),
+			SinkFactory:      sf,
+			Logger:           utils.Logger,
+			SinkErrorPolicy:  agent.SinkErrorPolicyContinue,
+			Monitor:          monitor,
+		})
+
+		run := r.Run(ctx, recipe.Recipe{
+			Name:   "sample",
+			Source: validRecipe.Source,
+			Sinks: []recipe.PluginRecipe{
+				{Name: "test-sink", Config: validRecipe.Sinks[0].Config, OnError: "stop"},
+			},
+		})
+		assert.False(t, run.Success)
+		assert.Error(t, run.Error)
+		assert.Equal(t, []agent.SinkReport{{Name: "test-sink", RecordCount: 1, FailedURNs: []string{"table-a"}}}, run.Sinks)
+	})
+
+	t.Run("should report records failed by sinks", func(t *testing.T) {
+		data := []models.Record{
+			models.NewRecord(&assetsv1beta1.Table{
+				Resource: &commonv1beta1.Resource{Urn: "table-a"},
+			}),
+			models.NewRecord(&assetsv1beta1.Table{
+				Resource: &commonv1beta1.Resource{Urn: "table-b"},
+			}),
+		}
+
+		for _, tc := range []struct {
+			delivery agent.Delivery
+			success  bool
+		}{
+			{delivery: agent.DeliveryBestEffort, success: true},
+			{delivery: agent.DeliveryAllOrNothing, success: false},
+		} {
+			extr := mocks.NewExtractor()
+			extr.SetEmit(data)
+			extr.On("Init", mockCtx, validRecipe.Source.Config).Return(nil).Once()
+			extr.On("Extract", mockCtx, mock.AnythingOfType("plugins.Emit")).Return(nil)
+			ef := registry.NewExtractorFactory()
+			if err := ef.Register("test-extractor", newExtractor(extr)); err != nil {
+				t.Fatal(err)
+			}
+
+			sink := mocks.NewSink()
+			sink.On("Init", mockCtx, validRecipe.Sinks[0].Config).Return(nil).Once()
+			sink.On("Sink", mockCtx, data).Return(nil).Once()
+			sink.On("Close").Return(nil)
+			failingSink := mocks.NewSink()
+			failingSink.On("Init", mockCtx, map[string]interface{}(nil)).Return(nil).Once()
+			failingSink.On("Sink", mockCtx, data).Return(plugins.PartialSinkError{
+				FailedURNs: []string{"table-b"},
+				Err:        errors.New("failed to deliver 1 record"),
+			}).Once()
+			failingSink.On("Close").Return(nil)
+			sf := registry.NewSinkFactory()
+			if err := sf.Register("test-sink", newSink(sink)); err != nil {
+				t.Fatal(err)
+			}
+			if err := sf.Register("failing-sink", newSink(failingSink)); err != nil {
+				t.Fatal(err)
+			}
+
+			monitor := newMockMonitor()
+			monitor.On("RecordRun", mock.AnythingOfType("agent.Run")).Once()
+			monitor.On("RecordPlugin", mock.AnythingOfType("string"), mock.AnythingOfType("string"), mock.AnythingOfType("string"), mock.AnythingOfType("bool"))
+
+			r := agent.NewAgent(agent.Config{
+				ExtractorFactory: ef,
+				ProcessorFactory: registry.NewProcessorFactory(),
+				SinkFactory:      sf,
+				Logger:           utils.Logger,
+				Delivery:         tc.delivery,
+				Monitor:          monitor,
+			})
+
+			run := r.Run(ctx, recipe.Recipe{
+				Name:   "sample",
+				Source: validRecipe.Source,
+				Sinks: []recipe.PluginRecipe{
+					{Name: "test-sink", Config: validRecipe.Sinks[0].Config, BatchSize: 2},
+					{Name: "failing-sink", BatchSize: 2},
+				},
+			})
+			assert.Equal(t, tc.success, run.Success, tc.delivery)
+			assert.Equal(t, []agent.SinkReport{
+				{Name: "test-sink", RecordCount: 2},
+				{Name: "failing-sink", RecordCount: 2, FailedURNs: []string{"table-b"}},
+			}, run.Sinks)
+			assert.Equal(t, 1, run.Sinks[1].AckedCount())
+			if !tc.success {
+				assert.EqualError(t, run.Error, "sinks failed to deliver records: \"failing-sink\" failed 1 of 2 records")
+			}
+			sink.AssertExpectations(t)
+			failingSink.AssertExpectations(t)
+			monitor.AssertExpectations(t)
+		}
+	})
+
+	t.Run("should sink records in batches of the batch size of the sink", func(t *testing.T) {
+		data := []models.Record{
+			models.NewRecord(&assetsv1beta1.Table{Resource: &commonv1beta1.Resource{Urn: "table-a"}}),
+			models.NewRecord(&assetsv1beta1.Table{Resource: &commonv1beta1.Resource{Urn: "table-b"}}),
+			models.NewRecord(&assetsv1beta1.Table{Resource: &commonv1beta1.Resource{Urn: "table-c"}}),
+		}
+
+		extr := mocks.NewExtractor()
+		extr.SetEmit(data)
+		extr.On("Init", mockCtx, validRecipe.Source.Config).Return(nil).Once()
+		extr.On("Extract", mockCtx, mock.AnythingOfType("plugins.Emit")).Return(nil)
+		ef := registry.NewExtractorFactory()
+		if err := ef.Register("test-extractor", newExtractor(extr)); err != nil {
+			t.Fatal(err)
+		}
+
+		sink := mocks.NewSink()
+		sink.On("Init", mockCtx, validRecipe.Sinks[0].Config).Return(nil).Once()
+		sink.On("Sink", mockCtx, data[:2]).Return(nil).Once()
+		sink.On("Sink", mockCtx, data[2:]).Return(nil).Once()
+		sink.On("Close").Return(nil)
+		defer sink.AssertExpectations(t)
+		sf := registry.NewSinkFactory()
+		if err := sf.Register("test-sink", newSink(sink)); err != nil {
+			t.Fatal(err)
+		}
+
+		monitor := newMockMonitor()
+		monitor.On("RecordRun", mock.AnythingOfType("agent.Run")).Once()
+		monitor.On("RecordPlugin", mock.AnythingOfType("string"), mock.AnythingOfType("string"), mock.AnythingOfType("string"), mock.AnythingOfType("bool"))
+		defer monitor.AssertExpectations(t)
+
+		r := agent.NewAgent(agent.Config{
+			ExtractorFactory: ef,
+			ProcessorFactory: registry.NewProcessorFactory(),
+			SinkFactory:      sf,
+			Logger:           utils.Logger,
+			Monitor:          monitor,
+		})
+		run := r.Run(ctx, recipe.Recipe{
+			Name:   "sample",
+			Source: validRecipe.Source,
+			Sinks: []recipe.PluginRecipe{
+				{Name: "test-sink", Config: validRecipe.Sinks[0].Config, BatchSize: 2},
+			},
+		})
+		assert.NoError(t, run.Error)
+		assert.Equal(t, 3, run.RecordCount)
+	})
+
+	t.Run("should only retry the records a sink failed to deliver", func(t *testing.T) {
+		data := []models.Record{
+			models.NewRecord(&assetsv1beta1.Table{
+				Resource: &commonv1beta1.Resource{Urn: "table-a"},
+			}),
+			models.NewRecord(&assetsv1beta1.Table{
+				Resource: &commonv1beta1.Resource{Urn: "table-b"},
+			}),
+			models.NewRecord(&assetsv1beta1.Table{
+				Resource: &commonv1beta1.Resource{Urn: "table-c"},
+			}),
+		}
+
+		extr := mocks.NewExtractor()
+		extr.SetEmit(data)
+		extr.On("Init", mockCtx, validRecipe.Source.Config).Return(nil).Once()
+		extr.On("Extract", mockCtx, mock.AnythingOfType("plugins.Emit")).Return(nil)
+		ef := registry.NewExtractorFactory()
+		if err := ef.Register("test-extractor", newExtractor(extr)); err != nil {
+			t.Fatal(err)
+		}
+
+		sink := mocks.NewSink()
+		sink.On("Init", mockCtx, validRecipe.Sinks[0].Config).Return(nil).Once()
+		sink.On("Sink", mockCtx, data).Return(plugins.NewRetryError(plugins.PartialSinkError{
+			FailedURNs: []string{"table-b", "table-c"},
+			Err:        errors.New("failed to deliver 2 records"),
+		})).Once()
+		sink.On("Sink", mockCtx, data[1:]).Return(plugins.NewRetryError(plugins.PartialSinkError{
+			FailedURNs: []string{"table-c"},
+			Err:        errors.New("failed to deliver 1 record"),
+		})).Once()
+		sink.On("Sink", mockCtx, data[2:]).Return(errors.New("invalid asset")).Once()
+		sink.On("Close").Return(nil)
+		defer sink.AssertExpectations(t)
+		sf := registry.NewSinkFactory()
+		if err := sf.Register("test-sink", newSink(sink)); err != nil {
+			t.Fatal(err)
+		}
+
+		monitor := newMockMonitor()
+		monitor.On("RecordRun", mock.AnythingOfType("agent.Run")).Once()
+		monitor.On("RecordPlugin", mock.AnythingOfType("string"), mock.AnythingOfType("string"), mock.AnythingOfType("string"), mock.AnythingOfType("bool"))
+		defer monitor.AssertExpectations(t)
+
+		r := agent.NewAgent(agent.Config{
+			ExtractorFactory:     ef,
+			ProcessorFactory:     registry.NewProcessorFactory(),
+			SinkFactory:          sf,
+			Logger:               utils.Logger,
+			Monitor:              monitor,
+			RetryInitialInterval: 1 * time.Millisecond,
+		})
+		run := r.Run(ctx, recipe.Recipe{
+			Name:   "sample",
+			Source: validRecipe.Source,
+			Sinks: []recipe.PluginRecipe{
+				{Name: "test-sink", Config: validRecipe.Sinks[0].Config, BatchSize: 3},
+			},
+		})
+		assert.NoError(t, run.Error)
+		assert.Equal(t, []agent.SinkReport{
+			{Name: "test-sink", RecordCount: 3, FailedURNs: []string{"table-c"}},
+		}, run.Sinks)
+	})
+
 	t.Run("should return run on success", func(t *testing.T) {
 		data := []models.Record{
-			models.NewRecord(&assetsv1beta1.Table{
-				Resource: &commonv1beta1.Resource{},
-			}),
+			models.NewRecord(&assetsv1beta1.Table{
+				Resource: &commonv1beta1.Resource{},
+			}),
+		}
+
+		extr := mocks.NewExtractor()
+		extr.SetEmit(data)
+		extr.On("Init", mockCtx, validRecipe.Source.Config).Return(nil).Once()
+		extr.On("Extract", mockCtx, mock.AnythingOfType("plugins.Emit")).Return(nil)
+		ef := registry.NewExtractorFactory()
+		if err := ef.Register("test-extractor", newExtractor(extr)); err != nil {
+			t.Fatal(err)
+		}
+
+		proc := mocks.NewProcessor()
+		proc.On("Init", mockCtx, validRecipe.Processors[0].Config).Return(nil).Once()
+		proc.On("Process", mockCtx, data[0]).Return(data[0], nil)
+		defer proc.AssertExpectations(t)
+		pf := registry.NewProcessorFactory()
+		if err := pf.Register("test-processor", newProcessor(proc)); err != nil {
+			t.Fatal(err)
+		}
+
+		sink := mocks.NewSink()
+		sink.On("Init", mockCtx, validRecipe.Sinks[0].Config).Return(nil).Once()
+		sink.On("Sink", mockCtx, data).Return(nil)
+		sink.On("Close").Return(nil)
+		defer sink.AssertExpectations(t)
+		sf := registry.NewSinkFactory()
+		if err := sf.Register("test-sink", newSink(sink)); err != nil {
+			t.Fatal(err)
+		}
+
+		monitor := newMockMonitor()
+		monitor.On("RecordRun", mock.AnythingOfType("agent.Run")).Once()
+		monitor.On("RecordPlugin", mock.AnythingOfType("string"), mock.AnythingOfType("string"), mock.AnythingOfType("string"), mock.AnythingOfType("bool"))
+		defer monitor.AssertExpectations(t)
+
+		r := agent.NewAgent(agent.Config{
+			ExtractorFactory: ef,
+			ProcessorFactory: pf,
+			SinkFactory:      sf,
+			Logger:           utils.Logger,
+			Monitor:          monitor,
+		})
+		run := r.Run(ctx, validRecipe)
+		assert.NoError(t, run.Error)
+		assert.Equal(t, validRecipe, run.Recipe)
+	})
+
+	t.Run("should pass records held by buffered processor once extraction is done", func(t *testing.T) {
+		data := []models.Record{
+			models.NewRecord(&assetsv1beta1.Table{
+				Resource: &commonv1beta1.Resource{Urn: "table-a"},
+			}),
+			models.NewRecord(&assetsv1beta1.Table{
+				Resource: &commonv1beta1.Resource{Urn: "table-b"},
+			}),
+		}
+		stub := models.NewRecord(&assetsv1beta1.Table{
+			Resource: &commonv1beta1.Resource{Urn: "table-c"},
+		})
+
+		extr := mocks.NewExtractor()
+		extr.SetEmit(data)
+		extr.On("Init", mockCtx, validRecipe.Source.Config).Return(nil).Once()
+		extr.On("Extract", mockCtx, mock.AnythingOfType("plugins.Emit")).Return(nil)
+		ef := registry.NewExtractorFactory()
+		if err := ef.Register("test-extractor", newExtractor(extr)); err != nil {
+			t.Fatal(err)
+		}
+
+		proc := &bufferedProcessor{extra: stub}
+		proc.On("Init", mockCtx, validRecipe.Processors[0].Config).Return(nil).Once()
+		defer proc.AssertExpectations(t)
+		pf := registry.NewProcessorFactory()
+		if err := pf.Register("test-processor", newProcessor(proc)); err != nil {
+			t.Fatal(err)
+		}
+
+		sink := mocks.NewSink()
+		sink.On("Init", mockCtx, validRecipe.Sinks[0].Config).Return(nil).Once()
+		sink.On("Sink", mockCtx, []models.Record{data[1]}).Return(nil).Once()
+		sink.On("Sink", mockCtx, []models.Record{data[0]}).Return(nil).Once()
+		sink.On("Sink", mockCtx, []models.Record{stub}).Return(nil).Once()
+		sink.On("Close").Return(nil)
+		defer sink.AssertExpectations(t)
+		sf := registry.NewSinkFactory()
+		if err := sf.Register("test-sink", newSink(sink)); err != nil {
+			t.Fatal(err)
+		}
+
+		monitor := newMockMonitor()
+		monitor.On("RecordRun", mock.AnythingOfType("agent.Run")).Once()
+		monitor.On("RecordPlugin", mock.AnythingOfType("string"), mock.AnythingOfType("string"), mock.AnythingOfType("string"), mock.AnythingOfType("bool"))
+		defer monitor.AssertExpectations(t)
+
+		r := agent.NewAgent(agent.Config{
+			ExtractorFactory: ef,
+			ProcessorFactory: pf,
+			SinkFactory:      sf,
+			Logger:           utils.Logger,
+			Monitor:          monitor,
+		})
+		run := r.Run(ctx, validRecipe)
+		assert.NoError(t, run.Error)
+		assert.Equal(t, 3, run.RecordCount)
+	})
+
+	t.Run("should commit processors once the run succeeded", func(t *testing.T) {
+		data := []models.Record{
+			models.NewRecord(&assetsv1beta1.Table{
+				Resource: &commonv1beta1.Resource{Urn: "table-a"},
+			}),
+		}
+
+		for _, tc := range []struct {
+			description string
+			sinkErr     error
+			commit      bool
+		}{
+			{description: "run succeeded", commit: true},
+			{description: "run failed", sinkErr: errors.New("some-error")},
+		} {
+			t.Run(tc.description, func(t *testing.T) {
+				extr := mocks.NewExtractor()
+				extr.SetEmit(data)
+				extr.On("Init", mockCtx, validRecipe.Source.Config).Return(nil).Once()
+				extr.On("Extract", mockCtx, mock.AnythingOfType("plugins.Emit")).Return(nil)
+				ef := registry.NewExtractorFactory()
+				if err := ef.Register("test-extractor", newExtractor(extr)); err != nil {
+					t.Fatal(err)
+				}
+
+				proc := &committingProcessor{}
+				proc.On("Init", mockCtx, validRecipe.Processors[0].Config).Return(nil).Once()
+				proc.On("Process", mockCtx, data[0]).Return(data[0], nil).Once()
+				if tc.commit {
+					proc.On("Commit", mockCtx).Return(nil).Once()
+				}
+				defer proc.AssertExpectations(t)
+				pf := registry.NewProcessorFactory()
+				if err := pf.Register("test-processor", newProcessor(proc)); err != nil {
+					t.Fatal(err)
+				}
+
+				sink := mocks.NewSink()
+				sink.On("Init", mockCtx, validRecipe.Sinks[0].Config).Return(nil).Once()
+				sink.On("Sink", mockCtx, data).Return(tc.sinkErr).Once()
+				sink.On("Close").Return(nil)
+				defer sink.AssertExpectations(t)
+				sf := registry.NewSinkFactory()
+				if err := sf.Register("test-sink", newSink(sink)); err != nil {
+					t.Fatal(err)
+				}
+
+				monitor := newMockMonitor()
+				monitor.On("RecordRun", mock.AnythingOfType("agent.Run")).Once()
+				monitor.On("RecordPlugin", mock.AnythingOfType("string"), mock.AnythingOfType("string"), mock.AnythingOfType("string"), mock.AnythingOfType("bool"))
+				defer monitor.AssertExpectations(t)
+
+				r := agent.NewAgent(agent.Config{
+					ExtractorFactory: ef,
+					ProcessorFactory: pf,
+					SinkFactory:      sf,
+					Logger:           utils.Logger,
+					Monitor:          monitor,
+					SinkErrorPolicy:  agent.SinkErrorPolicyStop,
+				})
+				run := r.Run(ctx, validRecipe)
+				assert.Equal(t, tc.commit, run.Success)
+			})
+		}
+	})
+
+	t.Run("should only send records selected by the when clause of a sink", func(t *testing.T) {
+		table := models.NewRecord(&assetsv1beta1.Table{
+			Resource:   &commonv1beta1.Resource{Urn: "bigquery::project/dataset/orders", Service: "bigquery"},
+			Properties: &facetsv1beta1.Properties{Tags: []string{"pii"}},
+		})
+		untagged := models.NewRecord(&assetsv1beta1.Table{
+			Resource: &commonv1beta1.Resource{Urn: "bigquery::project/dataset/users", Service: "bigquery"},
+		})
+		topic := models.NewRecord(&assetsv1beta1.Topic{
+			Resource:   &commonv1beta1.Resource{Urn: "kafka::broker/orders", Service: "kafka"},
+			Properties: &facetsv1beta1.Properties{Tags: []string{"pii"}},
+		})
+		data := []models.Record{table, untagged, topic}
+
+		extr := mocks.NewExtractor()
+		extr.SetEmit(data)
+		extr.On("Init", mockCtx, validRecipe.Source.Config).Return(nil).Once()
+		extr.On("Extract", mockCtx, mock.AnythingOfType("plugins.Emit")).Return(nil)
+		ef := registry.NewExtractorFactory()
+		if err := ef.Register("test-extractor", newExtractor(extr)); err != nil {
+			t.Fatal(err)
+		}
+
+		pf := registry.NewProcessorFactory()
+
+		sink := mocks.NewSink()
+		sink.On("Init", mockCtx, validRecipe.Sinks[0].Config).Return(nil).Once()
+		sink.On("Sink", mockCtx, data).Return(nil).Once()
+		sink.On("Close").Return(nil)
+		defer sink.AssertExpectations(t)
+		tablesSink := mocks.NewSink()
+		tablesSink.On("Init", mockCtx, map[string]interface{}(nil)).Return(nil).Once()
+		tablesSink.On("Sink", mockCtx, []models.Record{table}).Return(nil).Once()
+		tablesSink.On("Close").Return(nil)
+		defer tablesSink.AssertExpectations(t)
+		sf := registry.NewSinkFactory()
+		if err := sf.Register("test-sink", newSink(sink)); err != nil {
+			t.Fatal(err)
+		}
+		if err := sf.Register("tables-sink", newSink(tablesSink)); err != nil {
+			t.Fatal(err)
+		}
+
+		monitor := newMockMonitor()
+		monitor.On("RecordRun", mock.AnythingOfType("agent.Run")).Once()
+		monitor.On("RecordPlugin", mock.AnythingOfType("string"), mock.AnythingOfType("string"), mock.AnythingOfType("string"), mock.AnythingOfType("bool"))
+		defer monitor.AssertExpectations(t)
+
+		r := agent.NewAgent(agent.Config{
+			ExtractorFactory: ef,
+			ProcessorFactory: pf,
+			SinkFactory:      sf,
+			Logger:           utils.Logger,
+			Monitor:          monitor,
+		})
+		run := r.Run(ctx, recipe.Recipe{
+			Name:   "sample",
+			Source: validRecipe.Source,
+			Sinks: []recipe.PluginRecipe{
+				{Name: "test-sink", Config: validRecipe.Sinks[0].Config, BatchSize: 3},
+				{Name: "tables-sink", When: &recipe.Match{
+					Types: []string{"table"},
+					URNs:  []string{"bigquery::project/*"},
+					Tags:  []string{"pii"},
+				}},
+			},
+		})
+		assert.NoError(t, run.Error)
+		assert.Equal(t, 3, run.RecordCount)
+	})
+
+	t.Run("should sink up to max_in_flight batches at the same time", func(t *testing.T) {
+		data := []models.Record{
+			models.NewRecord(&assetsv1beta1.Table{Resource: &commonv1beta1.Resource{Urn: "table-1"}}),
+			models.NewRecord(&assetsv1beta1.Table{Resource: &commonv1beta1.Resource{Urn: "table-2"}}),
+		}
+
+		extr := mocks.NewExtractor()
+		extr.SetEmit(data)
+		extr.On("Init", mockCtx, validRecipe.Source.Config).Return(nil).Once()
+		extr.On("Extract", mockCtx, mock.AnythingOfType("plugins.Emit")).Return(nil)
+		ef := registry.NewExtractorFactory()
+		if err := ef.Register("test-extractor", newExtractor(extr)); err != nil {
+			t.Fatal(err)
+		}
+
+		pf := registry.NewProcessorFactory()
+
+		// every batch waits for the other one, so the run only succeeds if both are sunk at the same time
+		var inFlight sync.WaitGroup
+		inFlight.Add(len(data))
+		waitForOthers := func(mock.Arguments) {
+			inFlight.Done()
+			done := make(chan struct{})
+			go func() {
+				inFlight.Wait()
+				close(done)
+			}()
+			select {
+			case <-done:
+			case <-time.After(time.Second):
+				t.Error("batches were not sunk at the same time")
+			}
+		}
+		sink := mocks.NewSink()
+		sink.On("Init", mockCtx, validRecipe.Sinks[0].Config).Return(nil).Once()
+		sink.On("Sink", mockCtx, data[:1]).Run(waitForOthers).Return(nil).Once()
+		sink.On("Sink", mockCtx, data[1:]).Run(waitForOthers).Return(nil).Once()
+		sink.On("Close").Return(nil)
+		defer sink.AssertExpectations(t)
+		sf := registry.NewSinkFactory()
+		if err := sf.Register("test-sink", newSink(sink)); err != nil {
+			t.Fatal(err)
+		}
+
+		monitor := newMockMonitor()
+		monitor.On("RecordRun", mock.AnythingOfType("agent.Run")).Once()
+		monitor.On("RecordPlugin", mock.AnythingOfType("string"), mock.AnythingOfType("string"), mock.AnythingOfType("string"), mock.AnythingOfType("bool"))
+		defer monitor.AssertExpectations(t)
+
+		r := agent.NewAgent(agent.Config{
+			ExtractorFactory: ef,
+			ProcessorFactory: pf,
+			SinkFactory:      sf,
+			Logger:           utils.Logger,
+			Monitor:          monitor,
+		})
+		run := r.Run(ctx, recipe.Recipe{
+			Name:   "sample",
+			Source: validRecipe.Source,
+			Sinks: []recipe.PluginRecipe{
+				{Name: "test-sink", Config: validRecipe.Sinks[0].Config, MaxInFlight: 2},
+			},
+		})
+		assert.NoError(t, run.Error)
+		assert.Equal(t, 2, run.Sinks[0].AckedCount())
+	})
+
+	t.Run("should limit the rate of records emitted by the extractor", func(t *testing.T) {
+		data := []models.Record{
+			models.NewRecord(&assetsv1beta1.Table{Resource: &commonv1beta1.Resource{Urn: "table-1"}}),
+			models.NewRecord(&assetsv1beta1.Table{Resource: &commonv1beta1.Resource{Urn: "table-2"}}),
+			models.NewRecord(&assetsv1beta1.Table{Resource: &commonv1beta1.Resource{Urn: "table-3"}}),
 		}
 
 		extr := mocks.NewExtractor()
@@ -568,18 +1134,11 @@ func TestAgentRun(t *testing.T) {
 			t.Fatal(err)
 		}
 
-		proc := mocks.NewProcessor()
-		proc.On("Init", mockCtx, validRecipe.Processors[0].Config).Return(nil).Once()
-		proc.On("Process", mockCtx, data[0]).Return(data[0], nil)
-		defer proc.AssertExpectations(t)
 		pf := registry.NewProcessorFactory()
-		if err := pf.Register("test-processor", newProcessor(proc)); err != nil {
-			t.Fatal(err)
-		}
 
 		sink := mocks.NewSink()
 		sink.On("Init", mockCtx, validRecipe.Sinks[0].Config).Return(nil).Once()
-		sink.On("Sink", mockCtx, data).Return(nil)
+		sink.On("Sink", mockCtx, data).Return(nil).Once()
 		sink.On("Close").Return(nil)
 		defer sink.AssertExpectations(t)
 		sf := registry.NewSinkFactory()
@@ -599,9 +1158,20 @@ func TestAgentRun(t *testing.T) {
 			Logger:           utils.Logger,
 			Monitor:          monitor,
 		})
-		run := r.Run(ctx, validRecipe)
+		source := validRecipe.Source
+		source.RateLimit = 20
+		start := time.Now()
+		run := r.Run(ctx, recipe.Recipe{
+			Name:   "sample",
+			Source: source,
+			Sinks: []recipe.PluginRecipe{
+				{Name: "test-sink", Config: validRecipe.Sinks[0].Config, BatchSize: 3},
+			},
+		})
 		assert.NoError(t, run.Error)
-		assert.Equal(t, validRecipe, run.Recipe)
+		assert.Equal(t, 3, run.RecordCount)
+		// the second and third records wait 50ms each
+		assert.GreaterOrEqual(t, int64(time.Since(start)), int64(100*time.Millisecond))
 	})
 
 	t.Run("should collect run metrics", func(t *testing.T) {
@@ -719,6 +1289,170 @@ func TestAgentRun(t *testing.T) {
 		assert.NoError(t, run.Error)
 		assert.Equal(t, validRecipe, run.Recipe)
 	})
+
+	t.Run("should retry sinks with the retry policy of the recipe", func(t *testing.T) {
+		data := []models.Record{
+			models.NewRecord(&assetsv1beta1.Table{
+				Resource: &commonv1beta1.Resource{},
+			}),
+		}
+
+		extr := mocks.NewExtractor()
+		extr.SetEmit(data)
+		extr.On("Init", mockCtx, validRecipe.Source.Config).Return(nil).Once()
+		extr.On("Extract", mockCtx, mock.AnythingOfType("plugins.Emit")).Return(nil)
+		ef := registry.NewExtractorFactory()
+		if err := ef.Register("test-extractor", newExtractor(extr)); err != nil {
+			t.Fatal(err)
+		}
+
+		pf := registry.NewProcessorFactory()
+
+		// errors classified as retryable are retried without being wrapped in a RetryError
+		unavailable := plugins.HTTPStatusError{StatusCode: 503, Err: errors.New("service unavailable")}
+		sink := mocks.NewSink()
+		sink.On("Init", mockCtx, validRecipe.Sinks[0].Config).Return(nil).Once()
+		sink.On("Sink", mockCtx, data).Return(unavailable).Times(3)
+		sink.On("Sink", mockCtx, data).Return(nil).Once()
+		sink.On("Close").Return(nil)
+		defer sink.AssertExpectations(t)
+		sf := registry.NewSinkFactory()
+		if err := sf.Register("test-sink", newSink(sink)); err != nil {
+			t.Fatal(err)
+		}
+
+		monitor := newMockMonitor()
+		monitor.On("RecordRun", mock.AnythingOfType("agent.Run")).Once()
+		monitor.On("RecordPlugin", mock.AnythingOfType("string"), mock.AnythingOfType("string"), mock.AnythingOfType("string"), mock.AnythingOfType("bool"))
+		defer monitor.AssertExpectations(t)
+
+		r := agent.NewAgent(agent.Config{
+			ExtractorFactory:     ef,
+			ProcessorFactory:     pf,
+			SinkFactory:          sf,
+			Logger:               utils.Logger,
+			Monitor:              monitor,
+			MaxRetries:           1,                    // the recipe allows more retries for the sink
+			RetryInitialInterval: 1 * time.Millisecond, // this is to override default retry interval to reduce test time
+		})
+		run := r.Run(ctx, recipe.Recipe{
+			Name:   "sample",
+			Source: validRecipe.Source,
+			Sinks: []recipe.PluginRecipe{
+				{Name: "test-sink", Config: validRecipe.Sinks[0].Config, Retry: &recipe.RetryPolicy{
+					MaxRetries: intPtr(3),
+					Multiplier: floatPtr(1),
+					Jitter:     floatPtr(0.5),
+				}},
+			},
+		})
+		assert.NoError(t, run.Error)
+		assert.Empty(t, run.Sinks[0].FailedURNs)
+	})
+
+	t.Run("should not retry sinks with a retry policy of zero max_retries", func(t *testing.T) {
+		data := []models.Record{
+			models.NewRecord(&assetsv1beta1.Table{
+				Resource: &commonv1beta1.Resource{Urn: "table-1"},
+			}),
+		}
+
+		extr := mocks.NewExtractor()
+		extr.SetEmit(data)
+		extr.On("Init", mockCtx, validRecipe.Source.Config).Return(nil).Once()
+		extr.On("Extract", mockCtx, mock.AnythingOfType("plugins.Emit")).Return(nil)
+		ef := registry.NewExtractorFactory()
+		if err := ef.Register("test-extractor", newExtractor(extr)); err != nil {
+			t.Fatal(err)
+		}
+
+		sink := mocks.NewSink()
+		sink.On("Init", mockCtx, validRecipe.Sinks[0].Config).Return(nil).Once()
+		sink.On("Sink", mockCtx, data).Return(plugins.NewRetryError(errors.New("some-error"))).Once()
+		sink.On("Close").Return(nil)
+		defer sink.AssertExpectations(t)
+		sf := registry.NewSinkFactory()
+		if err := sf.Register("test-sink", newSink(sink)); err != nil {
+			t.Fatal(err)
+		}
+
+		monitor := newMockMonitor()
+		monitor.On("RecordRun", mock.AnythingOfType("agent.Run")).Once()
+		monitor.On("RecordPlugin", mock.AnythingOfType("string"), mock.AnythingOfType("string"), mock.AnythingOfType("string"), mock.AnythingOfType("bool"))
+		defer monitor.AssertExpectations(t)
+
+		r := agent.NewAgent(agent.Config{
+			ExtractorFactory:     ef,
+			ProcessorFactory:     registry.NewProcessorFactory(),
+			SinkFactory:          sf,
+			Logger:               utils.Logger,
+			Monitor:              monitor,
+			MaxRetries:           3, // the recipe disables the retries of the sink
+			RetryInitialInterval: 1 * time.Millisecond,
+		})
+		run := r.Run(ctx, recipe.Recipe{
+			Name:   "sample",
+			Source: validRecipe.Source,
+			Sinks: []recipe.PluginRecipe{
+				{Name: "test-sink", Config: validRecipe.Sinks[0].Config, Retry: &recipe.RetryPolicy{MaxRetries: intPtr(0)}},
+			},
+		})
+		assert.NoError(t, run.Error)
+		assert.Equal(t, []string{"table-1"}, run.Sinks[0].FailedURNs)
+	})
+
+	t.Run("should not retry sinks failing with errors which are not retryable", func(t *testing.T) {
+		data := []models.Record{
+			models.NewRecord(&assetsv1beta1.Table{
+				Resource: &commonv1beta1.Resource{Urn: "table-1"},
+			}),
+		}
+
+		extr := mocks.NewExtractor()
+		extr.SetEmit(data)
+		extr.On("Init", mockCtx, validRecipe.Source.Config).Return(nil).Once()
+		extr.On("Extract", mockCtx, mock.AnythingOfType("plugins.Emit")).Return(nil)
+		ef := registry.NewExtractorFactory()
+		if err := ef.Register("test-extractor", newExtractor(extr)); err != nil {
+			t.Fatal(err)
+		}
+
+		pf := registry.NewProcessorFactory()
+
+		badRequest := plugins.HTTPStatusError{StatusCode: 400, Err: errors.New("bad request")}
+		sink := mocks.NewSink()
+		sink.On("Init", mockCtx, validRecipe.Sinks[0].Config).Return(nil).Once()
+		sink.On("Sink", mockCtx, data).Return(badRequest).Once()
+		sink.On("Close").Return(nil)
+		defer sink.AssertExpectations(t)
+		sf := registry.NewSinkFactory()
+		if err := sf.Register("test-sink", newSink(sink)); err != nil {
+			t.Fatal(err)
+		}
+
+		monitor := newMockMonitor()
+		monitor.On("RecordRun", mock.AnythingOfType("agent.Run")).Once()
+		monitor.On("RecordPlugin", mock.AnythingOfType("string"), mock.AnythingOfType("string"), mock.AnythingOfType("string"), mock.AnythingOfType("bool"))
+		defer monitor.AssertExpectations(t)
+
+		r := agent.NewAgent(agent.Config{
+			ExtractorFactory:     ef,
+			ProcessorFactory:     pf,
+			SinkFactory:          sf,
+			Logger:               utils.Logger,
+			Monitor:              monitor,
+			RetryInitialInterval: 1 * time.Millisecond,
+		})
+		run := r.Run(ctx, recipe.Recipe{
+			Name:   "sample",
+			Source: validRecipe.Source,
+			Sinks: []recipe.PluginRecipe{
+				{Name: "test-sink", Config: validRecipe.Sinks[0].Config, Retry: &recipe.RetryPolicy{MaxRetries: intPtr(3)}},
+			},
+		})
+		assert.NoError(t, run.Error)
+		assert.Equal(t, []string{"table-1"}, run.Sinks[0].FailedURNs)
+	})
 }
 
 func TestAgentRunMultiple(t *testing.T) {
@@ -775,12 +1509,27 @@ func TestAgentRunMultiple(t *testing.T) {
 
 		assert.Len(t, runs, len(recipeList))
 		assert.Equal(t, []agent.Run{
-			{Recipe: validRecipe, RecordCount: len(data), Success: true},
-			{Recipe: validRecipe2, RecordCount: len(data), Success: true},
+			{Recipe: validRecipe, RecordCount: len(data), Success: true, Sinks: []agent.SinkReport{{Name: "test-sink", RecordCount: len(data)}}},
+			{Recipe: validRecipe2, RecordCount: len(data), Success: true, Sinks: []agent.SinkReport{{Name: "test-sink", RecordCount: len(data)}}},
 		}, runs)
 	})
 }
 
+func TestConfigValidate(t *testing.T) {
+	t.Run("should return error on unknown sink error policy", func(t *testing.T) {
+		err := agent.Config{SinkErrorPolicy: "ignore"}.Validate()
+		assert.EqualError(t, err, "invalid sink error policy \"ignore\", should be one of continue or stop")
+	})
+	t.Run("should return error on unknown delivery", func(t *testing.T) {
+		err := agent.Config{Delivery: "exactly_once"}.Validate()
+		assert.EqualError(t, err, "invalid delivery \"exactly_once\", should be one of best_effort or all_or_nothing")
+	})
+	t.Run("should accept known and empty values", func(t *testing.T) {
+		assert.NoError(t, agent.Config{}.Validate())
+		assert.NoError(t, agent.Config{SinkErrorPolicy: agent.SinkErrorPolicyStop, Delivery: agent.DeliveryAllOrNothing}.Validate())
+	})
+}
+
 func TestValidate(t *testing.T) {
 	t.Run("should return error if plugins in recipe not found in Factory", func(t *testing.T) {
 		r := agent.NewAgent(agent.Config{
@@ -797,6 +1546,101 @@ func TestValidate(t *testing.T) {
 		assert.Equal(t, 3, len(errs))
 		assert.Equal(t, expectedErrs, errs)
 	})
+	t.Run("should return error if on_error of a sink is invalid", func(t *testing.T) {
+		sink := mocks.NewSink()
+		sink.On("Validate", validRecipe.Sinks[0].Config).Return(nil).Once()
+		defer sink.AssertExpectations(t)
+		sf := registry.NewSinkFactory()
+		if err := sf.Register("test-sink", newSink(sink)); err != nil {
+			t.Fatal(err)
+		}
+		r := agent.NewAgent(agent.Config{
+			ExtractorFactory: registry.NewExtractorFactory(),
+			ProcessorFactory: registry.NewProcessorFactory(),
+			SinkFactory:      sf,
+			Logger:           utils.Logger,
+		})
+
+		errs := r.Validate(recipe.Recipe{
+			Source: validRecipe.Source,
+			Sinks: []recipe.PluginRecipe{
+				{Name: "test-sink", Config: validRecipe.Sinks[0].Config, OnError: "ignore"},
+			},
+		})
+		assert.Len(t, errs, 2)
+		assert.EqualError(t, errs[1], "invalid sink \"test-sink\": invalid sink error policy \"ignore\", should be one of continue or stop")
+	})
+	t.Run("should return error if limits of a plugin are negative", func(t *testing.T) {
+		sink := mocks.NewSink()
+		sink.On("Validate", validRecipe.Sinks[0].Config).Return(nil).Once()
+		defer sink.AssertExpectations(t)
+		sf := registry.NewSinkFactory()
+		if err := sf.Register("test-sink", newSink(sink)); err != nil {
+			t.Fatal(err)
+		}
+		r := agent.NewAgent(agent.Config{
+			ExtractorFactory: registry.NewExtractorFactory(),
+			ProcessorFactory: registry.NewProcessorFactory(),
+			SinkFactory:      sf,
+			Logger:           utils.Logger,
+		})
+
+		source := validRecipe.Source
+		source.RateLimit = -1
+		errs := r.Validate(recipe.Recipe{
+			Source: source,
+			Sinks: []recipe.PluginRecipe{
+				{Name: "test-sink", Config: validRecipe.Sinks[0].Config, MaxInFlight: -2},
+			},
+		})
+		assert.Len(t, errs, 3)
+		assert.EqualError(t, errs[1], "invalid extractor \"test-extractor\": invalid rate_limit -1, should not be negative")
+		assert.EqualError(t, errs[2], "invalid sink \"test-sink\": invalid max_in_flight -2, should not be negative")
+	})
+	t.Run("should return error if source sets max_in_flight or a negative buffer_size", func(t *testing.T) {
+		r := agent.NewAgent(agent.Config{
+			ExtractorFactory: registry.NewExtractorFactory(),
+			ProcessorFactory: registry.NewProcessorFactory(),
+			SinkFactory:      registry.NewSinkFactory(),
+			Logger:           utils.Logger,
+		})
+
+		source := validRecipe.Source
+		source.MaxInFlight = 100
+		errs := r.Validate(recipe.Recipe{Source: source})
+		assert.Len(t, errs, 2)
+		assert.EqualError(t, errs[1], "invalid extractor \"test-extractor\": max_in_flight only applies to sinks, use buffer_size to limit the records emitted ahead of sinks")
+
+		source = validRecipe.Source
+		source.BufferSize = -1
+		errs = r.Validate(recipe.Recipe{Source: source})
+		assert.Len(t, errs, 2)
+		assert.EqualError(t, errs[1], "invalid extractor \"test-extractor\": invalid buffer_size -1, should not be negative")
+	})
+	t.Run("should return error if retry policy of a sink is invalid", func(t *testing.T) {
+		sink := mocks.NewSink()
+		sink.On("Validate", validRecipe.Sinks[0].Config).Return(nil).Once()
+		defer sink.AssertExpectations(t)
+		sf := registry.NewSinkFactory()
+		if err := sf.Register("test-sink", newSink(sink)); err != nil {
+			t.Fatal(err)
+		}
+		r := agent.NewAgent(agent.Config{
+			ExtractorFactory: registry.NewExtractorFactory(),
+			ProcessorFactory: registry.NewProcessorFactory(),
+			SinkFactory:      sf,
+			Logger:           utils.Logger,
+		})
+
+		errs := r.Validate(recipe.Recipe{
+			Source: validRecipe.Source,
+			Sinks: []recipe.PluginRecipe{
+				{Name: "test-sink", Config: validRecipe.Sinks[0].Config, Retry: &recipe.RetryPolicy{Multiplier: floatPtr(0.5)}},
+			},
+		})
+		assert.Len(t, errs, 2)
+		assert.EqualError(t, errs[1], "invalid sink \"test-sink\": invalid retry multiplier 0.5, should be at least 1")
+	})
 	t.Run("", func(t *testing.T) {
 		var invalidRecipe = recipe.Recipe{
 			Name: "sample",
@@ -916,6 +1760,35 @@ func (p *panicProcessor) Process(_ context.Context, _ models.Record) (dst models
 	panic("panicking")
 }
 
+type bufferedProcessor struct {
+	mocks.Processor
+	records []models.Record
+	extra   models.Record
+}
+
+func (p *bufferedProcessor) Process(_ context.Context, src models.Record) (dst models.Record, err error) {
+	p.records = append(p.records, src)
+	return src, nil
+}
+
+// Flush emits held records in reverse order followed by the extra record
+func (p *bufferedProcessor) Flush(_ context.Context, emit plugins.Emit) error {
+	for i := len(p.records) - 1; i >= 0; i-- {
+		emit(p.records[i])
+	}
+	emit(p.extra)
+	return nil
+}
+
+type committingProcessor struct {
+	mocks.Processor
+}
+
+func (p *committingProcessor) Commit(ctx context.Context) error {
+	args := p.Called(ctx)
+	return args.Error(0)
+}
+
 // enrichInvalidConfigError enrich the error with plugin information
 func enrichInvalidConfigError(err error, pluginName string, pluginType plugins.PluginType) error {
 	if errors.As(err, &plugins.InvalidConfigError{}) {
@@ -928,3 +1801,7 @@ func enrichInvalidConfigError(err error, pluginName string, pluginType plugins.P
 
 	return err
 }
+
+func intPtr(i int) *int { return &i }
+
+func floatPtr(f float64) *float64 { return &f }
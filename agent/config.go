@@ -15,6 +15,22 @@ type Config struct {
 	Logger               log.Logger
 	MaxRetries           int
 	RetryInitialInterval time.Duration
-	StopOnSinkError      bool
-	TimerFn              TimerFn
+	// StopOnSinkError stops runs once a sink failed if SinkErrorPolicy is not set.
+	//
+	// Deprecated: use SinkErrorPolicy instead.
+	StopOnSinkError bool
+	// SinkErrorPolicy applies to sinks without on_error in the recipe, defaults to continue.
+	SinkErrorPolicy SinkErrorPolicy
+	// Delivery decides whether runs succeed when sinks failed to deliver records, defaults to best effort.
+	Delivery Delivery
+	TimerFn  TimerFn
+}
+
+// Validate returns an error if the sink error policy or the delivery is unknown.
+func (c Config) Validate() error {
+	if err := validateSinkErrorPolicy(string(c.SinkErrorPolicy)); err != nil {
+		return err
+	}
+
+	return validateDelivery(string(c.Delivery))
 }
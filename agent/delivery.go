@@ -0,0 +1,111 @@
+package agent
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/odpf/meteor/models"
+	"github.com/odpf/meteor/plugins"
+	"github.com/pkg/errors"
+)
+
+// Delivery decides whether a run succeeds when sinks failed to deliver records.
+type Delivery string
+
+const (
+	// DeliveryBestEffort lets runs succeed even if sinks failed to deliver records,
+	// the failed records are reported by sink.
+	DeliveryBestEffort Delivery = "best_effort"
+	// DeliveryAllOrNothing fails runs unless every sink delivered every record sent to it.
+	DeliveryAllOrNothing Delivery = "all_or_nothing"
+)
+
+// SinkErrorPolicy decides whether a run goes on once a sink failed to deliver records.
+type SinkErrorPolicy string
+
+const (
+	// SinkErrorPolicyContinue keeps sending records to the sinks.
+	SinkErrorPolicyContinue SinkErrorPolicy = "continue"
+	// SinkErrorPolicyStop stops the run.
+	SinkErrorPolicyStop SinkErrorPolicy = "stop"
+)
+
+// SinkReport is the delivery of the records of a run to a sink.
+type SinkReport struct {
+	Name string `json:"name"`
+	// RecordCount is the number of records sent to the sink.
+	RecordCount int `json:"record_count"`
+	// FailedURNs are the urns of the records the sink failed to deliver.
+	FailedURNs []string `json:"failed_urns"`
+}
+
+// AckedCount returns the number of records delivered by the sink.
+func (r SinkReport) AckedCount() int {
+	return r.RecordCount - len(r.FailedURNs)
+}
+
+// record adds the result of sinking a batch to the report, pending are the records of the batch
+// the sink was last sent, only the records of a PartialSinkError are failed, every pending record is failed on other errors.
+func (r *SinkReport) record(batch, pending []models.Record, err error) {
+	r.RecordCount += len(batch)
+	if err == nil {
+		return
+	}
+
+	var partialErr plugins.PartialSinkError
+	if errors.As(err, &partialErr) {
+		r.FailedURNs = append(r.FailedURNs, partialErr.FailedURNs...)
+		return
+	}
+	for _, record := range pending {
+		r.FailedURNs = append(r.FailedURNs, record.Data().GetResource().GetUrn())
+	}
+}
+
+// selectRecords returns the records whose urn is one of urns.
+func selectRecords(records []models.Record, urns []string) []models.Record {
+	selected := make(map[string]bool, len(urns))
+	for _, urn := range urns {
+		selected[urn] = true
+	}
+
+	var res []models.Record
+	for _, record := range records {
+		if selected[record.Data().GetResource().GetUrn()] {
+			res = append(res, record)
+		}
+	}
+	return res
+}
+
+func validateSinkErrorPolicy(policy string) error {
+	switch SinkErrorPolicy(policy) {
+	case "", SinkErrorPolicyContinue, SinkErrorPolicyStop:
+		return nil
+	}
+	return fmt.Errorf("invalid sink error policy \"%s\", should be one of %s or %s", policy, SinkErrorPolicyContinue, SinkErrorPolicyStop)
+}
+
+func validateDelivery(delivery string) error {
+	switch Delivery(delivery) {
+	case "", DeliveryBestEffort, DeliveryAllOrNothing:
+		return nil
+	}
+	return fmt.Errorf("invalid delivery \"%s\", should be one of %s or %s", delivery, DeliveryBestEffort, DeliveryAllOrNothing)
+}
+
+// deliveryError returns the error of a run whose sinks failed to deliver records,
+// nil is returned if every sink delivered every record.
+func deliveryError(reports []SinkReport) error {
+	var failures []string
+	for _, report := range reports {
+		if len(report.FailedURNs) > 0 {
+			failures = append(failures, fmt.Sprintf("\"%s\" failed %d of %d records", report.Name, len(report.FailedURNs), report.RecordCount))
+		}
+	}
+	if len(failures) == 0 {
+		return nil
+	}
+
+	return fmt.Errorf("sinks failed to deliver records: %s", strings.Join(failures, ", "))
+}
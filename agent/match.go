@@ -0,0 +1,101 @@
+package agent
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/odpf/meteor/models"
+	"github.com/odpf/meteor/recipe"
+	"github.com/odpf/meteor/utils"
+)
+
+// recordMatcher returns true for the records selected by a sink
+type recordMatcher func(models.Record) bool
+
+// newRecordMatcher returns a matcher of the records selected by the clause,
+// nil is returned if there is no clause so every record is selected
+func newRecordMatcher(when *recipe.Match) recordMatcher {
+	if when == nil {
+		return nil
+	}
+
+	urns := make([]*regexp.Regexp, len(when.URNs))
+	for i, pattern := range when.URNs {
+		urns[i] = globToRegexp(pattern)
+	}
+
+	return func(record models.Record) bool {
+		data := record.Data()
+		resource := data.GetResource()
+
+		if len(when.Types) > 0 && !contains(when.Types, utils.GetAssetType(data)) {
+			return false
+		}
+		if len(when.Services) > 0 && !contains(when.Services, resource.GetService()) {
+			return false
+		}
+		if len(urns) > 0 && !matchesAny(urns, resource.GetUrn()) {
+			return false
+		}
+
+		properties := data.GetProperties()
+		if len(when.Tags) > 0 && !containsAny(when.Tags, properties.GetTags()) {
+			return false
+		}
+		labels := properties.GetLabels()
+		for key, value := range when.Labels {
+			actual, ok := labels[key]
+			if !ok || (value != "*" && value != actual) {
+				return false
+			}
+		}
+
+		return true
+	}
+}
+
+// globToRegexp returns the regexp of a pattern where * matches any sequence of characters and ? any single character
+func globToRegexp(pattern string) *regexp.Regexp {
+	var b strings.Builder
+	b.WriteString("^")
+	for _, r := range pattern {
+		switch r {
+		case '*':
+			b.WriteString(".*")
+		case '?':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	b.WriteString("$")
+
+	return regexp.MustCompile(b.String())
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func containsAny(values, others []string) bool {
+	for _, other := range others {
+		if contains(values, other) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesAny(patterns []*regexp.Regexp, value string) bool {
+	for _, pattern := range patterns {
+		if pattern.MatchString(value) {
+			return true
+		}
+	}
+	return false
+}
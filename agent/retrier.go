@@ -1,21 +1,28 @@
 package agent
 
 import (
-	"errors"
+	"context"
+	"fmt"
 	"time"
 
 	"github.com/cenkalti/backoff/v4"
 	"github.com/odpf/meteor/plugins"
+	"github.com/odpf/meteor/recipe"
 )
 
 const (
 	defaultMaxRetries      = 5
 	defaultInitialInterval = 5 * time.Second
+	defaultMultiplier      = 5
 )
 
 type retrier struct {
 	maxRetries      int
 	initialInterval time.Duration
+	maxInterval     time.Duration
+	multiplier      float64
+	jitter          float64
+	deadline        time.Duration
 }
 
 func newRetrier(maxRetries int, initialInterval time.Duration) *retrier {
@@ -29,31 +36,109 @@ func newRetrier(maxRetries int, initialInterval time.Duration) *retrier {
 	if r.initialInterval == 0 {
 		r.initialInterval = defaultInitialInterval
 	}
+	r.maxInterval = backoff.DefaultMaxInterval
+	r.multiplier = defaultMultiplier
+	r.deadline = backoff.DefaultMaxElapsedTime
 
 	return r
 }
 
-func (r *retrier) retry(operation func() error, notify func(e error, d time.Duration)) error {
-	bo := backoff.WithMaxRetries(r.createExponentialBackoff(r.initialInterval), uint64(r.maxRetries))
+// withPolicy returns a copy of the retrier with the settings of the policy which are set,
+// the retrier itself is returned if there is no policy.
+func (r *retrier) withPolicy(p *recipe.RetryPolicy) *retrier {
+	if p == nil {
+		return r
+	}
+
+	res := *r
+	if p.MaxRetries != nil {
+		res.maxRetries = *p.MaxRetries
+	}
+	if p.InitialIntervalSeconds != nil {
+		res.initialInterval = time.Duration(*p.InitialIntervalSeconds) * time.Second
+	}
+	if p.MaxIntervalSeconds != nil {
+		res.maxInterval = time.Duration(*p.MaxIntervalSeconds) * time.Second
+	}
+	if p.Multiplier != nil {
+		res.multiplier = *p.Multiplier
+	}
+	if p.Jitter != nil {
+		res.jitter = *p.Jitter
+	}
+	if p.DeadlineSeconds != nil {
+		res.deadline = time.Duration(*p.DeadlineSeconds) * time.Second
+	}
+
+	return &res
+}
+
+// retry runs the operation until it succeeds, fails with an error which is not retryable,
+// the retries are exhausted or ctx is done.
+func (r *retrier) retry(ctx context.Context, operation func() error, notify func(e error, d time.Duration)) error {
+	bo := backoff.WithContext(backoff.WithMaxRetries(r.createExponentialBackoff(), uint64(r.maxRetries)), ctx)
 	return backoff.RetryNotify(func() error {
 		err := operation()
 		if err == nil {
 			return err
 		}
-		// if err is RetryError, returns err directly to retry
-		if errors.Is(err, plugins.RetryError{}) {
+		// retryable errors, such as RetryError or network timeouts, are returned directly to retry
+		if plugins.IsRetryable(err) {
 			return err
 		}
-		// if err is not RetryError, wraps error to prevent retrying
+		// other errors are wrapped to prevent retrying
 		return backoff.Permanent(err)
 	}, bo, notify)
 }
 
-func (r *retrier) createExponentialBackoff(initialInterval time.Duration) backoff.BackOff {
+func (r *retrier) createExponentialBackoff() backoff.BackOff {
 	ebo := backoff.NewExponentialBackOff()
-	ebo.InitialInterval = initialInterval // first interval duration to be used
-	ebo.RandomizationFactor = 0           // to make sure we get constant increment in interval instead of random
-	ebo.Multiplier = 5                    // interval multiplier e.g. 5s -> 25s -> 125s -> 625s
+	ebo.InitialInterval = r.initialInterval // first interval duration to be used
+	ebo.MaxInterval = r.maxInterval         // intervals stop growing past it
+	ebo.RandomizationFactor = r.jitter      // 0 by default to get a constant increment in interval instead of random
+	ebo.Multiplier = r.multiplier           // interval multiplier e.g. 5s -> 25s -> 125s -> 625s
+	ebo.MaxElapsedTime = r.deadline         // no retry is started past it
 
 	return ebo
 }
+
+// validateRetryPolicy checks the settings of a policy which are set, none can be negative,
+// the multiplier should be at least 1 and the jitter at most 1.
+func validateRetryPolicy(p *recipe.RetryPolicy) error {
+	if p == nil {
+		return nil
+	}
+
+	settings := []struct {
+		key   string
+		value *float64
+	}{
+		{"max_retries", intToFloat(p.MaxRetries)},
+		{"initial_interval_seconds", intToFloat(p.InitialIntervalSeconds)},
+		{"max_interval_seconds", intToFloat(p.MaxIntervalSeconds)},
+		{"multiplier", p.Multiplier},
+		{"jitter", p.Jitter},
+		{"deadline_seconds", intToFloat(p.DeadlineSeconds)},
+	}
+	for _, setting := range settings {
+		if setting.value != nil && *setting.value < 0 {
+			return fmt.Errorf("invalid retry %s %v, should not be negative", setting.key, *setting.value)
+		}
+	}
+	if p.Multiplier != nil && *p.Multiplier < 1 {
+		return fmt.Errorf("invalid retry multiplier %v, should be at least 1", *p.Multiplier)
+	}
+	if p.Jitter != nil && *p.Jitter > 1 {
+		return fmt.Errorf("invalid retry jitter %v, should be at most 1", *p.Jitter)
+	}
+
+	return nil
+}
+
+func intToFloat(i *int) *float64 {
+	if i == nil {
+		return nil
+	}
+	f := float64(*i)
+	return &f
+}
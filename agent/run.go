@@ -21,4 +21,6 @@ type Run struct {
 	DurationInMs int           `json:"duration_in_ms"`
 	RecordCount  int           `json:"record_count"`
 	Success      bool          `json:"success"`
+	// Sinks are the reports of the sinks of the recipe, in the order of the recipe.
+	Sinks []SinkReport `json:"sinks"`
 }
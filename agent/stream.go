@@ -8,38 +8,66 @@ import (
 	"github.com/pkg/errors"
 )
 
+// errRecordHeld signals that a middleware held on to the record
+// and it should not be passed further down the stream.
+var errRecordHeld = errors.New("record is held by middleware")
+
 type streamMiddleware func(src models.Record) (dst models.Record, err error)
+type streamFlusher struct {
+	index int
+	flush func(emit func(models.Record)) error
+}
 type subscriber struct {
-	callback  func([]models.Record) error
-	channel   chan models.Record
+	callback func([]models.Record) error
+	channel  chan models.Record
+	subscription
+}
+
+// subscription holds the settings of a subscriber.
+type subscription struct {
 	batchSize int
+	// match selects the records sent to the subscriber, every record is sent if nil.
+	match recordMatcher
+	// maxInFlight is the number of batches the callback is called with at the same time, defaults to 1.
+	maxInFlight int
 }
 
 type stream struct {
 	middlewares []streamMiddleware
+	flushers    []streamFlusher
 	subscribers []*subscriber
 	onCloses    []func()
+	bufferSize  int
+	mu          sync.Mutex
 	closed      bool
 	err         error
 }
 
-func newStream() *stream {
-	return &stream{}
+// newStream returns a stream whose subscribers are each sent up to bufferSize records ahead of their callback,
+// pushing blocks once a subscriber is that far behind. The batch size of the subscriber is used if bufferSize is not positive.
+func newStream(bufferSize int) *stream {
+	return &stream{bufferSize: bufferSize}
 }
 
-// subscribe() will register callback with a batch size to the emitter.
+// subscribe() will register callback with a batch size to the emitter,
+// only records selected by the match of the subscription are sent to the callback.
 // Calling this will not start listening yet, use broadcast() to start sending data to subscriber.
-func (s *stream) subscribe(callback func(batchedData []models.Record) error, batchSize int) *stream {
+func (s *stream) subscribe(callback func(batchedData []models.Record) error, sub subscription) *stream {
+	bufferSize := s.bufferSize
+	if bufferSize <= 0 {
+		bufferSize = sub.batchSize
+	}
 	s.subscribers = append(s.subscribers, &subscriber{
-		callback:  callback,
-		batchSize: batchSize,
-		channel:   make(chan models.Record),
+		callback:     callback,
+		channel:      make(chan models.Record, bufferSize),
+		subscription: sub,
 	})
 
 	return s
 }
 
-// onClose() is used to register callback for after stream is closed.
+// onClose() is used to register callback for after stream is closed
+// and every subscriber is done with the records it received.
 func (s *stream) onClose(callback func()) *stream {
 	s.onCloses = append(s.onCloses, callback)
 
@@ -60,44 +88,100 @@ func (s *stream) broadcast() error {
 				wg.Done()
 			}()
 
+			send, wait := s.sender(l)
+			defer wait()
+
 			batch := newBatch(l.batchSize)
 			// listen to channel and emit data to subscriber callback if batch is full
 			for d := range l.channel {
+				// records still buffered once the stream failed are dropped
+				if s.failed() {
+					continue
+				}
 				if err := batch.add(d); err != nil {
 					s.closeWithError(err)
 				}
 				if batch.isFull() {
-					if err := l.callback(batch.flush()); err != nil {
-						s.closeWithError(err)
-					}
+					send(batch.flush())
 				}
 			}
 
 			// emit leftover data in the batch if any after channel is closed
 			if !batch.isEmpty() {
-				if err := l.callback(batch.flush()); err != nil {
-					s.closeWithError(err)
-				}
+				send(batch.flush())
 			}
 		}(l)
 	}
 
 	wg.Wait()
 
+	// subscribers are done, it is now safe to run the close callbacks
+	for _, onClose := range s.onCloses {
+		onClose()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	return s.err
 }
 
+// sender returns a function calling the callback of the subscriber with a batch,
+// up to maxInFlight batches are sent at the same time and sending blocks until one of them is done.
+// wait blocks until every batch sent is done.
+func (s *stream) sender(l *subscriber) (send func([]models.Record), wait func()) {
+	if l.maxInFlight <= 1 {
+		send = func(batch []models.Record) {
+			if err := l.callback(batch); err != nil {
+				s.closeWithError(err)
+			}
+		}
+		return send, func() {}
+	}
+
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, l.maxInFlight)
+	send = func(batch []models.Record) {
+		slots <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				if r := recover(); r != nil {
+					s.closeWithError(fmt.Errorf("%s", r))
+				}
+				<-slots
+				wg.Done()
+			}()
+			if err := l.callback(batch); err != nil {
+				s.closeWithError(err)
+			}
+		}()
+	}
+
+	return send, wg.Wait
+}
+
 // push() will run the record through all the registered middleware
 // and emit the record to all registered subscribers.
 func (s *stream) push(data models.Record) {
-	data, err := s.runMiddlewares(data)
+	s.pushFrom(0, data)
+}
+
+// pushFrom() runs the record through the registered middlewares starting at the given index
+// and emits the record to all registered subscribers.
+func (s *stream) pushFrom(start int, data models.Record) {
+	data, err := s.runMiddlewares(start, data)
+	if errors.Is(err, errRecordHeld) {
+		return
+	}
 	if err != nil {
-		s.err = errors.Wrap(err, "emitter: error running middleware")
-		s.Close()
+		s.closeWithError(errors.Wrap(err, "emitter: error running middleware"))
 		return
 	}
 
 	for _, l := range s.subscribers {
+		if l.match != nil && !l.match(data) {
+			continue
+		}
 		l.channel <- data
 	}
 }
@@ -109,13 +193,53 @@ func (s *stream) setMiddleware(m streamMiddleware) *stream {
 	return s
 }
 
+// setBufferedMiddleware registers a middleware that holds on to every record it receives.
+// Held records are passed on to the next middlewares and subscribers when flush() is called.
+func (s *stream) setBufferedMiddleware(m func(src models.Record) error, flush func(emit func(models.Record)) error) *stream {
+	s.flushers = append(s.flushers, streamFlusher{
+		index: len(s.middlewares),
+		flush: flush,
+	})
+	s.middlewares = append(s.middlewares, func(src models.Record) (models.Record, error) {
+		if err := m(src); err != nil {
+			return src, err
+		}
+		return src, errRecordHeld
+	})
+
+	return s
+}
+
+// flush() releases records held by buffered middlewares in the order they were registered,
+// so records released by one buffered middleware can still be held by the next one.
+func (s *stream) flush() error {
+	for _, f := range s.flushers {
+		if s.isClosed() {
+			return nil
+		}
+
+		next := f.index + 1
+		if err := f.flush(func(data models.Record) {
+			s.pushFrom(next, data)
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func (s *stream) closeWithError(err error) {
+	s.mu.Lock()
 	s.err = err
+	s.mu.Unlock()
 	s.Close()
 }
 
 // Close the emitter and signalling all subscriber of the event.
 func (s *stream) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	if s.closed {
 		return
 	}
@@ -124,16 +248,25 @@ func (s *stream) Close() {
 		close(l.channel)
 	}
 	s.closed = true
+}
 
-	for _, onClose := range s.onCloses {
-		onClose()
-	}
+func (s *stream) isClosed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closed
+}
+
+// failed returns true once the stream is closed with an error.
+func (s *stream) failed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err != nil
 }
 
-func (s *stream) runMiddlewares(d models.Record) (res models.Record, err error) {
+func (s *stream) runMiddlewares(start int, d models.Record) (res models.Record, err error) {
 	res = d
-	for _, middleware := range s.middlewares {
-		res, err = middleware(d)
+	for _, middleware := range s.middlewares[start:] {
+		res, err = middleware(res)
 		if err != nil {
 			return
 		}
@@ -66,7 +66,7 @@ func RunCmd(lg log.Logger, mt *metrics.StatsdMonitor, cfg config.Config) *cobra.
 			}
 
 			cs := term.NewColorScheme()
-			runner := agent.NewAgent(agent.Config{
+			agentCfg := agent.Config{
 				ExtractorFactory:     registry.Extractors,
 				ProcessorFactory:     registry.Processors,
 				SinkFactory:          registry.Sinks,
@@ -75,7 +75,13 @@ func RunCmd(lg log.Logger, mt *metrics.StatsdMonitor, cfg config.Config) *cobra.
 				MaxRetries:           cfg.MaxRetries,
 				RetryInitialInterval: time.Duration(cfg.RetryInitialIntervalSeconds) * time.Second,
 				StopOnSinkError:      cfg.StopOnSinkError,
-			})
+				SinkErrorPolicy:      agent.SinkErrorPolicy(cfg.SinkErrorPolicy),
+				Delivery:             agent.Delivery(cfg.SinkDelivery),
+			}
+			if err := agentCfg.Validate(); err != nil {
+				return err
+			}
+			runner := agent.NewAgent(agentCfg)
 
 			// Monitoring system signals and creating context
 			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
@@ -103,6 +109,11 @@ func RunCmd(lg log.Logger, mt *metrics.StatsdMonitor, cfg config.Config) *cobra.
 			runs := runner.RunMultiple(ctx, recipes)
 			for _, run := range runs {
 				lg.Debug("recipe details", "recipe", run.Recipe)
+				for _, sink := range run.Sinks {
+					if len(sink.FailedURNs) > 0 {
+						lg.Debug("records failed by sink", "recipe", run.Recipe.Name, "sink", sink.Name, "urns", sink.FailedURNs)
+					}
+				}
 				var row []string
 				if run.Error != nil {
 					lg.Error(run.Error.Error(), "recipe")
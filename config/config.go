@@ -16,6 +16,10 @@ type Config struct {
 	MaxRetries                  int    `mapstructure:"MAX_RETRIES" default:"5"`
 	RetryInitialIntervalSeconds int    `mapstructure:"RETRY_INITIAL_INTERVAL_SECONDS" default:"5"`
 	StopOnSinkError             bool   `mapstructure:"STOP_ON_SINK_ERROR" default:"false"`
+	// SinkErrorPolicy is one of continue or stop, STOP_ON_SINK_ERROR is used if not set
+	SinkErrorPolicy string `mapstructure:"SINK_ERROR_POLICY"`
+	// SinkDelivery is one of best_effort or all_or_nothing
+	SinkDelivery string `mapstructure:"SINK_DELIVERY" default:"best_effort"`
 }
 
 func Load(configFile string) (cfg Config, err error) {
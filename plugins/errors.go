@@ -57,3 +57,33 @@ func NewRetryError(err error) error {
 	}
 	return RetryError{Err: err}
 }
+
+// HTTPStatusError is returned for HTTP responses with an unexpected status,
+// it is retried if IsRetryableStatus is true for the status.
+type HTTPStatusError struct {
+	StatusCode int
+	Err        error
+}
+
+func (e HTTPStatusError) Error() string {
+	return e.Err.Error()
+}
+
+func (e HTTPStatusError) Unwrap() error {
+	return e.Err
+}
+
+// PartialSinkError is returned by sinks which delivered only some of the records of a batch,
+// FailedURNs are the urns of the records which were not delivered.
+type PartialSinkError struct {
+	FailedURNs []string
+	Err        error
+}
+
+func (e PartialSinkError) Error() string {
+	return e.Err.Error()
+}
+
+func (e PartialSinkError) Unwrap() error {
+	return e.Err
+}
@@ -16,10 +16,10 @@ import (
 
 	"cloud.google.com/go/storage"
 	"github.com/odpf/meteor/plugins"
+	"github.com/odpf/meteor/plugins/gcsutil"
 	"github.com/odpf/meteor/utils"
 	"github.com/odpf/salt/log"
 	"google.golang.org/api/iterator"
-	"google.golang.org/api/option"
 )
 
 //go:embed README.md
@@ -29,9 +29,9 @@ const metadataSource = "googlecloudstorage"
 
 // Config holds the set of configuration for the extractor
 type Config struct {
-	ProjectID          string `mapstructure:"project_id" validate:"required"`
-	ServiceAccountJSON string `mapstructure:"service_account_json"`
-	ExtractBlob        bool   `mapstructure:"extract_blob"`
+	gcsutil.ClientConfig `mapstructure:",squash"`
+	ProjectID            string `mapstructure:"project_id" validate:"required"`
+	ExtractBlob          bool   `mapstructure:"extract_blob"`
 }
 
 var sampleConfig = `
@@ -89,7 +89,7 @@ func (e *Extractor) Init(ctx context.Context, configMap map[string]interface{})
 	}
 
 	// create client
-	e.client, err = e.createClient(ctx)
+	e.client, err = e.config.NewClient(ctx, e.logger)
 	if err != nil {
 		return errors.Wrap(err, "failed to create client")
 	}
@@ -180,15 +180,6 @@ func (e *Extractor) buildBlob(blob *storage.ObjectAttrs, projectID string) *asse
 	}
 }
 
-func (e *Extractor) createClient(ctx context.Context) (*storage.Client, error) {
-	if e.config.ServiceAccountJSON == "" {
-		e.logger.Info("credentials are not specified, creating google cloud storage client using Default Credentials...")
-		return storage.NewClient(ctx)
-	}
-
-	return storage.NewClient(ctx, option.WithCredentialsJSON([]byte(e.config.ServiceAccountJSON)))
-}
-
 // Register the extractor to catalog
 func init() {
 	if err := registry.Extractors.Register("gcs", func() plugins.Extractor {
@@ -0,0 +1,176 @@
+package kafka
+
+import (
+	"context"
+	"time"
+
+	"github.com/odpf/meteor/plugins/kafkautil"
+	"github.com/pkg/errors"
+	kafka "github.com/segmentio/kafka-go"
+)
+
+// Client is the set of broker operations used by the extractor
+type Client interface {
+	Connect(ctx context.Context, broker string, auth kafkautil.AuthConfig) error
+	// ReadPartitions returns the partitions of every topic
+	ReadPartitions(ctx context.Context) ([]kafka.Partition, error)
+	// DescribeTopicConfigs returns the configs of the topics keyed by topic name
+	DescribeTopicConfigs(ctx context.Context, topics []string) (map[string]map[string]string, error)
+	// ListConsumerGroups returns the topics consumed by every consumer group keyed by group id
+	ListConsumerGroups(ctx context.Context) (map[string][]string, error)
+	// ReadMessages returns up to limit latest messages of the first partition of the topic
+	ReadMessages(ctx context.Context, topic string, limit int) ([]kafka.Message, error)
+	Close() error
+}
+
+func newClient() Client {
+	return &client{}
+}
+
+type client struct {
+	broker string
+	dialer *kafka.Dialer
+	conn   *kafka.Conn
+	admin  *kafka.Client
+}
+
+func (c *client) Connect(ctx context.Context, broker string, auth kafkautil.AuthConfig) (err error) {
+	if c.dialer, err = auth.Dialer(); err != nil {
+		return
+	}
+	transport, err := auth.Transport()
+	if err != nil {
+		return
+	}
+
+	if c.conn, err = c.dialer.DialContext(ctx, "tcp", broker); err != nil {
+		return errors.Wrap(err, "failed to dial broker")
+	}
+	c.broker = broker
+	c.admin = &kafka.Client{
+		Addr:      kafka.TCP(broker),
+		Timeout:   10 * time.Second,
+		Transport: transport,
+	}
+
+	return
+}
+
+func (c *client) ReadPartitions(ctx context.Context) ([]kafka.Partition, error) {
+	return c.conn.ReadPartitions()
+}
+
+func (c *client) DescribeTopicConfigs(ctx context.Context, topics []string) (map[string]map[string]string, error) {
+	req := &kafka.DescribeConfigsRequest{}
+	for _, topic := range topics {
+		req.Resources = append(req.Resources, kafka.DescribeConfigRequestResource{
+			ResourceType: kafka.ResourceTypeTopic,
+			ResourceName: topic,
+		})
+	}
+
+	res, err := c.admin.DescribeConfigs(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	configs := make(map[string]map[string]string)
+	for _, resource := range res.Resources {
+		if resource.Error != nil {
+			return nil, errors.Wrapf(resource.Error, "failed to describe configs of topic \"%s\"", resource.ResourceName)
+		}
+		entries := make(map[string]string)
+		for _, entry := range resource.ConfigEntries {
+			entries[entry.ConfigName] = entry.ConfigValue
+		}
+		configs[resource.ResourceName] = entries
+	}
+
+	return configs, nil
+}
+
+func (c *client) ListConsumerGroups(ctx context.Context) (map[string][]string, error) {
+	listRes, err := c.admin.ListGroups(ctx, &kafka.ListGroupsRequest{})
+	if err != nil {
+		return nil, err
+	}
+	if listRes.Error != nil {
+		return nil, listRes.Error
+	}
+	if len(listRes.Groups) == 0 {
+		return nil, nil
+	}
+
+	var groupIDs []string
+	for _, group := range listRes.Groups {
+		groupIDs = append(groupIDs, group.GroupID)
+	}
+	describeRes, err := c.admin.DescribeGroups(ctx, &kafka.DescribeGroupsRequest{GroupIDs: groupIDs})
+	if err != nil {
+		return nil, err
+	}
+
+	groups := make(map[string][]string)
+	for _, group := range describeRes.Groups {
+		if group.Error != nil {
+			return nil, errors.Wrapf(group.Error, "failed to describe consumer group \"%s\"", group.GroupID)
+		}
+		seen := make(map[string]bool)
+		for _, member := range group.Members {
+			for _, assignment := range member.MemberAssignments.Topics {
+				if seen[assignment.Topic] {
+					continue
+				}
+				seen[assignment.Topic] = true
+				groups[group.GroupID] = append(groups[group.GroupID], assignment.Topic)
+			}
+		}
+	}
+
+	return groups, nil
+}
+
+func (c *client) ReadMessages(ctx context.Context, topic string, limit int) (messages []kafka.Message, err error) {
+	conn, err := c.dialer.DialLeader(ctx, "tcp", c.broker, topic, 0)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to dial partition leader")
+	}
+	defer conn.Close()
+
+	first, last, err := conn.ReadOffsets()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read offsets")
+	}
+	start := last - int64(limit)
+	if start < first {
+		start = first
+	}
+	if start >= last {
+		return nil, nil
+	}
+	if _, err = conn.Seek(start, kafka.SeekAbsolute); err != nil {
+		return nil, errors.Wrap(err, "failed to seek offset")
+	}
+
+	if err = conn.SetReadDeadline(time.Now().Add(10 * time.Second)); err != nil {
+		return nil, err
+	}
+	batch := conn.ReadBatch(1, 10e6)
+	defer batch.Close()
+	for i := start; i < last; i++ {
+		msg, err := batch.ReadMessage()
+		if err != nil {
+			break
+		}
+		messages = append(messages, msg)
+	}
+
+	return messages, nil
+}
+
+func (c *client) Close() error {
+	if c.conn == nil {
+		return nil
+	}
+	return c.conn.Close()
+}
@@ -0,0 +1,166 @@
+package kafka_test
+
+import (
+	"context"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	commonv1beta1 "github.com/odpf/meteor/models/odpf/assets/common/v1beta1"
+	facetsv1beta1 "github.com/odpf/meteor/models/odpf/assets/facets/v1beta1"
+	assetsv1beta1 "github.com/odpf/meteor/models/odpf/assets/v1beta1"
+	"github.com/odpf/meteor/plugins/extractors/kafka"
+	"github.com/odpf/meteor/plugins/kafkautil"
+	"github.com/odpf/meteor/test/mocks"
+	testUtils "github.com/odpf/meteor/test/utils"
+	"github.com/odpf/meteor/utils"
+	kafkaLib "github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestExtractWithFakeBroker(t *testing.T) {
+	now := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	protoValue, err := proto.Marshal(&assetsv1beta1.Topic{Resource: &commonv1beta1.Resource{Urn: "urn"}})
+	require.NoError(t, err)
+	framed := append([]byte{0, 0, 0, 0, 7, 0}, protoValue...)
+
+	client := &fakeClient{
+		partitions: []kafkaLib.Partition{
+			{Topic: "orders", ID: 0, Replicas: []kafkaLib.Broker{{ID: 1}, {ID: 2}}},
+			{Topic: "orders", ID: 1, Replicas: []kafkaLib.Broker{{ID: 1}, {ID: 2}}},
+			{Topic: "payments", ID: 0, Replicas: []kafkaLib.Broker{{ID: 1}}},
+			{Topic: "__consumer_offsets", ID: 0},
+		},
+		configs: map[string]map[string]string{
+			"orders": {"retention.ms": "604800000", "cleanup.policy": "delete"},
+		},
+		groups: map[string][]string{
+			"order-service":   {"orders"},
+			"billing-service": {"orders", "payments"},
+		},
+		messages: map[string][]kafkaLib.Message{
+			"orders": {
+				{Value: []byte(`{"id":1,"item":"book","tags":["a"]}`), Time: now},
+				{Value: []byte(`{"id":2,"item":"pen","price":1.5}`), Time: now.Add(2 * time.Second)},
+			},
+			"payments": {
+				{Value: framed, Time: now},
+			},
+		},
+	}
+
+	extr := kafka.New(testUtils.Logger, kafka.WithClient(client))
+	err = extr.Init(context.TODO(), map[string]interface{}{
+		"broker":              "localhost:9092",
+		"label":               "my-kafka",
+		"sample_size":         5,
+		"schema_registry_url": "http://registry:8081/",
+	})
+	require.NoError(t, err)
+
+	emitter := mocks.NewEmitter()
+	require.NoError(t, extr.Extract(context.TODO(), emitter.Push))
+
+	records := emitter.Get()
+	require.Len(t, records, 2)
+	assert.True(t, client.closed)
+
+	orders := records[0].Data().(*assetsv1beta1.Topic)
+	assert.Equal(t, "kafka::my-kafka/orders", orders.Resource.Urn)
+	assert.Equal(t, int64(2), orders.Profile.NumberOfPartitions)
+	assert.Equal(t, "0.50 msg/s", orders.Profile.Throughput)
+	assert.Equal(t, &facetsv1beta1.TopicSchema{Format: "json"}, orders.Schema)
+	assert.Equal(t, []*commonv1beta1.Resource{
+		{Urn: "kafka::my-kafka/consumer_group/billing-service", Name: "billing-service", Service: "kafka", Type: "consumer_group"},
+		{Urn: "kafka::my-kafka/consumer_group/order-service", Name: "order-service", Service: "kafka", Type: "consumer_group"},
+	}, orders.Lineage.Downstreams)
+
+	attributes := utils.GetCustomProperties(orders)
+	assert.EqualValues(t, 2, attributes["replication_factor"])
+	assert.EqualValues(t, 604800000, attributes["retention_ms"])
+	assert.Equal(t, "delete", attributes["cleanup_policy"])
+	assert.Equal(t, map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"id":    map[string]interface{}{"type": "integer"},
+			"item":  map[string]interface{}{"type": "string"},
+			"price": map[string]interface{}{"type": "number"},
+			"tags": map[string]interface{}{
+				"type":  "array",
+				"items": map[string]interface{}{"type": "string"},
+			},
+		},
+	}, attributes["json_schema"])
+
+	payments := records[1].Data().(*assetsv1beta1.Topic)
+	assert.Equal(t, &facetsv1beta1.TopicSchema{
+		Format:    "protobuf",
+		SchemaUrl: "http://registry:8081/schemas/ids/7",
+	}, payments.Schema)
+	assert.EqualValues(t, 7, utils.GetCustomProperties(payments)["schema_id"])
+	assert.EqualValues(t, 1, utils.GetCustomProperties(payments)["replication_factor"])
+}
+
+func TestDetectAvroFraming(t *testing.T) {
+	value := make([]byte, 5)
+	binary.BigEndian.PutUint32(value[1:], 3)
+	value = append(value, 0x02, 0x06, 'f', 'o', 'o')
+
+	client := &fakeClient{
+		partitions: []kafkaLib.Partition{{Topic: "users"}},
+		messages: map[string][]kafkaLib.Message{
+			"users": {{Value: value}},
+		},
+	}
+	extr := kafka.New(testUtils.Logger, kafka.WithClient(client))
+	require.NoError(t, extr.Init(context.TODO(), map[string]interface{}{
+		"broker":      "localhost:9092",
+		"label":       "my-kafka",
+		"sample_size": 1,
+	}))
+
+	emitter := mocks.NewEmitter()
+	require.NoError(t, extr.Extract(context.TODO(), emitter.Push))
+
+	users := emitter.Get()[0].Data().(*assetsv1beta1.Topic)
+	assert.Equal(t, &facetsv1beta1.TopicSchema{Format: "avro"}, users.Schema)
+}
+
+type fakeClient struct {
+	partitions []kafkaLib.Partition
+	configs    map[string]map[string]string
+	groups     map[string][]string
+	messages   map[string][]kafkaLib.Message
+	closed     bool
+}
+
+func (c *fakeClient) Connect(ctx context.Context, broker string, auth kafkautil.AuthConfig) error {
+	return nil
+}
+
+func (c *fakeClient) ReadPartitions(ctx context.Context) ([]kafkaLib.Partition, error) {
+	return c.partitions, nil
+}
+
+func (c *fakeClient) DescribeTopicConfigs(ctx context.Context, topics []string) (map[string]map[string]string, error) {
+	return c.configs, nil
+}
+
+func (c *fakeClient) ListConsumerGroups(ctx context.Context) (map[string][]string, error) {
+	return c.groups, nil
+}
+
+func (c *fakeClient) ReadMessages(ctx context.Context, topic string, limit int) ([]kafkaLib.Message, error) {
+	messages := c.messages[topic]
+	if len(messages) > limit {
+		messages = messages[len(messages)-limit:]
+	}
+	return messages, nil
+}
+
+func (c *fakeClient) Close() error {
+	c.closed = true
+	return nil
+}
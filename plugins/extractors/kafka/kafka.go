@@ -3,14 +3,20 @@ package kafka
 import (
 	"context"
 	_ "embed" // used to print the embedded assets
+	"encoding/json"
 	"fmt"
+	"sort"
+	"strconv"
+	"strings"
 
 	"github.com/pkg/errors"
 
 	"github.com/odpf/meteor/models"
 	commonv1beta1 "github.com/odpf/meteor/models/odpf/assets/common/v1beta1"
+	facetsv1beta1 "github.com/odpf/meteor/models/odpf/assets/facets/v1beta1"
 	assetsv1beta1 "github.com/odpf/meteor/models/odpf/assets/v1beta1"
 	"github.com/odpf/meteor/plugins"
+	"github.com/odpf/meteor/plugins/kafkautil"
 	"github.com/odpf/meteor/registry"
 	kafka "github.com/segmentio/kafka-go"
 
@@ -27,30 +33,69 @@ var defaultTopics = map[string]byte{
 	"_schemas":           0,
 }
 
+// topic configs added to the properties attributes, numeric values are parsed as integers
+var topicConfigs = map[string]string{
+	"retention.ms":        "retention_ms",
+	"retention.bytes":     "retention_bytes",
+	"cleanup.policy":      "cleanup_policy",
+	"min.insync.replicas": "min_insync_replicas",
+}
+
 // Config holds the set of configuration for the kafka extractor
 type Config struct {
-	Broker string `mapstructure:"broker" validate:"required"`
-	Label  string `mapstructure:"label" validate:"required"`
+	Broker               string `mapstructure:"broker" validate:"required"`
+	Label                string `mapstructure:"label" validate:"required"`
+	SampleSize           int    `mapstructure:"sample_size" validate:"min=0" default:"0"`
+	SchemaRegistryURL    string `mapstructure:"schema_registry_url"`
+	kafkautil.AuthConfig `mapstructure:",squash"`
 }
 
 var sampleConfig = `
 broker: "localhost:9092"
-label: "my-kafka"`
+label: "my-kafka"
+# Number of latest messages read from every topic to detect its format, 0 disables sampling
+sample_size: 10
+# Schema registry of framed messages, used to build the schema url
+schema_registry_url: http://localhost:8081
+# SASL authentication, mechanism is one of PLAIN, SCRAM-SHA-256 or SCRAM-SHA-512
+sasl:
+  mechanism: PLAIN
+  username: meteor
+  password: secret
+# TLS connection to the broker
+tls:
+  enabled: true
+  ca_file: /etc/meteor/ca.pem`
+
+// Option provides extension abstraction to Extractor constructor
+type Option func(*Extractor)
+
+// WithClient assign custom client to the Extractor constructor
+func WithClient(client Client) Option {
+	return func(e *Extractor) {
+		e.client = client
+	}
+}
 
 // Extractor manages the extraction of data
 // from a kafka broker
 type Extractor struct {
 	// internal states
-	conn   *kafka.Conn
+	client Client
 	logger log.Logger
 	config Config
 }
 
 // New returns a pointer to an initialized Extractor Object
-func New(logger log.Logger) *Extractor {
-	return &Extractor{
+func New(logger log.Logger, opts ...Option) *Extractor {
+	e := &Extractor{
 		logger: logger,
 	}
+	for _, opt := range opts {
+		opt(e)
+	}
+
+	return e
 }
 
 // Info returns the brief information about the extractor
@@ -75,9 +120,12 @@ func (e *Extractor) Init(ctx context.Context, configMap map[string]interface{})
 		return plugins.InvalidConfigError{}
 	}
 
+	if e.client == nil {
+		e.client = newClient()
+	}
+
 	// create connection
-	e.conn, err = kafka.Dial("tcp", e.config.Broker)
-	if err != nil {
+	if err = e.client.Connect(ctx, e.config.Broker, e.config.AuthConfig); err != nil {
 		return errors.Wrap(err, "failed to create connection")
 	}
 
@@ -87,37 +135,190 @@ func (e *Extractor) Init(ctx context.Context, configMap map[string]interface{})
 // Extract checks if the extractor is ready to extract
 // if so, then extracts metadata from the kafka broker
 func (e *Extractor) Extract(ctx context.Context, emit plugins.Emit) (err error) {
-	defer e.conn.Close()
+	defer e.client.Close()
 
-	partitions, err := e.conn.ReadPartitions()
+	partitions, err := e.client.ReadPartitions(ctx)
 	if err != nil {
 		return errors.Wrap(err, "failed to fetch partitions")
 	}
 
 	// collect topic list from partition list
 	topics := map[string]int{}
+	replicationFactors := map[string]int{}
 	for _, p := range partitions {
-		_, ok := topics[p.Topic]
-		if !ok {
-			topics[p.Topic] = 0
+		// skip if topic is a default topic
+		if _, isDefaultTopic := defaultTopics[p.Topic]; isDefaultTopic {
+			continue
 		}
 
 		topics[p.Topic]++
+		if len(p.Replicas) > replicationFactors[p.Topic] {
+			replicationFactors[p.Topic] = len(p.Replicas)
+		}
+	}
+
+	topicNames := make([]string, 0, len(topics))
+	for topic := range topics {
+		topicNames = append(topicNames, topic)
+	}
+	sort.Strings(topicNames)
+
+	configs, err := e.client.DescribeTopicConfigs(ctx, topicNames)
+	if err != nil {
+		e.logger.Warn("failed to describe topic configs", "error", err)
+	}
+	consumers, err := e.consumerGroupsByTopic(ctx)
+	if err != nil {
+		e.logger.Warn("failed to list consumer groups", "error", err)
 	}
 
 	// build and push topics
-	for topic, numOfPartitions := range topics {
-		// skip if topic is a default topic
-		_, isDefaultTopic := defaultTopics[topic]
-		if isDefaultTopic {
+	for _, topic := range topicNames {
+		asset := e.buildTopic(topic, topics[topic])
+
+		attributes := buildAttributes(configs[topic], replicationFactors[topic])
+		if _, err = utils.SetCustomProperties(asset, attributes); err != nil {
+			return errors.Wrapf(err, "failed to set properties of topic \"%s\"", topic)
+		}
+		if groups := consumers[topic]; len(groups) > 0 {
+			asset.Lineage = &facetsv1beta1.Lineage{
+				Downstreams: e.buildConsumerGroups(groups),
+			}
+		}
+		if e.config.SampleSize > 0 {
+			if err = e.sample(ctx, asset); err != nil {
+				e.logger.Warn("failed to sample topic", "topic", topic, "error", err)
+			}
+		}
+
+		emit(models.NewRecord(asset))
+	}
+
+	return nil
+}
+
+// consumerGroupsByTopic returns the ids of the consumer groups consuming every topic
+func (e *Extractor) consumerGroupsByTopic(ctx context.Context) (map[string][]string, error) {
+	groups, err := e.client.ListConsumerGroups(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	consumers := make(map[string][]string)
+	for group, topics := range groups {
+		for _, topic := range topics {
+			consumers[topic] = append(consumers[topic], group)
+		}
+	}
+	for topic := range consumers {
+		sort.Strings(consumers[topic])
+	}
+
+	return consumers, nil
+}
+
+// sample reads the latest messages of the topic to detect their format and infer a JSON schema
+func (e *Extractor) sample(ctx context.Context, asset *assetsv1beta1.Topic) error {
+	messages, err := e.client.ReadMessages(ctx, asset.Resource.Name, e.config.SampleSize)
+	if err != nil {
+		return err
+	}
+	if len(messages) == 0 {
+		return nil
+	}
+
+	var (
+		detected   sampleFormat
+		jsonSample = newJSONSchema()
+	)
+	for _, msg := range messages {
+		f := detectFormat(msg.Value)
+		if f.format == "" {
 			continue
 		}
+		if detected.format == "" {
+			detected = f
+		}
+		if f.format != detected.format {
+			continue
+		}
+		if f.format == formatJSON {
+			var value interface{}
+			if err := json.Unmarshal(f.payload, &value); err == nil {
+				jsonSample.add(value)
+			}
+		}
+	}
 
-		record := models.NewRecord(e.buildTopic(topic, numOfPartitions))
-		emit(record)
+	if throughput := buildThroughput(messages); throughput != "" {
+		asset.Profile.Throughput = throughput
+	}
+	if detected.format == "" {
+		return nil
 	}
 
-	return
+	asset.Schema = &facetsv1beta1.TopicSchema{Format: detected.format}
+	// the topic attributes are already set, the schema keys are merged into them
+	attributes := utils.GetCustomProperties(asset)
+	if detected.schemaID >= 0 {
+		attributes["schema_id"] = detected.schemaID
+		if e.config.SchemaRegistryURL != "" {
+			asset.Schema.SchemaUrl = fmt.Sprintf("%s/schemas/ids/%d", strings.TrimSuffix(e.config.SchemaRegistryURL, "/"), detected.schemaID)
+		}
+	}
+	if detected.format == formatJSON {
+		attributes["json_schema"] = jsonSample.toMap()
+	}
+	_, err = utils.SetCustomProperties(asset, attributes)
+
+	return err
+}
+
+// buildThroughput returns the number of messages per second of the sampled messages
+func buildThroughput(messages []kafka.Message) string {
+	if len(messages) < 2 {
+		return ""
+	}
+
+	elapsed := messages[len(messages)-1].Time.Sub(messages[0].Time).Seconds()
+	if elapsed <= 0 {
+		return ""
+	}
+
+	return fmt.Sprintf("%.2f msg/s", float64(len(messages)-1)/elapsed)
+}
+
+func buildAttributes(configs map[string]string, replicationFactor int) map[string]interface{} {
+	attributes := map[string]interface{}{
+		"replication_factor": replicationFactor,
+	}
+	for configName, attrName := range topicConfigs {
+		value, ok := configs[configName]
+		if !ok {
+			continue
+		}
+		if i, err := strconv.ParseInt(value, 10, 64); err == nil {
+			attributes[attrName] = i
+			continue
+		}
+		attributes[attrName] = value
+	}
+
+	return attributes
+}
+
+func (e *Extractor) buildConsumerGroups(groups []string) []*commonv1beta1.Resource {
+	resources := make([]*commonv1beta1.Resource, 0, len(groups))
+	for _, group := range groups {
+		resources = append(resources, &commonv1beta1.Resource{
+			Urn:     fmt.Sprintf("kafka::%s/consumer_group/%s", e.config.Label, group),
+			Name:    group,
+			Service: "kafka",
+			Type:    "consumer_group",
+		})
+	}
+
+	return resources
 }
 
 // Build topic metadata model using a topic and number of partitions
@@ -10,6 +10,7 @@ import (
 	"net"
 
 	"github.com/odpf/meteor/test/utils"
+	metautils "github.com/odpf/meteor/utils"
 
 	"os"
 	"strconv"
@@ -204,7 +205,13 @@ func assertResults(t *testing.T, expected []models.Record, result []models.Recor
 	for _, record := range result {
 		topic := record.Data().(*assetsv1beta1.Topic)
 		assert.Contains(t, expectedMap, topic.Resource.Urn)
-		assert.Equal(t, expectedMap[topic.Resource.Urn], topic)
+		assert.Equal(t, expectedMap[topic.Resource.Urn].Resource, topic.Resource)
+		assert.Equal(t, expectedMap[topic.Resource.Urn].Profile, topic.Profile)
+
+		// topic configs are described into properties
+		attributes := metautils.GetCustomProperties(topic)
+		assert.EqualValues(t, 1, attributes["replication_factor"])
+		assert.Equal(t, "delete", attributes["cleanup_policy"])
 
 		// delete entry to make sure there is no duplicate
 		delete(expectedMap, topic.Resource.Urn)
@@ -0,0 +1,186 @@
+package kafka
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"sort"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Formats detected from sampled messages
+const (
+	formatJSON     = "json"
+	formatProtobuf = "protobuf"
+	formatAvro     = "avro"
+)
+
+var (
+	avroSingleObjectMagic = []byte{0xC3, 0x01}
+	avroContainerMagic    = []byte{'O', 'b', 'j', 0x01}
+)
+
+// sampleFormat is the format detected for a single message value
+type sampleFormat struct {
+	format string
+	// schemaID is the schema registry id of framed values, -1 if not framed
+	schemaID int
+	// payload is the value without the schema registry framing
+	payload []byte
+}
+
+// detectFormat detects the format of a message value,
+// an empty format is returned if the value is not recognized
+func detectFormat(value []byte) sampleFormat {
+	if len(value) > 5 && value[0] == 0 {
+		schemaID := int(binary.BigEndian.Uint32(value[1:5]))
+		payload := value[5:]
+		if isJSONObject(payload) {
+			return sampleFormat{format: formatJSON, schemaID: schemaID, payload: payload}
+		}
+		if msg, ok := consumeMessageIndexes(payload); ok && isProtobuf(msg) {
+			return sampleFormat{format: formatProtobuf, schemaID: schemaID, payload: msg}
+		}
+		return sampleFormat{format: formatAvro, schemaID: schemaID, payload: payload}
+	}
+
+	switch {
+	case bytes.HasPrefix(value, avroSingleObjectMagic), bytes.HasPrefix(value, avroContainerMagic):
+		return sampleFormat{format: formatAvro, schemaID: -1, payload: value}
+	case isJSONObject(value):
+		return sampleFormat{format: formatJSON, schemaID: -1, payload: value}
+	case isProtobuf(value):
+		return sampleFormat{format: formatProtobuf, schemaID: -1, payload: value}
+	}
+
+	return sampleFormat{schemaID: -1}
+}
+
+func isJSONObject(value []byte) bool {
+	value = bytes.TrimSpace(value)
+	return len(value) > 0 && value[0] == '{' && json.Valid(value)
+}
+
+// consumeMessageIndexes strips the message indexes that follow the schema id of framed protobuf values
+func consumeMessageIndexes(value []byte) ([]byte, bool) {
+	count, n := protowire.ConsumeVarint(value)
+	if n < 0 {
+		return nil, false
+	}
+	value = value[n:]
+	for i := int64(0); i < protowire.DecodeZigZag(count); i++ {
+		if _, n = protowire.ConsumeVarint(value); n < 0 {
+			return nil, false
+		}
+		value = value[n:]
+	}
+
+	return value, true
+}
+
+// isProtobuf returns true if the value can be entirely read as protobuf fields
+func isProtobuf(value []byte) bool {
+	if len(value) == 0 {
+		return false
+	}
+	for len(value) > 0 {
+		num, typ, n := protowire.ConsumeTag(value)
+		if n < 0 || num <= 0 || typ == protowire.StartGroupType || typ == protowire.EndGroupType {
+			return false
+		}
+		value = value[n:]
+		if n = protowire.ConsumeFieldValue(num, typ, value); n < 0 {
+			return false
+		}
+		value = value[n:]
+	}
+
+	return true
+}
+
+// jsonSchema infers a JSON schema from sampled JSON values
+type jsonSchema struct {
+	types      map[string]bool
+	properties map[string]*jsonSchema
+	items      *jsonSchema
+}
+
+func newJSONSchema() *jsonSchema {
+	return &jsonSchema{types: make(map[string]bool)}
+}
+
+// add merges the value into the schema
+func (s *jsonSchema) add(value interface{}) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		s.types["object"] = true
+		if s.properties == nil {
+			s.properties = make(map[string]*jsonSchema)
+		}
+		for key, val := range v {
+			prop, ok := s.properties[key]
+			if !ok {
+				prop = newJSONSchema()
+				s.properties[key] = prop
+			}
+			prop.add(val)
+		}
+	case []interface{}:
+		s.types["array"] = true
+		if s.items == nil {
+			s.items = newJSONSchema()
+		}
+		for _, item := range v {
+			s.items.add(item)
+		}
+	case string:
+		s.types["string"] = true
+	case float64:
+		if v == float64(int64(v)) {
+			s.types["integer"] = true
+		} else {
+			s.types["number"] = true
+		}
+	case bool:
+		s.types["boolean"] = true
+	case nil:
+		s.types["null"] = true
+	}
+}
+
+// toMap renders the schema as a JSON schema document
+func (s *jsonSchema) toMap() map[string]interface{} {
+	if s.types["integer"] && s.types["number"] {
+		delete(s.types, "integer")
+	}
+
+	var types []string
+	for t := range s.types {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+
+	schema := make(map[string]interface{})
+	if len(types) == 1 {
+		schema["type"] = types[0]
+	} else if len(types) > 1 {
+		typeList := make([]interface{}, len(types))
+		for i, t := range types {
+			typeList[i] = t
+		}
+		schema["type"] = typeList
+	}
+	if s.properties != nil {
+		properties := make(map[string]interface{})
+		for key, prop := range s.properties {
+			properties[key] = prop.toMap()
+		}
+		schema["properties"] = properties
+	}
+	if s.items != nil && len(s.items.types) > 0 {
+		schema["items"] = s.items.toMap()
+	}
+
+	return schema
+}
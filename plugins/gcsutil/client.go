@@ -0,0 +1,36 @@
+package gcsutil
+
+import (
+	"context"
+
+	"cloud.google.com/go/storage"
+	"github.com/odpf/salt/log"
+	"google.golang.org/api/option"
+)
+
+// ClientConfig holds the settings to connect to google cloud storage
+type ClientConfig struct {
+	ServiceAccountJSON string `mapstructure:"service_account_json"`
+	// Endpoint overrides the url of the storage api, e.g. http://localhost:4443/storage/v1/ for a fake server
+	Endpoint string `mapstructure:"endpoint"`
+}
+
+// NewClient returns a client authenticated with the service account, or the default credentials if none is set.
+// Requests to an overridden endpoint are not authenticated unless a service account is set.
+func (c ClientConfig) NewClient(ctx context.Context, logger log.Logger) (*storage.Client, error) {
+	var opts []option.ClientOption
+	if c.Endpoint != "" {
+		opts = append(opts, option.WithEndpoint(c.Endpoint))
+	}
+
+	switch {
+	case c.ServiceAccountJSON != "":
+		opts = append(opts, option.WithCredentialsJSON([]byte(c.ServiceAccountJSON)))
+	case c.Endpoint != "":
+		opts = append(opts, option.WithoutAuthentication())
+	default:
+		logger.Info("credentials are not specified, creating google cloud storage client using Default Credentials...")
+	}
+
+	return storage.NewClient(ctx, opts...)
+}
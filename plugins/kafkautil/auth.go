@@ -0,0 +1,90 @@
+package kafkautil
+
+import (
+	"crypto/tls"
+	"time"
+
+	"github.com/odpf/meteor/utils"
+	"github.com/pkg/errors"
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/sasl"
+	"github.com/segmentio/kafka-go/sasl/plain"
+	"github.com/segmentio/kafka-go/sasl/scram"
+)
+
+// SASL mechanisms supported to authenticate against brokers
+const (
+	MechanismPlain       = "PLAIN"
+	MechanismScramSHA256 = "SCRAM-SHA-256"
+	MechanismScramSHA512 = "SCRAM-SHA-512"
+)
+
+// SASLConfig holds the SASL settings to authenticate against brokers
+type SASLConfig struct {
+	Mechanism string `mapstructure:"mechanism" validate:"omitempty,oneof=PLAIN SCRAM-SHA-256 SCRAM-SHA-512"`
+	Username  string `mapstructure:"username" validate:"required_with=Mechanism"`
+	Password  string `mapstructure:"password" validate:"required_with=Mechanism"`
+}
+
+// Build returns the sasl.Mechanism, nil is returned if no mechanism is configured
+func (c SASLConfig) Build() (sasl.Mechanism, error) {
+	switch c.Mechanism {
+	case "":
+		return nil, nil
+	case MechanismPlain:
+		return plain.Mechanism{Username: c.Username, Password: c.Password}, nil
+	case MechanismScramSHA256:
+		return scram.Mechanism(scram.SHA256, c.Username, c.Password)
+	case MechanismScramSHA512:
+		return scram.Mechanism(scram.SHA512, c.Username, c.Password)
+	}
+
+	return nil, errors.Errorf("unsupported sasl mechanism \"%s\"", c.Mechanism)
+}
+
+// AuthConfig holds the settings to connect to secured brokers
+type AuthConfig struct {
+	SASL SASLConfig      `mapstructure:"sasl"`
+	TLS  utils.TLSConfig `mapstructure:"tls"`
+}
+
+// Transport returns the transport used by writers to connect to the brokers
+func (c AuthConfig) Transport() (*kafka.Transport, error) {
+	mechanism, tlsCfg, err := c.build()
+	if err != nil {
+		return nil, err
+	}
+
+	return &kafka.Transport{
+		SASL: mechanism,
+		TLS:  tlsCfg,
+	}, nil
+}
+
+// Dialer returns the dialer used by connections to the brokers
+func (c AuthConfig) Dialer() (*kafka.Dialer, error) {
+	mechanism, tlsCfg, err := c.build()
+	if err != nil {
+		return nil, err
+	}
+
+	return &kafka.Dialer{
+		Timeout:       10 * time.Second,
+		DualStack:     true,
+		SASLMechanism: mechanism,
+		TLS:           tlsCfg,
+	}, nil
+}
+
+func (c AuthConfig) build() (sasl.Mechanism, *tls.Config, error) {
+	mechanism, err := c.SASL.Build()
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "invalid sasl config")
+	}
+	tlsCfg, err := c.TLS.Build()
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "invalid tls config")
+	}
+
+	return mechanism, tlsCfg, nil
+}
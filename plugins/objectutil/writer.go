@@ -0,0 +1,272 @@
+package objectutil
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/odpf/meteor/models"
+	"github.com/odpf/meteor/plugins"
+	"github.com/odpf/meteor/plugins/sinks/parquet"
+	"github.com/odpf/meteor/plugins/tabular"
+	"github.com/odpf/meteor/utils"
+	"github.com/odpf/salt/log"
+	"github.com/pkg/errors"
+	ndjson "github.com/scizorman/go-ndjson"
+)
+
+// Formats and compressions of objects
+const (
+	FormatNDJSON  = "ndjson"
+	FormatParquet = "parquet"
+
+	CompressionNone = "none"
+	CompressionGzip = "gzip"
+)
+
+var extensions = map[string]string{
+	FormatNDJSON:  ".ndjson",
+	FormatParquet: ".parquet",
+}
+
+var contentTypes = map[string]string{
+	FormatNDJSON:  "application/x-ndjson",
+	FormatParquet: "application/octet-stream",
+}
+
+// Config holds the encoding of the objects shared by the object storage sinks
+type Config struct {
+	Format      string `mapstructure:"format" validate:"oneof=ndjson parquet" default:"ndjson"`
+	Compression string `mapstructure:"compression" validate:"oneof=none gzip" default:"none"`
+}
+
+// Validate returns an error if the compression does not apply to the format
+func (c Config) Validate() error {
+	if c.Format == FormatParquet && c.Compression != CompressionNone {
+		return errors.New("compression is not supported for parquet")
+	}
+
+	return nil
+}
+
+// NameTemplate returns the template of the object names, {{ .Recipe }}/{{ .Date }}/{{ .Type }} with the extension
+// of the format if name is empty, ".gz" is appended to the names of compressed objects
+func (c Config) NameTemplate(name string) string {
+	if name == "" {
+		name = "{{ .Recipe }}/{{ .Date }}/{{ .Type }}" + extensions[c.Format]
+	}
+	if c.Compression == CompressionGzip && !strings.HasSuffix(name, ".gz") {
+		name += ".gz"
+	}
+
+	return name
+}
+
+// ContentType returns the content type of the objects of the format
+func (c Config) ContentType() string {
+	return contentTypes[c.Format]
+}
+
+// ContentEncoding returns the content encoding of the objects, empty if they are not compressed
+func (c Config) ContentEncoding() string {
+	if c.Compression == CompressionGzip {
+		return "gzip"
+	}
+	return ""
+}
+
+// nameData is the data available to the name templates
+type nameData struct {
+	Recipe string
+	Type   string
+	Date   string
+	Time   time.Time
+}
+
+// Render executes a name template with .Recipe, .Type, .Date and .Time of the run, the leading slash is trimmed
+func Render(tmpl *template.Template, run plugins.RunInfo, assetType string) (string, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, nameData{
+		Recipe: run.RecipeName,
+		Type:   assetType,
+		Date:   run.StartedAt.Format("2006-01-02"),
+		Time:   run.StartedAt,
+	}); err != nil {
+		return "", err
+	}
+
+	return strings.TrimPrefix(buf.String(), "/"), nil
+}
+
+// Opener opens the object of the name for its records of the asset type, the object is complete once closed
+type Opener func(name, assetType string) (io.WriteCloser, error)
+
+// Object is an object written by a run
+type Object struct {
+	Name    string
+	Records int
+	// Size is the size in bytes of the object once encoded and compressed
+	Size int64
+}
+
+// object is an object being written, rows of parquet objects are buffered until the run is done
+type object struct {
+	Object
+	writer     io.WriteCloser
+	counter    *counter
+	w          io.Writer
+	compressor io.WriteCloser
+	table      tabular.Table
+	rows       []tabular.Row
+}
+
+// Writer writes the records of a run to an object per name rendered from the record,
+// as ndjson optionally compressed, or as a parquet table per asset type
+type Writer struct {
+	config   Config
+	logger   log.Logger
+	run      plugins.RunInfo
+	nameTmpl *template.Template
+	encoder  tabular.Encoder
+	open     Opener
+	objects  map[string]*object
+}
+
+// NewWriter returns a writer of the objects of the run named by nameTmpl and opened with open
+func NewWriter(config Config, nameTmpl *template.Template, run plugins.RunInfo, open Opener, logger log.Logger) *Writer {
+	w := &Writer{
+		config:   config,
+		logger:   logger,
+		run:      run,
+		nameTmpl: nameTmpl,
+		open:     open,
+		objects:  make(map[string]*object),
+	}
+	if config.Format == FormatParquet {
+		w.encoder = parquet.NewEncoder()
+	}
+
+	return w
+}
+
+// Write writes the records to the objects of their names, the objects are opened by their first record.
+// Records of types without a table are skipped for parquet.
+func (w *Writer) Write(batch []models.Record) (err error) {
+	for _, record := range batch {
+		data := record.Data()
+		assetType := utils.GetAssetType(data)
+
+		var table tabular.Table
+		if w.encoder != nil {
+			var ok bool
+			if table, ok = tabular.AssetTable(assetType); !ok {
+				w.logger.Warn("skipping asset of unsupported type", "urn", data.GetResource().GetUrn(), "type", assetType)
+				continue
+			}
+		}
+
+		name, err := Render(w.nameTmpl, w.run, assetType)
+		if err != nil {
+			return errors.Wrap(err, "failed to build name")
+		}
+		o, ok := w.objects[name]
+		if !ok {
+			if o, err = w.newObject(name, assetType); err != nil {
+				return err
+			}
+			o.table = table
+			w.objects[name] = o
+		}
+		if o.table.Name != table.Name {
+			return errors.Errorf("tables \"%s\" and \"%s\" have the same name \"%s\", parquet names should contain the type", o.table.Name, table.Name, name)
+		}
+
+		o.Records++
+		if w.encoder != nil {
+			o.rows = append(o.rows, tabular.AssetRow(data))
+			continue
+		}
+		b, err := ndjson.Marshal([]models.Metadata{data})
+		if err != nil {
+			return errors.Wrap(err, "failed to marshal record")
+		}
+		if _, err = o.w.Write(b); err != nil {
+			return errors.Wrapf(err, "failed to write object \"%s\"", name)
+		}
+	}
+
+	return nil
+}
+
+// Close completes the objects in the order of their names, the objects are closed even if one fails
+// and the first error is returned along with the objects which were completed
+func (w *Writer) Close() (objects []Object, err error) {
+	names := make([]string, 0, len(w.objects))
+	for name := range w.objects {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		o := w.objects[name]
+		if closeErr := w.closeObject(o); closeErr != nil {
+			if err == nil {
+				err = errors.Wrapf(closeErr, "failed to write object \"%s\"", name)
+			}
+			continue
+		}
+		objects = append(objects, o.Object)
+	}
+	w.objects = nil
+
+	return objects, err
+}
+
+func (w *Writer) newObject(name, assetType string) (*object, error) {
+	writer, err := w.open(name, assetType)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to open object \"%s\"", name)
+	}
+
+	o := &object{Object: Object{Name: name}, writer: writer, counter: &counter{w: writer}}
+	o.w = o.counter
+	if w.config.Compression == CompressionGzip {
+		o.compressor = gzip.NewWriter(o.counter)
+		o.w = o.compressor
+	}
+
+	return o, nil
+}
+
+func (w *Writer) closeObject(o *object) error {
+	if w.encoder != nil {
+		if err := w.encoder.Encode(o.w, o.table, o.rows); err != nil {
+			return errors.Wrap(err, "failed to encode rows")
+		}
+		o.rows = nil
+	}
+	if o.compressor != nil {
+		if err := o.compressor.Close(); err != nil {
+			return errors.Wrap(err, "failed to close compressor")
+		}
+	}
+	o.Size = o.counter.n
+
+	return o.writer.Close()
+}
+
+// counter counts the bytes written to w
+type counter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *counter) Write(b []byte) (int, error) {
+	n, err := c.w.Write(b)
+	c.n += int64(n)
+	return n, err
+}
@@ -51,6 +51,21 @@ type Processor interface {
 	Process(ctx context.Context, src models.Record) (dst models.Record, err error)
 }
 
+// BufferedProcessor is a processor that needs every record of a run before releasing them.
+// Records passed to Process are held back, Flush is called once the extractor is done
+// and the emitted records are passed on to the next processors and sinks.
+type BufferedProcessor interface {
+	Processor
+	Flush(ctx context.Context, emit Emit) error
+}
+
+// CommittingProcessor is a processor keeping state across runs, such as the last seen version of records.
+// Commit is called once the run succeeded, so the state only moves forward with runs whose records were delivered.
+type CommittingProcessor interface {
+	Processor
+	Commit(ctx context.Context) error
+}
+
 // Syncer is a plugin that can be used to sync data from one source to another.
 type Syncer interface {
 	Plugin
@@ -0,0 +1,303 @@
+package dedupe
+
+import (
+	"context"
+	_ "embed"
+	"reflect"
+
+	"github.com/odpf/meteor/models"
+	commonv1beta1 "github.com/odpf/meteor/models/odpf/assets/common/v1beta1"
+	facetsv1beta1 "github.com/odpf/meteor/models/odpf/assets/facets/v1beta1"
+	"github.com/odpf/meteor/plugins"
+	"github.com/odpf/meteor/registry"
+	"github.com/odpf/meteor/utils"
+	"github.com/odpf/salt/log"
+)
+
+//go:embed README.md
+var summary string
+
+// Merge strategies
+const (
+	StrategyFirstWins = "first_wins"
+	StrategyLastWins  = "last_wins"
+	StrategyUnion     = "union"
+	StrategyDeepMerge = "deep_merge"
+)
+
+// Config holds the set of configuration for the processor
+type Config struct {
+	Strategy   string `mapstructure:"strategy" validate:"oneof=first_wins last_wins" default:"first_wins"`
+	Tags       string `mapstructure:"tags" validate:"oneof=first_wins last_wins union" default:"union"`
+	Labels     string `mapstructure:"labels" validate:"oneof=first_wins last_wins union" default:"union"`
+	Attributes string `mapstructure:"attributes" validate:"oneof=first_wins last_wins deep_merge" default:"deep_merge"`
+	Lineage    string `mapstructure:"lineage" validate:"oneof=first_wins last_wins union" default:"union"`
+	Ownership  string `mapstructure:"ownership" validate:"oneof=first_wins last_wins union" default:"union"`
+}
+
+var sampleConfig = `
+# Which record to keep for fields without a dedicated strategy, first_wins or last_wins
+strategy: first_wins
+# Strategy for properties tags, first_wins, last_wins or union
+tags: union
+# Strategy for properties labels, first_wins, last_wins or union
+labels: union
+# Strategy for properties attributes, first_wins, last_wins or deep_merge
+attributes: deep_merge
+# Strategy for upstreams and downstreams, first_wins, last_wins or union
+lineage: union
+# Strategy for owners, first_wins, last_wins or union
+ownership: union`
+
+// Processor merges records sharing the same URN within a run
+type Processor struct {
+	config  Config
+	logger  log.Logger
+	entries []models.Metadata
+	index   map[string]int
+}
+
+// New create a new processor
+func New(logger log.Logger) *Processor {
+	return &Processor{
+		logger: logger,
+		index:  make(map[string]int),
+	}
+}
+
+// Info returns the plugin information
+func (p *Processor) Info() plugins.Info {
+	return plugins.Info{
+		Description:  "Merge records sharing the same URN into a single record",
+		SampleConfig: sampleConfig,
+		Summary:      summary,
+		Tags:         []string{"processor", "transform"},
+	}
+}
+
+// Validate validates the plugin configuration
+func (p *Processor) Validate(configMap map[string]interface{}) (err error) {
+	return utils.BuildConfig(configMap, &Config{})
+}
+
+// Init initializes the processor
+func (p *Processor) Init(ctx context.Context, configMap map[string]interface{}) (err error) {
+	if err = utils.BuildConfig(configMap, &p.config); err != nil {
+		return plugins.InvalidConfigError{Type: plugins.PluginTypeProcessor}
+	}
+
+	return
+}
+
+// Process holds the record and merges it with previous records of the same URN
+func (p *Processor) Process(ctx context.Context, src models.Record) (dst models.Record, err error) {
+	data := src.Data()
+	urn := data.GetResource().GetUrn()
+	if urn == "" {
+		p.entries = append(p.entries, data)
+		return src, nil
+	}
+
+	i, ok := p.index[urn]
+	if !ok {
+		p.index[urn] = len(p.entries)
+		p.entries = append(p.entries, data)
+		return src, nil
+	}
+
+	p.logger.Debug("merging duplicate record", "record", urn)
+	p.entries[i] = p.merge(p.entries[i], data)
+
+	return src, nil
+}
+
+// Flush emits a single consolidated record per URN
+func (p *Processor) Flush(ctx context.Context, emit plugins.Emit) error {
+	for _, data := range p.entries {
+		emit(models.NewRecord(data))
+	}
+	p.entries = nil
+	p.index = make(map[string]int)
+
+	return nil
+}
+
+// merge consolidates the incoming record into the existing one
+func (p *Processor) merge(existing, incoming models.Metadata) models.Metadata {
+	base := existing
+	if p.config.Strategy == StrategyLastWins {
+		base = incoming
+	}
+	if reflect.TypeOf(existing) != reflect.TypeOf(incoming) {
+		p.logger.Warn("cannot merge records of different types", "record", existing.GetResource().GetUrn())
+		return base
+	}
+
+	utils.SetProperties(base, p.mergeProperties(existing.GetProperties(), incoming.GetProperties()))
+	utils.SetLineage(base, p.mergeLineage(utils.GetLineage(existing), utils.GetLineage(incoming)))
+	utils.SetOwnership(base, p.mergeOwnership(utils.GetOwnership(existing), utils.GetOwnership(incoming)))
+
+	return base
+}
+
+func (p *Processor) mergeProperties(first, last *facetsv1beta1.Properties) *facetsv1beta1.Properties {
+	if first == nil && last == nil {
+		return nil
+	}
+
+	result := &facetsv1beta1.Properties{}
+	switch p.config.Tags {
+	case StrategyUnion:
+		result.Tags = unionStrings(first.GetTags(), last.GetTags())
+	case StrategyLastWins:
+		result.Tags = last.GetTags()
+	default:
+		result.Tags = first.GetTags()
+	}
+
+	switch p.config.Labels {
+	case StrategyUnion:
+		result.Labels = p.unionLabels(first.GetLabels(), last.GetLabels())
+	case StrategyLastWins:
+		result.Labels = last.GetLabels()
+	default:
+		result.Labels = first.GetLabels()
+	}
+
+	switch p.config.Attributes {
+	case StrategyDeepMerge:
+		attributes := deepMerge(first.GetAttributes().AsMap(), last.GetAttributes().AsMap(), p.config.Strategy == StrategyLastWins)
+		if len(attributes) > 0 {
+			result.Attributes = utils.TryParseMapToProto(attributes)
+		}
+	case StrategyLastWins:
+		result.Attributes = last.GetAttributes()
+	default:
+		result.Attributes = first.GetAttributes()
+	}
+
+	return result
+}
+
+func (p *Processor) mergeLineage(first, last *facetsv1beta1.Lineage) *facetsv1beta1.Lineage {
+	if first == nil && last == nil {
+		return nil
+	}
+	switch p.config.Lineage {
+	case StrategyFirstWins:
+		return first
+	case StrategyLastWins:
+		return last
+	}
+
+	return &facetsv1beta1.Lineage{
+		Upstreams:   unionResources(first.GetUpstreams(), last.GetUpstreams()),
+		Downstreams: unionResources(first.GetDownstreams(), last.GetDownstreams()),
+	}
+}
+
+func (p *Processor) mergeOwnership(first, last *facetsv1beta1.Ownership) *facetsv1beta1.Ownership {
+	if first == nil && last == nil {
+		return nil
+	}
+	switch p.config.Ownership {
+	case StrategyFirstWins:
+		return first
+	case StrategyLastWins:
+		return last
+	}
+
+	var owners []*facetsv1beta1.Owner
+	seen := make(map[string]bool)
+	for _, owner := range append(append([]*facetsv1beta1.Owner{}, first.GetOwners()...), last.GetOwners()...) {
+		key := owner.GetUrn()
+		if key == "" {
+			key = owner.GetEmail()
+		}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		owners = append(owners, owner)
+	}
+
+	return &facetsv1beta1.Ownership{Owners: owners}
+}
+
+// unionLabels keeps the labels of both records, conflicting keys are resolved by the main strategy
+func (p *Processor) unionLabels(first, last map[string]string) map[string]string {
+	if first == nil && last == nil {
+		return nil
+	}
+
+	result := make(map[string]string, len(first)+len(last))
+	for key, value := range first {
+		result[key] = value
+	}
+	for key, value := range last {
+		if _, ok := result[key]; !ok || p.config.Strategy == StrategyLastWins {
+			result[key] = value
+		}
+	}
+
+	return result
+}
+
+func unionStrings(first, last []string) (result []string) {
+	seen := make(map[string]bool)
+	for _, value := range append(append([]string{}, first...), last...) {
+		if seen[value] {
+			continue
+		}
+		seen[value] = true
+		result = append(result, value)
+	}
+
+	return
+}
+
+func unionResources(first, last []*commonv1beta1.Resource) (result []*commonv1beta1.Resource) {
+	seen := make(map[string]bool)
+	for _, resource := range append(append([]*commonv1beta1.Resource{}, first...), last...) {
+		if seen[resource.GetUrn()] {
+			continue
+		}
+		seen[resource.GetUrn()] = true
+		result = append(result, resource)
+	}
+
+	return
+}
+
+// deepMerge merges src into dst recursively, conflicting values are replaced only if overwrite is true
+func deepMerge(dst, src map[string]interface{}, overwrite bool) map[string]interface{} {
+	if dst == nil {
+		dst = make(map[string]interface{})
+	}
+	for key, srcVal := range src {
+		dstVal, ok := dst[key]
+		if !ok {
+			dst[key] = srcVal
+			continue
+		}
+
+		dstMap, dstIsMap := dstVal.(map[string]interface{})
+		srcMap, srcIsMap := srcVal.(map[string]interface{})
+		switch {
+		case dstIsMap && srcIsMap:
+			dst[key] = deepMerge(dstMap, srcMap, overwrite)
+		case overwrite:
+			dst[key] = srcVal
+		}
+	}
+
+	return dst
+}
+
+func init() {
+	if err := registry.Processors.Register("dedupe", func() plugins.Processor {
+		return New(plugins.GetLog())
+	}); err != nil {
+		return
+	}
+}
@@ -0,0 +1,113 @@
+package dedupe_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/odpf/meteor/models"
+	commonv1beta1 "github.com/odpf/meteor/models/odpf/assets/common/v1beta1"
+	facetsv1beta1 "github.com/odpf/meteor/models/odpf/assets/facets/v1beta1"
+	assetsv1beta1 "github.com/odpf/meteor/models/odpf/assets/v1beta1"
+	"github.com/odpf/meteor/plugins"
+	"github.com/odpf/meteor/plugins/processors/dedupe"
+	"github.com/odpf/meteor/test/mocks"
+	testUtils "github.com/odpf/meteor/test/utils"
+	"github.com/odpf/meteor/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const urn = "bigquery::project/dataset/table"
+
+func TestInit(t *testing.T) {
+	t.Run("should return InvalidConfigError on unknown strategy", func(t *testing.T) {
+		err := dedupe.New(testUtils.Logger).Init(context.TODO(), map[string]interface{}{
+			"strategy": "union",
+		})
+		assert.Equal(t, plugins.InvalidConfigError{Type: plugins.PluginTypeProcessor}, err)
+	})
+}
+
+func TestFlush(t *testing.T) {
+	t.Run("should emit a single record per urn", func(t *testing.T) {
+		emitter := process(t, map[string]interface{}{},
+			&assetsv1beta1.Table{Resource: &commonv1beta1.Resource{Urn: urn, Name: "first"}},
+			&assetsv1beta1.Table{Resource: &commonv1beta1.Resource{Urn: "other"}},
+			&assetsv1beta1.Table{Resource: &commonv1beta1.Resource{Urn: urn, Name: "last"}},
+		)
+
+		data := emitter.GetAllData()
+		require.Len(t, data, 2)
+		assert.Equal(t, "first", data[0].GetResource().Name)
+		assert.Equal(t, "other", data[1].GetResource().Urn)
+	})
+
+	t.Run("should keep the last record if strategy is last_wins", func(t *testing.T) {
+		emitter := process(t, map[string]interface{}{"strategy": "last_wins"},
+			&assetsv1beta1.Table{Resource: &commonv1beta1.Resource{Urn: urn, Name: "first"}},
+			&assetsv1beta1.Table{Resource: &commonv1beta1.Resource{Urn: urn, Name: "last"}},
+		)
+
+		data := emitter.GetAllData()
+		require.Len(t, data, 1)
+		assert.Equal(t, "last", data[0].GetResource().Name)
+	})
+
+	t.Run("should merge facets using configured strategies", func(t *testing.T) {
+		emitter := process(t, map[string]interface{}{},
+			&assetsv1beta1.Table{
+				Resource: &commonv1beta1.Resource{Urn: urn},
+				Properties: &facetsv1beta1.Properties{
+					Tags:   []string{"a", "b"},
+					Labels: map[string]string{"team": "data", "tier": "1"},
+					Attributes: utils.TryParseMapToProto(map[string]interface{}{
+						"owner": map[string]interface{}{"name": "first"},
+					}),
+				},
+				Lineage: &facetsv1beta1.Lineage{
+					Upstreams: []*commonv1beta1.Resource{{Urn: "upstream-a"}},
+				},
+			},
+			&assetsv1beta1.Table{
+				Resource: &commonv1beta1.Resource{Urn: urn},
+				Properties: &facetsv1beta1.Properties{
+					Tags:   []string{"b", "c"},
+					Labels: map[string]string{"tier": "2"},
+					Attributes: utils.TryParseMapToProto(map[string]interface{}{
+						"owner": map[string]interface{}{"name": "last", "email": "last@odpf.io"},
+					}),
+				},
+				Lineage: &facetsv1beta1.Lineage{
+					Upstreams: []*commonv1beta1.Resource{{Urn: "upstream-a"}, {Urn: "upstream-b"}},
+				},
+			},
+		)
+
+		data := emitter.GetAllData()
+		require.Len(t, data, 1)
+		table := data[0].(*assetsv1beta1.Table)
+		assert.Equal(t, []string{"a", "b", "c"}, table.Properties.Tags)
+		assert.Equal(t, map[string]string{"team": "data", "tier": "1"}, table.Properties.Labels)
+		assert.Equal(t, map[string]interface{}{
+			"owner": map[string]interface{}{"name": "first", "email": "last@odpf.io"},
+		}, table.Properties.Attributes.AsMap())
+		require.Len(t, table.Lineage.Upstreams, 2)
+		assert.Equal(t, "upstream-b", table.Lineage.Upstreams[1].Urn)
+	})
+}
+
+func process(t *testing.T, config map[string]interface{}, data ...models.Metadata) *mocks.Emitter {
+	ctx := context.TODO()
+	proc := dedupe.New(testUtils.Logger)
+	require.NoError(t, proc.Init(ctx, config))
+
+	for _, d := range data {
+		_, err := proc.Process(ctx, models.NewRecord(d))
+		require.NoError(t, err)
+	}
+
+	emitter := mocks.NewEmitter()
+	require.NoError(t, proc.Flush(ctx, emitter.Push))
+
+	return emitter
+}
@@ -0,0 +1,38 @@
+package httpenrich
+
+import (
+	"context"
+	"net/url"
+	"sync"
+)
+
+// hostLimits holds the semaphores limiting the requests in flight to every host,
+// they are shared by the processors of every recipe running in the process
+var hostLimits = struct {
+	mu   sync.Mutex
+	sems map[string]chan struct{}
+}{sems: make(map[string]chan struct{})}
+
+// acquire waits for a slot of the semaphore of the host of rawURL, the semaphore is created with size
+// by the first request to the host. The returned func releases the slot.
+func acquire(ctx context.Context, rawURL string, size int) (release func(), err error) {
+	host := rawURL
+	if u, err := url.Parse(rawURL); err == nil && u.Host != "" {
+		host = u.Host
+	}
+
+	hostLimits.mu.Lock()
+	sem, ok := hostLimits.sems[host]
+	if !ok {
+		sem = make(chan struct{}, size)
+		hostLimits.sems[host] = sem
+	}
+	hostLimits.mu.Unlock()
+
+	select {
+	case sem <- struct{}{}:
+		return func() { <-sem }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
@@ -0,0 +1,318 @@
+package httpenrich
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	"github.com/odpf/meteor/models"
+	facetsv1beta1 "github.com/odpf/meteor/models/odpf/assets/facets/v1beta1"
+	"github.com/odpf/meteor/plugins"
+	"github.com/odpf/meteor/registry"
+	"github.com/odpf/meteor/utils"
+	"github.com/odpf/salt/log"
+	"github.com/pkg/errors"
+)
+
+//go:embed README.md
+var summary string
+
+// Config holds the set of configuration for the processor
+type Config struct {
+	URL                        string            `mapstructure:"url" validate:"required"`
+	Method                     string            `mapstructure:"method" validate:"oneof=GET POST" default:"GET"`
+	Body                       string            `mapstructure:"body"`
+	Headers                    map[string]string `mapstructure:"headers"`
+	Labels                     map[string]string `mapstructure:"labels"`
+	Attributes                 map[string]string `mapstructure:"attributes"`
+	TimeoutSeconds             int               `mapstructure:"timeout_seconds" default:"10"`
+	CacheSize                  int               `mapstructure:"cache_size" default:"1000"`
+	CacheTTLSeconds            int               `mapstructure:"cache_ttl_seconds" default:"300"`
+	MaxConcurrency             int               `mapstructure:"max_concurrency" validate:"min=1" default:"5"`
+	MaxRetries                 int               `mapstructure:"max_retries" default:"3"`
+	RetryInitialIntervalMillis int               `mapstructure:"retry_initial_interval_ms" default:"500"`
+	SkipOnError                bool              `mapstructure:"skip_on_error" default:"false"`
+}
+
+var sampleConfig = `
+# URL to call for every record, templated from the record fields
+url: "https://glossary.com/api/assets?urn={{ .Resource.Urn | urlquery }}"
+# HTTP method, GET or POST
+method: GET
+# Optional request body, templated from the record fields
+body: ""
+# Additional HTTP headers, multiple headers value are separated by a comma
+headers:
+  Authorization: Bearer token
+# Map response JSON paths into properties labels
+labels:
+  cost_center: $.data.cost_center
+# Map response JSON paths into properties attributes
+attributes:
+  glossary_terms: $.data.terms
+# Number of responses to cache and for how long
+cache_size: 1000
+cache_ttl_seconds: 300
+# Maximum number of requests in flight to the host, shared by the recipes running at the same time
+max_concurrency: 5
+# Retries on network errors, 429 and 5xx responses
+max_retries: 3
+retry_initial_interval_ms: 500
+# Pass the record as is instead of failing the run when the lookup fails
+skip_on_error: false`
+
+// httpClient holds the set of methods require for creating request
+type httpClient interface {
+	Do(*http.Request) (*http.Response, error)
+}
+
+// Processor enriches records with values looked up from an HTTP service
+type Processor struct {
+	client   httpClient
+	config   Config
+	logger   log.Logger
+	urlTmpl  *template.Template
+	bodyTmpl *template.Template
+	cache    *lruCache
+}
+
+// New create a new processor
+func New(c httpClient, logger log.Logger) *Processor {
+	return &Processor{
+		client: c,
+		logger: logger,
+	}
+}
+
+// Info returns the plugin information
+func (p *Processor) Info() plugins.Info {
+	return plugins.Info{
+		Description:  "Enrich records with values from an HTTP service",
+		SampleConfig: sampleConfig,
+		Summary:      summary,
+		Tags:         []string{"processor", "http", "transform"},
+	}
+}
+
+// Validate validates the plugin configuration
+func (p *Processor) Validate(configMap map[string]interface{}) (err error) {
+	var config Config
+	if err = utils.BuildConfig(configMap, &config); err != nil {
+		return err
+	}
+	if _, err = template.New("url").Parse(config.URL); err != nil {
+		return errors.Wrap(err, "invalid url template")
+	}
+	if _, err = template.New("body").Parse(config.Body); err != nil {
+		return errors.Wrap(err, "invalid body template")
+	}
+
+	return
+}
+
+// Init initializes the processor
+func (p *Processor) Init(ctx context.Context, configMap map[string]interface{}) (err error) {
+	if err = utils.BuildConfig(configMap, &p.config); err != nil {
+		return plugins.InvalidConfigError{Type: plugins.PluginTypeProcessor}
+	}
+
+	if p.urlTmpl, err = template.New("url").Option("missingkey=error").Parse(p.config.URL); err != nil {
+		return errors.Wrap(err, "invalid url template")
+	}
+	if p.bodyTmpl, err = template.New("body").Option("missingkey=error").Parse(p.config.Body); err != nil {
+		return errors.Wrap(err, "invalid body template")
+	}
+	p.cache = newLRUCache(p.config.CacheSize, time.Duration(p.config.CacheTTLSeconds)*time.Second)
+
+	return
+}
+
+// Process looks up the record and maps the response into its properties
+func (p *Processor) Process(ctx context.Context, src models.Record) (dst models.Record, err error) {
+	data := src.Data()
+
+	res, err := p.lookup(ctx, data)
+	if err != nil {
+		if p.config.SkipOnError {
+			p.logger.Warn("skipping http enrichment", "record", data.GetResource().GetUrn(), "error", err)
+			return src, nil
+		}
+		return src, errors.Wrap(err, "failed to look up record")
+	}
+	if res == nil {
+		return src, nil
+	}
+
+	if err = p.enrich(data, res); err != nil {
+		return src, errors.Wrap(err, "failed to enrich record")
+	}
+
+	return models.NewRecord(data), nil
+}
+
+// lookup returns the response of the record, responses are cached by their request
+func (p *Processor) lookup(ctx context.Context, data models.Metadata) (interface{}, error) {
+	url, err := render(p.urlTmpl, data)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to build url")
+	}
+	body, err := render(p.bodyTmpl, data)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to build body")
+	}
+
+	key := p.config.Method + " " + url + " " + body
+	if res, ok := p.cache.get(key); ok {
+		return res, nil
+	}
+
+	var res interface{}
+	bo := backoff.WithContext(backoff.WithMaxRetries(p.createBackoff(), uint64(p.config.MaxRetries)), ctx)
+	err = backoff.Retry(func() (err error) {
+		res, err = p.request(ctx, url, body)
+		if err == nil || errors.Is(err, plugins.RetryError{}) {
+			return err
+		}
+		return backoff.Permanent(err)
+	}, bo)
+	if err != nil {
+		return nil, err
+	}
+	p.cache.set(key, res)
+
+	return res, nil
+}
+
+// request calls the service, a nil response is returned if nothing is found
+func (p *Processor) request(ctx context.Context, url, body string) (res interface{}, err error) {
+	release, err := acquire(ctx, url, p.config.MaxConcurrency)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(p.config.TimeoutSeconds)*time.Second)
+	defer cancel()
+
+	var reqBody io.Reader
+	if body != "" {
+		reqBody = strings.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, p.config.Method, url, reqBody)
+	if err != nil {
+		return
+	}
+	for hdrKey, hdrVal := range p.config.Headers {
+		for _, val := range strings.Split(hdrVal, ",") {
+			req.Header.Add(hdrKey, strings.TrimSpace(val))
+		}
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, plugins.NewRetryError(err)
+	}
+	defer resp.Body.Close()
+
+	bodyBytes, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, plugins.NewRetryError(err)
+	}
+
+	switch code := resp.StatusCode; {
+	case code == http.StatusNotFound:
+		return nil, nil
+	case plugins.IsRetryableStatus(code):
+		return nil, plugins.NewRetryError(fmt.Errorf("%s returns %d: %v", url, code, string(bodyBytes)))
+	case code >= 300:
+		return nil, fmt.Errorf("%s returns %d: %v", url, code, string(bodyBytes))
+	}
+
+	if err = json.Unmarshal(bodyBytes, &res); err != nil {
+		return nil, errors.Wrap(err, "failed to parse response")
+	}
+
+	return
+}
+
+// enrich maps the response paths into labels and attributes of the record
+func (p *Processor) enrich(data models.Metadata, res interface{}) error {
+	properties := data.GetProperties()
+	if properties == nil {
+		properties = &facetsv1beta1.Properties{}
+		utils.SetProperties(data, properties)
+	}
+
+	for key, path := range p.config.Labels {
+		value, ok := utils.GetJSONPath(res, path)
+		if !ok {
+			continue
+		}
+		if properties.Labels == nil {
+			properties.Labels = make(map[string]string)
+		}
+		properties.Labels[key] = toString(value)
+	}
+
+	if len(p.config.Attributes) == 0 {
+		return nil
+	}
+	customProps := utils.GetCustomProperties(data)
+	if customProps == nil {
+		customProps = make(map[string]interface{})
+	}
+	for key, path := range p.config.Attributes {
+		if value, ok := utils.GetJSONPath(res, path); ok {
+			customProps[key] = value
+		}
+	}
+	_, err := utils.SetCustomProperties(data, customProps)
+
+	return err
+}
+
+func (p *Processor) createBackoff() backoff.BackOff {
+	ebo := backoff.NewExponentialBackOff()
+	ebo.InitialInterval = time.Duration(p.config.RetryInitialIntervalMillis) * time.Millisecond
+	return ebo
+}
+
+func render(tmpl *template.Template, data models.Metadata) (string, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+func toString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case nil:
+		return ""
+	case map[string]interface{}, []interface{}:
+		b, _ := json.Marshal(v)
+		return string(b)
+	}
+
+	return fmt.Sprint(value)
+}
+
+func init() {
+	if err := registry.Processors.Register("http_enrich", func() plugins.Processor {
+		return New(&http.Client{}, plugins.GetLog())
+	}); err != nil {
+		return
+	}
+}
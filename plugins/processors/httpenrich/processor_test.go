@@ -0,0 +1,163 @@
+package httpenrich_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/odpf/meteor/models"
+	commonv1beta1 "github.com/odpf/meteor/models/odpf/assets/common/v1beta1"
+	assetsv1beta1 "github.com/odpf/meteor/models/odpf/assets/v1beta1"
+	"github.com/odpf/meteor/plugins"
+	"github.com/odpf/meteor/plugins/processors/httpenrich"
+	testUtils "github.com/odpf/meteor/test/utils"
+	"github.com/odpf/meteor/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInit(t *testing.T) {
+	t.Run("should return InvalidConfigError if url is missing", func(t *testing.T) {
+		err := httpenrich.New(http.DefaultClient, testUtils.Logger).Init(context.TODO(), map[string]interface{}{})
+		assert.Equal(t, plugins.InvalidConfigError{Type: plugins.PluginTypeProcessor}, err)
+	})
+}
+
+func TestProcess(t *testing.T) {
+	t.Run("should map response paths into labels and attributes", func(t *testing.T) {
+		var calls int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&calls, 1)
+			assert.Equal(t, "bigquery::project/dataset/table", r.URL.Query().Get("urn"))
+			assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte(`{"data":{"cost_center":"CC-42","terms":["revenue","finance"]}}`))
+		}))
+		defer server.Close()
+
+		proc := newProcessor(t, server.URL, nil)
+		table := newTable()
+
+		_, err := proc.Process(context.TODO(), models.NewRecord(table))
+		require.NoError(t, err)
+		_, err = proc.Process(context.TODO(), models.NewRecord(newTable()))
+		require.NoError(t, err)
+
+		assert.Equal(t, "CC-42", table.Properties.Labels["cost_center"])
+		assert.Equal(t, []interface{}{"revenue", "finance"}, utils.GetCustomProperties(table)["terms"])
+		assert.Equal(t, int32(1), atomic.LoadInt32(&calls), "second lookup should be served from cache")
+	})
+
+	t.Run("should retry on 5xx responses", func(t *testing.T) {
+		var calls int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if atomic.AddInt32(&calls, 1) == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			_, _ = w.Write([]byte(`{"data":{"cost_center":"CC-42"}}`))
+		}))
+		defer server.Close()
+
+		proc := newProcessor(t, server.URL, nil)
+		table := newTable()
+
+		_, err := proc.Process(context.TODO(), models.NewRecord(table))
+		require.NoError(t, err)
+
+		assert.Equal(t, "CC-42", table.Properties.Labels["cost_center"])
+		assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+	})
+
+	t.Run("should return error on 4xx responses unless skip_on_error is set", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadRequest)
+		}))
+		defer server.Close()
+
+		proc := newProcessor(t, server.URL, nil)
+		_, err := proc.Process(context.TODO(), models.NewRecord(newTable()))
+		assert.Error(t, err)
+		assert.False(t, errors.Is(err, plugins.RetryError{}))
+
+		proc = newProcessor(t, server.URL, map[string]interface{}{"skip_on_error": true})
+		_, err = proc.Process(context.TODO(), models.NewRecord(newTable()))
+		assert.NoError(t, err)
+	})
+}
+
+func TestConcurrency(t *testing.T) {
+	t.Run("should share the limit of requests in flight to a host between processors", func(t *testing.T) {
+		var inFlight, maxInFlight int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			n := atomic.AddInt32(&inFlight, 1)
+			defer atomic.AddInt32(&inFlight, -1)
+			for {
+				max := atomic.LoadInt32(&maxInFlight)
+				if n <= max || atomic.CompareAndSwapInt32(&maxInFlight, max, n) {
+					break
+				}
+			}
+			time.Sleep(10 * time.Millisecond)
+			_, _ = w.Write([]byte(`{"data":{"cost_center":"CC-42"}}`))
+		}))
+		defer server.Close()
+
+		procs := []*httpenrich.Processor{
+			newProcessor(t, server.URL, map[string]interface{}{"max_concurrency": 2}),
+			newProcessor(t, server.URL, map[string]interface{}{"max_concurrency": 2}),
+		}
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				table := newTable()
+				table.Resource.Urn += "/" + strconv.Itoa(i)
+				_, err := procs[i%2].Process(context.TODO(), models.NewRecord(table))
+				assert.NoError(t, err)
+			}(i)
+		}
+		wg.Wait()
+
+		assert.Equal(t, int32(2), atomic.LoadInt32(&maxInFlight))
+	})
+}
+
+func newProcessor(t *testing.T, host string, extra map[string]interface{}) *httpenrich.Processor {
+	config := map[string]interface{}{
+		"url": host + "/assets?urn={{ .Resource.Urn | urlquery }}",
+		"headers": map[string]string{
+			"Authorization": "Bearer token",
+		},
+		"labels": map[string]string{
+			"cost_center": "$.data.cost_center",
+		},
+		"attributes": map[string]string{
+			"terms": "$.data.terms",
+		},
+		"retry_initial_interval_ms": 1,
+	}
+	for k, v := range extra {
+		config[k] = v
+	}
+
+	proc := httpenrich.New(http.DefaultClient, testUtils.Logger)
+	require.NoError(t, proc.Init(context.TODO(), config))
+
+	return proc
+}
+
+func newTable() *assetsv1beta1.Table {
+	return &assetsv1beta1.Table{
+		Resource: &commonv1beta1.Resource{
+			Urn: "bigquery::project/dataset/table",
+		},
+	}
+}
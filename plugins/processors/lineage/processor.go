@@ -0,0 +1,178 @@
+package lineage
+
+import (
+	"context"
+	_ "embed"
+
+	"github.com/odpf/meteor/models"
+	commonv1beta1 "github.com/odpf/meteor/models/odpf/assets/common/v1beta1"
+	facetsv1beta1 "github.com/odpf/meteor/models/odpf/assets/facets/v1beta1"
+	assetsv1beta1 "github.com/odpf/meteor/models/odpf/assets/v1beta1"
+	"github.com/odpf/meteor/plugins"
+	"github.com/odpf/meteor/registry"
+	"github.com/odpf/meteor/utils"
+	"github.com/odpf/salt/log"
+)
+
+//go:embed README.md
+var summary string
+
+// Config holds the set of configuration for the processor
+type Config struct {
+	EmitStubs bool `mapstructure:"emit_stubs" default:"true"`
+}
+
+var sampleConfig = `
+# Emit lineage-only records for upstreams that were not extracted in the run
+emit_stubs: true`
+
+// Processor holds the records of a run and stitches downstreams from their upstreams
+type Processor struct {
+	config  Config
+	logger  log.Logger
+	records []models.Record
+}
+
+// New create a new processor
+func New(logger log.Logger) *Processor {
+	return &Processor{
+		logger: logger,
+	}
+}
+
+// Info returns the plugin information
+func (p *Processor) Info() plugins.Info {
+	return plugins.Info{
+		Description:  "Build downstreams of assets from the upstreams of a run",
+		SampleConfig: sampleConfig,
+		Summary:      summary,
+		Tags:         []string{"processor", "lineage"},
+	}
+}
+
+// Validate validates the plugin configuration
+func (p *Processor) Validate(configMap map[string]interface{}) (err error) {
+	return utils.BuildConfig(configMap, &Config{})
+}
+
+// Init initializes the processor
+func (p *Processor) Init(ctx context.Context, configMap map[string]interface{}) (err error) {
+	if err = utils.BuildConfig(configMap, &p.config); err != nil {
+		return plugins.InvalidConfigError{Type: plugins.PluginTypeProcessor}
+	}
+
+	return
+}
+
+// Process holds the record until the run is flushed
+func (p *Processor) Process(ctx context.Context, src models.Record) (dst models.Record, err error) {
+	p.records = append(p.records, src)
+	return src, nil
+}
+
+// Flush stitches lineage of the held records and emits them along with the stubs
+func (p *Processor) Flush(ctx context.Context, emit plugins.Emit) error {
+	assets := make(map[string]models.Metadata, len(p.records))
+	for _, record := range p.records {
+		data := record.Data()
+		if urn := data.GetResource().GetUrn(); urn != "" {
+			assets[urn] = data
+		}
+	}
+
+	var stubs []models.Metadata
+	for _, record := range p.records {
+		data := record.Data()
+		resource := data.GetResource()
+		for _, upstream := range utils.GetLineage(data).GetUpstreams() {
+			urn := upstream.GetUrn()
+			if urn == "" || urn == resource.GetUrn() {
+				continue
+			}
+
+			target, ok := assets[urn]
+			if !ok {
+				if !p.config.EmitStubs {
+					continue
+				}
+				if target = buildStub(upstream); target == nil {
+					p.logger.Debug("skipping stub for unsupported type", "record", urn, "type", upstream.GetType())
+					continue
+				}
+				assets[urn] = target
+				stubs = append(stubs, target)
+			}
+
+			if !addDownstream(target, resource) {
+				p.logger.Debug("skipping downstream for asset without lineage", "record", urn)
+			}
+		}
+	}
+
+	for _, record := range p.records {
+		emit(record)
+	}
+	for _, stub := range stubs {
+		emit(models.NewRecord(stub))
+	}
+	p.records = nil
+
+	return nil
+}
+
+// addDownstream adds the resource as a downstream of the target if it is not there yet
+func addDownstream(target models.Metadata, resource *commonv1beta1.Resource) bool {
+	if _, ok := target.(models.LineageMetadata); !ok {
+		return false
+	}
+
+	lineage := utils.GetLineage(target)
+	if lineage == nil {
+		lineage = &facetsv1beta1.Lineage{}
+		utils.SetLineage(target, lineage)
+	}
+	for _, downstream := range lineage.Downstreams {
+		if downstream.GetUrn() == resource.GetUrn() {
+			return true
+		}
+	}
+	lineage.Downstreams = append(lineage.Downstreams, &commonv1beta1.Resource{
+		Urn:     resource.GetUrn(),
+		Name:    resource.GetName(),
+		Service: resource.GetService(),
+		Type:    resource.GetType(),
+	})
+
+	return true
+}
+
+// buildStub creates a lineage-only asset for the referenced resource
+func buildStub(ref *commonv1beta1.Resource) models.Metadata {
+	resource := &commonv1beta1.Resource{
+		Urn:     ref.GetUrn(),
+		Name:    ref.GetName(),
+		Service: ref.GetService(),
+		Type:    ref.GetType(),
+	}
+
+	switch ref.GetType() {
+	case "table":
+		return &assetsv1beta1.Table{Resource: resource}
+	case "topic":
+		return &assetsv1beta1.Topic{Resource: resource}
+	case "dashboard":
+		return &assetsv1beta1.Dashboard{Resource: resource}
+	case "job":
+		return &assetsv1beta1.Job{Resource: resource}
+	}
+
+	return nil
+}
+
+func init() {
+	if err := registry.Processors.Register("lineage", func() plugins.Processor {
+		return New(plugins.GetLog())
+	}); err != nil {
+		return
+	}
+}
@@ -0,0 +1,114 @@
+package lineage_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/odpf/meteor/models"
+	commonv1beta1 "github.com/odpf/meteor/models/odpf/assets/common/v1beta1"
+	facetsv1beta1 "github.com/odpf/meteor/models/odpf/assets/facets/v1beta1"
+	assetsv1beta1 "github.com/odpf/meteor/models/odpf/assets/v1beta1"
+	"github.com/odpf/meteor/plugins/processors/lineage"
+	"github.com/odpf/meteor/test/mocks"
+	testUtils "github.com/odpf/meteor/test/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	tableResource = &commonv1beta1.Resource{
+		Urn:     "bigquery::project/dataset/table",
+		Name:    "table",
+		Service: "bigquery",
+		Type:    "table",
+	}
+	missingResource = &commonv1beta1.Resource{
+		Urn:     "bigquery::project/dataset/missing",
+		Name:    "missing",
+		Service: "bigquery",
+		Type:    "table",
+	}
+	dashboardResource = &commonv1beta1.Resource{
+		Urn:     "metabase::instance/dashboard/1",
+		Name:    "dashboard",
+		Service: "metabase",
+		Type:    "dashboard",
+	}
+)
+
+func TestFlush(t *testing.T) {
+	t.Run("should add downstreams to upstreams extracted in the same run", func(t *testing.T) {
+		table := &assetsv1beta1.Table{Resource: tableResource}
+		dashboard := &assetsv1beta1.Dashboard{
+			Resource: dashboardResource,
+			Lineage: &facetsv1beta1.Lineage{
+				Upstreams: []*commonv1beta1.Resource{tableResource},
+			},
+		}
+
+		emitter := process(t, map[string]interface{}{}, table, dashboard)
+
+		data := emitter.GetAllData()
+		require.Len(t, data, 2)
+		downstreams := data[0].(*assetsv1beta1.Table).Lineage.Downstreams
+		require.Len(t, downstreams, 1)
+		assert.Equal(t, dashboardResource.Urn, downstreams[0].Urn)
+		assert.Equal(t, dashboardResource.Type, downstreams[0].Type)
+	})
+
+	t.Run("should emit stubs for upstreams missing from the run", func(t *testing.T) {
+		dashboard := &assetsv1beta1.Dashboard{
+			Resource: dashboardResource,
+			Lineage: &facetsv1beta1.Lineage{
+				Upstreams: []*commonv1beta1.Resource{missingResource},
+			},
+		}
+		job := &assetsv1beta1.Job{
+			Resource: &commonv1beta1.Resource{Urn: "optimus::host/job", Type: "job"},
+			Lineage: &facetsv1beta1.Lineage{
+				Upstreams: []*commonv1beta1.Resource{missingResource},
+			},
+		}
+
+		emitter := process(t, map[string]interface{}{}, dashboard, job)
+
+		data := emitter.GetAllData()
+		require.Len(t, data, 3)
+		stub, ok := data[2].(*assetsv1beta1.Table)
+		require.True(t, ok)
+		assert.Equal(t, missingResource.Urn, stub.Resource.Urn)
+		assert.Equal(t, []string{dashboardResource.Urn, "optimus::host/job"}, []string{
+			stub.Lineage.Downstreams[0].Urn,
+			stub.Lineage.Downstreams[1].Urn,
+		})
+	})
+
+	t.Run("should not emit stubs if disabled", func(t *testing.T) {
+		dashboard := &assetsv1beta1.Dashboard{
+			Resource: dashboardResource,
+			Lineage: &facetsv1beta1.Lineage{
+				Upstreams: []*commonv1beta1.Resource{missingResource},
+			},
+		}
+
+		emitter := process(t, map[string]interface{}{"emit_stubs": false}, dashboard)
+
+		assert.Len(t, emitter.Get(), 1)
+	})
+}
+
+func process(t *testing.T, config map[string]interface{}, data ...models.Metadata) *mocks.Emitter {
+	ctx := context.TODO()
+	proc := lineage.New(testUtils.Logger)
+	require.NoError(t, proc.Init(ctx, config))
+
+	for _, d := range data {
+		_, err := proc.Process(ctx, models.NewRecord(d))
+		require.NoError(t, err)
+	}
+
+	emitter := mocks.NewEmitter()
+	require.NoError(t, proc.Flush(ctx, emitter.Push))
+
+	return emitter
+}
@@ -1,5 +1,10 @@
 package processors
 
 import (
+	_ "github.com/odpf/meteor/plugins/processors/dedupe"
 	_ "github.com/odpf/meteor/plugins/processors/enrich"
+	_ "github.com/odpf/meteor/plugins/processors/httpenrich"
+	_ "github.com/odpf/meteor/plugins/processors/lineage"
+	_ "github.com/odpf/meteor/plugins/processors/schemadiff"
+	_ "github.com/odpf/meteor/plugins/processors/urn"
 )
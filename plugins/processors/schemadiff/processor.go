@@ -0,0 +1,240 @@
+package schemadiff
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/odpf/meteor/models"
+	commonv1beta1 "github.com/odpf/meteor/models/odpf/assets/common/v1beta1"
+	assetsv1beta1 "github.com/odpf/meteor/models/odpf/assets/v1beta1"
+	"github.com/odpf/meteor/plugins"
+	"github.com/odpf/meteor/registry"
+	"github.com/odpf/meteor/utils"
+	"github.com/odpf/salt/log"
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+//go:embed README.md
+var summary string
+
+// Change types
+const (
+	ChangeColumnAdded       = "column_added"
+	ChangeColumnRemoved     = "column_removed"
+	ChangeColumnTypeChanged = "column_type_changed"
+	ChangeColumnNullability = "column_nullability_changed"
+)
+
+// Event actions
+const (
+	EventActionSchemaChanged  = "schema_changed"
+	EventActionBreakingChange = "schema_breaking_change"
+)
+
+const attributeKey = "schema_changes"
+
+// Config holds the set of configuration for the processor
+type Config struct {
+	StorePath string `mapstructure:"store_path" validate:"required"`
+	EmitEvent bool   `mapstructure:"emit_event" default:"false"`
+}
+
+var sampleConfig = `
+# Directory where the last seen schema of each table is stored
+store_path: ./.meteor/schemas
+# Set an event on the table describing the change
+emit_event: true`
+
+// Processor compares table schemas against the last seen ones
+type Processor struct {
+	config Config
+	logger log.Logger
+	store  *fileStore
+	// pending holds the columns seen during the run by table urn, they are stored once the run succeeded
+	pending map[string][]column
+}
+
+// New create a new processor
+func New(logger log.Logger) *Processor {
+	return &Processor{
+		logger:  logger,
+		pending: make(map[string][]column),
+	}
+}
+
+// Info returns the plugin information
+func (p *Processor) Info() plugins.Info {
+	return plugins.Info{
+		Description:  "Track column changes of tables between runs",
+		SampleConfig: sampleConfig,
+		Summary:      summary,
+		Tags:         []string{"processor", "schema"},
+	}
+}
+
+// Validate validates the plugin configuration
+func (p *Processor) Validate(configMap map[string]interface{}) (err error) {
+	return utils.BuildConfig(configMap, &Config{})
+}
+
+// Init initializes the processor
+func (p *Processor) Init(ctx context.Context, configMap map[string]interface{}) (err error) {
+	if err = utils.BuildConfig(configMap, &p.config); err != nil {
+		return plugins.InvalidConfigError{Type: plugins.PluginTypeProcessor}
+	}
+
+	p.store, err = newFileStore(p.config.StorePath)
+	return
+}
+
+// Process annotates the table with the changes since its last seen schema
+func (p *Processor) Process(ctx context.Context, src models.Record) (dst models.Record, err error) {
+	table, ok := src.Data().(*assetsv1beta1.Table)
+	if !ok {
+		return src, nil
+	}
+	urn := table.GetResource().GetUrn()
+	if urn == "" {
+		return src, nil
+	}
+
+	current := buildColumns(table)
+	previous, found, err := p.store.get(urn)
+	if err != nil {
+		return src, err
+	}
+	p.pending[urn] = current
+	if !found {
+		return src, nil
+	}
+
+	changes := diff(previous, current)
+	if len(changes) == 0 {
+		return src, nil
+	}
+	p.logger.Info("schema changed", "record", urn, "changes", len(changes))
+
+	customProps := utils.GetCustomProperties(table)
+	if customProps == nil {
+		customProps = make(map[string]interface{})
+	}
+	customProps[attributeKey] = changes
+	if _, err = utils.SetCustomProperties(table, customProps); err != nil {
+		return src, errors.Wrap(err, "failed to set schema changes")
+	}
+	if p.config.EmitEvent {
+		table.Event = buildEvent(changes)
+	}
+
+	return models.NewRecord(table), nil
+}
+
+// Commit stores the columns seen during the run as the last seen schemas,
+// so the changes of a run which failed are reported again by the next one
+func (p *Processor) Commit(ctx context.Context) error {
+	for urn, columns := range p.pending {
+		if err := p.store.put(urn, columns); err != nil {
+			return err
+		}
+		delete(p.pending, urn)
+	}
+
+	return nil
+}
+
+func buildColumns(table *assetsv1beta1.Table) (columns []column) {
+	for _, c := range table.GetSchema().GetColumns() {
+		columns = append(columns, column{
+			Name:       c.GetName(),
+			DataType:   c.GetDataType(),
+			IsNullable: c.GetIsNullable(),
+		})
+	}
+
+	return
+}
+
+// diff returns the list of changes from previous to current columns,
+// each change is a map so it can be stored in attributes as is
+func diff(previous, current []column) (changes []interface{}) {
+	prevByName := make(map[string]column, len(previous))
+	for _, c := range previous {
+		prevByName[c.Name] = c
+	}
+	currByName := make(map[string]column, len(current))
+	for _, c := range current {
+		currByName[c.Name] = c
+	}
+
+	for _, c := range current {
+		prev, ok := prevByName[c.Name]
+		switch {
+		case !ok:
+			changes = append(changes, map[string]interface{}{
+				"change":   ChangeColumnAdded,
+				"column":   c.Name,
+				"new_type": c.DataType,
+				"breaking": false,
+			})
+		case prev.DataType != c.DataType:
+			changes = append(changes, map[string]interface{}{
+				"change":   ChangeColumnTypeChanged,
+				"column":   c.Name,
+				"old_type": prev.DataType,
+				"new_type": c.DataType,
+				"breaking": true,
+			})
+		case prev.IsNullable != c.IsNullable:
+			// columns which stop accepting nulls break the writers still sending them
+			changes = append(changes, map[string]interface{}{
+				"change":       ChangeColumnNullability,
+				"column":       c.Name,
+				"old_nullable": prev.IsNullable,
+				"new_nullable": c.IsNullable,
+				"breaking":     !c.IsNullable,
+			})
+		}
+	}
+	for _, c := range previous {
+		if _, ok := currByName[c.Name]; !ok {
+			changes = append(changes, map[string]interface{}{
+				"change":   ChangeColumnRemoved,
+				"column":   c.Name,
+				"old_type": c.DataType,
+				"breaking": true,
+			})
+		}
+	}
+
+	return
+}
+
+func buildEvent(changes []interface{}) *commonv1beta1.Event {
+	action := EventActionSchemaChanged
+	var descriptions []string
+	for _, c := range changes {
+		change := c.(map[string]interface{})
+		if change["breaking"] == true {
+			action = EventActionBreakingChange
+		}
+		descriptions = append(descriptions, fmt.Sprintf("%s: %s", change["change"], change["column"]))
+	}
+
+	return &commonv1beta1.Event{
+		Timestamp:   timestamppb.New(time.Now()),
+		Action:      action,
+		Description: strings.Join(descriptions, ", "),
+	}
+}
+
+func init() {
+	if err := registry.Processors.Register("schema_diff", func() plugins.Processor {
+		return New(plugins.GetLog())
+	}); err != nil {
+		return
+	}
+}
@@ -0,0 +1,156 @@
+package schemadiff_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/odpf/meteor/models"
+	commonv1beta1 "github.com/odpf/meteor/models/odpf/assets/common/v1beta1"
+	facetsv1beta1 "github.com/odpf/meteor/models/odpf/assets/facets/v1beta1"
+	assetsv1beta1 "github.com/odpf/meteor/models/odpf/assets/v1beta1"
+	"github.com/odpf/meteor/plugins"
+	"github.com/odpf/meteor/plugins/processors/schemadiff"
+	testUtils "github.com/odpf/meteor/test/utils"
+	"github.com/odpf/meteor/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInit(t *testing.T) {
+	t.Run("should return InvalidConfigError if store_path is missing", func(t *testing.T) {
+		err := schemadiff.New(testUtils.Logger).Init(context.TODO(), map[string]interface{}{})
+		assert.Equal(t, plugins.InvalidConfigError{Type: plugins.PluginTypeProcessor}, err)
+	})
+}
+
+func TestProcess(t *testing.T) {
+	t.Run("should not annotate tables seen for the first time", func(t *testing.T) {
+		proc := newProcessor(t, false)
+
+		dst, err := proc.Process(context.TODO(), newTable(&facetsv1beta1.Column{Name: "id", DataType: "INT64"}))
+		require.NoError(t, err)
+
+		assert.NotContains(t, utils.GetCustomProperties(dst.Data()), "schema_changes")
+	})
+
+	t.Run("should annotate added, removed and retyped columns", func(t *testing.T) {
+		ctx := context.TODO()
+		proc := newProcessor(t, true)
+
+		_, err := proc.Process(ctx, newTable(
+			&facetsv1beta1.Column{Name: "id", DataType: "INT64"},
+			&facetsv1beta1.Column{Name: "total", DataType: "INT64"},
+			&facetsv1beta1.Column{Name: "legacy", DataType: "STRING"},
+		))
+		require.NoError(t, err)
+		require.NoError(t, proc.Commit(ctx))
+
+		dst, err := proc.Process(ctx, newTable(
+			&facetsv1beta1.Column{Name: "id", DataType: "INT64"},
+			&facetsv1beta1.Column{Name: "total", DataType: "STRING"},
+			&facetsv1beta1.Column{Name: "created_at", DataType: "TIMESTAMP"},
+		))
+		require.NoError(t, err)
+
+		table := dst.Data().(*assetsv1beta1.Table)
+		assert.Equal(t, []interface{}{
+			map[string]interface{}{
+				"change":   schemadiff.ChangeColumnTypeChanged,
+				"column":   "total",
+				"old_type": "INT64",
+				"new_type": "STRING",
+				"breaking": true,
+			},
+			map[string]interface{}{
+				"change":   schemadiff.ChangeColumnAdded,
+				"column":   "created_at",
+				"new_type": "TIMESTAMP",
+				"breaking": false,
+			},
+			map[string]interface{}{
+				"change":   schemadiff.ChangeColumnRemoved,
+				"column":   "legacy",
+				"old_type": "STRING",
+				"breaking": true,
+			},
+		}, utils.GetCustomProperties(table)["schema_changes"])
+		require.NotNil(t, table.Event)
+		assert.Equal(t, schemadiff.EventActionBreakingChange, table.Event.Action)
+	})
+
+	t.Run("should annotate nullability changes", func(t *testing.T) {
+		ctx := context.TODO()
+		proc := newProcessor(t, true)
+
+		_, err := proc.Process(ctx, newTable(
+			&facetsv1beta1.Column{Name: "id", DataType: "INT64", IsNullable: true},
+			&facetsv1beta1.Column{Name: "note", DataType: "STRING"},
+		))
+		require.NoError(t, err)
+		require.NoError(t, proc.Commit(ctx))
+
+		dst, err := proc.Process(ctx, newTable(
+			&facetsv1beta1.Column{Name: "id", DataType: "INT64"},
+			&facetsv1beta1.Column{Name: "note", DataType: "STRING", IsNullable: true},
+		))
+		require.NoError(t, err)
+
+		assert.Equal(t, []interface{}{
+			map[string]interface{}{
+				"change":       schemadiff.ChangeColumnNullability,
+				"column":       "id",
+				"old_nullable": true,
+				"new_nullable": false,
+				"breaking":     true,
+			},
+			map[string]interface{}{
+				"change":       schemadiff.ChangeColumnNullability,
+				"column":       "note",
+				"old_nullable": false,
+				"new_nullable": true,
+				"breaking":     false,
+			},
+		}, utils.GetCustomProperties(dst.Data())["schema_changes"])
+	})
+
+	t.Run("should compare against the columns of the last committed run", func(t *testing.T) {
+		ctx := context.TODO()
+		proc := newProcessor(t, false)
+
+		_, err := proc.Process(ctx, newTable(&facetsv1beta1.Column{Name: "id", DataType: "INT64"}))
+		require.NoError(t, err)
+		require.NoError(t, proc.Commit(ctx))
+
+		// the run failed so nothing is committed
+		dst, err := proc.Process(ctx, newTable(&facetsv1beta1.Column{Name: "id", DataType: "STRING"}))
+		require.NoError(t, err)
+		assert.Len(t, utils.GetCustomProperties(dst.Data())["schema_changes"], 1)
+
+		dst, err = proc.Process(ctx, newTable(&facetsv1beta1.Column{Name: "id", DataType: "STRING"}))
+		require.NoError(t, err)
+		assert.Len(t, utils.GetCustomProperties(dst.Data())["schema_changes"], 1)
+	})
+}
+
+func newProcessor(t *testing.T, emitEvent bool) *schemadiff.Processor {
+	proc := schemadiff.New(testUtils.Logger)
+	err := proc.Init(context.TODO(), map[string]interface{}{
+		"store_path": t.TempDir(),
+		"emit_event": emitEvent,
+	})
+	require.NoError(t, err)
+
+	return proc
+}
+
+func newTable(columns ...*facetsv1beta1.Column) models.Record {
+	return models.NewRecord(&assetsv1beta1.Table{
+		Resource: &commonv1beta1.Resource{
+			Urn:  "bigquery::project/dataset/table",
+			Type: "table",
+		},
+		Schema: &facetsv1beta1.Columns{
+			Columns: columns,
+		},
+	})
+}
@@ -0,0 +1,76 @@
+package schemadiff
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+)
+
+// column is the persisted state of a table column
+type column struct {
+	Name       string `json:"name"`
+	DataType   string `json:"data_type"`
+	IsNullable bool   `json:"is_nullable"`
+}
+
+// snapshot is the last seen schema of a table
+type snapshot struct {
+	URN     string   `json:"urn"`
+	Columns []column `json:"columns"`
+}
+
+// fileStore keeps a snapshot file per table URN inside a directory
+type fileStore struct {
+	dir string
+}
+
+func newFileStore(dir string) (*fileStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, errors.Wrapf(err, "failed to create store directory \"%s\"", dir)
+	}
+
+	return &fileStore{dir: dir}, nil
+}
+
+// get returns the last seen columns of the table, false is returned if the table has never been seen
+func (s *fileStore) get(urn string) (columns []column, found bool, err error) {
+	b, err := ioutil.ReadFile(s.path(urn))
+	if os.IsNotExist(err) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, errors.Wrapf(err, "failed to read snapshot of \"%s\"", urn)
+	}
+
+	var snap snapshot
+	if err = json.Unmarshal(b, &snap); err != nil {
+		return nil, false, errors.Wrapf(err, "failed to parse snapshot of \"%s\"", urn)
+	}
+
+	return snap.Columns, true, nil
+}
+
+// put saves the columns as the last seen schema of the table
+func (s *fileStore) put(urn string, columns []column) error {
+	b, err := json.Marshal(snapshot{URN: urn, Columns: columns})
+	if err != nil {
+		return errors.Wrapf(err, "failed to serialize snapshot of \"%s\"", urn)
+	}
+
+	// write to a temp file first so a crash never leaves a half written snapshot
+	tmp := s.path(urn) + ".tmp"
+	if err = ioutil.WriteFile(tmp, b, 0644); err != nil {
+		return errors.Wrapf(err, "failed to write snapshot of \"%s\"", urn)
+	}
+
+	return os.Rename(tmp, s.path(urn))
+}
+
+func (s *fileStore) path(urn string) string {
+	return filepath.Join(s.dir, fmt.Sprintf("%x.json", sha256.Sum256([]byte(urn))))
+}
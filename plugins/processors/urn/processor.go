@@ -0,0 +1,163 @@
+package urn
+
+import (
+	"context"
+	_ "embed"
+
+	"github.com/odpf/meteor/models"
+	facetsv1beta1 "github.com/odpf/meteor/models/odpf/assets/facets/v1beta1"
+	assetsv1beta1 "github.com/odpf/meteor/models/odpf/assets/v1beta1"
+	"github.com/odpf/meteor/plugins"
+	"github.com/odpf/meteor/registry"
+	"github.com/odpf/meteor/utils"
+	"github.com/odpf/salt/log"
+	"github.com/pkg/errors"
+)
+
+//go:embed README.md
+var summary string
+
+// Config holds the set of configuration for the processor
+type Config struct {
+	Rules []Rule `mapstructure:"rules" validate:"required,min=1,dive"`
+}
+
+var sampleConfig = `
+# Rules are applied in order to every urn of the record
+rules:
+  # Replace using a regular expression, capture groups can be referenced with $1
+  - type: regex
+    pattern: "^bq::(.+)$"
+    replacement: "bigquery::$1"
+  # Replace the host of "service::host/path" urns
+  - type: host_alias
+    aliases:
+      10.0.0.12:5432: postgres-main
+  # Convert the urn to lower or upper case
+  - type: case
+    case: lower`
+
+// Processor rewrites urns of records using ordered rules
+type Processor struct {
+	config    Config
+	logger    log.Logger
+	rewriters []rewriter
+}
+
+// New create a new processor
+func New(logger log.Logger) *Processor {
+	return &Processor{
+		logger: logger,
+	}
+}
+
+// Info returns the plugin information
+func (p *Processor) Info() plugins.Info {
+	return plugins.Info{
+		Description:  "Rewrite and normalize urns of records",
+		SampleConfig: sampleConfig,
+		Summary:      summary,
+		Tags:         []string{"processor", "transform"},
+	}
+}
+
+// Validate validates the plugin configuration
+func (p *Processor) Validate(configMap map[string]interface{}) (err error) {
+	var config Config
+	if err = utils.BuildConfig(configMap, &config); err != nil {
+		return err
+	}
+	for i, rule := range config.Rules {
+		if _, err = buildRewriter(rule); err != nil {
+			return errors.Wrapf(err, "invalid rule #%d", i+1)
+		}
+	}
+
+	return
+}
+
+// Init initializes the processor
+func (p *Processor) Init(ctx context.Context, configMap map[string]interface{}) (err error) {
+	if err = utils.BuildConfig(configMap, &p.config); err != nil {
+		return plugins.InvalidConfigError{Type: plugins.PluginTypeProcessor}
+	}
+
+	for i, rule := range p.config.Rules {
+		rw, err := buildRewriter(rule)
+		if err != nil {
+			return errors.Wrapf(err, "invalid rule #%d", i+1)
+		}
+		p.rewriters = append(p.rewriters, rw)
+	}
+
+	return
+}
+
+// Process rewrites the urn of the record and every urn it references
+func (p *Processor) Process(ctx context.Context, src models.Record) (dst models.Record, err error) {
+	data := src.Data()
+	if resource := data.GetResource(); resource != nil {
+		resource.Urn = p.rewrite(resource.Urn)
+	}
+	p.rewriteLineage(utils.GetLineage(data))
+	p.rewriteOwnership(utils.GetOwnership(data))
+
+	switch data := data.(type) {
+	case *assetsv1beta1.Dashboard:
+		for _, chart := range data.GetCharts() {
+			chart.Urn = p.rewrite(chart.Urn)
+			chart.DashboardUrn = p.rewrite(chart.DashboardUrn)
+			p.rewriteLineage(chart.GetLineage())
+			p.rewriteOwnership(chart.GetOwnership())
+		}
+	case *assetsv1beta1.Table:
+		for _, join := range data.GetProfile().GetJoins() {
+			join.Urn = p.rewrite(join.Urn)
+		}
+	case *assetsv1beta1.Group:
+		for _, member := range data.GetMembers() {
+			member.Urn = p.rewrite(member.Urn)
+		}
+	case *assetsv1beta1.User:
+		for _, membership := range data.GetMemberships() {
+			membership.GroupUrn = p.rewrite(membership.GroupUrn)
+		}
+	}
+
+	return src, nil
+}
+
+func (p *Processor) rewriteLineage(lineage *facetsv1beta1.Lineage) {
+	for _, upstream := range lineage.GetUpstreams() {
+		upstream.Urn = p.rewrite(upstream.Urn)
+	}
+	for _, downstream := range lineage.GetDownstreams() {
+		downstream.Urn = p.rewrite(downstream.Urn)
+	}
+}
+
+func (p *Processor) rewriteOwnership(ownership *facetsv1beta1.Ownership) {
+	for _, owner := range ownership.GetOwners() {
+		owner.Urn = p.rewrite(owner.Urn)
+	}
+}
+
+// rewrite applies every rule to the urn in order
+func (p *Processor) rewrite(urn string) string {
+	if urn == "" {
+		return urn
+	}
+	for _, rw := range p.rewriters {
+		urn = rw(urn)
+	}
+
+	return urn
+}
+
+func init() {
+	if err := registry.Processors.Register("urn", func() plugins.Processor {
+		return New(plugins.GetLog())
+	}); err != nil {
+		return
+	}
+}
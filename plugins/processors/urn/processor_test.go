@@ -0,0 +1,117 @@
+package urn_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/odpf/meteor/models"
+	commonv1beta1 "github.com/odpf/meteor/models/odpf/assets/common/v1beta1"
+	facetsv1beta1 "github.com/odpf/meteor/models/odpf/assets/facets/v1beta1"
+	assetsv1beta1 "github.com/odpf/meteor/models/odpf/assets/v1beta1"
+	"github.com/odpf/meteor/plugins"
+	"github.com/odpf/meteor/plugins/processors/urn"
+	testUtils "github.com/odpf/meteor/test/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	t.Run("should return error on invalid pattern", func(t *testing.T) {
+		err := urn.New(testUtils.Logger).Validate(map[string]interface{}{
+			"rules": []interface{}{
+				map[string]interface{}{"type": "regex", "pattern": "(unclosed"},
+			},
+		})
+		assert.Error(t, err)
+	})
+}
+
+func TestInit(t *testing.T) {
+	t.Run("should return InvalidConfigError if rules are missing", func(t *testing.T) {
+		err := urn.New(testUtils.Logger).Init(context.TODO(), map[string]interface{}{})
+		assert.Equal(t, plugins.InvalidConfigError{Type: plugins.PluginTypeProcessor}, err)
+	})
+}
+
+func TestProcess(t *testing.T) {
+	config := map[string]interface{}{
+		"rules": []interface{}{
+			map[string]interface{}{
+				"type":        "regex",
+				"pattern":     "^bq::(.+)$",
+				"replacement": "bigquery::$1",
+			},
+			map[string]interface{}{
+				"type": "host_alias",
+				"aliases": map[string]interface{}{
+					"10.0.0.12:5432": "postgres-main",
+				},
+			},
+			map[string]interface{}{
+				"type": "case",
+			},
+		},
+	}
+
+	t.Run("should rewrite urn of the record and its references", func(t *testing.T) {
+		ctx := context.TODO()
+		proc := urn.New(testUtils.Logger)
+		require.NoError(t, proc.Init(ctx, config))
+
+		dashboard := &assetsv1beta1.Dashboard{
+			Resource: &commonv1beta1.Resource{Urn: "Metabase::Prod/dashboard/1"},
+			Charts: []*assetsv1beta1.Chart{
+				{
+					Urn:          "Metabase::Prod/card/1",
+					DashboardUrn: "Metabase::Prod/dashboard/1",
+				},
+			},
+			Lineage: &facetsv1beta1.Lineage{
+				Upstreams: []*commonv1beta1.Resource{
+					{Urn: "bq::project/dataset/table"},
+					{Urn: "postgres::10.0.0.12:5432/db/table"},
+				},
+			},
+			Ownership: &facetsv1beta1.Ownership{
+				Owners: []*facetsv1beta1.Owner{{Urn: "User@ODPF.io"}},
+			},
+		}
+
+		_, err := proc.Process(ctx, models.NewRecord(dashboard))
+		require.NoError(t, err)
+
+		assert.Equal(t, "metabase::prod/dashboard/1", dashboard.Resource.Urn)
+		assert.Equal(t, "metabase::prod/card/1", dashboard.Charts[0].Urn)
+		assert.Equal(t, "metabase::prod/dashboard/1", dashboard.Charts[0].DashboardUrn)
+		assert.Equal(t, "bigquery::project/dataset/table", dashboard.Lineage.Upstreams[0].Urn)
+		assert.Equal(t, "postgres::postgres-main/db/table", dashboard.Lineage.Upstreams[1].Urn)
+		assert.Equal(t, "user@odpf.io", dashboard.Ownership.Owners[0].Urn)
+	})
+
+	t.Run("should rewrite joins and members", func(t *testing.T) {
+		ctx := context.TODO()
+		proc := urn.New(testUtils.Logger)
+		require.NoError(t, proc.Init(ctx, config))
+
+		table := &assetsv1beta1.Table{
+			Resource: &commonv1beta1.Resource{Urn: "bq::project/dataset/a"},
+			Profile: &assetsv1beta1.TableProfile{
+				Joins: []*assetsv1beta1.Join{{Urn: "bq::project/dataset/b"}},
+			},
+		}
+		group := &assetsv1beta1.Group{
+			Resource: &commonv1beta1.Resource{Urn: "shield::Group"},
+			Members:  []*assetsv1beta1.Member{{Urn: "shield::User"}},
+		}
+
+		_, err := proc.Process(ctx, models.NewRecord(table))
+		require.NoError(t, err)
+		_, err = proc.Process(ctx, models.NewRecord(group))
+		require.NoError(t, err)
+
+		assert.Equal(t, "bigquery::project/dataset/a", table.Resource.Urn)
+		assert.Equal(t, "bigquery::project/dataset/b", table.Profile.Joins[0].Urn)
+		assert.Equal(t, "shield::group", group.Resource.Urn)
+		assert.Equal(t, "shield::user", group.Members[0].Urn)
+	})
+}
@@ -0,0 +1,70 @@
+package urn
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Rule types
+const (
+	RuleTypeRegex     = "regex"
+	RuleTypeHostAlias = "host_alias"
+	RuleTypeCase      = "case"
+)
+
+// Rule holds the configuration of a single rewrite rule
+type Rule struct {
+	Type        string            `mapstructure:"type" validate:"oneof=regex host_alias case"`
+	Pattern     string            `mapstructure:"pattern" validate:"required_if=Type regex"`
+	Replacement string            `mapstructure:"replacement"`
+	Aliases     map[string]string `mapstructure:"aliases" validate:"required_if=Type host_alias"`
+	Case        string            `mapstructure:"case" validate:"omitempty,oneof=lower upper"`
+}
+
+type rewriter func(urn string) string
+
+// buildRewriter builds the rewrite function of the rule
+func buildRewriter(rule Rule) (rewriter, error) {
+	switch rule.Type {
+	case RuleTypeRegex:
+		re, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid pattern \"%s\"", rule.Pattern)
+		}
+		return func(urn string) string {
+			return re.ReplaceAllString(urn, rule.Replacement)
+		}, nil
+	case RuleTypeHostAlias:
+		return hostAlias(rule.Aliases), nil
+	case RuleTypeCase:
+		if rule.Case == "upper" {
+			return strings.ToUpper, nil
+		}
+		return strings.ToLower, nil
+	}
+
+	return nil, errors.Errorf("unknown rule type \"%s\"", rule.Type)
+}
+
+// hostAlias replaces the host of urns built as "service::host/path"
+func hostAlias(aliases map[string]string) rewriter {
+	return func(urn string) string {
+		i := strings.Index(urn, "::")
+		if i < 0 {
+			return urn
+		}
+
+		host, path := urn[i+2:], ""
+		if j := strings.Index(host, "/"); j >= 0 {
+			host, path = host[:j], host[j:]
+		}
+		alias, ok := aliases[host]
+		if !ok {
+			return urn
+		}
+
+		return urn[:i+2] + alias + path
+	}
+}
@@ -0,0 +1,43 @@
+package plugins
+
+import (
+	"errors"
+	"net"
+	"net/http"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// IsRetryableStatus returns true for HTTP statuses worth retrying, too many requests and server errors.
+func IsRetryableStatus(code int) bool {
+	return code == http.StatusTooManyRequests || code >= 500
+}
+
+// IsRetryable returns true for errors worth retrying, which are errors wrapped in a RetryError,
+// network timeouts, HTTPStatusErrors of retryable statuses and gRPC errors of the Unavailable code.
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, RetryError{}) {
+		return true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+
+	var statusErr HTTPStatusError
+	if errors.As(err, &statusErr) && IsRetryableStatus(statusErr.StatusCode) {
+		return true
+	}
+
+	var grpcErr interface{ GRPCStatus() *status.Status }
+	if errors.As(err, &grpcErr) && grpcErr.GRPCStatus().Code() == codes.Unavailable {
+		return true
+	}
+
+	return false
+}
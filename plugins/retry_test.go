@@ -0,0 +1,38 @@
+package plugins_test
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/odpf/meteor/plugins"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestIsRetryable(t *testing.T) {
+	cases := []struct {
+		description string
+		err         error
+		expected    bool
+	}{
+		{"nil", nil, false},
+		{"plain error", errors.New("some-error"), false},
+		{"retry error", plugins.NewRetryError(errors.New("some-error")), true},
+		{"wrapped retry error", fmt.Errorf("sink failed: %w", plugins.NewRetryError(errors.New("some-error"))), true},
+		{"network timeout", &net.DNSError{Err: "i/o timeout", IsTimeout: true}, true},
+		{"network error which is not a timeout", &net.DNSError{Err: "no such host", IsNotFound: true}, false},
+		{"too many requests", plugins.HTTPStatusError{StatusCode: 429, Err: errors.New("slow down")}, true},
+		{"server error", fmt.Errorf("request failed: %w", plugins.HTTPStatusError{StatusCode: 503, Err: errors.New("unavailable")}), true},
+		{"client error", plugins.HTTPStatusError{StatusCode: 400, Err: errors.New("bad request")}, false},
+		{"grpc unavailable", fmt.Errorf("request failed: %w", status.Error(codes.Unavailable, "connection refused")), true},
+		{"grpc invalid argument", status.Error(codes.InvalidArgument, "invalid asset"), false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.description, func(t *testing.T) {
+			assert.Equal(t, tc.expected, plugins.IsRetryable(tc.err))
+		})
+	}
+}
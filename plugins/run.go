@@ -0,0 +1,26 @@
+package plugins
+
+import (
+	"context"
+	"time"
+)
+
+type runInfoKey struct{}
+
+// RunInfo describes the recipe run a plugin is executed in.
+type RunInfo struct {
+	RecipeName string
+	StartedAt  time.Time
+}
+
+// ContextWithRunInfo returns a copy of ctx carrying the run information.
+func ContextWithRunInfo(ctx context.Context, info RunInfo) context.Context {
+	return context.WithValue(ctx, runInfoKey{}, info)
+}
+
+// RunInfoFromContext returns the run information carried by ctx,
+// a zero RunInfo is returned when ctx does not carry any.
+func RunInfoFromContext(ctx context.Context) RunInfo {
+	info, _ := ctx.Value(runInfoKey{}).(RunInfo)
+	return info
+}
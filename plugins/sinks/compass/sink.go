@@ -8,7 +8,9 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/odpf/meteor/models"
 	"github.com/odpf/meteor/plugins"
@@ -16,15 +18,19 @@ import (
 	"github.com/odpf/meteor/utils"
 	"github.com/odpf/salt/log"
 	"github.com/pkg/errors"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
 )
 
 //go:embed README.md
 var summary string
 
 type Config struct {
-	Host    string            `mapstructure:"host" validate:"required"`
-	Headers map[string]string `mapstructure:"headers"`
-	Labels  map[string]string `mapstructure:"labels"`
+	Host       string            `mapstructure:"host" validate:"required"`
+	Headers    map[string]string `mapstructure:"headers"`
+	Labels     map[string]string `mapstructure:"labels"`
+	MaxWorkers int               `mapstructure:"max_workers" validate:"min=1" default:"1"`
+	RateLimit  float64           `mapstructure:"rate_limit" validate:"min=0" default:"0"`
 }
 
 var sampleConfig = `
@@ -34,10 +40,16 @@ host: https://compass.com
 headers:
 	Compass-User-Email: meteor@odpf.io
 	X-Other-Header: value1, value2
-# The labels to pass as payload label of the patch api
+# The labels to pass as payload label of the patch api, values can reference any field path of the asset
 labels:
 	myCustom: $properties.attributes.myCustomField
 	sampleLabel: $properties.labels.sampleLabelField
+	service: $resource.service
+	columnCount: $schema.columns.length
+# Number of records of a batch sent concurrently
+max_workers: 4
+# Maximum number of requests per second sent to compass, 0 means no limit
+rate_limit: 10
 `
 
 type httpClient interface {
@@ -45,9 +57,10 @@ type httpClient interface {
 }
 
 type Sink struct {
-	client httpClient
-	config Config
-	logger log.Logger
+	client  httpClient
+	config  Config
+	logger  log.Logger
+	limiter *utils.RateLimiter
 }
 
 func New(c httpClient, logger log.Logger) plugins.Syncer {
@@ -72,32 +85,78 @@ func (s *Sink) Init(ctx context.Context, configMap map[string]interface{}) (err
 	if err = utils.BuildConfig(configMap, &s.config); err != nil {
 		return plugins.InvalidConfigError{Type: plugins.PluginTypeSink}
 	}
+	s.limiter = utils.NewRateLimiter(s.config.RateLimit)
 
 	return
 }
 
+// Sink sends records of the batch to compass using up to max_workers concurrent requests.
+// The records which failed are returned in a PartialSinkError, as compass upserts assets one at a time.
+// It is a RetryError if every failure can be retried, so only the failed records are sent again.
 func (s *Sink) Sink(ctx context.Context, batch []models.Record) (err error) {
-	for _, record := range batch {
-		metadata := record.Data()
-		s.logger.Info("sinking record to compass", "record", metadata.GetResource().Urn)
-
-		compassPayload, err := s.buildCompassPayload(metadata)
-		if err != nil {
-			return errors.Wrap(err, "failed to build compass payload")
+	errs := make([]error, len(batch))
+	sem := make(chan struct{}, s.config.MaxWorkers)
+	var wg sync.WaitGroup
+	for i, record := range batch {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, metadata models.Metadata) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			errs[i] = s.sinkRecord(ctx, metadata)
+		}(i, record.Data())
+	}
+	wg.Wait()
+
+	var (
+		partialErr plugins.PartialSinkError
+		retryable  = true
+	)
+	for i, e := range errs {
+		if e == nil {
+			continue
 		}
-		if err = s.send(compassPayload); err != nil {
-			return errors.Wrap(err, "error sending data")
+		partialErr.FailedURNs = append(partialErr.FailedURNs, batch[i].Data().GetResource().GetUrn())
+		// a permanent failure is kept as the cause so the batch is not retried
+		isRetryable := errors.Is(e, plugins.RetryError{})
+		if partialErr.Err == nil || (retryable && !isRetryable) {
+			partialErr.Err = e
 		}
+		retryable = retryable && isRetryable
+	}
+	if partialErr.Err == nil {
+		return nil
+	}
+	if retryable {
+		return plugins.NewRetryError(partialErr)
+	}
+
+	return partialErr
+}
 
-		s.logger.Info("successfully sinked record to compass", "record", metadata.GetResource().Urn)
+func (s *Sink) sinkRecord(ctx context.Context, metadata models.Metadata) error {
+	s.logger.Info("sinking record to compass", "record", metadata.GetResource().Urn)
+
+	compassPayload, err := s.buildCompassPayload(metadata)
+	if err != nil {
+		return errors.Wrap(err, "failed to build compass payload")
+	}
+	if err = s.limiter.Wait(ctx); err != nil {
+		return err
+	}
+	if err = s.send(ctx, compassPayload); err != nil {
+		return errors.Wrap(err, "error sending data")
 	}
 
-	return
+	s.logger.Info("successfully sinked record to compass", "record", metadata.GetResource().Urn)
+	return nil
 }
 
 func (s *Sink) Close() (err error) { return }
 
-func (s *Sink) send(record RequestPayload) (err error) {
+func (s *Sink) send(ctx context.Context, record RequestPayload) (err error) {
 	payloadBytes, err := json.Marshal(record)
 	if err != nil {
 		return
@@ -105,7 +164,7 @@ func (s *Sink) send(record RequestPayload) (err error) {
 
 	// send request
 	url := fmt.Sprintf("%s/v1beta1/assets", s.config.Host)
-	req, err := http.NewRequest(http.MethodPatch, url, bytes.NewBuffer(payloadBytes))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPatch, url, bytes.NewBuffer(payloadBytes))
 	if err != nil {
 		return
 	}
@@ -119,9 +178,10 @@ func (s *Sink) send(record RequestPayload) (err error) {
 
 	res, err := s.client.Do(req)
 	if err != nil {
-		return
+		return plugins.NewRetryError(err)
 	}
-	if res.StatusCode == 200 {
+	defer res.Body.Close()
+	if res.StatusCode >= 200 && res.StatusCode < 300 {
 		return
 	}
 
@@ -132,8 +192,9 @@ func (s *Sink) send(record RequestPayload) (err error) {
 	}
 	err = fmt.Errorf("compass returns %d: %v", res.StatusCode, string(bodyBytes))
 
+	// too many requests and server errors are worth retrying, any other status is a permanent failure
 	switch code := res.StatusCode; {
-	case code >= 500:
+	case plugins.IsRetryableStatus(code):
 		return plugins.NewRetryError(err)
 	default:
 		return err
@@ -224,10 +285,16 @@ func (s *Sink) buildLabels(metadata models.Metadata) (labels map[string]string,
 		return
 	}
 
+	asset, err := toMap(metadata)
+	if err != nil {
+		err = errors.Wrap(err, "failed to read asset fields")
+		return
+	}
+
 	labels = map[string]string{}
 	for key, template := range s.config.Labels {
 		var value string
-		value, err = s.buildLabelValue(template, metadata)
+		value, err = s.buildLabelValue(template, asset)
 		if err != nil {
 			err = errors.Wrapf(err, "could not find \"%s\"", template)
 			return
@@ -239,63 +306,66 @@ func (s *Sink) buildLabels(metadata models.Metadata) (labels map[string]string,
 	return
 }
 
-func (s *Sink) buildLabelValue(template string, metadata models.Metadata) (value string, err error) {
-	fields := strings.Split(template, ".")
-	if len(fields) < 3 {
-		err = errors.New("label template has to be at least nested 3 levels")
+// buildLabelValue resolves a template such as "$resource.service" or "$schema.columns.length" on the asset,
+// "length" returns the number of items of a list and list items can be referenced by their index
+func (s *Sink) buildLabelValue(template string, asset map[string]interface{}) (value string, err error) {
+	if !strings.HasPrefix(template, "$") || len(template) < 2 {
+		err = errors.New("invalid label template format, has to start with \"$\" followed by a field path")
 		return
 	}
 
-	switch fields[0] {
-	case "$properties":
-		value, err = s.getLabelValueFromProperties(fields[1], fields[2], metadata)
-		if err != nil {
-			err = errors.Wrapf(err, "error getting label value from $properties")
+	var current interface{} = asset
+	for _, field := range strings.Split(template[1:], ".") {
+		switch v := current.(type) {
+		case map[string]interface{}:
+			var ok bool
+			if current, ok = v[field]; !ok || current == nil {
+				err = fmt.Errorf("could not find \"%s\" field", field)
+				return
+			}
+		case []interface{}:
+			if field == "length" {
+				current = float64(len(v))
+				continue
+			}
+			i, convErr := strconv.Atoi(field)
+			if convErr != nil || i < 0 || i >= len(v) {
+				err = fmt.Errorf("invalid index \"%s\"", field)
+				return
+			}
+			current = v[i]
+		default:
+			err = fmt.Errorf("could not find \"%s\" field", field)
+			return
 		}
-		return
 	}
 
-	err = errors.New("invalid label template format")
+	switch v := current.(type) {
+	case string:
+		return v, nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	}
+
+	err = errors.New("field is not a scalar value")
 	return
 }
 
-func (s *Sink) getLabelValueFromProperties(field1 string, field2 string, metadata models.Metadata) (value string, err error) {
-	switch field1 {
-	case "attributes":
-		attr := utils.GetCustomProperties(metadata)
-		v, ok := attr[field2]
-		if !ok {
-			err = fmt.Errorf("could not find \"%s\" field on attributes", field2)
-			return
-		}
-		value, ok = v.(string)
-		if !ok {
-			err = fmt.Errorf("\"%s\" field is not a string", field2)
-			return
-		}
-		return
-	case "labels":
-		properties := metadata.GetProperties()
-		if properties == nil {
-			err = errors.New("could not find properties field")
-			return
-		}
-		labels := properties.GetLabels()
-		if properties == nil {
-			err = errors.New("could not find labels field")
-			return
-		}
-		var ok bool
-		value, ok = labels[field2]
-		if !ok {
-			err = fmt.Errorf("could not find \"%s\" from labels", field2)
-			return
-		}
+// toMap converts the asset into a map keyed by proto field names
+func toMap(metadata models.Metadata) (asset map[string]interface{}, err error) {
+	msg, ok := metadata.(proto.Message)
+	if !ok {
+		return nil, errors.New("asset is not a proto message")
+	}
 
+	b, err := protojson.MarshalOptions{UseProtoNames: true, EmitUnpopulated: true}.Marshal(msg)
+	if err != nil {
 		return
 	}
+	err = json.Unmarshal(b, &asset)
 
-	err = errors.New("invalid label template format")
 	return
 }
 
@@ -8,7 +8,10 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 
 	testUtils "github.com/odpf/meteor/test/utils"
@@ -21,6 +24,7 @@ import (
 	"github.com/odpf/meteor/plugins"
 	"github.com/odpf/meteor/plugins/sinks/compass"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 var (
@@ -469,6 +473,161 @@ func TestSink(t *testing.T) {
 	}
 }
 
+func TestSinkWithServer(t *testing.T) {
+	t.Run("should send every record of the batch using workers", func(t *testing.T) {
+		var (
+			mu   sync.Mutex
+			urns []string
+		)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, http.MethodPatch, r.Method)
+			assert.Equal(t, "/v1beta1/assets", r.URL.Path)
+
+			var payload struct {
+				Asset struct {
+					URN string `json:"urn"`
+				} `json:"asset"`
+			}
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+			mu.Lock()
+			urns = append(urns, payload.Asset.URN)
+			mu.Unlock()
+			w.WriteHeader(http.StatusCreated)
+		}))
+		defer server.Close()
+
+		compassSink := newServerSink(t, map[string]interface{}{
+			"host":        server.URL,
+			"max_workers": 3,
+			"rate_limit":  1000,
+		})
+
+		var batch []models.Record
+		for i := 0; i < 10; i++ {
+			batch = append(batch, models.NewRecord(&assetsv1beta1.Table{
+				Resource: &commonv1beta1.Resource{Urn: fmt.Sprintf("table-%d", i)},
+			}))
+		}
+		err := compassSink.Sink(context.TODO(), batch)
+		assert.NoError(t, err)
+		assert.Len(t, urns, 10)
+	})
+
+	t.Run("should return RetryError on 429", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusTooManyRequests)
+		}))
+		defer server.Close()
+
+		compassSink := newServerSink(t, map[string]interface{}{"host": server.URL})
+		err := compassSink.Sink(context.TODO(), []models.Record{
+			models.NewRecord(&assetsv1beta1.Table{Resource: &commonv1beta1.Resource{Urn: "table-a"}}),
+		})
+		assert.True(t, errors.Is(err, plugins.RetryError{}))
+	})
+
+	t.Run("should return permanent error if any record fails with 4xx", func(t *testing.T) {
+		var calls int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if atomic.AddInt32(&calls, 1) == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.WriteHeader(http.StatusBadRequest)
+		}))
+		defer server.Close()
+
+		compassSink := newServerSink(t, map[string]interface{}{"host": server.URL})
+		err := compassSink.Sink(context.TODO(), []models.Record{
+			models.NewRecord(&assetsv1beta1.Table{Resource: &commonv1beta1.Resource{Urn: "table-a"}}),
+			models.NewRecord(&assetsv1beta1.Table{Resource: &commonv1beta1.Resource{Urn: "table-b"}}),
+		})
+		assert.Error(t, err)
+		assert.False(t, errors.Is(err, plugins.RetryError{}))
+		var partialErr plugins.PartialSinkError
+		require.True(t, errors.As(err, &partialErr))
+		assert.ElementsMatch(t, []string{"table-a", "table-b"}, partialErr.FailedURNs)
+	})
+
+	t.Run("should return the records which failed with a RetryError", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var payload struct {
+				Asset struct {
+					URN string `json:"urn"`
+				} `json:"asset"`
+			}
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+			if payload.Asset.URN == "table-b" {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.WriteHeader(http.StatusCreated)
+		}))
+		defer server.Close()
+
+		compassSink := newServerSink(t, map[string]interface{}{"host": server.URL, "max_workers": 2})
+		err := compassSink.Sink(context.TODO(), []models.Record{
+			models.NewRecord(&assetsv1beta1.Table{Resource: &commonv1beta1.Resource{Urn: "table-a"}}),
+			models.NewRecord(&assetsv1beta1.Table{Resource: &commonv1beta1.Resource{Urn: "table-b"}}),
+			models.NewRecord(&assetsv1beta1.Table{Resource: &commonv1beta1.Resource{Urn: "table-c"}}),
+		})
+		assert.True(t, errors.Is(err, plugins.RetryError{}))
+		var partialErr plugins.PartialSinkError
+		require.True(t, errors.As(err, &partialErr))
+		assert.Equal(t, []string{"table-b"}, partialErr.FailedURNs)
+	})
+
+	t.Run("should build labels from asset field paths", func(t *testing.T) {
+		var labels map[string]string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var payload struct {
+				Asset struct {
+					Labels map[string]string `json:"labels"`
+				} `json:"asset"`
+			}
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+			labels = payload.Asset.Labels
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+
+		compassSink := newServerSink(t, map[string]interface{}{
+			"host": server.URL,
+			"labels": map[string]string{
+				"service":     "$resource.service",
+				"columnCount": "$schema.columns.length",
+				"firstColumn": "$schema.columns.0.name",
+				"nullable":    "$schema.columns.1.is_nullable",
+			},
+		})
+		err := compassSink.Sink(context.TODO(), []models.Record{
+			models.NewRecord(&assetsv1beta1.Table{
+				Resource: &commonv1beta1.Resource{Urn: "table-a", Service: "bigquery"},
+				Schema: &facetsv1beta1.Columns{
+					Columns: []*facetsv1beta1.Column{
+						{Name: "id"},
+						{Name: "email", IsNullable: true},
+					},
+				},
+			}),
+		})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{
+			"service":     "bigquery",
+			"columnCount": "2",
+			"firstColumn": "id",
+			"nullable":    "true",
+		}, labels)
+	})
+}
+
+func newServerSink(t *testing.T, config map[string]interface{}) plugins.Syncer {
+	compassSink := compass.New(http.DefaultClient, testUtils.Logger)
+	require.NoError(t, compassSink.Init(context.TODO(), config))
+
+	return compassSink
+}
+
 type mockHTTPClient struct {
 	URL            string
 	Method         string
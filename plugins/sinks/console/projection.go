@@ -0,0 +1,127 @@
+package console
+
+import (
+	"strings"
+)
+
+// project returns the asset with the fields of paths only,
+// a path is a list of fields separated by dots where "[]" selects the field of every element of a list,
+// e.g. "schema.columns[].name"
+func project(asset map[string]interface{}, paths []string) map[string]interface{} {
+	result := make(map[string]interface{})
+	for _, path := range paths {
+		value, ok := pick(asset, splitPath(path))
+		if !ok {
+			continue
+		}
+		result = merge(result, value).(map[string]interface{})
+	}
+
+	return result
+}
+
+// splitPath splits "schema.columns[].name" into "schema", "columns", "[]" and "name"
+func splitPath(path string) (segments []string) {
+	for _, field := range strings.Split(path, ".") {
+		name := strings.TrimSuffix(field, "[]")
+		if name != "" {
+			segments = append(segments, name)
+		}
+		if name != field {
+			segments = append(segments, "[]")
+		}
+	}
+
+	return segments
+}
+
+// pick returns a copy of value holding only the path
+func pick(value interface{}, path []string) (interface{}, bool) {
+	if len(path) == 0 {
+		return value, true
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		field, ok := v[path[0]]
+		if !ok {
+			return nil, false
+		}
+		picked, ok := pick(field, path[1:])
+		if !ok {
+			return nil, false
+		}
+		return map[string]interface{}{path[0]: picked}, true
+	case []interface{}:
+		if path[0] != "[]" {
+			return nil, false
+		}
+		list := make([]interface{}, len(v))
+		for i, elem := range v {
+			// elements missing the field are kept empty so that lists of different paths can be merged by index
+			picked, ok := pick(elem, path[1:])
+			if !ok {
+				picked = map[string]interface{}{}
+			}
+			list[i] = picked
+		}
+		return list, true
+	}
+
+	return nil, false
+}
+
+// merge merges the maps recursively and the lists element by element, b wins for other values
+func merge(a, b interface{}) interface{} {
+	switch bv := b.(type) {
+	case map[string]interface{}:
+		av, ok := a.(map[string]interface{})
+		if !ok {
+			return bv
+		}
+		for key, val := range bv {
+			if existing, ok := av[key]; ok {
+				av[key] = merge(existing, val)
+				continue
+			}
+			av[key] = val
+		}
+		return av
+	case []interface{}:
+		av, ok := a.([]interface{})
+		if !ok || len(av) != len(bv) {
+			return bv
+		}
+		for i := range bv {
+			av[i] = merge(av[i], bv[i])
+		}
+		return av
+	}
+
+	return b
+}
+
+// lookup returns the value at the path, the values of the elements for "[]"
+func lookup(value interface{}, path []string) interface{} {
+	if len(path) == 0 {
+		return value
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return lookup(v[path[0]], path[1:])
+	case []interface{}:
+		if path[0] != "[]" {
+			return nil
+		}
+		var values []interface{}
+		for _, elem := range v {
+			if found := lookup(elem, path[1:]); found != nil {
+				values = append(values, found)
+			}
+		}
+		return values
+	}
+
+	return nil
+}
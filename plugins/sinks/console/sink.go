@@ -1,42 +1,96 @@
 package console
 
 import (
+	"bytes"
 	"context"
 	_ "embed"
 	"encoding/json"
 	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
 
 	"github.com/odpf/meteor/models"
 	"github.com/odpf/meteor/plugins"
 	"github.com/odpf/meteor/registry"
+	"github.com/odpf/meteor/utils"
 	"github.com/odpf/salt/log"
+	"github.com/odpf/salt/term"
+	"github.com/olekukonko/tablewriter"
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"gopkg.in/yaml.v3"
 )
 
 //go:embed README.md
 var summary string
 
+// Output formats
+const (
+	formatJSON  = "json"
+	formatYAML  = "yaml"
+	formatTable = "table"
+	formatTree  = "tree"
+)
+
+// defaultTableFields are the columns of the table format when no fields are set
+var defaultTableFields = []string{"resource.type", "resource.urn", "resource.name", "resource.service"}
+
+type Config struct {
+	Format string `mapstructure:"format" validate:"oneof=json yaml table tree" default:"json"`
+	// Fields are the paths of the fields to print, e.g. resource.urn or schema.columns[].name
+	Fields  []string `mapstructure:"fields"`
+	Color   bool     `mapstructure:"color" default:"true"`
+	Summary bool     `mapstructure:"summary" default:"false"`
+}
+
+var sampleConfig = `
+# Output format, one of json, yaml, table or tree
+format: tree
+# Fields to print, every field is printed if empty
+fields:
+  - resource.urn
+  - schema.columns[].name
+# Colorize the table and tree formats
+color: true
+# Print the number of records by asset type once the run completes
+summary: true`
+
 type Sink struct {
+	out    io.Writer
 	logger log.Logger
+	config Config
+	cs     *term.ColorScheme
+	rows   [][]string
+	counts map[string]int
 }
 
-func New() plugins.Syncer {
-	return new(Sink)
+func New(out io.Writer, logger log.Logger) plugins.Syncer {
+	return &Sink{out: out, logger: logger}
 }
 
 func (s *Sink) Info() plugins.Info {
 	return plugins.Info{
 		Description:  "Log to standard output",
-		SampleConfig: "",
+		SampleConfig: sampleConfig,
 		Summary:      summary,
 		Tags:         []string{"log", "sink"},
 	}
 }
 
 func (s *Sink) Validate(configMap map[string]interface{}) (err error) {
-	return nil
+	return utils.BuildConfig(configMap, &Config{})
 }
 
-func (s *Sink) Init(ctx context.Context, config map[string]interface{}) (err error) {
+func (s *Sink) Init(ctx context.Context, configMap map[string]interface{}) (err error) {
+	if err = utils.BuildConfig(configMap, &s.config); err != nil {
+		return plugins.InvalidConfigError{Type: plugins.PluginTypeSink, PluginName: "console"}
+	}
+	s.cs = term.NewColorScheme()
+	s.counts = make(map[string]int)
+
 	return
 }
 
@@ -49,22 +103,221 @@ func (s *Sink) Sink(ctx context.Context, batch []models.Record) (err error) {
 	return nil
 }
 
-func (s *Sink) Close() (err error) { return }
+// Close prints the table of the records and the summary
+func (s *Sink) Close() (err error) {
+	if s.config.Format == formatTable && len(s.rows) > 0 {
+		fields := s.tableFields()
+		header := make([]string, len(fields))
+		for i, field := range fields {
+			header[i] = s.paint(s.cs.Greenf, "%s", strings.ToUpper(field))
+		}
+		s.printTable(append([][]string{header}, s.rows...))
+	}
+	if s.config.Summary {
+		s.printSummary()
+	}
+
+	return
+}
+
+// printTable writes the rows as a borderless table to the output of the sink
+func (s *Sink) printTable(rows [][]string) {
+	table := tablewriter.NewWriter(s.out)
+	table.SetAutoWrapText(false)
+	table.SetAutoFormatHeaders(true)
+	table.SetHeaderAlignment(tablewriter.ALIGN_LEFT)
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+	table.SetCenterSeparator("")
+	table.SetColumnSeparator("")
+	table.SetRowSeparator("")
+	table.SetHeaderLine(false)
+	table.SetBorder(false)
+	table.SetTablePadding("\t")
+	table.SetNoWhiteSpace(true)
+	table.AppendBulk(rows)
+	table.Render()
+}
+
+func (s *Sink) process(metadata models.Metadata) error {
+	s.counts[utils.GetAssetType(metadata)]++
+	if s.config.Format == formatJSON {
+		return s.printJSON(metadata)
+	}
 
-func (s *Sink) process(value interface{}) error {
-	jsonBytes, err := json.Marshal(value)
+	asset, err := toMap(metadata)
+	if err != nil {
+		return errors.Wrapf(err, "failed to convert \"%s\"", metadata.GetResource().GetUrn())
+	}
+
+	switch s.config.Format {
+	case formatYAML:
+		if len(s.config.Fields) > 0 {
+			asset = project(asset, s.config.Fields)
+		}
+		b, err := yaml.Marshal(asset)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(s.out, "---\n%s", b)
+	case formatTable:
+		row := make([]string, 0, len(s.tableFields()))
+		for _, field := range s.tableFields() {
+			row = append(row, cell(lookup(asset, splitPath(field))))
+		}
+		s.rows = append(s.rows, row)
+	case formatTree:
+		if len(s.config.Fields) > 0 {
+			asset = project(asset, s.config.Fields)
+		}
+		fmt.Fprintln(s.out, s.paint(s.cs.Greenf, "%s", utils.GetAssetType(metadata)), metadata.GetResource().GetUrn())
+		s.printTree(asset, "")
+	}
+
+	return nil
+}
+
+// printJSON prints the record on a line encoded with encoding/json as the sink always did,
+// int64 values are numbers and timestamps are objects of seconds and nanos
+func (s *Sink) printJSON(metadata models.Metadata) error {
+	b, err := json.Marshal(metadata)
 	if err != nil {
 		return err
 	}
-	fmt.Println(string(jsonBytes))
+	if len(s.config.Fields) > 0 {
+		var asset map[string]interface{}
+		d := json.NewDecoder(bytes.NewReader(b))
+		d.UseNumber()
+		if err = d.Decode(&asset); err != nil {
+			return err
+		}
+		if b, err = json.Marshal(project(asset, s.config.Fields)); err != nil {
+			return err
+		}
+	}
+	fmt.Fprintln(s.out, string(b))
+
 	return nil
 }
 
+func (s *Sink) tableFields() []string {
+	if len(s.config.Fields) > 0 {
+		return s.config.Fields
+	}
+	return defaultTableFields
+}
+
+// printTree prints the fields of value as the branches of a tree, keys of maps are sorted
+func (s *Sink) printTree(value interface{}, indent string) {
+	var (
+		keys   []string
+		values []interface{}
+	)
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			values = append(values, v[key])
+		}
+	case []interface{}:
+		for i, elem := range v {
+			keys = append(keys, fmt.Sprintf("[%d]", i))
+			values = append(values, elem)
+		}
+	}
+
+	for i, key := range keys {
+		branch, next := "├─ ", "│  "
+		if i == len(keys)-1 {
+			branch, next = "└─ ", "   "
+		}
+		label := s.paint(s.cs.Greyf, "%s", key)
+		switch values[i].(type) {
+		case map[string]interface{}, []interface{}:
+			fmt.Fprintf(s.out, "%s%s%s\n", indent, branch, label)
+			s.printTree(values[i], indent+next)
+		default:
+			fmt.Fprintf(s.out, "%s%s%s: %s\n", indent, branch, label, cell(values[i]))
+		}
+	}
+}
+
+// printSummary prints the number of records by asset type
+func (s *Sink) printSummary() {
+	var (
+		types []string
+		total int
+	)
+	for typ, count := range s.counts {
+		types = append(types, typ)
+		total += count
+	}
+	sort.Strings(types)
+
+	counts := make([]string, len(types))
+	for i, typ := range types {
+		counts[i] = fmt.Sprintf("%s %s", s.paint(s.cs.Greenf, "%d", s.counts[typ]), typ)
+	}
+	fmt.Fprintf(s.out, "\n%s records", s.paint(s.cs.Greenf, "%d", total))
+	if len(counts) > 0 {
+		fmt.Fprintf(s.out, " (%s)", strings.Join(counts, ", "))
+	}
+	fmt.Fprintln(s.out)
+}
+
+// paint colors the text with colorf if colors are enabled
+func (s *Sink) paint(colorf func(string, ...interface{}) string, format string, a ...interface{}) string {
+	if !s.config.Color {
+		return fmt.Sprintf(format, a...)
+	}
+	return colorf(format, a...)
+}
+
+// toMap returns the asset in JSON as a map, the names of the fields are the names in the proto files
+func toMap(metadata models.Metadata) (map[string]interface{}, error) {
+	msg, ok := metadata.(proto.Message)
+	if !ok {
+		return nil, errors.New("asset is not a proto message")
+	}
+	b, err := protojson.MarshalOptions{UseProtoNames: true}.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	var asset map[string]interface{}
+	if err = json.Unmarshal(b, &asset); err != nil {
+		return nil, err
+	}
+
+	return asset, nil
+}
+
+// cell returns the value as text, lists of values are separated by commas and objects are in JSON
+func cell(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []interface{}:
+		values := make([]string, len(v))
+		for i, elem := range v {
+			values[i] = cell(elem)
+		}
+		return strings.Join(values, ", ")
+	case map[string]interface{}:
+		b, _ := json.Marshal(v)
+		return string(b)
+	}
+
+	return fmt.Sprint(value)
+}
+
 func init() {
 	if err := registry.Sinks.Register("console", func() plugins.Syncer {
-		return &Sink{
-			logger: plugins.GetLog(),
-		}
+		return New(os.Stdout, plugins.GetLog())
 	}); err != nil {
 		panic(err)
 	}
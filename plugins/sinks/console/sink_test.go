@@ -0,0 +1,128 @@
+package console_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/odpf/meteor/models"
+	commonv1beta1 "github.com/odpf/meteor/models/odpf/assets/common/v1beta1"
+	facetsv1beta1 "github.com/odpf/meteor/models/odpf/assets/facets/v1beta1"
+	assetsv1beta1 "github.com/odpf/meteor/models/odpf/assets/v1beta1"
+	"github.com/odpf/meteor/plugins"
+	"github.com/odpf/meteor/plugins/sinks/console"
+	testUtils "github.com/odpf/meteor/test/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+var records = []models.Record{
+	models.NewRecord(&assetsv1beta1.Table{
+		Resource: &commonv1beta1.Resource{Urn: "bigquery::p/d/orders", Name: "orders", Service: "bigquery", Type: "table"},
+		Schema: &facetsv1beta1.Columns{Columns: []*facetsv1beta1.Column{
+			{Name: "id", DataType: "INT64"},
+			{Name: "total", DataType: "FLOAT64", Description: "total price"},
+		}},
+	}),
+	models.NewRecord(&assetsv1beta1.Topic{
+		Resource: &commonv1beta1.Resource{Urn: "kafka::k/orders", Name: "orders", Service: "kafka", Type: "topic"},
+	}),
+}
+
+func TestInit(t *testing.T) {
+	t.Run("should return InvalidConfigError on invalid format", func(t *testing.T) {
+		err := console.New(&bytes.Buffer{}, testUtils.Logger).Init(context.TODO(), map[string]interface{}{
+			"format": "xml",
+		})
+		assert.Equal(t, plugins.InvalidConfigError{Type: plugins.PluginTypeSink, PluginName: "console"}, err)
+	})
+}
+
+func TestSink(t *testing.T) {
+	t.Run("should print projected fields in json", func(t *testing.T) {
+		out := run(t, map[string]interface{}{
+			"fields": []string{"resource.urn", "schema.columns[].name"},
+		})
+
+		assert.Equal(t, `{"resource":{"urn":"bigquery::p/d/orders"},"schema":{"columns":[{"name":"id"},{"name":"total"}]}}
+{"resource":{"urn":"kafka::k/orders"}}
+`, out)
+	})
+	t.Run("should print records with encoding/json by default", func(t *testing.T) {
+		record := models.NewRecord(&assetsv1beta1.Table{
+			Resource:   &commonv1beta1.Resource{Urn: "bigquery::p/d/orders"},
+			Profile:    &assetsv1beta1.TableProfile{TotalRows: 42},
+			Timestamps: &commonv1beta1.Timestamp{CreateTime: timestamppb.New(time.Unix(1640995200, 0))},
+		})
+		var out bytes.Buffer
+		sink := console.New(&out, testUtils.Logger)
+		require.NoError(t, sink.Init(context.TODO(), map[string]interface{}{
+			"fields": []string{"resource.urn", "profile", "timestamps.create_time"},
+		}))
+		require.NoError(t, sink.Sink(context.TODO(), []models.Record{record}))
+
+		assert.Equal(t, `{"profile":{"total_rows":42},"resource":{"urn":"bigquery::p/d/orders"},"timestamps":{"create_time":{"seconds":1640995200}}}
+`, out.String())
+	})
+	t.Run("should merge projected fields of list elements", func(t *testing.T) {
+		out := run(t, map[string]interface{}{
+			"format": "yaml",
+			"fields": []string{"schema.columns[].name", "schema.columns[].description"},
+		})
+
+		assert.Equal(t, `---
+schema:
+    columns:
+        - name: id
+        - description: total price
+          name: total
+---
+{}
+`, out)
+	})
+	t.Run("should print tree", func(t *testing.T) {
+		out := run(t, map[string]interface{}{
+			"format": "tree",
+			"fields": []string{"resource.name", "schema.columns[].name"},
+		})
+
+		assert.Equal(t, `table bigquery::p/d/orders
+├─ resource
+│  └─ name: orders
+└─ schema
+   └─ columns
+      ├─ [0]
+      │  └─ name: id
+      └─ [1]
+         └─ name: total
+topic kafka::k/orders
+└─ resource
+   └─ name: orders
+`, out)
+	})
+	t.Run("should print table and summary on close", func(t *testing.T) {
+		out := run(t, map[string]interface{}{
+			"format":  "table",
+			"fields":  []string{"resource.urn", "schema.columns[].name"},
+			"summary": true,
+		})
+
+		assert.Regexp(t, `RESOURCE.URN\s+SCHEMA.COLUMNS\[\].NAME`, out)
+		assert.Regexp(t, `bigquery::p/d/orders\s+id, total`, out)
+		assert.Contains(t, out, "kafka::k/orders")
+		assert.Contains(t, out, "2 records (1 table, 1 topic)")
+	})
+}
+
+func run(t *testing.T, config map[string]interface{}) string {
+	var out bytes.Buffer
+	config["color"] = false
+	sink := console.New(&out, testUtils.Logger)
+	require.NoError(t, sink.Init(context.TODO(), config))
+	require.NoError(t, sink.Sink(context.TODO(), records))
+	require.NoError(t, sink.Close())
+
+	return out.String()
+}
@@ -0,0 +1,60 @@
+package csv
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/odpf/meteor/plugins/tabular"
+)
+
+// encoder writes a table as CSV with a header row, null values are written as empty fields
+type encoder struct{}
+
+func (encoder) Extension() string {
+	return ".csv"
+}
+
+func (encoder) Encode(w io.Writer, table tabular.Table, rows []tabular.Row) error {
+	cw := csv.NewWriter(w)
+
+	header := make([]string, len(table.Columns))
+	for i, column := range table.Columns {
+		header[i] = column.Name
+	}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+
+	record := make([]string, len(table.Columns))
+	for _, row := range rows {
+		for i, value := range row {
+			record[i] = formatValue(value)
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+
+	return cw.Error()
+}
+
+func formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case bool:
+		return strconv.FormatBool(v)
+	case time.Time:
+		return v.UTC().Format(time.RFC3339)
+	}
+
+	return fmt.Sprint(value)
+}
@@ -0,0 +1,38 @@
+package csv
+
+import (
+	_ "embed"
+
+	"github.com/odpf/meteor/plugins"
+	"github.com/odpf/meteor/plugins/tabular"
+	"github.com/odpf/meteor/registry"
+	"github.com/odpf/salt/log"
+)
+
+//go:embed README.md
+var summary string
+
+var sampleConfig = `
+# Directory the tables are written to, partitioned by recipe and run date
+path: ./output
+# Start a new file once a table has this many rows
+max_rows_per_file: 100000
+`
+
+// New returns a sink writing assets, their columns and their lineage as CSV tables
+func New(logger log.Logger) *tabular.Sink {
+	return tabular.NewSink("csv", plugins.Info{
+		Description:  "save assets as partitioned csv tables",
+		SampleConfig: sampleConfig,
+		Summary:      summary,
+		Tags:         []string{"file", "csv", "sink"},
+	}, encoder{}, logger)
+}
+
+func init() {
+	if err := registry.Sinks.Register("csv", func() plugins.Syncer {
+		return New(plugins.GetLog())
+	}); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,111 @@
+package csv_test
+
+import (
+	"context"
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/odpf/meteor/models"
+	commonv1beta1 "github.com/odpf/meteor/models/odpf/assets/common/v1beta1"
+	facetsv1beta1 "github.com/odpf/meteor/models/odpf/assets/facets/v1beta1"
+	assetsv1beta1 "github.com/odpf/meteor/models/odpf/assets/v1beta1"
+	"github.com/odpf/meteor/plugins"
+	csvSink "github.com/odpf/meteor/plugins/sinks/csv"
+	testUtils "github.com/odpf/meteor/test/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestInit(t *testing.T) {
+	t.Run("should return InvalidConfigError on invalid config", func(t *testing.T) {
+		err := csvSink.New(testUtils.Logger).Init(context.TODO(), map[string]interface{}{})
+		assert.Equal(t, plugins.InvalidConfigError{Type: "sink", PluginName: "csv"}, err)
+	})
+}
+
+func TestSink(t *testing.T) {
+	dir := t.TempDir()
+	ctx := plugins.ContextWithRunInfo(context.TODO(), plugins.RunInfo{
+		RecipeName: "sample",
+		StartedAt:  time.Date(2022, 1, 2, 15, 4, 5, 0, time.UTC),
+	})
+
+	sink := csvSink.New(testUtils.Logger)
+	require.NoError(t, sink.Init(ctx, map[string]interface{}{
+		"path":              dir,
+		"max_rows_per_file": 2,
+	}))
+
+	table := func(name string) *assetsv1beta1.Table {
+		return &assetsv1beta1.Table{
+			Resource: &commonv1beta1.Resource{Urn: "bigquery::p/d/" + name, Name: name, Service: "bigquery", Type: "table"},
+			Profile:  &assetsv1beta1.TableProfile{TotalRows: 10},
+			Schema: &facetsv1beta1.Columns{Columns: []*facetsv1beta1.Column{
+				{Name: "id", DataType: "INT64"},
+				{Name: "name", DataType: "STRING", IsNullable: true, Length: 64},
+			}},
+			Properties: &facetsv1beta1.Properties{Tags: []string{"pii"}},
+			Timestamps: &commonv1beta1.Timestamp{CreateTime: timestamppb.New(time.Date(2021, 5, 1, 0, 0, 0, 0, time.UTC))},
+			Lineage: &facetsv1beta1.Lineage{
+				Upstreams: []*commonv1beta1.Resource{{Urn: "kafka::k/orders", Type: "topic"}},
+			},
+		}
+	}
+	topic := &assetsv1beta1.Topic{
+		Resource: &commonv1beta1.Resource{Urn: "kafka::k/orders", Name: "orders", Service: "kafka", Type: "topic"},
+		Profile:  &assetsv1beta1.TopicProfile{NumberOfPartitions: 3},
+		Lineage: &facetsv1beta1.Lineage{
+			Downstreams: []*commonv1beta1.Resource{{Urn: "bigquery::p/d/orders", Type: "table"}},
+		},
+	}
+
+	require.NoError(t, sink.Sink(ctx, []models.Record{
+		models.NewRecord(table("orders")),
+		models.NewRecord(topic),
+		models.NewRecord(table("payments")),
+	}))
+	require.NoError(t, sink.Close())
+
+	partition := filepath.Join("recipe=sample", "date=2022-01-02")
+	assert.Equal(t, [][]string{
+		{"urn", "name", "service", "type", "url", "description", "owners", "tags", "labels", "attributes", "create_time", "update_time", "total_rows", "partition_key", "partition_value", "usage_count", "column_count"},
+		{"bigquery::p/d/orders", "orders", "bigquery", "table", "", "", "", `["pii"]`, "", "", "2021-05-01T00:00:00Z", "", "10", "", "", "0", "2"},
+		{"bigquery::p/d/payments", "payments", "bigquery", "table", "", "", "", `["pii"]`, "", "", "2021-05-01T00:00:00Z", "", "10", "", "", "0", "2"},
+	}, readCSV(t, filepath.Join(dir, "tables", partition, "part-150405-1.csv")))
+
+	assert.Equal(t, [][]string{
+		{"asset_urn", "position", "name", "data_type", "description", "is_nullable", "length"},
+		{"bigquery::p/d/orders", "1", "id", "INT64", "", "false", ""},
+		{"bigquery::p/d/orders", "2", "name", "STRING", "", "true", "64"},
+	}, readCSV(t, filepath.Join(dir, "columns", partition, "part-150405-1.csv")))
+	assert.Equal(t, [][]string{
+		{"asset_urn", "position", "name", "data_type", "description", "is_nullable", "length"},
+		{"bigquery::p/d/payments", "1", "id", "INT64", "", "false", ""},
+		{"bigquery::p/d/payments", "2", "name", "STRING", "", "true", "64"},
+	}, readCSV(t, filepath.Join(dir, "columns", partition, "part-150405-2.csv")))
+
+	assert.Equal(t, [][]string{
+		{"source_urn", "source_type", "target_urn", "target_type"},
+		{"kafka::k/orders", "topic", "bigquery::p/d/orders", "table"},
+		{"kafka::k/orders", "topic", "bigquery::p/d/payments", "table"},
+	}, readCSV(t, filepath.Join(dir, "lineage", partition, "part-150405-1.csv")))
+
+	topics := readCSV(t, filepath.Join(dir, "topics", partition, "part-150405-1.csv"))
+	require.Len(t, topics, 2)
+	assert.Equal(t, []string{"kafka::k/orders", "3"}, []string{topics[1][0], topics[1][12]})
+}
+
+func readCSV(t *testing.T, path string) [][]string {
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	records, err := csv.NewReader(f).ReadAll()
+	require.NoError(t, err)
+
+	return records
+}
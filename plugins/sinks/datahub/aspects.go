@@ -0,0 +1,165 @@
+package datahub
+
+import (
+	"sort"
+	"strings"
+
+	commonv1beta1 "github.com/odpf/meteor/models/odpf/assets/common/v1beta1"
+	facetsv1beta1 "github.com/odpf/meteor/models/odpf/assets/facets/v1beta1"
+)
+
+// actor is the actor of the audit stamps of the aspects
+const actor = "urn:li:corpuser:meteor"
+
+// proposal is a metadata change proposal upserting an aspect of an entity
+type proposal struct {
+	EntityType string
+	EntityURN  string
+	AspectName string
+	Aspect     map[string]interface{}
+}
+
+func auditStamp(timeMillis int64) map[string]interface{} {
+	return map[string]interface{}{"time": timeMillis, "actor": actor}
+}
+
+// ownershipAspect returns the owners of the entity as data owners
+func ownershipAspect(ownership *facetsv1beta1.Ownership, timeMillis int64) map[string]interface{} {
+	var owners []interface{}
+	for _, owner := range ownership.GetOwners() {
+		if owner.GetEmail() == "" && owner.GetUrn() == "" {
+			continue
+		}
+		owners = append(owners, map[string]interface{}{
+			"owner": userURN(owner.GetEmail(), owner.GetUrn()),
+			"type":  "DATAOWNER",
+		})
+	}
+	if len(owners) == 0 {
+		return nil
+	}
+
+	return map[string]interface{}{
+		"owners":       owners,
+		"lastModified": auditStamp(timeMillis),
+	}
+}
+
+func globalTagsAspect(properties *facetsv1beta1.Properties) map[string]interface{} {
+	var tags []interface{}
+	for _, tag := range properties.GetTags() {
+		tags = append(tags, map[string]interface{}{"tag": tagURN(tag)})
+	}
+	if len(tags) == 0 {
+		return nil
+	}
+
+	return map[string]interface{}{"tags": tags}
+}
+
+// customProperties returns the labels and the attributes of the properties as strings,
+// attributes which are not strings are in JSON
+func customProperties(properties *facetsv1beta1.Properties) map[string]string {
+	custom := make(map[string]string)
+	for key, value := range properties.GetAttributes().AsMap() {
+		if s, ok := value.(string); ok {
+			custom[key] = s
+			continue
+		}
+		b, err := properties.GetAttributes().GetFields()[key].MarshalJSON()
+		if err != nil {
+			continue
+		}
+		custom[key] = string(b)
+	}
+	for key, value := range properties.GetLabels() {
+		custom[key] = value
+	}
+
+	return custom
+}
+
+// upstreamLineageAspect returns the upstreams which are datasets as transformed upstreams
+func upstreamLineageAspect(upstreams []*commonv1beta1.Resource, m urnMapper, timeMillis int64) map[string]interface{} {
+	urns := datasetURNs(upstreams, m)
+	if len(urns) == 0 {
+		return nil
+	}
+
+	list := make([]interface{}, len(urns))
+	for i, urn := range urns {
+		list[i] = map[string]interface{}{
+			"dataset":    urn,
+			"type":       "TRANSFORMED",
+			"auditStamp": auditStamp(timeMillis),
+		}
+	}
+
+	return map[string]interface{}{"upstreams": list}
+}
+
+// datasetURNs returns the urns of the resources which are datasets
+func datasetURNs(resources []*commonv1beta1.Resource, m urnMapper) []string {
+	var urns []string
+	for _, resource := range resources {
+		switch resource.GetType() {
+		case "", "table", "topic", "bucket":
+			urns = append(urns, m.datasetURN(resource.GetUrn(), resource.GetService()))
+		}
+	}
+	sort.Strings(urns)
+
+	return urns
+}
+
+// schemaField returns the field of a column with the datahub type of its data type
+func schemaField(column *facetsv1beta1.Column) map[string]interface{} {
+	field := map[string]interface{}{
+		"fieldPath":      column.GetName(),
+		"nativeDataType": column.GetDataType(),
+		"nullable":       column.GetIsNullable(),
+		"type": map[string]interface{}{
+			"type": map[string]interface{}{"com.linkedin.schema." + fieldType(column.GetDataType()): map[string]interface{}{}},
+		},
+	}
+	if column.GetDescription() != "" {
+		field["description"] = column.GetDescription()
+	}
+
+	return field
+}
+
+var fieldTypes = map[string]string{
+	"int": "NumberType", "integer": "NumberType", "tinyint": "NumberType", "smallint": "NumberType",
+	"mediumint": "NumberType", "bigint": "NumberType", "float": "NumberType", "double": "NumberType",
+	"double precision": "NumberType", "real": "NumberType", "numeric": "NumberType", "bignumeric": "NumberType",
+	"decimal": "NumberType", "number": "NumberType", "money": "NumberType", "serial": "NumberType",
+	"smallserial": "NumberType", "bigserial": "NumberType", "long": "NumberType",
+	"bool": "BooleanType", "boolean": "BooleanType",
+	"bytes": "BytesType", "bytea": "BytesType", "blob": "BytesType", "binary": "BytesType", "varbinary": "BytesType",
+	"date": "DateType",
+	"time": "TimeType", "datetime": "TimeType", "timestamp": "TimeType", "timestamptz": "TimeType",
+	"timestamp with time zone": "TimeType", "timestamp without time zone": "TimeType",
+	"record": "RecordType", "struct": "RecordType", "json": "RecordType", "jsonb": "RecordType", "map": "MapType",
+	"array": "ArrayType",
+}
+
+// fieldType returns the datahub type of a data type, e.g. NumberType for INT64 or ArrayType for integer[]
+func fieldType(dataType string) string {
+	base := strings.ToLower(strings.TrimSpace(dataType))
+	if strings.HasSuffix(base, "[]") || strings.HasPrefix(base, "array<") {
+		return "ArrayType"
+	}
+	if strings.HasPrefix(base, "struct<") {
+		return "RecordType"
+	}
+	if i := strings.IndexByte(base, '('); i > 0 {
+		base = strings.TrimSpace(base[:i])
+	}
+	base = strings.TrimRight(base, "0123456789")
+
+	if t, ok := fieldTypes[base]; ok {
+		return t
+	}
+	return "StringType"
+}
@@ -0,0 +1,440 @@
+package datahub
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/odpf/meteor/models"
+	commonv1beta1 "github.com/odpf/meteor/models/odpf/assets/common/v1beta1"
+	facetsv1beta1 "github.com/odpf/meteor/models/odpf/assets/facets/v1beta1"
+	assetsv1beta1 "github.com/odpf/meteor/models/odpf/assets/v1beta1"
+	"github.com/odpf/meteor/plugins"
+	"github.com/odpf/meteor/registry"
+	"github.com/odpf/meteor/utils"
+	"github.com/odpf/salt/log"
+	"github.com/pkg/errors"
+)
+
+//go:embed README.md
+var summary string
+
+type Config struct {
+	// Server is the url of the metadata service, proposals are sent to its ingestion api
+	Server string `mapstructure:"server" validate:"required_without=Path"`
+	Token  string `mapstructure:"token"`
+	// Path is the file the proposals are written to as a JSON array instead of being sent
+	Path string `mapstructure:"path" validate:"required_without=Server"`
+	// Platforms maps the services to datahub platforms, e.g. postgres to postgresql
+	Platforms map[string]string `mapstructure:"platforms"`
+	Env       string            `mapstructure:"env" default:"PROD"`
+}
+
+var sampleConfig = `
+# URL of the DataHub metadata service
+server: http://localhost:8080
+token: my-access-token
+# File the metadata change proposals are written to instead of server
+# path: ./datahub.json
+# DataHub platforms of the services, the service name is used if missing
+platforms:
+  postgres: postgresql
+  optimus: airflow
+# Environment of the datasets and flows
+env: PROD`
+
+type httpClient interface {
+	Do(*http.Request) (*http.Response, error)
+}
+
+type Sink struct {
+	client    httpClient
+	config    Config
+	logger    log.Logger
+	mapper    urnMapper
+	run       plugins.RunInfo
+	proposals []interface{}
+}
+
+func New(c httpClient, logger log.Logger) plugins.Syncer {
+	return &Sink{client: c, logger: logger}
+}
+
+func (s *Sink) Info() plugins.Info {
+	return plugins.Info{
+		Description:  "Send metadata to DataHub as metadata change proposals",
+		SampleConfig: sampleConfig,
+		Summary:      summary,
+		Tags:         []string{"http", "sink"},
+	}
+}
+
+func (s *Sink) Validate(configMap map[string]interface{}) (err error) {
+	return utils.BuildConfig(configMap, &Config{})
+}
+
+func (s *Sink) Init(ctx context.Context, configMap map[string]interface{}) (err error) {
+	if err = utils.BuildConfig(configMap, &s.config); err != nil {
+		return plugins.InvalidConfigError{Type: plugins.PluginTypeSink, PluginName: "datahub"}
+	}
+	if s.config.Server != "" && s.config.Path != "" {
+		return errors.New("only one of server and path can be set")
+	}
+	s.config.Server = strings.TrimSuffix(s.config.Server, "/")
+	s.mapper = urnMapper{platforms: s.config.Platforms, env: s.config.Env}
+
+	s.run = plugins.RunInfoFromContext(ctx)
+	if s.run.StartedAt.IsZero() {
+		s.run.StartedAt = time.Now()
+	}
+
+	return
+}
+
+// Sink sends the aspects of every record as proposals, or keeps them to be written to the file on close
+func (s *Sink) Sink(ctx context.Context, batch []models.Record) (err error) {
+	for _, record := range batch {
+		for _, p := range s.buildProposals(record.Data()) {
+			if s.config.Path != "" {
+				s.proposals = append(s.proposals, fileProposal(p))
+				continue
+			}
+			if err = s.send(ctx, p); err != nil {
+				return errors.Wrapf(err, "failed to send %s of \"%s\"", p.AspectName, p.EntityURN)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Close writes the proposals to the file in the format of the file source of datahub
+func (s *Sink) Close() (err error) {
+	if s.config.Path == "" {
+		return
+	}
+	if s.proposals == nil {
+		s.proposals = []interface{}{}
+	}
+
+	b, err := json.MarshalIndent(s.proposals, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(s.config.Path, b, 0644)
+}
+
+func (s *Sink) buildProposals(metadata models.Metadata) []proposal {
+	resource := metadata.GetResource()
+	ts := s.timeMillis(metadata)
+
+	var (
+		entityType, urn string
+		aspects         = make(map[string]map[string]interface{})
+	)
+	switch asset := metadata.(type) {
+	case *assetsv1beta1.Table:
+		entityType, urn = "dataset", s.mapper.datasetURN(resource.GetUrn(), resource.GetService())
+		aspects["datasetProperties"] = s.datasetProperties(resource, asset.GetProperties())
+		aspects["schemaMetadata"] = s.schemaMetadata(asset, ts)
+		aspects["upstreamLineage"] = upstreamLineageAspect(asset.GetLineage().GetUpstreams(), s.mapper, ts)
+	case *assetsv1beta1.Topic, *assetsv1beta1.Bucket:
+		entityType, urn = "dataset", s.mapper.datasetURN(resource.GetUrn(), resource.GetService())
+		aspects["datasetProperties"] = s.datasetProperties(resource, metadata.GetProperties())
+		aspects["upstreamLineage"] = upstreamLineageAspect(utils.GetLineage(metadata).GetUpstreams(), s.mapper, ts)
+	case *assetsv1beta1.Dashboard:
+		entityType, urn = "dashboard", s.mapper.dashboardURN(resource.GetUrn(), resource.GetService())
+		aspects["dashboardInfo"] = s.dashboardInfo(asset, ts)
+		proposals := s.chartProposals(asset, ts)
+		return append(proposals, s.toProposals(entityType, urn, aspects, metadata, ts)...)
+	case *assetsv1beta1.Job:
+		entityType, urn = "dataJob", s.mapper.dataJobURN(resource.GetUrn(), resource.GetService())
+		flowURN := s.mapper.dataFlowURN(resource.GetUrn(), resource.GetService())
+		flow, _ := splitJob(resource.GetUrn())
+		aspects["dataJobInfo"] = map[string]interface{}{
+			"name":             resource.GetName(),
+			"description":      resource.GetDescription(),
+			"type":             map[string]interface{}{"string": "COMMAND"},
+			"flowUrn":          flowURN,
+			"customProperties": customProperties(asset.GetProperties()),
+		}
+		aspects["dataJobInputOutput"] = map[string]interface{}{
+			"inputDatasets":  nonNil(datasetURNs(asset.GetLineage().GetUpstreams(), s.mapper)),
+			"outputDatasets": nonNil(datasetURNs(asset.GetLineage().GetDownstreams(), s.mapper)),
+		}
+		flowInfo := proposal{
+			EntityType: "dataFlow",
+			EntityURN:  flowURN,
+			AspectName: "dataFlowInfo",
+			Aspect:     map[string]interface{}{"name": flow},
+		}
+		return append([]proposal{flowInfo}, s.toProposals(entityType, urn, aspects, metadata, ts)...)
+	case *assetsv1beta1.User:
+		entityType, urn = "corpuser", userURN(asset.GetEmail(), resource.GetUrn())
+		aspects["corpUserInfo"] = map[string]interface{}{
+			"active":      asset.GetStatus() != "suspended",
+			"displayName": firstOf(asset.GetDisplayName(), asset.GetFullName(), resource.GetName()),
+			"fullName":    firstOf(asset.GetFullName(), asset.GetDisplayName(), resource.GetName()),
+			"firstName":   asset.GetFirstName(),
+			"lastName":    asset.GetLastName(),
+			"email":       asset.GetEmail(),
+			"title":       asset.GetTitle(),
+		}
+		var groups []string
+		for _, membership := range asset.GetMemberships() {
+			groups = append(groups, groupURN(membership.GetGroupUrn()))
+		}
+		if len(groups) > 0 {
+			aspects["groupMembership"] = map[string]interface{}{"groups": groups}
+		}
+	case *assetsv1beta1.Group:
+		entityType, urn = "corpGroup", groupURN(resource.GetUrn())
+		members := []string{}
+		admins := []string{}
+		for _, member := range asset.GetMembers() {
+			user := userURN("", member.GetUrn())
+			members = append(members, user)
+			if member.GetRole() == "admin" || member.GetRole() == "owner" {
+				admins = append(admins, user)
+			}
+		}
+		aspects["corpGroupInfo"] = map[string]interface{}{
+			"displayName": resource.GetName(),
+			"description": resource.GetDescription(),
+			"email":       asset.GetEmail(),
+			"admins":      admins,
+			"members":     members,
+			"groups":      []string{},
+		}
+	default:
+		return nil
+	}
+
+	return s.toProposals(entityType, urn, aspects, metadata, ts)
+}
+
+// toProposals returns the proposals of the aspects and of the ownership and tags of the asset, nil aspects are skipped
+func (s *Sink) toProposals(entityType, urn string, aspects map[string]map[string]interface{}, metadata models.Metadata, ts int64) []proposal {
+	aspects["ownership"] = ownershipAspect(utils.GetOwnership(metadata), ts)
+	aspects["globalTags"] = globalTagsAspect(metadata.GetProperties())
+
+	var proposals []proposal
+	for _, name := range aspectOrder {
+		aspect, ok := aspects[name]
+		if !ok || aspect == nil {
+			continue
+		}
+		proposals = append(proposals, proposal{EntityType: entityType, EntityURN: urn, AspectName: name, Aspect: aspect})
+	}
+
+	return proposals
+}
+
+// aspectOrder is the order in which the aspects of an entity are sent
+var aspectOrder = []string{
+	"datasetProperties", "schemaMetadata", "upstreamLineage",
+	"dashboardInfo",
+	"dataJobInfo", "dataJobInputOutput",
+	"corpUserInfo", "groupMembership", "corpGroupInfo",
+	"ownership", "globalTags",
+}
+
+func (s *Sink) datasetProperties(resource *commonv1beta1.Resource, properties *facetsv1beta1.Properties) map[string]interface{} {
+	aspect := map[string]interface{}{
+		"name":             resource.GetName(),
+		"description":      resource.GetDescription(),
+		"customProperties": customProperties(properties),
+		"tags":             []string{},
+	}
+	if resource.GetUrl() != "" {
+		aspect["externalUrl"] = resource.GetUrl()
+	}
+
+	return aspect
+}
+
+func (s *Sink) schemaMetadata(table *assetsv1beta1.Table, ts int64) map[string]interface{} {
+	columns := table.GetSchema().GetColumns()
+	if len(columns) == 0 {
+		return nil
+	}
+
+	fields := make([]interface{}, len(columns))
+	for i, column := range columns {
+		fields[i] = schemaField(column)
+	}
+	resource := table.GetResource()
+
+	return map[string]interface{}{
+		"schemaName":     resource.GetName(),
+		"platform":       s.mapper.platformURN(resource.GetService()),
+		"version":        0,
+		"hash":           "",
+		"created":        auditStamp(ts),
+		"lastModified":   auditStamp(ts),
+		"platformSchema": map[string]interface{}{"com.linkedin.schema.OtherSchema": map[string]interface{}{"rawSchema": ""}},
+		"fields":         fields,
+	}
+}
+
+func (s *Sink) dashboardInfo(dashboard *assetsv1beta1.Dashboard, ts int64) map[string]interface{} {
+	resource := dashboard.GetResource()
+	charts := []string{}
+	for _, chart := range dashboard.GetCharts() {
+		charts = append(charts, s.mapper.chartURN(chart.GetUrn(), resource.GetService()))
+	}
+
+	aspect := map[string]interface{}{
+		"title":            resource.GetName(),
+		"description":      resource.GetDescription(),
+		"charts":           charts,
+		"datasets":         nonNil(datasetURNs(dashboard.GetLineage().GetUpstreams(), s.mapper)),
+		"customProperties": customProperties(dashboard.GetProperties()),
+		"lastModified": map[string]interface{}{
+			"created":      auditStamp(ts),
+			"lastModified": auditStamp(ts),
+		},
+	}
+	if resource.GetUrl() != "" {
+		aspect["dashboardUrl"] = resource.GetUrl()
+	}
+
+	return aspect
+}
+
+// chartProposals returns the chart info of the charts of the dashboard with their datasets as inputs
+func (s *Sink) chartProposals(dashboard *assetsv1beta1.Dashboard, ts int64) (proposals []proposal) {
+	service := dashboard.GetResource().GetService()
+	for _, chart := range dashboard.GetCharts() {
+		inputs := []interface{}{}
+		for _, urn := range datasetURNs(chart.GetLineage().GetUpstreams(), s.mapper) {
+			inputs = append(inputs, map[string]interface{}{"string": urn})
+		}
+		aspect := map[string]interface{}{
+			"title":            chart.GetName(),
+			"description":      chart.GetDescription(),
+			"inputs":           inputs,
+			"customProperties": customProperties(chart.GetProperties()),
+			"lastModified": map[string]interface{}{
+				"created":      auditStamp(ts),
+				"lastModified": auditStamp(ts),
+			},
+		}
+		if chart.GetUrl() != "" {
+			aspect["chartUrl"] = chart.GetUrl()
+		}
+
+		urn := s.mapper.chartURN(chart.GetUrn(), service)
+		proposals = append(proposals, proposal{EntityType: "chart", EntityURN: urn, AspectName: "chartInfo", Aspect: aspect})
+		if ownership := ownershipAspect(chart.GetOwnership(), ts); ownership != nil {
+			proposals = append(proposals, proposal{EntityType: "chart", EntityURN: urn, AspectName: "ownership", Aspect: ownership})
+		}
+	}
+
+	return proposals
+}
+
+// send sends the proposal to the ingestion api, the aspect is serialized as JSON
+func (s *Sink) send(ctx context.Context, p proposal) error {
+	value, err := json.Marshal(p.Aspect)
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(map[string]interface{}{
+		"proposal": map[string]interface{}{
+			"entityType": p.EntityType,
+			"entityUrn":  p.EntityURN,
+			"changeType": "UPSERT",
+			"aspectName": p.AspectName,
+			"aspect": map[string]interface{}{
+				"contentType": "application/json",
+				"value":       string(value),
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.config.Server+"/aspects?action=ingestProposal", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-RestLi-Protocol-Version", "2.0.0")
+	if s.config.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.config.Token)
+	}
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return plugins.NewRetryError(err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= 200 && res.StatusCode < 300 {
+		return nil
+	}
+
+	bodyBytes, _ := ioutil.ReadAll(res.Body)
+	err = fmt.Errorf("datahub returns %d: %s", res.StatusCode, string(bodyBytes))
+	if plugins.IsRetryableStatus(res.StatusCode) {
+		return plugins.NewRetryError(err)
+	}
+
+	return err
+}
+
+// timeMillis returns the update time of the asset, or the time the run started at
+func (s *Sink) timeMillis(metadata models.Metadata) int64 {
+	t := s.run.StartedAt
+	if timestamps, ok := metadata.(interface {
+		GetTimestamps() *commonv1beta1.Timestamp
+	}); ok && timestamps.GetTimestamps().GetUpdateTime() != nil {
+		t = timestamps.GetTimestamps().GetUpdateTime().AsTime()
+	}
+
+	return t.UnixNano() / int64(time.Millisecond)
+}
+
+// fileProposal returns the proposal in the format of the file source of datahub
+func fileProposal(p proposal) map[string]interface{} {
+	return map[string]interface{}{
+		"entityType": p.EntityType,
+		"entityUrn":  p.EntityURN,
+		"changeType": "UPSERT",
+		"aspectName": p.AspectName,
+		"aspect":     map[string]interface{}{"json": p.Aspect},
+	}
+}
+
+func firstOf(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// nonNil returns an empty list for nil so that required lists are not null
+func nonNil(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
+
+func init() {
+	if err := registry.Sinks.Register("datahub", func() plugins.Syncer {
+		return New(&http.Client{}, plugins.GetLog())
+	}); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,259 @@
+package datahub_test
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/odpf/meteor/models"
+	commonv1beta1 "github.com/odpf/meteor/models/odpf/assets/common/v1beta1"
+	facetsv1beta1 "github.com/odpf/meteor/models/odpf/assets/facets/v1beta1"
+	assetsv1beta1 "github.com/odpf/meteor/models/odpf/assets/v1beta1"
+	"github.com/odpf/meteor/plugins"
+	"github.com/odpf/meteor/plugins/sinks/datahub"
+	testUtils "github.com/odpf/meteor/test/utils"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	startedAt = time.Date(2022, 3, 4, 5, 6, 7, 0, time.UTC)
+	table     = &assetsv1beta1.Table{
+		Resource: &commonv1beta1.Resource{Urn: "postgres::db:5432/shop/orders", Name: "orders", Service: "postgres", Description: "orders of the shop"},
+		Schema: &facetsv1beta1.Columns{Columns: []*facetsv1beta1.Column{
+			{Name: "id", DataType: "integer", Description: "order id"},
+			{Name: "note", DataType: "varchar(255)", IsNullable: true},
+		}},
+		Ownership: &facetsv1beta1.Ownership{Owners: []*facetsv1beta1.Owner{{Urn: "u1", Email: "jane@example.com"}}},
+		Lineage: &facetsv1beta1.Lineage{
+			Upstreams: []*commonv1beta1.Resource{{Urn: "kafka::broker:9092/orders", Type: "topic"}},
+		},
+		Properties: &facetsv1beta1.Properties{Tags: []string{"pii"}, Labels: map[string]string{"team": "sales"}},
+	}
+	dashboard = &assetsv1beta1.Dashboard{
+		Resource: &commonv1beta1.Resource{Urn: "metabase::host/42", Name: "sales", Service: "metabase", Url: "http://host/dashboard/42"},
+		Charts: []*assetsv1beta1.Chart{{
+			Urn:  "metabase::host/42/7",
+			Name: "orders per day",
+			Lineage: &facetsv1beta1.Lineage{
+				Upstreams: []*commonv1beta1.Resource{{Urn: "postgres::db:5432/shop/orders", Type: "table"}},
+			},
+		}},
+	}
+	job = &assetsv1beta1.Job{
+		Resource: &commonv1beta1.Resource{Urn: "optimus::scheduler/project.orders_daily", Name: "orders_daily", Service: "optimus"},
+		Lineage: &facetsv1beta1.Lineage{
+			Upstreams:   []*commonv1beta1.Resource{{Urn: "bigquery::p/d/orders", Type: "table"}},
+			Downstreams: []*commonv1beta1.Resource{{Urn: "bigquery::p/d/orders_daily", Type: "table"}},
+		},
+	}
+	user = &assetsv1beta1.User{
+		Resource:    &commonv1beta1.Resource{Urn: "github::jane", Name: "jane"},
+		Email:       "jane@example.com",
+		FullName:    "Jane Doe",
+		Memberships: []*assetsv1beta1.Membership{{GroupUrn: "github::odpf/data"}},
+	}
+	group = &assetsv1beta1.Group{
+		Resource: &commonv1beta1.Resource{Urn: "github::odpf/data", Name: "data"},
+		Members:  []*assetsv1beta1.Member{{Urn: "github::jane", Role: "admin"}},
+	}
+)
+
+type mcp struct {
+	EntityType string `json:"entityType"`
+	EntityURN  string `json:"entityUrn"`
+	ChangeType string `json:"changeType"`
+	AspectName string `json:"aspectName"`
+	Aspect     struct {
+		JSON map[string]interface{} `json:"json"`
+	} `json:"aspect"`
+}
+
+func TestInit(t *testing.T) {
+	t.Run("should return InvalidConfigError without server and path", func(t *testing.T) {
+		err := datahub.New(http.DefaultClient, testUtils.Logger).Init(context.TODO(), map[string]interface{}{})
+		assert.Equal(t, plugins.InvalidConfigError{Type: plugins.PluginTypeSink, PluginName: "datahub"}, err)
+	})
+	t.Run("should return error if both server and path are set", func(t *testing.T) {
+		err := datahub.New(http.DefaultClient, testUtils.Logger).Init(context.TODO(), map[string]interface{}{
+			"server": "http://localhost:8080",
+			"path":   filepath.Join(t.TempDir(), "datahub.json"),
+		})
+		assert.EqualError(t, err, "only one of server and path can be set")
+	})
+}
+
+func TestSink(t *testing.T) {
+	config := map[string]interface{}{
+		"platforms": map[string]string{"postgres": "postgresql", "optimus": "airflow"},
+	}
+
+	t.Run("should convert tables to dataset aspects", func(t *testing.T) {
+		proposals := sinkToFile(t, config, table)
+		datasetURN := "urn:li:dataset:(urn:li:dataPlatform:postgresql,db:5432.shop.orders,PROD)"
+
+		require.Len(t, proposals, 5)
+		assert.Equal(t, []string{"datasetProperties", "schemaMetadata", "upstreamLineage", "ownership", "globalTags"}, aspectNames(proposals))
+		for _, p := range proposals {
+			assert.Equal(t, "dataset", p.EntityType)
+			assert.Equal(t, datasetURN, p.EntityURN)
+			assert.Equal(t, "UPSERT", p.ChangeType)
+		}
+
+		assert.Equal(t, map[string]interface{}{"team": "sales"}, proposals[0].Aspect.JSON["customProperties"])
+		assert.Equal(t, "urn:li:dataPlatform:postgresql", proposals[1].Aspect.JSON["platform"])
+		assert.Equal(t, []interface{}{
+			map[string]interface{}{
+				"fieldPath":      "id",
+				"nativeDataType": "integer",
+				"nullable":       false,
+				"description":    "order id",
+				"type":           map[string]interface{}{"type": map[string]interface{}{"com.linkedin.schema.NumberType": map[string]interface{}{}}},
+			},
+			map[string]interface{}{
+				"fieldPath":      "note",
+				"nativeDataType": "varchar(255)",
+				"nullable":       true,
+				"type":           map[string]interface{}{"type": map[string]interface{}{"com.linkedin.schema.StringType": map[string]interface{}{}}},
+			},
+		}, proposals[1].Aspect.JSON["fields"])
+		upstream := proposals[2].Aspect.JSON["upstreams"].([]interface{})[0].(map[string]interface{})
+		assert.Equal(t, "urn:li:dataset:(urn:li:dataPlatform:kafka,broker:9092.orders,PROD)", upstream["dataset"])
+		assert.Equal(t, "TRANSFORMED", upstream["type"])
+		owner := proposals[3].Aspect.JSON["owners"].([]interface{})[0].(map[string]interface{})
+		assert.Equal(t, map[string]interface{}{"owner": "urn:li:corpuser:jane@example.com", "type": "DATAOWNER"}, owner)
+		assert.Equal(t, []interface{}{map[string]interface{}{"tag": "urn:li:tag:pii"}}, proposals[4].Aspect.JSON["tags"])
+	})
+	t.Run("should convert dashboards and their charts", func(t *testing.T) {
+		proposals := sinkToFile(t, config, dashboard)
+
+		require.Len(t, proposals, 2)
+		assert.Equal(t, "chart", proposals[0].EntityType)
+		assert.Equal(t, "urn:li:chart:(metabase,host/42/7)", proposals[0].EntityURN)
+		assert.Equal(t, []interface{}{
+			map[string]interface{}{"string": "urn:li:dataset:(urn:li:dataPlatform:postgresql,db:5432.shop.orders,PROD)"},
+		}, proposals[0].Aspect.JSON["inputs"])
+		assert.Equal(t, "dashboard", proposals[1].EntityType)
+		assert.Equal(t, "urn:li:dashboard:(metabase,host/42)", proposals[1].EntityURN)
+		assert.Equal(t, []interface{}{"urn:li:chart:(metabase,host/42/7)"}, proposals[1].Aspect.JSON["charts"])
+		assert.Equal(t, "http://host/dashboard/42", proposals[1].Aspect.JSON["dashboardUrl"])
+	})
+	t.Run("should convert jobs to jobs of flows", func(t *testing.T) {
+		proposals := sinkToFile(t, config, job)
+		flowURN := "urn:li:dataFlow:(airflow,scheduler,PROD)"
+
+		require.Len(t, proposals, 3)
+		assert.Equal(t, flowURN, proposals[0].EntityURN)
+		assert.Equal(t, "dataFlowInfo", proposals[0].AspectName)
+		assert.Equal(t, "urn:li:dataJob:("+flowURN+",project.orders_daily)", proposals[1].EntityURN)
+		assert.Equal(t, "dataJobInfo", proposals[1].AspectName)
+		assert.Equal(t, map[string]interface{}{
+			"inputDatasets":  []interface{}{"urn:li:dataset:(urn:li:dataPlatform:bigquery,p.d.orders,PROD)"},
+			"outputDatasets": []interface{}{"urn:li:dataset:(urn:li:dataPlatform:bigquery,p.d.orders_daily,PROD)"},
+		}, proposals[2].Aspect.JSON)
+	})
+	t.Run("should convert users and groups", func(t *testing.T) {
+		proposals := sinkToFile(t, config, user, group)
+
+		require.Len(t, proposals, 3)
+		assert.Equal(t, "urn:li:corpuser:jane@example.com", proposals[0].EntityURN)
+		assert.Equal(t, "Jane Doe", proposals[0].Aspect.JSON["displayName"])
+		assert.Equal(t, map[string]interface{}{"groups": []interface{}{"urn:li:corpGroup:odpf/data"}}, proposals[1].Aspect.JSON)
+		assert.Equal(t, "urn:li:corpGroup:odpf/data", proposals[2].EntityURN)
+		assert.Equal(t, []interface{}{"urn:li:corpuser:jane"}, proposals[2].Aspect.JSON["admins"])
+	})
+	t.Run("should send proposals to the ingestion api", func(t *testing.T) {
+		var bodies []map[string]interface{}
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/aspects", r.URL.Path)
+			assert.Equal(t, "ingestProposal", r.URL.Query().Get("action"))
+			assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+			assert.Equal(t, "2.0.0", r.Header.Get("X-RestLi-Protocol-Version"))
+
+			var body map[string]interface{}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			bodies = append(bodies, body)
+		}))
+		defer server.Close()
+
+		sink := datahub.New(http.DefaultClient, testUtils.Logger)
+		require.NoError(t, sink.Init(runContext(), map[string]interface{}{
+			"server": server.URL + "/",
+			"token":  "secret",
+		}))
+		require.NoError(t, sink.Sink(context.TODO(), []models.Record{models.NewRecord(user)}))
+
+		require.Len(t, bodies, 2)
+		proposal := bodies[0]["proposal"].(map[string]interface{})
+		assert.Equal(t, "corpuser", proposal["entityType"])
+		assert.Equal(t, "corpUserInfo", proposal["aspectName"])
+		aspect := proposal["aspect"].(map[string]interface{})
+		assert.Equal(t, "application/json", aspect["contentType"])
+		var value map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(aspect["value"].(string)), &value))
+		assert.Equal(t, "jane@example.com", value["email"])
+	})
+	t.Run("should return RetryError on server error", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer server.Close()
+
+		sink := datahub.New(http.DefaultClient, testUtils.Logger)
+		require.NoError(t, sink.Init(runContext(), map[string]interface{}{"server": server.URL}))
+
+		err := sink.Sink(context.TODO(), []models.Record{models.NewRecord(job)})
+		assert.True(t, errors.Is(err, plugins.RetryError{}))
+	})
+	t.Run("should not retry on client error", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadRequest)
+		}))
+		defer server.Close()
+
+		sink := datahub.New(http.DefaultClient, testUtils.Logger)
+		require.NoError(t, sink.Init(runContext(), map[string]interface{}{"server": server.URL}))
+
+		err := sink.Sink(context.TODO(), []models.Record{models.NewRecord(job)})
+		assert.Error(t, err)
+		assert.False(t, errors.Is(err, plugins.RetryError{}))
+	})
+}
+
+func runContext() context.Context {
+	return plugins.ContextWithRunInfo(context.TODO(), plugins.RunInfo{RecipeName: "datahub", StartedAt: startedAt})
+}
+
+// sinkToFile sinks the assets to a file and returns the proposals in it
+func sinkToFile(t *testing.T, config map[string]interface{}, assets ...models.Metadata) (proposals []mcp) {
+	path := filepath.Join(t.TempDir(), "datahub.json")
+	config["path"] = path
+	sink := datahub.New(http.DefaultClient, testUtils.Logger)
+	require.NoError(t, sink.Init(runContext(), config))
+
+	var records []models.Record
+	for _, asset := range assets {
+		records = append(records, models.NewRecord(asset))
+	}
+	require.NoError(t, sink.Sink(context.TODO(), records))
+	require.NoError(t, sink.Close())
+
+	b, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(b, &proposals))
+
+	return proposals
+}
+
+func aspectNames(proposals []mcp) (names []string) {
+	for _, p := range proposals {
+		names = append(names, p.AspectName)
+	}
+	return names
+}
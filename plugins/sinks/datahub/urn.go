@@ -0,0 +1,102 @@
+package datahub
+
+import (
+	"fmt"
+	"strings"
+)
+
+// urnMapper converts meteor urns into datahub urns
+type urnMapper struct {
+	platforms map[string]string
+	env       string
+}
+
+// splitURN splits an urn such as bigquery::project/dataset/table into its service and path,
+// the service is empty for urns without service
+func splitURN(urn string) (service, path string) {
+	parts := strings.SplitN(urn, "::", 2)
+	if len(parts) != 2 {
+		return "", urn
+	}
+	return parts[0], parts[1]
+}
+
+// platform returns the datahub platform of the service, the service itself if it is not mapped
+func (m urnMapper) platform(service string) string {
+	if platform, ok := m.platforms[service]; ok {
+		return platform
+	}
+	return service
+}
+
+func (m urnMapper) platformURN(service string) string {
+	return "urn:li:dataPlatform:" + m.platform(service)
+}
+
+// datasetURN returns the dataset urn of an asset, the name of the dataset is the path of the urn joined with dots
+func (m urnMapper) datasetURN(urn, service string) string {
+	urnService, path := splitURN(urn)
+	if service == "" {
+		service = urnService
+	}
+	return fmt.Sprintf("urn:li:dataset:(%s,%s,%s)", m.platformURN(service), strings.ReplaceAll(path, "/", "."), m.env)
+}
+
+func (m urnMapper) dashboardURN(urn, service string) string {
+	urnService, path := splitURN(urn)
+	if service == "" {
+		service = urnService
+	}
+	return fmt.Sprintf("urn:li:dashboard:(%s,%s)", m.platform(service), path)
+}
+
+func (m urnMapper) chartURN(urn, service string) string {
+	urnService, path := splitURN(urn)
+	if service == "" {
+		service = urnService
+	}
+	return fmt.Sprintf("urn:li:chart:(%s,%s)", m.platform(service), path)
+}
+
+// dataFlowURN returns the flow of a job, the host of the urn of the job
+func (m urnMapper) dataFlowURN(urn, service string) string {
+	flow, _ := splitJob(urn)
+	if service == "" {
+		service, _ = splitURN(urn)
+	}
+	return fmt.Sprintf("urn:li:dataFlow:(%s,%s,%s)", m.platform(service), flow, m.env)
+}
+
+// dataJobURN returns the job in the flow of the host of the urn of the job
+func (m urnMapper) dataJobURN(urn, service string) string {
+	_, id := splitJob(urn)
+	return fmt.Sprintf("urn:li:dataJob:(%s,%s)", m.dataFlowURN(urn, service), id)
+}
+
+func splitJob(urn string) (flow, id string) {
+	_, path := splitURN(urn)
+	parts := strings.SplitN(path, "/", 2)
+	if len(parts) == 1 {
+		return parts[0], parts[0]
+	}
+	return parts[0], strings.ReplaceAll(parts[1], "/", ".")
+}
+
+// userURN returns the user named after its email, or the path of its urn if it has none
+func userURN(email, urn string) string {
+	if email != "" {
+		return "urn:li:corpuser:" + email
+	}
+	_, path := splitURN(urn)
+	return "urn:li:corpuser:" + path
+}
+
+// groupURN returns the group named after the path of its urn
+func groupURN(urn string) string {
+	_, path := splitURN(urn)
+	return "urn:li:corpGroup:" + path
+}
+
+func tagURN(tag string) string {
+	return "urn:li:tag:" + tag
+}
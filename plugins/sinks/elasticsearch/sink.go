@@ -0,0 +1,325 @@
+package elasticsearch
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/odpf/meteor/models"
+	"github.com/odpf/meteor/plugins"
+	"github.com/odpf/meteor/registry"
+	"github.com/odpf/meteor/utils"
+	"github.com/odpf/salt/log"
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+//go:embed README.md
+var summary string
+
+// maxIDLength is the maximum length in bytes of a document id
+const maxIDLength = 512
+
+type Config struct {
+	Host           string `mapstructure:"host" validate:"required"`
+	User           string `mapstructure:"user"`
+	Password       string `mapstructure:"password"`
+	IndexPrefix    string `mapstructure:"index_prefix" default:"meteor-"`
+	CreateTemplate bool   `mapstructure:"create_template" default:"true"`
+}
+
+var sampleConfig = `
+# Elasticsearch or OpenSearch host
+host: http://localhost:9200
+user: elastic
+password: changeme
+# Assets are indexed to the index <index_prefix><type>, e.g. meteor-table
+index_prefix: meteor-
+# Create the index template mapping the fields of the assets
+create_template: true
+`
+
+type httpClient interface {
+	Do(*http.Request) (*http.Response, error)
+}
+
+// document is an asset to index
+type document struct {
+	urn    string
+	index  string
+	id     string
+	source []byte
+}
+
+// bulkResponse is the response of the bulk api
+type bulkResponse struct {
+	Errors bool `json:"errors"`
+	Items  []map[string]struct {
+		ID     string `json:"_id"`
+		Status int    `json:"status"`
+		Error  *struct {
+			Type   string `json:"type"`
+			Reason string `json:"reason"`
+		} `json:"error"`
+	} `json:"items"`
+}
+
+type Sink struct {
+	client httpClient
+	config Config
+	logger log.Logger
+}
+
+func New(c httpClient, logger log.Logger) plugins.Syncer {
+	return &Sink{client: c, logger: logger}
+}
+
+func (s *Sink) Info() plugins.Info {
+	return plugins.Info{
+		Description:  "Index metadata to elasticsearch or opensearch",
+		SampleConfig: sampleConfig,
+		Summary:      summary,
+		Tags:         []string{"http", "search", "sink"},
+	}
+}
+
+func (s *Sink) Validate(configMap map[string]interface{}) (err error) {
+	return utils.BuildConfig(configMap, &Config{})
+}
+
+func (s *Sink) Init(ctx context.Context, configMap map[string]interface{}) (err error) {
+	if err = utils.BuildConfig(configMap, &s.config); err != nil {
+		return plugins.InvalidConfigError{Type: plugins.PluginTypeSink, PluginName: "elasticsearch"}
+	}
+	s.config.Host = strings.TrimSuffix(s.config.Host, "/")
+
+	if s.config.CreateTemplate {
+		if err = s.putTemplate(ctx); err != nil {
+			return errors.Wrap(err, "failed to create index template")
+		}
+	}
+
+	return
+}
+
+// Sink indexes the records of the batch with the bulk api.
+// A permanent error is returned if any document is rejected permanently, otherwise a RetryError
+// with the documents rejected with a retryable status, so that the agent only retries them.
+// As the agent does not retry a batch failing permanently, the documents rejected with a retryable status
+// along with permanent rejections are bulked again once, only the documents still rejected are failed.
+func (s *Sink) Sink(ctx context.Context, batch []models.Record) (err error) {
+	docs := make([]document, 0, len(batch))
+	for _, record := range batch {
+		doc, err := s.buildDocument(record.Data())
+		if err != nil {
+			return err
+		}
+		docs = append(docs, doc)
+	}
+
+	retryable, permanent, err := s.bulk(ctx, docs)
+	if err != nil {
+		return err
+	}
+	if len(permanent) > 0 && len(retryable) > 0 {
+		permanent = append(permanent, s.rebulk(ctx, retryable)...)
+		retryable = nil
+	}
+
+	failedURNs := make([]string, 0, len(permanent)+len(retryable))
+	reasons := make([]string, 0, len(permanent))
+	for _, f := range permanent {
+		failedURNs = append(failedURNs, f.urn)
+		reasons = append(reasons, fmt.Sprintf("\"%s\" %s", f.urn, f.reason))
+	}
+	for _, doc := range retryable {
+		failedURNs = append(failedURNs, doc.urn)
+	}
+
+	if len(permanent) > 0 {
+		return plugins.PartialSinkError{
+			FailedURNs: failedURNs,
+			Err:        fmt.Errorf("failed to index %d documents: %s", len(permanent), strings.Join(reasons, "; ")),
+		}
+	}
+	if len(retryable) > 0 {
+		return plugins.NewRetryError(plugins.PartialSinkError{
+			FailedURNs: failedURNs,
+			Err:        fmt.Errorf("%d documents rejected by bulk request", len(retryable)),
+		})
+	}
+
+	return nil
+}
+
+func (s *Sink) Close() (err error) { return }
+
+func (s *Sink) buildDocument(metadata models.Metadata) (document, error) {
+	msg, ok := metadata.(proto.Message)
+	if !ok {
+		return document{}, errors.New("asset is not a proto message")
+	}
+	source, err := protojson.MarshalOptions{UseProtoNames: true}.Marshal(msg)
+	if err != nil {
+		return document{}, errors.Wrap(err, "failed to marshal asset")
+	}
+
+	urn := metadata.GetResource().GetUrn()
+	return document{
+		urn:    urn,
+		index:  strings.ToLower(s.config.IndexPrefix + utils.GetAssetType(metadata)),
+		id:     documentID(urn),
+		source: source,
+	}, nil
+}
+
+// failure is a document rejected permanently
+type failure struct {
+	urn    string
+	reason string
+}
+
+// bulk indexes the documents, documents rejected with a retryable status are returned
+// and the documents rejected permanently
+func (s *Sink) bulk(ctx context.Context, docs []document) (retryable []document, failed []failure, err error) {
+	var body bytes.Buffer
+	for _, doc := range docs {
+		action, err := json.Marshal(map[string]interface{}{
+			"index": map[string]string{"_index": doc.index, "_id": doc.id},
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		body.Write(action)
+		body.WriteByte('\n')
+		body.Write(doc.source)
+		body.WriteByte('\n')
+	}
+
+	res, err := s.request(ctx, http.MethodPost, "/_bulk", "application/x-ndjson", &body)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer res.Body.Close()
+
+	var bulkRes bulkResponse
+	if err = json.NewDecoder(res.Body).Decode(&bulkRes); err != nil {
+		return nil, nil, plugins.NewRetryError(errors.Wrap(err, "failed to decode bulk response"))
+	}
+	if !bulkRes.Errors {
+		return nil, nil, nil
+	}
+	if len(bulkRes.Items) != len(docs) {
+		return nil, nil, fmt.Errorf("bulk response has %d items for %d documents", len(bulkRes.Items), len(docs))
+	}
+
+	// items are in the order of the actions of the request
+	for i, item := range bulkRes.Items {
+		for _, result := range item {
+			if result.Status >= 200 && result.Status < 300 {
+				continue
+			}
+			if plugins.IsRetryableStatus(result.Status) {
+				retryable = append(retryable, docs[i])
+				continue
+			}
+			reason := http.StatusText(result.Status)
+			if result.Error != nil {
+				reason = fmt.Sprintf("%s: %s", result.Error.Type, result.Error.Reason)
+			}
+			failed = append(failed, failure{urn: docs[i].urn, reason: reason})
+		}
+	}
+
+	return retryable, failed, nil
+}
+
+// rebulk indexes the documents again, the documents which are still rejected are returned
+func (s *Sink) rebulk(ctx context.Context, docs []document) []failure {
+	s.logger.Warn("bulking rejected documents again", "count", len(docs))
+
+	retryable, failed, err := s.bulk(ctx, docs)
+	if err != nil {
+		retryable, failed = docs, nil
+	}
+	for _, doc := range retryable {
+		reason := "rejected with a retryable status twice"
+		if err != nil {
+			reason = err.Error()
+		}
+		failed = append(failed, failure{urn: doc.urn, reason: reason})
+	}
+
+	return failed
+}
+
+func (s *Sink) putTemplate(ctx context.Context) error {
+	body, err := json.Marshal(buildTemplate(s.config.IndexPrefix + "*"))
+	if err != nil {
+		return err
+	}
+
+	res, err := s.request(ctx, http.MethodPut, "/_index_template/"+s.config.IndexPrefix+"assets", "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+
+	return nil
+}
+
+// request sends a request to the host, a RetryError is returned for connection errors,
+// too many requests and server errors and an error for any other non 2xx status
+func (s *Sink) request(ctx context.Context, method, path, contentType string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, s.config.Host+path, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	if s.config.User != "" {
+		req.SetBasicAuth(s.config.User, s.config.Password)
+	}
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return nil, plugins.NewRetryError(err)
+	}
+	if res.StatusCode >= 200 && res.StatusCode < 300 {
+		return res, nil
+	}
+	defer res.Body.Close()
+
+	bodyBytes, _ := ioutil.ReadAll(res.Body)
+	err = fmt.Errorf("%s %s returns %d: %s", method, path, res.StatusCode, string(bodyBytes))
+	if plugins.IsRetryableStatus(res.StatusCode) {
+		return nil, plugins.NewRetryError(err)
+	}
+
+	return nil, err
+}
+
+// documentID returns the urn as document id, urns longer than allowed are replaced by their sha256 hash
+func documentID(urn string) string {
+	if len(urn) <= maxIDLength {
+		return urn
+	}
+	sum := sha256.Sum256([]byte(urn))
+	return hex.EncodeToString(sum[:])
+}
+
+func init() {
+	if err := registry.Sinks.Register("elasticsearch", func() plugins.Syncer {
+		return New(&http.Client{}, plugins.GetLog())
+	}); err != nil {
+		panic(err)
+	}
+}
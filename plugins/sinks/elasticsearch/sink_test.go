@@ -0,0 +1,239 @@
+package elasticsearch_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/odpf/meteor/models"
+	commonv1beta1 "github.com/odpf/meteor/models/odpf/assets/common/v1beta1"
+	facetsv1beta1 "github.com/odpf/meteor/models/odpf/assets/facets/v1beta1"
+	assetsv1beta1 "github.com/odpf/meteor/models/odpf/assets/v1beta1"
+	"github.com/odpf/meteor/plugins"
+	"github.com/odpf/meteor/plugins/sinks/elasticsearch"
+	testUtils "github.com/odpf/meteor/test/utils"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var records = []models.Record{
+	models.NewRecord(&assetsv1beta1.Table{
+		Resource: &commonv1beta1.Resource{Urn: "bigquery::p/d/orders", Name: "orders", Service: "bigquery", Type: "table"},
+		Schema: &facetsv1beta1.Columns{Columns: []*facetsv1beta1.Column{
+			{Name: "id", DataType: "INT64"},
+		}},
+	}),
+	models.NewRecord(&assetsv1beta1.Topic{
+		Resource: &commonv1beta1.Resource{Urn: "kafka::k/orders", Name: "orders", Service: "kafka", Type: "topic"},
+	}),
+}
+
+func TestInit(t *testing.T) {
+	t.Run("should return InvalidConfigError on invalid config", func(t *testing.T) {
+		err := elasticsearch.New(http.DefaultClient, testUtils.Logger).Init(context.TODO(), map[string]interface{}{})
+		assert.Equal(t, plugins.InvalidConfigError{Type: plugins.PluginTypeSink, PluginName: "elasticsearch"}, err)
+	})
+	t.Run("should create index template", func(t *testing.T) {
+		es := newFakeES(t)
+		sink := elasticsearch.New(http.DefaultClient, testUtils.Logger)
+		require.NoError(t, sink.Init(context.TODO(), map[string]interface{}{
+			"host":     es.server.URL,
+			"user":     "elastic",
+			"password": "changeme",
+		}))
+
+		template := es.templates["meteor-assets"]
+		require.NotNil(t, template)
+		assert.Equal(t, []interface{}{"meteor-*"}, template["index_patterns"])
+		mappings := template["template"].(map[string]interface{})["mappings"].(map[string]interface{})
+		assert.Contains(t, mappings["properties"], "resource")
+		assert.Contains(t, mappings["properties"], "schema")
+		assert.Contains(t, mappings["properties"], "properties")
+	})
+}
+
+func TestSink(t *testing.T) {
+	t.Run("should index documents by type with urn ids", func(t *testing.T) {
+		es := newFakeES(t)
+		sink := initSink(t, es)
+
+		require.NoError(t, sink.Sink(context.TODO(), records))
+
+		require.Len(t, es.requests, 1)
+		assert.Equal(t, []action{
+			{Index: "meteor-table", ID: "bigquery::p/d/orders"},
+			{Index: "meteor-topic", ID: "kafka::k/orders"},
+		}, es.requests[0])
+		assert.Equal(t, "orders", es.documents["bigquery::p/d/orders"]["resource"].(map[string]interface{})["name"])
+	})
+	t.Run("should return RetryError with documents rejected with a retryable status", func(t *testing.T) {
+		es := newFakeES(t)
+		es.statuses = map[string][]int{"kafka::k/orders": {http.StatusTooManyRequests}}
+		sink := initSink(t, es)
+
+		err := sink.Sink(context.TODO(), records)
+		assert.True(t, errors.Is(err, plugins.RetryError{}))
+		assert.Len(t, es.requests, 1)
+		var partialErr plugins.PartialSinkError
+		require.True(t, errors.As(err, &partialErr))
+		assert.Equal(t, []string{"kafka::k/orders"}, partialErr.FailedURNs)
+	})
+	t.Run("should return permanent error on rejected documents", func(t *testing.T) {
+		es := newFakeES(t)
+		es.statuses = map[string][]int{"bigquery::p/d/orders": {http.StatusBadRequest}}
+		sink := initSink(t, es)
+
+		err := sink.Sink(context.TODO(), records)
+		require.Error(t, err)
+		assert.False(t, errors.Is(err, plugins.RetryError{}))
+		assert.Contains(t, err.Error(), "mapper_parsing_exception")
+		var partialErr plugins.PartialSinkError
+		require.True(t, errors.As(err, &partialErr))
+		assert.Equal(t, []string{"bigquery::p/d/orders"}, partialErr.FailedURNs)
+	})
+	t.Run("should bulk documents rejected with a retryable status again along with permanent rejections", func(t *testing.T) {
+		es := newFakeES(t)
+		es.statuses = map[string][]int{
+			"bigquery::p/d/orders": {http.StatusBadRequest},
+			"kafka::k/orders":      {http.StatusTooManyRequests},
+		}
+		sink := initSink(t, es)
+
+		err := sink.Sink(context.TODO(), records)
+		require.Error(t, err)
+		assert.False(t, errors.Is(err, plugins.RetryError{}))
+		require.Len(t, es.requests, 2)
+		assert.Len(t, es.requests[1], 1)
+		var partialErr plugins.PartialSinkError
+		require.True(t, errors.As(err, &partialErr))
+		assert.Equal(t, []string{"bigquery::p/d/orders"}, partialErr.FailedURNs)
+	})
+	t.Run("should fail documents rejected with a retryable status twice along with permanent rejections", func(t *testing.T) {
+		es := newFakeES(t)
+		es.statuses = map[string][]int{
+			"bigquery::p/d/orders": {http.StatusBadRequest},
+			"kafka::k/orders":      {http.StatusTooManyRequests, http.StatusTooManyRequests},
+		}
+		sink := initSink(t, es)
+
+		err := sink.Sink(context.TODO(), records)
+		require.Error(t, err)
+		assert.False(t, errors.Is(err, plugins.RetryError{}))
+		var partialErr plugins.PartialSinkError
+		require.True(t, errors.As(err, &partialErr))
+		assert.Equal(t, []string{"bigquery::p/d/orders", "kafka::k/orders"}, partialErr.FailedURNs)
+	})
+	t.Run("should return RetryError on server error", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadGateway)
+		}))
+		defer server.Close()
+
+		sink := elasticsearch.New(http.DefaultClient, testUtils.Logger)
+		require.NoError(t, sink.Init(context.TODO(), map[string]interface{}{
+			"host":            server.URL,
+			"create_template": false,
+		}))
+
+		err := sink.Sink(context.TODO(), records)
+		assert.True(t, errors.Is(err, plugins.RetryError{}))
+	})
+}
+
+func initSink(t *testing.T, es *fakeES) plugins.Syncer {
+	sink := elasticsearch.New(http.DefaultClient, testUtils.Logger)
+	require.NoError(t, sink.Init(context.TODO(), map[string]interface{}{
+		"host": es.server.URL,
+	}))
+
+	return sink
+}
+
+type action struct {
+	Index string `json:"_index"`
+	ID    string `json:"_id"`
+}
+
+// fakeES is a stand-in of the index template and bulk apis,
+// statuses holds the statuses returned for a document id by successive bulk requests
+type fakeES struct {
+	server    *httptest.Server
+	mu        sync.Mutex
+	templates map[string]map[string]interface{}
+	requests  [][]action
+	documents map[string]map[string]interface{}
+	statuses  map[string][]int
+}
+
+func newFakeES(t *testing.T) *fakeES {
+	es := &fakeES{
+		templates: make(map[string]map[string]interface{}),
+		documents: make(map[string]map[string]interface{}),
+	}
+	es.server = httptest.NewServer(http.HandlerFunc(es.handle))
+	t.Cleanup(es.server.Close)
+
+	return es
+}
+
+func (es *fakeES) handle(w http.ResponseWriter, r *http.Request) {
+	es.mu.Lock()
+	defer es.mu.Unlock()
+
+	switch {
+	case r.Method == http.MethodPut && strings.HasPrefix(r.URL.Path, "/_index_template/"):
+		var template map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&template); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		es.templates[strings.TrimPrefix(r.URL.Path, "/_index_template/")] = template
+		w.Write([]byte(`{"acknowledged":true}`))
+	case r.Method == http.MethodPost && r.URL.Path == "/_bulk":
+		es.bulk(w, r)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (es *fakeES) bulk(w http.ResponseWriter, r *http.Request) {
+	var (
+		actions []action
+		items   []interface{}
+		errs    bool
+	)
+	scanner := bufio.NewScanner(r.Body)
+	for scanner.Scan() {
+		var meta map[string]action
+		if err := json.Unmarshal(scanner.Bytes(), &meta); err != nil || !scanner.Scan() {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		a := meta["index"]
+		actions = append(actions, a)
+
+		status := http.StatusCreated
+		if statuses := es.statuses[a.ID]; len(statuses) > 0 {
+			status, es.statuses[a.ID] = statuses[0], statuses[1:]
+		}
+		item := map[string]interface{}{"_index": a.Index, "_id": a.ID, "status": status}
+		if status >= 300 {
+			errs = true
+			item["error"] = map[string]string{"type": "mapper_parsing_exception", "reason": "failed to parse"}
+		} else {
+			var doc map[string]interface{}
+			json.Unmarshal(scanner.Bytes(), &doc)
+			es.documents[a.ID] = doc
+		}
+		items = append(items, map[string]interface{}{"index": item})
+	}
+	es.requests = append(es.requests, actions)
+
+	json.NewEncoder(w).Encode(map[string]interface{}{"errors": errs, "items": items})
+}
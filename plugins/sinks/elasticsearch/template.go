@@ -0,0 +1,67 @@
+package elasticsearch
+
+// keyword is a text field that can also be matched and aggregated exactly with the ".keyword" sub field
+var keyword = map[string]interface{}{
+	"type": "text",
+	"fields": map[string]interface{}{
+		"keyword": map[string]interface{}{"type": "keyword", "ignore_above": 256},
+	},
+}
+
+// buildTemplate returns the index template of the indices matching pattern,
+// it maps the fields of the resource, the columns and the properties of the assets
+func buildTemplate(pattern string) map[string]interface{} {
+	return map[string]interface{}{
+		"index_patterns": []string{pattern},
+		"template": map[string]interface{}{
+			"mappings": map[string]interface{}{
+				"properties": map[string]interface{}{
+					"resource": map[string]interface{}{
+						"properties": map[string]interface{}{
+							"urn":         map[string]interface{}{"type": "keyword"},
+							"name":        keyword,
+							"service":     map[string]interface{}{"type": "keyword"},
+							"type":        map[string]interface{}{"type": "keyword"},
+							"url":         map[string]interface{}{"type": "keyword"},
+							"description": map[string]interface{}{"type": "text"},
+						},
+					},
+					"schema": map[string]interface{}{
+						"properties": map[string]interface{}{
+							"columns": map[string]interface{}{
+								"properties": map[string]interface{}{
+									"name":        keyword,
+									"description": map[string]interface{}{"type": "text"},
+									"data_type":   map[string]interface{}{"type": "keyword"},
+									"is_nullable": map[string]interface{}{"type": "boolean"},
+									"length":      map[string]interface{}{"type": "long"},
+									// column properties are free form and kept in the source only
+									"properties": map[string]interface{}{"type": "object", "enabled": false},
+								},
+							},
+						},
+					},
+					"properties": map[string]interface{}{
+						"properties": map[string]interface{}{
+							"tags": map[string]interface{}{"type": "keyword"},
+							"labels": map[string]interface{}{
+								"type":    "object",
+								"dynamic": true,
+							},
+							// attributes are free form and kept in the source only
+							"attributes": map[string]interface{}{"type": "object", "enabled": false},
+						},
+					},
+				},
+				"dynamic_templates": []interface{}{
+					map[string]interface{}{
+						"labels": map[string]interface{}{
+							"path_match": "properties.labels.*",
+							"mapping":    map[string]interface{}{"type": "keyword"},
+						},
+					},
+				},
+			},
+		},
+	}
+}
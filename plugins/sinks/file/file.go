@@ -1,17 +1,23 @@
 package file
 
 import (
+	"bytes"
 	"context"
 	_ "embed"
+	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
+	"text/template"
+	"time"
 
 	"github.com/odpf/meteor/models"
 	"github.com/odpf/meteor/plugins"
 	"github.com/odpf/meteor/registry"
 	"github.com/odpf/meteor/utils"
 	"github.com/odpf/salt/log"
+	"github.com/pkg/errors"
 	ndjson "github.com/scizorman/go-ndjson"
 	"gopkg.in/yaml.v3"
 )
@@ -19,22 +25,63 @@ import (
 //go:embed README.md
 var summary string
 
+const (
+	formatNDJSON = "ndjson"
+	formatYAML   = "yaml"
+	formatJSON   = "json"
+
+	compressionNone = "none"
+	compressionGzip = "gzip"
+	compressionZstd = "zstd"
+)
+
+var compressionExtensions = map[string]string{
+	compressionGzip: ".gz",
+	compressionZstd: ".zst",
+}
+
 type Config struct {
-	Overwrite bool   `mapstructure:"overwrite" default:"true"`
-	Path      string `mapstructure:"path" validate:"required"`
-	Format    string `mapstructure:"format" validate:"required"`
+	Overwrite   bool   `mapstructure:"overwrite" default:"true"`
+	Path        string `mapstructure:"path" validate:"required"`
+	Format      string `mapstructure:"format" validate:"required,oneof=ndjson yaml json"`
+	Compression string `mapstructure:"compression" validate:"oneof=none gzip zstd" default:"none"`
+	MaxRecords  int    `mapstructure:"max_records" validate:"min=0"`
+	MaxBytes    int64  `mapstructure:"max_bytes" validate:"min=0"`
 }
 
 var sampleConfig = `
-path: ./output-filename.txt
+# Path of the output file, can be templated with .Recipe, .Type, .Date and .Time
+path: ./output/{{ .Recipe }}/{{ .Type }}-{{ .Date }}.ndjson
+# One of ndjson, yaml or json
 format: ndjson
+# Compression of the output file, one of none, gzip or zstd
+compression: gzip
+# Start a new file once the file has this many records or bytes, 0 disables rotation
+max_records: 10000
+max_bytes: 0
 `
 
+// pathData is the data available to the path template
+type pathData struct {
+	Recipe string
+	Type   string
+	Date   string
+	Time   time.Time
+}
+
+// target is an output path and its currently open file
+type target struct {
+	file  *atomicFile
+	index int
+}
+
 type Sink struct {
-	logger log.Logger
-	config Config
-	format string
-	File   *os.File
+	logger   log.Logger
+	config   Config
+	format   string
+	pathTmpl *template.Template
+	run      plugins.RunInfo
+	targets  map[string]*target
 }
 
 func New() plugins.Syncer {
@@ -62,76 +109,173 @@ func (s *Sink) Init(ctx context.Context, config map[string]interface{}) (err err
 	if err := s.validateFilePath(s.config.Path); err != nil {
 		return err
 	}
+	if s.config.Format == formatJSON && !s.config.Overwrite {
+		return errors.New("json format can not be appended to, overwrite has to be set")
+	}
+	if s.pathTmpl, err = template.New("path").Option("missingkey=error").Parse(s.config.Path); err != nil {
+		return errors.Wrap(err, "invalid path template")
+	}
 
 	s.format = s.config.Format
-	if s.config.Overwrite {
-		s.File, err = os.Create(s.config.Path)
-		return err
-	}
-	s.File, err = os.OpenFile(s.config.Path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0777)
-	if err != nil {
-		return err
+	s.run = plugins.RunInfoFromContext(ctx)
+	if s.run.StartedAt.IsZero() {
+		s.run.StartedAt = time.Now()
 	}
+	s.targets = make(map[string]*target)
+
 	return
 }
 
 func (s *Sink) Sink(ctx context.Context, batch []models.Record) (err error) {
-	var data []models.Metadata
 	for _, record := range batch {
-		data = append(data, record.Data())
-	}
-	if s.format == "ndjson" {
-		err := s.ndjsonOut(data)
+		data := record.Data()
+
+		path, err := s.renderPath(data)
 		if err != nil {
+			return errors.Wrap(err, "failed to build path")
+		}
+		t, ok := s.targets[path]
+		if !ok {
+			t = &target{}
+			s.targets[path] = t
+		}
+		if t.file == nil {
+			if t.file, err = createAtomicFile(s.outputPath(path, t.index), s.config.Compression, !s.config.Overwrite); err != nil {
+				return err
+			}
+		}
+
+		if err = s.writeRecord(t.file, data); err != nil {
 			return err
 		}
-		return nil
-	}
-	err = s.yamlOut(data)
-	if err != nil {
-		return err
+
+		if s.isFull(t.file) {
+			err = s.commit(t.file)
+			t.file = nil
+			t.index++
+			if err != nil {
+				return err
+			}
+		}
 	}
+
 	return nil
 }
 
+// Close commits every open file
 func (s *Sink) Close() (err error) {
-	// return s.File.Close()
-	return nil
+	for _, t := range s.targets {
+		if t.file == nil {
+			continue
+		}
+		if commitErr := s.commit(t.file); commitErr != nil && err == nil {
+			err = commitErr
+		}
+		t.file = nil
+	}
+
+	return err
 }
 
-func (s *Sink) ndjsonOut(data []models.Metadata) error {
-	jsnBy, err := ndjson.Marshal(data)
-	if err != nil {
-		return err
+func (s *Sink) renderPath(data models.Metadata) (string, error) {
+	var buf bytes.Buffer
+	if err := s.pathTmpl.Execute(&buf, pathData{
+		Recipe: s.run.RecipeName,
+		Type:   utils.GetAssetType(data),
+		Date:   s.run.StartedAt.Format("2006-01-02"),
+		Time:   s.run.StartedAt,
+	}); err != nil {
+		return "", err
 	}
-	err = s.writeBytes(jsnBy)
-	return err
+
+	return buf.String(), nil
 }
 
-func (s *Sink) yamlOut(data []models.Metadata) error {
-	ymlByte, err := yaml.Marshal(data)
-	if err != nil {
-		return err
+// outputPath adds the rotation index and compression extension to path
+func (s *Sink) outputPath(path string, index int) string {
+	if s.config.MaxRecords > 0 || s.config.MaxBytes > 0 {
+		ext := filepath.Ext(path)
+		path = fmt.Sprintf("%s-%d%s", strings.TrimSuffix(path, ext), index+1, ext)
 	}
-	err = s.writeBytes(ymlByte)
-	return err
+	if ext, ok := compressionExtensions[s.config.Compression]; ok && !strings.HasSuffix(path, ext) {
+		path += ext
+	}
+
+	return path
+}
+
+// isFull returns true if the file reached max_records or max_bytes
+func (s *Sink) isFull(f *atomicFile) bool {
+	return (s.config.MaxRecords > 0 && f.records >= s.config.MaxRecords) ||
+		(s.config.MaxBytes > 0 && f.bytes >= s.config.MaxBytes)
 }
 
-func (s *Sink) writeBytes(b []byte) error {
-	_, err := s.File.Write(b)
+func (s *Sink) writeRecord(f *atomicFile, data models.Metadata) (err error) {
+	var b []byte
+	switch s.format {
+	case formatNDJSON:
+		b, err = ndjson.Marshal([]models.Metadata{data})
+	case formatJSON:
+		if b, err = json.Marshal(data); err != nil {
+			break
+		}
+		// records of the json format are items of a single array
+		if f.records == 0 {
+			b = append([]byte("["), b...)
+		} else {
+			b = append([]byte(","), b...)
+		}
+	default:
+		b, err = yaml.Marshal([]models.Metadata{data})
+	}
 	if err != nil {
-		return err
+		return errors.Wrap(err, "failed to marshal record")
 	}
+
+	if _, err = f.Write(b); err != nil {
+		return errors.Wrap(err, "failed to write record")
+	}
+	f.records++
+
+	return nil
+}
+
+// commit terminates the format of the file before committing it
+func (s *Sink) commit(f *atomicFile) error {
+	if s.format == formatJSON {
+		end := "]"
+		if f.records == 0 {
+			end = "[]"
+		}
+		if _, err := f.Write([]byte(end)); err != nil {
+			f.abort()
+			return errors.Wrap(err, "failed to write record")
+		}
+	}
+
+	if err := f.commit(); err != nil {
+		return errors.Wrapf(err, "failed to commit file \"%s\"", f.path)
+	}
+
 	return nil
 }
 
+// validateFilePath checks the path has a file name with an extension
+// and the directory up to the first templated segment exists
 func (s *Sink) validateFilePath(path string) error {
-	dirs := strings.Split(path, "/")
-	filename := dirs[len(dirs)-1]
-	format := strings.Split(filename, ".")
-	if len(format) != 2 {
+	if filepath.Ext(filepath.Base(path)) == "" {
 		return fmt.Errorf("invalid filename")
 	}
+
+	dir := path
+	if i := strings.Index(path, "{{"); i >= 0 {
+		dir = path[:i]
+	}
+	dir = filepath.Dir(dir)
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return fmt.Errorf("directory \"%s\" does not exist", dir)
+	}
+
 	return nil
 }
 
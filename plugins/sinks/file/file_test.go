@@ -1,9 +1,16 @@
 package file_test
 
 import (
+	"compress/gzip"
 	"context"
 	_ "embed"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/odpf/meteor/models"
 	commonv1beta1 "github.com/odpf/meteor/models/odpf/assets/common/v1beta1"
@@ -12,6 +19,7 @@ import (
 	"github.com/odpf/meteor/plugins"
 	f "github.com/odpf/meteor/plugins/sinks/file"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 //go:embed README.md
@@ -82,6 +90,95 @@ func TestMain(t *testing.T) {
 	})
 }
 
+func TestSink(t *testing.T) {
+	t.Run("should only expose the file once the sink is closed", func(t *testing.T) {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "sample.ndjson")
+		fileSink := initSink(t, context.TODO(), map[string]interface{}{
+			"path":   path,
+			"format": "ndjson",
+		})
+
+		require.NoError(t, fileSink.Sink(context.TODO(), getExpectedVal()))
+		assert.NoFileExists(t, path)
+
+		require.NoError(t, fileSink.Close())
+		content, err := ioutil.ReadFile(path)
+		require.NoError(t, err)
+		assert.Len(t, strings.Split(strings.TrimSpace(string(content)), "\n"), 2)
+		assertNoTempFiles(t, dir)
+	})
+
+	t.Run("should write a json array", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "sample.json")
+		fileSink := initSink(t, context.TODO(), map[string]interface{}{
+			"path":   path,
+			"format": "json",
+		})
+
+		require.NoError(t, fileSink.Sink(context.TODO(), getExpectedVal()))
+		require.NoError(t, fileSink.Sink(context.TODO(), getExpectedVal()[:1]))
+		require.NoError(t, fileSink.Close())
+
+		var items []map[string]interface{}
+		content, err := ioutil.ReadFile(path)
+		require.NoError(t, err)
+		require.NoError(t, json.Unmarshal(content, &items))
+		assert.Len(t, items, 3)
+	})
+
+	t.Run("should rotate files by number of records", func(t *testing.T) {
+		dir := t.TempDir()
+		fileSink := initSink(t, context.TODO(), map[string]interface{}{
+			"path":        filepath.Join(dir, "sample.ndjson"),
+			"format":      "ndjson",
+			"max_records": 1,
+		})
+
+		require.NoError(t, fileSink.Sink(context.TODO(), getExpectedVal()))
+		require.NoError(t, fileSink.Close())
+
+		assert.FileExists(t, filepath.Join(dir, "sample-1.ndjson"))
+		assert.FileExists(t, filepath.Join(dir, "sample-2.ndjson"))
+		assert.NoFileExists(t, filepath.Join(dir, "sample-3.ndjson"))
+		assertNoTempFiles(t, dir)
+	})
+
+	t.Run("should write gzip files to templated paths", func(t *testing.T) {
+		dir := t.TempDir()
+		ctx := plugins.ContextWithRunInfo(context.TODO(), plugins.RunInfo{
+			RecipeName: "sample-recipe",
+			StartedAt:  time.Date(2022, 1, 2, 0, 0, 0, 0, time.UTC),
+		})
+		fileSink := initSink(t, ctx, map[string]interface{}{
+			"path":        filepath.Join(dir, "{{ .Recipe }}/{{ .Type }}-{{ .Date }}.ndjson"),
+			"format":      "ndjson",
+			"compression": "gzip",
+		})
+
+		require.NoError(t, fileSink.Sink(ctx, getExpectedVal()))
+		require.NoError(t, fileSink.Close())
+
+		f, err := os.Open(filepath.Join(dir, "sample-recipe", "table-2022-01-02.ndjson.gz"))
+		require.NoError(t, err)
+		defer f.Close()
+		r, err := gzip.NewReader(f)
+		require.NoError(t, err)
+		content, err := ioutil.ReadAll(r)
+		require.NoError(t, err)
+		assert.Contains(t, string(content), "elasticsearch.index2")
+	})
+
+	t.Run("should return error when appending to json format", func(t *testing.T) {
+		err := f.New().Init(context.TODO(), map[string]interface{}{
+			"path":      filepath.Join(t.TempDir(), "sample.json"),
+			"format":    "json",
+			"overwrite": false,
+		})
+		assert.Error(t, err)
+	})
+}
+
 func TestInfo(t *testing.T) {
 	info := f.New().Info()
 	assert.Equal(t, summary, info.Summary)
@@ -149,3 +246,16 @@ func getExpectedVal() []models.Record {
 		}),
 	}
 }
+
+func initSink(t *testing.T, ctx context.Context, config map[string]interface{}) plugins.Syncer {
+	fileSink := f.New()
+	require.NoError(t, fileSink.Init(ctx, config))
+
+	return fileSink
+}
+
+func assertNoTempFiles(t *testing.T, dir string) {
+	matches, err := filepath.Glob(filepath.Join(dir, ".*.tmp-*"))
+	require.NoError(t, err)
+	assert.Empty(t, matches)
+}
@@ -0,0 +1,113 @@
+package file
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/pkg/errors"
+)
+
+// atomicFile writes to a temporary file next to its path and renames it once committed,
+// so a half written file is never observed at path
+type atomicFile struct {
+	path       string
+	tmp        *os.File
+	w          io.Writer
+	compressor io.WriteCloser
+
+	records int
+	bytes   int64
+}
+
+// createAtomicFile creates the temporary file of path, existing content of path is copied over if appendExisting is set
+func createAtomicFile(path, compression string, appendExisting bool) (f *atomicFile, err error) {
+	dir := filepath.Dir(path)
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		return nil, errors.Wrap(err, "failed to create directory")
+	}
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create temporary file")
+	}
+	f = &atomicFile{path: path, tmp: tmp, w: tmp}
+	defer func() {
+		if err != nil {
+			f.abort()
+		}
+	}()
+
+	if appendExisting {
+		if err = copyFile(path, tmp); err != nil {
+			return nil, errors.Wrap(err, "failed to copy existing file")
+		}
+	}
+
+	switch compression {
+	case compressionGzip:
+		f.compressor = gzip.NewWriter(tmp)
+	case compressionZstd:
+		if f.compressor, err = zstd.NewWriter(tmp); err != nil {
+			return nil, errors.Wrap(err, "failed to create zstd writer")
+		}
+	}
+	if f.compressor != nil {
+		f.w = f.compressor
+	}
+
+	return f, nil
+}
+
+func (f *atomicFile) Write(b []byte) (int, error) {
+	n, err := f.w.Write(b)
+	f.bytes += int64(n)
+	return n, err
+}
+
+// commit flushes the temporary file and renames it to its path
+func (f *atomicFile) commit() (err error) {
+	defer func() {
+		if err != nil {
+			f.abort()
+		}
+	}()
+
+	if f.compressor != nil {
+		if err = f.compressor.Close(); err != nil {
+			return errors.Wrap(err, "failed to close compressor")
+		}
+	}
+	if err = f.tmp.Chmod(0644); err != nil {
+		return err
+	}
+	if err = f.tmp.Sync(); err != nil {
+		return err
+	}
+	if err = f.tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(f.tmp.Name(), f.path)
+}
+
+// abort removes the temporary file, path is left untouched
+func (f *atomicFile) abort() {
+	f.tmp.Close()
+	os.Remove(f.tmp.Name())
+}
+
+func copyFile(path string, w io.Writer) error {
+	src, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	_, err = io.Copy(w, src)
+	return err
+}
@@ -0,0 +1,179 @@
+package gcs
+
+import (
+	"context"
+	_ "embed"
+	"io"
+	"text/template"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/odpf/meteor/models"
+	"github.com/odpf/meteor/plugins"
+	"github.com/odpf/meteor/plugins/gcsutil"
+	"github.com/odpf/meteor/plugins/objectutil"
+	"github.com/odpf/meteor/registry"
+	"github.com/odpf/meteor/utils"
+	"github.com/odpf/salt/log"
+	"github.com/pkg/errors"
+)
+
+//go:embed README.md
+var summary string
+
+type Config struct {
+	gcsutil.ClientConfig `mapstructure:",squash"`
+	objectutil.Config    `mapstructure:",squash"`
+	Bucket               string `mapstructure:"bucket" validate:"required"`
+	// Name is the template of the object names, {{ .Recipe }}/{{ .Date }}/{{ .Type }} with the extension of the format by default
+	Name string `mapstructure:"name"`
+	// ContentType is the template of the content types of the objects, the type of the format by default
+	ContentType string `mapstructure:"content_type"`
+	// ChunkSize is the size of the chunks of resumable uploads, objects smaller than a chunk are uploaded at once
+	ChunkSize int               `mapstructure:"chunk_size" validate:"min=262144" default:"16777216"`
+	Metadata  map[string]string `mapstructure:"metadata"`
+}
+
+var sampleConfig = `
+bucket: meteor-archive
+# Template of the object names, with .Recipe, .Type, .Date and .Time
+name: "{{ .Recipe }}/{{ .Date }}/{{ .Type }}.ndjson"
+# One of ndjson or parquet
+format: ndjson
+# Compression of ndjson objects, one of none or gzip
+compression: gzip
+# Template of the content types of the objects
+content_type: application/x-ndjson
+# Objects are uploaded in chunks of this size
+chunk_size: 16777216
+# Metadata added to the objects along with the recipe and run
+metadata:
+  team: data-platform
+service_account_json: |-
+  {
+    "type": "service_account",
+    "private_key_id": "xxxxxxx",
+    "private_key": "xxxxxxx",
+    "client_email": "xxxxxxx",
+    "client_id": "xxxxxxx",
+    "auth_uri": "https://accounts.google.com/o/oauth2/auth",
+    "token_uri": "https://oauth2.googleapis.com/token",
+    "auth_provider_x509_cert_url": "xxxxxxx",
+    "client_x509_cert_url": "xxxxxxx"
+  }`
+
+type Sink struct {
+	logger      log.Logger
+	config      Config
+	client      *storage.Client
+	contentTmpl *template.Template
+	run         plugins.RunInfo
+	writer      *objectutil.Writer
+
+	// ctx outlives the batches, objects are written until the sink is closed
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func New(logger log.Logger) plugins.Syncer {
+	return &Sink{logger: logger}
+}
+
+func (s *Sink) Info() plugins.Info {
+	return plugins.Info{
+		Description:  "Write the output of runs to google cloud storage",
+		SampleConfig: sampleConfig,
+		Summary:      summary,
+		Tags:         []string{"gcp", "object storage", "sink"},
+	}
+}
+
+func (s *Sink) Validate(configMap map[string]interface{}) (err error) {
+	return utils.BuildConfig(configMap, &Config{})
+}
+
+func (s *Sink) Init(ctx context.Context, configMap map[string]interface{}) (err error) {
+	if err = utils.BuildConfig(configMap, &s.config); err != nil {
+		return plugins.InvalidConfigError{Type: plugins.PluginTypeSink, PluginName: "gcs"}
+	}
+	if err = s.config.Validate(); err != nil {
+		return err
+	}
+
+	if s.config.ContentType == "" {
+		s.config.ContentType = s.config.Config.ContentType()
+	}
+	nameTmpl, err := template.New("name").Option("missingkey=error").Parse(s.config.NameTemplate(s.config.Name))
+	if err != nil {
+		return errors.Wrap(err, "invalid name template")
+	}
+	if s.contentTmpl, err = template.New("content_type").Option("missingkey=error").Parse(s.config.ContentType); err != nil {
+		return errors.Wrap(err, "invalid content_type template")
+	}
+
+	if s.client, err = s.config.NewClient(ctx, s.logger); err != nil {
+		return errors.Wrap(err, "failed to create client")
+	}
+
+	s.run = plugins.RunInfoFromContext(ctx)
+	if s.run.StartedAt.IsZero() {
+		s.run.StartedAt = time.Now()
+	}
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	s.writer = objectutil.NewWriter(s.config.Config, nameTmpl, s.run, s.openObject, s.logger)
+
+	return
+}
+
+// Sink writes the records to the objects of their names, the chunks of an object are uploaded as they fill up
+func (s *Sink) Sink(ctx context.Context, batch []models.Record) (err error) {
+	return s.writer.Write(batch)
+}
+
+// Close finalizes the uploads of the objects, an object is only visible in the bucket once finalized
+func (s *Sink) Close() (err error) {
+	if s.writer == nil {
+		return nil
+	}
+	defer s.cancel()
+
+	_, err = s.writer.Close()
+	return err
+}
+
+// openObject starts the resumable upload of the object of the name
+func (s *Sink) openObject(name, assetType string) (io.WriteCloser, error) {
+	contentType, err := objectutil.Render(s.contentTmpl, s.run, assetType)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to build content type")
+	}
+
+	w := s.client.Bucket(s.config.Bucket).Object(name).NewWriter(s.ctx)
+	w.ChunkSize = s.config.ChunkSize
+	w.ContentType = contentType
+	w.ContentEncoding = s.config.ContentEncoding()
+	w.Metadata = s.metadata(assetType)
+
+	return w, nil
+}
+
+// metadata returns the configured metadata with the recipe and the run of the object
+func (s *Sink) metadata(assetType string) map[string]string {
+	metadata := make(map[string]string, len(s.config.Metadata)+3)
+	for key, value := range s.config.Metadata {
+		metadata[key] = value
+	}
+	metadata["recipe"] = s.run.RecipeName
+	metadata["run_started_at"] = s.run.StartedAt.UTC().Format(time.RFC3339)
+	metadata["asset_type"] = assetType
+
+	return metadata
+}
+
+func init() {
+	if err := registry.Sinks.Register("gcs", func() plugins.Syncer {
+		return New(plugins.GetLog())
+	}); err != nil {
+		panic(err)
+	}
+}
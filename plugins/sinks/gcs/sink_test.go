@@ -0,0 +1,279 @@
+package gcs_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/odpf/meteor/models"
+	commonv1beta1 "github.com/odpf/meteor/models/odpf/assets/common/v1beta1"
+	assetsv1beta1 "github.com/odpf/meteor/models/odpf/assets/v1beta1"
+	"github.com/odpf/meteor/plugins"
+	"github.com/odpf/meteor/plugins/sinks/gcs"
+	testUtils "github.com/odpf/meteor/test/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	table = &assetsv1beta1.Table{
+		Resource: &commonv1beta1.Resource{Urn: "postgres::db/shop/orders", Name: "orders", Service: "postgres"},
+	}
+	topic = &assetsv1beta1.Topic{
+		Resource: &commonv1beta1.Resource{Urn: "kafka::broker/orders", Name: "orders", Service: "kafka"},
+	}
+)
+
+func TestInit(t *testing.T) {
+	t.Run("should return InvalidConfigError without bucket", func(t *testing.T) {
+		err := gcs.New(testUtils.Logger).Init(context.TODO(), map[string]interface{}{})
+		assert.Equal(t, plugins.InvalidConfigError{Type: plugins.PluginTypeSink, PluginName: "gcs"}, err)
+	})
+	t.Run("should return error on compressed parquet", func(t *testing.T) {
+		err := gcs.New(testUtils.Logger).Init(context.TODO(), map[string]interface{}{
+			"bucket":      "meteor-archive",
+			"format":      "parquet",
+			"compression": "gzip",
+		})
+		assert.EqualError(t, err, "compression is not supported for parquet")
+	})
+}
+
+func TestSink(t *testing.T) {
+	t.Run("should write objects by type with run metadata", func(t *testing.T) {
+		server := newFakeGCS(t)
+		sinkAll(t, server, map[string]interface{}{
+			"compression": "gzip",
+			"metadata":    map[string]string{"team": "data"},
+		}, table, topic, table)
+
+		assert.Equal(t, []string{
+			"sample/2022-01-02/table.ndjson.gz",
+			"sample/2022-01-02/topic.ndjson.gz",
+		}, server.names())
+
+		o := server.objects["sample/2022-01-02/table.ndjson.gz"]
+		assert.Equal(t, "meteor-archive", o.Bucket)
+		assert.Equal(t, "application/x-ndjson", o.ContentType)
+		assert.Equal(t, "gzip", o.ContentEncoding)
+		assert.Equal(t, map[string]string{
+			"team":           "data",
+			"recipe":         "sample",
+			"run_started_at": "2022-01-02T15:04:05Z",
+			"asset_type":     "table",
+		}, o.Metadata)
+
+		lines := strings.Split(strings.TrimSpace(gunzip(t, o.data)), "\n")
+		require.Len(t, lines, 2)
+		assert.Contains(t, lines[0], `"urn":"postgres::db/shop/orders"`)
+	})
+	t.Run("should write parquet objects with templated names and content types", func(t *testing.T) {
+		server := newFakeGCS(t)
+		sinkAll(t, server, map[string]interface{}{
+			"format":       "parquet",
+			"name":         `{{ .Time.Format "2006/01" }}/{{ .Type }}s.parquet`,
+			"content_type": "application/vnd.meteor.{{ .Type }}+parquet",
+		}, table)
+
+		o := server.objects["2022/01/tables.parquet"]
+		require.NotNil(t, o)
+		assert.Equal(t, "application/vnd.meteor.table+parquet", o.ContentType)
+		assert.Equal(t, "PAR1", string(o.data[:4]))
+	})
+	t.Run("should upload large objects resumably in chunks", func(t *testing.T) {
+		large := &assetsv1beta1.Table{
+			Resource: &commonv1beta1.Resource{
+				Urn:         "postgres::db/shop/large",
+				Description: strings.Repeat("x", 600<<10),
+			},
+		}
+		server := newFakeGCS(t)
+		sinkAll(t, server, map[string]interface{}{"chunk_size": 256 << 10}, large)
+
+		o := server.objects["sample/2022-01-02/table.ndjson"]
+		require.NotNil(t, o)
+		assert.True(t, o.resumable)
+		assert.Greater(t, o.chunks, 2)
+		assert.Greater(t, len(o.data), 600<<10)
+		assert.Contains(t, string(o.data), `"urn":"postgres::db/shop/large"`)
+	})
+}
+
+func runContext() context.Context {
+	return plugins.ContextWithRunInfo(context.TODO(), plugins.RunInfo{
+		RecipeName: "sample",
+		StartedAt:  time.Date(2022, 1, 2, 15, 4, 5, 0, time.UTC),
+	})
+}
+
+// sinkAll sinks every asset in its own batch to the server and closes the sink
+func sinkAll(t *testing.T, server *fakeGCS, config map[string]interface{}, assets ...models.Metadata) {
+	config["bucket"] = "meteor-archive"
+	config["endpoint"] = server.URL + "/storage/v1/"
+
+	sink := gcs.New(testUtils.Logger)
+	require.NoError(t, sink.Init(runContext(), config))
+	for _, asset := range assets {
+		require.NoError(t, sink.Sink(context.TODO(), []models.Record{models.NewRecord(asset)}))
+	}
+	require.NoError(t, sink.Close())
+}
+
+func gunzip(t *testing.T, b []byte) string {
+	r, err := gzip.NewReader(bytes.NewReader(b))
+	require.NoError(t, err)
+	out, err := ioutil.ReadAll(r)
+	require.NoError(t, err)
+
+	return string(out)
+}
+
+type fakeObject struct {
+	Bucket          string            `json:"bucket"`
+	Name            string            `json:"name"`
+	ContentType     string            `json:"contentType"`
+	ContentEncoding string            `json:"contentEncoding"`
+	Metadata        map[string]string `json:"metadata"`
+	Size            string            `json:"size"`
+
+	data      []byte
+	resumable bool
+	chunks    int
+}
+
+// fakeGCS serves the multipart and resumable uploads of the json api of google cloud storage,
+// unexpected requests are answered with 500 and the errors are asserted once the test completes
+type fakeGCS struct {
+	*httptest.Server
+	mu      sync.Mutex
+	objects map[string]*fakeObject
+	uploads map[string]*fakeObject
+	errs    []error
+}
+
+func newFakeGCS(t *testing.T) *fakeGCS {
+	f := &fakeGCS{
+		objects: make(map[string]*fakeObject),
+		uploads: make(map[string]*fakeObject),
+	}
+	f.Server = httptest.NewServer(http.HandlerFunc(f.handle))
+	t.Cleanup(func() {
+		f.Server.Close()
+		assert.Empty(t, f.errs)
+	})
+
+	return f
+}
+
+func (f *fakeGCS) names() []string {
+	names := make([]string, 0, len(f.objects))
+	for name := range f.objects {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (f *fakeGCS) handle(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.serve(w, r); err != nil {
+		f.errs = append(f.errs, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (f *fakeGCS) serve(w http.ResponseWriter, r *http.Request) error {
+	query := r.URL.Query()
+	bucket := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/upload/storage/v1/b/"), "/o")
+	switch {
+	// chunks are sent to the session uri which also has the upload type, the upload id is matched first
+	case query.Get("upload_id") != "":
+		o, ok := f.uploads[query.Get("upload_id")]
+		if !ok {
+			return fmt.Errorf("unknown upload id %q", query.Get("upload_id"))
+		}
+		chunk, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			return err
+		}
+		o.data = append(o.data, chunk...)
+		o.chunks++
+
+		// the total size is only known with the last chunk, e.g. bytes 0-262143/* before it,
+		// the client asks for 200 with a status override instead of 308 for incomplete uploads
+		if strings.HasSuffix(r.Header.Get("Content-Range"), "/*") {
+			w.Header().Set("Range", fmt.Sprintf("bytes=0-%d", len(o.data)-1))
+			w.Header().Set("X-Http-Status-Code-Override", "308")
+			return nil
+		}
+		return f.finalize(w, o)
+	case r.Method == http.MethodPost && query.Get("uploadType") == "multipart":
+		o, err := f.readMultipart(r)
+		if err != nil {
+			return err
+		}
+		o.Bucket = bucket
+		return f.finalize(w, o)
+	case r.Method == http.MethodPost && query.Get("uploadType") == "resumable":
+		o := &fakeObject{Bucket: bucket, resumable: true}
+		if err := json.NewDecoder(r.Body).Decode(o); err != nil {
+			return err
+		}
+		id := strconv.Itoa(len(f.uploads) + 1)
+		f.uploads[id] = o
+		w.Header().Set("Location", fmt.Sprintf("%s%s?uploadType=resumable&upload_id=%s", f.URL, r.URL.Path, id))
+		return nil
+	}
+
+	return fmt.Errorf("unexpected request %s %s", r.Method, r.URL)
+}
+
+// readMultipart reads the metadata and the media of a multipart/related upload
+func (f *fakeGCS) readMultipart(r *http.Request) (*fakeObject, error) {
+	_, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		return nil, err
+	}
+	reader := multipart.NewReader(r.Body, params["boundary"])
+
+	part, err := reader.NextPart()
+	if err != nil {
+		return nil, err
+	}
+	o := &fakeObject{}
+	if err = json.NewDecoder(part).Decode(o); err != nil {
+		return nil, err
+	}
+
+	part, err = reader.NextPart()
+	if err != nil {
+		return nil, err
+	}
+	if o.data, err = ioutil.ReadAll(part); err != nil {
+		return nil, err
+	}
+	o.chunks = 1
+
+	return o, nil
+}
+
+func (f *fakeGCS) finalize(w http.ResponseWriter, o *fakeObject) error {
+	o.Size = strconv.Itoa(len(o.data))
+	f.objects[o.Name] = o
+	return json.NewEncoder(w).Encode(o)
+}
@@ -0,0 +1,102 @@
+package graph
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// statement is a cypher statement with its parameters, as sent to the transactional endpoint
+type statement struct {
+	Statement  string                 `json:"statement"`
+	Parameters map[string]interface{} `json:"parameters"`
+}
+
+// cypherStatements returns the statements merging the nodes before the edges between them
+func cypherStatements(g *graph) []statement {
+	statements := make([]statement, 0, len(g.nodes)+len(g.edges))
+	for _, n := range g.nodes {
+		statements = append(statements, nodeStatement(n))
+	}
+	for _, e := range g.edges {
+		statements = append(statements, edgeStatement(e))
+	}
+
+	return statements
+}
+
+// nodeStatement merges the node on its urn, a placeholder only sets its labels and properties
+// when it is created so that it does not overwrite an extracted asset
+func nodeStatement(n node) statement {
+	set := []string{}
+	for _, l := range n.Labels {
+		if l != assetLabel {
+			set = append(set, "n:"+quote(l))
+		}
+	}
+	set = append(set, "n += $props", fmt.Sprintf("n.placeholder = %t", n.Placeholder))
+
+	text := fmt.Sprintf("MERGE (n:%s {urn: $urn}) SET %s", assetLabel, strings.Join(set, ", "))
+	if n.Placeholder {
+		text = fmt.Sprintf("MERGE (n:%s {urn: $urn}) ON CREATE SET %s", assetLabel, strings.Join(set, ", "))
+	}
+
+	return statement{
+		Statement:  text,
+		Parameters: map[string]interface{}{"urn": n.URN, "props": n.Properties},
+	}
+}
+
+func edgeStatement(e edge) statement {
+	props := e.Properties
+	if props == nil {
+		props = map[string]interface{}{}
+	}
+
+	return statement{
+		Statement: fmt.Sprintf("MATCH (a:%s {urn: $from}), (b:%s {urn: $to}) MERGE (a)-[r:%s]->(b) SET r += $props",
+			assetLabel, assetLabel, quote(e.Type)),
+		Parameters: map[string]interface{}{"from": e.From, "to": e.To, "props": props},
+	}
+}
+
+var parameterPattern = regexp.MustCompile(`\$(\w+)`)
+
+// inline returns the statement with its parameters replaced by literals, to be run by cypher-shell
+func (s statement) inline() string {
+	return parameterPattern.ReplaceAllStringFunc(s.Statement, func(p string) string {
+		return literal(s.Parameters[p[1:]])
+	}) + ";"
+}
+
+// literal returns the cypher literal of a string, bool, number or map of them
+func literal(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return `"` + stringEscaper.Replace(v) + `"`
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		entries := make([]string, len(keys))
+		for i, key := range keys {
+			entries[i] = quote(key) + ": " + literal(v[key])
+		}
+		return "{" + strings.Join(entries, ", ") + "}"
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+var stringEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "\t", `\t`)
+
+// quote returns the name quoted with backticks
+func quote(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
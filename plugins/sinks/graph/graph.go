@@ -0,0 +1,200 @@
+package graph
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/odpf/meteor/models"
+	commonv1beta1 "github.com/odpf/meteor/models/odpf/assets/common/v1beta1"
+	assetsv1beta1 "github.com/odpf/meteor/models/odpf/assets/v1beta1"
+	"github.com/odpf/meteor/utils"
+)
+
+// assetLabel is the label of every node, nodes are merged on their urn with it
+const assetLabel = "Asset"
+
+// Types of the edges
+const (
+	edgeUpstream   = "UPSTREAM"
+	edgeDownstream = "DOWNSTREAM"
+	edgeOwns       = "OWNS"
+	edgeMemberOf   = "MEMBER_OF"
+)
+
+// node is an asset, a placeholder node is an asset which is referred to but has not been extracted
+type node struct {
+	URN         string
+	Labels      []string
+	Properties  map[string]interface{}
+	Placeholder bool
+}
+
+// edge is a typed relation from an asset to another
+type edge struct {
+	From       string
+	To         string
+	Type       string
+	Properties map[string]interface{}
+}
+
+// buildGraph returns the node of the asset, placeholder nodes of the assets it refers to,
+// and the edges of its lineage, ownership and memberships
+func buildGraph(metadata models.Metadata) (nodes []node, edges []edge) {
+	resource := metadata.GetResource()
+	urn := resource.GetUrn()
+	assetType := utils.GetAssetType(metadata)
+	props := properties(resource)
+	if assetType != "" {
+		props["type"] = assetType
+	}
+	nodes = append(nodes, node{URN: urn, Labels: labels(assetType), Properties: props})
+
+	lineage := utils.GetLineage(metadata)
+	for _, upstream := range lineage.GetUpstreams() {
+		nodes = append(nodes, placeholder(upstream.GetUrn(), upstream))
+		edges = append(edges, edge{From: urn, To: upstream.GetUrn(), Type: edgeUpstream})
+	}
+	for _, downstream := range lineage.GetDownstreams() {
+		nodes = append(nodes, placeholder(downstream.GetUrn(), downstream))
+		edges = append(edges, edge{From: urn, To: downstream.GetUrn(), Type: edgeDownstream})
+	}
+
+	for _, owner := range utils.GetOwnership(metadata).GetOwners() {
+		ownerURN := owner.GetUrn()
+		if ownerURN == "" {
+			ownerURN = owner.GetEmail()
+		}
+		if ownerURN == "" {
+			continue
+		}
+		nodes = append(nodes, placeholder(ownerURN, &commonv1beta1.Resource{
+			Urn:  ownerURN,
+			Name: owner.GetName(),
+			Type: utils.AssetTypeUser,
+		}))
+		edges = append(edges, edge{From: ownerURN, To: urn, Type: edgeOwns, Properties: role(owner.GetRole())})
+	}
+
+	switch asset := metadata.(type) {
+	case *assetsv1beta1.User:
+		for _, membership := range asset.GetMemberships() {
+			groupURN := membership.GetGroupUrn()
+			nodes = append(nodes, placeholder(groupURN, &commonv1beta1.Resource{Urn: groupURN, Type: utils.AssetTypeGroup}))
+			edges = append(edges, edge{From: urn, To: groupURN, Type: edgeMemberOf, Properties: role(strings.Join(membership.GetRole(), ","))})
+		}
+	case *assetsv1beta1.Group:
+		for _, member := range asset.GetMembers() {
+			memberURN := member.GetUrn()
+			nodes = append(nodes, placeholder(memberURN, &commonv1beta1.Resource{Urn: memberURN, Type: utils.AssetTypeUser}))
+			edges = append(edges, edge{From: memberURN, To: urn, Type: edgeMemberOf, Properties: role(member.GetRole())})
+		}
+	}
+
+	return nodes, edges
+}
+
+// placeholder returns the node of an asset which is referred to, it does not replace the asset once extracted
+func placeholder(urn string, resource *commonv1beta1.Resource) node {
+	return node{
+		URN:         urn,
+		Labels:      labels(resource.GetType()),
+		Properties:  properties(resource),
+		Placeholder: true,
+	}
+}
+
+// properties returns the non empty fields of the resource
+func properties(resource *commonv1beta1.Resource) map[string]interface{} {
+	props := map[string]interface{}{"urn": resource.GetUrn()}
+	for key, value := range map[string]string{
+		"name":        resource.GetName(),
+		"service":     resource.GetService(),
+		"type":        resource.GetType(),
+		"description": resource.GetDescription(),
+		"url":         resource.GetUrl(),
+	} {
+		if value != "" {
+			props[key] = value
+		}
+	}
+
+	return props
+}
+
+func role(r string) map[string]interface{} {
+	if r == "" {
+		return nil
+	}
+	return map[string]interface{}{"role": r}
+}
+
+// labels returns the asset label and the label of the type, e.g. Asset and Table for table
+func labels(assetType string) []string {
+	if label := label(assetType); label != "" {
+		return []string{assetLabel, label}
+	}
+	return []string{assetLabel}
+}
+
+// label returns the type in camel case without the characters which are not letters or digits,
+// e.g. FeatureTable for feature_table
+func label(assetType string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range assetType {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if b.Len() == 0 && unicode.IsDigit(r) {
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
+
+// graph is a set of nodes and edges, the nodes of the extracted assets replace their placeholders
+type graph struct {
+	nodes     []node
+	nodeIndex map[string]int
+	edges     []edge
+	edgeIndex map[string]bool
+}
+
+func newGraph() *graph {
+	return &graph{nodeIndex: make(map[string]int), edgeIndex: make(map[string]bool)}
+}
+
+func (g *graph) add(nodes []node, edges []edge) {
+	for _, n := range nodes {
+		if n.URN == "" {
+			continue
+		}
+		i, ok := g.nodeIndex[n.URN]
+		if !ok {
+			g.nodeIndex[n.URN] = len(g.nodes)
+			g.nodes = append(g.nodes, n)
+			continue
+		}
+		if g.nodes[i].Placeholder && !n.Placeholder {
+			g.nodes[i] = n
+		}
+	}
+	for _, e := range edges {
+		if e.From == "" || e.To == "" {
+			continue
+		}
+		key := e.From + "|" + e.Type + "|" + e.To
+		if g.edgeIndex[key] {
+			continue
+		}
+		g.edgeIndex[key] = true
+		g.edges = append(g.edges, e)
+	}
+}
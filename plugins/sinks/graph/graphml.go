@@ -0,0 +1,134 @@
+package graph
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+type graphML struct {
+	XMLName xml.Name     `xml:"graphml"`
+	XMLNS   string       `xml:"xmlns,attr"`
+	Keys    []graphMLKey `xml:"key"`
+	Graph   graphMLGraph `xml:"graph"`
+}
+
+type graphMLKey struct {
+	ID       string `xml:"id,attr"`
+	For      string `xml:"for,attr"`
+	AttrName string `xml:"attr.name,attr"`
+	AttrType string `xml:"attr.type,attr"`
+}
+
+type graphMLGraph struct {
+	ID          string        `xml:"id,attr"`
+	EdgeDefault string        `xml:"edgedefault,attr"`
+	Nodes       []graphMLNode `xml:"node"`
+	Edges       []graphMLEdge `xml:"edge"`
+}
+
+type graphMLNode struct {
+	ID     string        `xml:"id,attr"`
+	Labels string        `xml:"labels,attr"`
+	Data   []graphMLData `xml:"data"`
+}
+
+type graphMLEdge struct {
+	ID     string        `xml:"id,attr"`
+	Source string        `xml:"source,attr"`
+	Target string        `xml:"target,attr"`
+	Label  string        `xml:"label,attr"`
+	Data   []graphMLData `xml:"data"`
+}
+
+type graphMLData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+// writeGraphML writes the graph as GraphML, with the labels of the nodes and the types of the edges
+// as their labels attributes the way Neo4j exports them
+func writeGraphML(w io.Writer, g *graph) error {
+	doc := graphML{
+		XMLNS: "http://graphml.graphdrawing.org/xmlns",
+		Graph: graphMLGraph{ID: "meteor", EdgeDefault: "directed"},
+	}
+
+	nodeKeys := map[string]bool{"labels": true, "placeholder": true}
+	for _, n := range g.nodes {
+		data := []graphMLData{
+			{Key: "node_labels", Value: ":" + strings.Join(n.Labels, ":")},
+			{Key: "node_placeholder", Value: fmt.Sprint(n.Placeholder)},
+		}
+		data = append(data, propertyData(n.Properties, "node", nodeKeys)...)
+		doc.Graph.Nodes = append(doc.Graph.Nodes, graphMLNode{
+			ID:     n.URN,
+			Labels: ":" + strings.Join(n.Labels, ":"),
+			Data:   data,
+		})
+	}
+
+	edgeKeys := map[string]bool{"label": true}
+	for i, e := range g.edges {
+		data := append([]graphMLData{{Key: "edge_label", Value: e.Type}}, propertyData(e.Properties, "edge", edgeKeys)...)
+		doc.Graph.Edges = append(doc.Graph.Edges, graphMLEdge{
+			ID:     fmt.Sprintf("e%d", i),
+			Source: e.From,
+			Target: e.To,
+			Label:  e.Type,
+			Data:   data,
+		})
+	}
+
+	doc.Keys = append(doc.Keys, keys(nodeKeys, "node")...)
+	doc.Keys = append(doc.Keys, keys(edgeKeys, "edge")...)
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+
+	return err
+}
+
+// propertyData returns the properties sorted by name and adds their names to used,
+// the keys of the data are prefixed with node or edge
+func propertyData(props map[string]interface{}, of string, used map[string]bool) (data []graphMLData) {
+	names := make([]string, 0, len(props))
+	for name := range props {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		used[name] = true
+		data = append(data, graphMLData{Key: of + "_" + name, Value: fmt.Sprint(props[name])})
+	}
+
+	return data
+}
+
+func keys(used map[string]bool, of string) (keys []graphMLKey) {
+	names := make([]string, 0, len(used))
+	for name := range used {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		attrType := "string"
+		if name == "placeholder" {
+			attrType = "boolean"
+		}
+		keys = append(keys, graphMLKey{ID: of + "_" + name, For: of, AttrName: name, AttrType: attrType})
+	}
+
+	return keys
+}
@@ -0,0 +1,215 @@
+package graph
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/odpf/meteor/models"
+	"github.com/odpf/meteor/plugins"
+	"github.com/odpf/meteor/registry"
+	"github.com/odpf/meteor/utils"
+	"github.com/odpf/salt/log"
+	"github.com/pkg/errors"
+)
+
+//go:embed README.md
+var summary string
+
+// Formats of the files written in offline mode
+const (
+	formatCypher  = "cypher"
+	formatGraphML = "graphml"
+)
+
+type Config struct {
+	// URL is the http url of the Neo4j server, e.g. http://localhost:7474
+	URL      string `mapstructure:"url" validate:"required_without=Path"`
+	Database string `mapstructure:"database" default:"neo4j"`
+	Username string `mapstructure:"username"`
+	Password string `mapstructure:"password"`
+	// Path is the file the graph is written to instead of being sent
+	Path   string `mapstructure:"path" validate:"required_without=URL"`
+	Format string `mapstructure:"format" validate:"oneof=cypher graphml" default:"cypher"`
+}
+
+var sampleConfig = `
+# HTTP url of the Neo4j server
+url: http://localhost:7474
+database: neo4j
+username: neo4j
+password: secret
+# File the graph is written to instead of url, as cypher statements or GraphML
+# path: ./lineage.cypher
+# format: cypher`
+
+type httpClient interface {
+	Do(*http.Request) (*http.Response, error)
+}
+
+type Sink struct {
+	client httpClient
+	config Config
+	logger log.Logger
+	file   *os.File
+	graph  *graph
+}
+
+func New(c httpClient, logger log.Logger) plugins.Syncer {
+	return &Sink{client: c, logger: logger}
+}
+
+func (s *Sink) Info() plugins.Info {
+	return plugins.Info{
+		Description:  "Upsert assets and their lineage to a graph database",
+		SampleConfig: sampleConfig,
+		Summary:      summary,
+		Tags:         []string{"http", "lineage", "sink"},
+	}
+}
+
+func (s *Sink) Validate(configMap map[string]interface{}) (err error) {
+	return utils.BuildConfig(configMap, &Config{})
+}
+
+func (s *Sink) Init(ctx context.Context, configMap map[string]interface{}) (err error) {
+	if err = utils.BuildConfig(configMap, &s.config); err != nil {
+		return plugins.InvalidConfigError{Type: plugins.PluginTypeSink, PluginName: "graph"}
+	}
+	if s.config.URL != "" && s.config.Path != "" {
+		return errors.New("only one of url and path can be set")
+	}
+	s.config.URL = strings.TrimSuffix(s.config.URL, "/")
+	s.graph = newGraph()
+
+	if s.config.Path != "" && s.config.Format == formatCypher {
+		if s.file, err = os.Create(s.config.Path); err != nil {
+			return errors.Wrap(err, "failed to create cypher file")
+		}
+	}
+
+	return
+}
+
+// Sink merges the nodes and edges of a batch in a transaction.
+// Files are written once all batches are received, so that every node is written before the edges.
+func (s *Sink) Sink(ctx context.Context, batch []models.Record) (err error) {
+	g := s.graph
+	if s.config.Path == "" {
+		g = newGraph()
+	}
+	for _, record := range batch {
+		g.add(buildGraph(record.Data()))
+	}
+
+	if s.config.Path == "" {
+		return s.commit(ctx, cypherStatements(g))
+	}
+
+	return nil
+}
+
+func (s *Sink) Close() (err error) {
+	if s.config.Path == "" {
+		return
+	}
+	if s.file != nil {
+		if err = s.write(cypherStatements(s.graph)); err != nil {
+			s.file.Close()
+			return errors.Wrap(err, "failed to write cypher file")
+		}
+		return s.file.Close()
+	}
+
+	var buf bytes.Buffer
+	if err = writeGraphML(&buf, s.graph); err != nil {
+		return errors.Wrap(err, "failed to build graphml")
+	}
+
+	return ioutil.WriteFile(s.config.Path, buf.Bytes(), 0644)
+}
+
+func (s *Sink) write(statements []statement) error {
+	var buf bytes.Buffer
+	for _, st := range statements {
+		buf.WriteString(st.inline())
+		buf.WriteByte('\n')
+	}
+	_, err := s.file.Write(buf.Bytes())
+
+	return err
+}
+
+type commitResponse struct {
+	Errors []struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+// commit runs the statements in a transaction with the transactional http endpoint,
+// transient errors of neo4j are retried
+func (s *Sink) commit(ctx context.Context, statements []statement) error {
+	if len(statements) == 0 {
+		return nil
+	}
+	body, err := json.Marshal(map[string]interface{}{"statements": statements})
+	if err != nil {
+		return err
+	}
+
+	url := fmt.Sprintf("%s/db/%s/tx/commit", s.config.URL, s.config.Database)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	if s.config.Username != "" {
+		req.SetBasicAuth(s.config.Username, s.config.Password)
+	}
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return plugins.NewRetryError(err)
+	}
+	defer res.Body.Close()
+
+	bodyBytes, _ := ioutil.ReadAll(res.Body)
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		err = fmt.Errorf("neo4j returns %d: %s", res.StatusCode, string(bodyBytes))
+		if plugins.IsRetryableStatus(res.StatusCode) {
+			return plugins.NewRetryError(err)
+		}
+		return err
+	}
+
+	var response commitResponse
+	if err = json.Unmarshal(bodyBytes, &response); err != nil {
+		return errors.Wrap(err, "failed to parse neo4j response")
+	}
+	if len(response.Errors) == 0 {
+		return nil
+	}
+	e := response.Errors[0]
+	err = fmt.Errorf("neo4j returns %s: %s", e.Code, e.Message)
+	if strings.HasPrefix(e.Code, "Neo.TransientError.") {
+		return plugins.NewRetryError(err)
+	}
+
+	return err
+}
+
+func init() {
+	if err := registry.Sinks.Register("graph", func() plugins.Syncer {
+		return New(&http.Client{}, plugins.GetLog())
+	}); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,186 @@
+package graph_test
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/odpf/meteor/models"
+	commonv1beta1 "github.com/odpf/meteor/models/odpf/assets/common/v1beta1"
+	facetsv1beta1 "github.com/odpf/meteor/models/odpf/assets/facets/v1beta1"
+	assetsv1beta1 "github.com/odpf/meteor/models/odpf/assets/v1beta1"
+	"github.com/odpf/meteor/plugins"
+	"github.com/odpf/meteor/plugins/sinks/graph"
+	testUtils "github.com/odpf/meteor/test/utils"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	table = &assetsv1beta1.Table{
+		Resource: &commonv1beta1.Resource{Urn: "postgres::db/shop/orders", Name: "orders", Service: "postgres", Type: "table"},
+		Ownership: &facetsv1beta1.Ownership{Owners: []*facetsv1beta1.Owner{
+			{Urn: "user::jane", Email: "jane@example.com", Role: "owner"},
+		}},
+		Lineage: &facetsv1beta1.Lineage{
+			Upstreams: []*commonv1beta1.Resource{{Urn: "kafka::broker/orders", Type: "topic"}},
+		},
+	}
+	dashboard = &assetsv1beta1.Dashboard{
+		Resource: &commonv1beta1.Resource{Urn: "metabase::host/42", Name: "sales \"daily\"", Service: "metabase"},
+		Lineage: &facetsv1beta1.Lineage{
+			Upstreams: []*commonv1beta1.Resource{{Urn: "postgres::db/shop/orders", Type: "table"}},
+		},
+	}
+	user = &assetsv1beta1.User{
+		Resource:    &commonv1beta1.Resource{Urn: "user::jane", Name: "jane"},
+		Memberships: []*assetsv1beta1.Membership{{GroupUrn: "group::data", Role: []string{"maintainer"}}},
+	}
+)
+
+func TestInit(t *testing.T) {
+	t.Run("should return InvalidConfigError without url and path", func(t *testing.T) {
+		err := graph.New(http.DefaultClient, testUtils.Logger).Init(context.TODO(), map[string]interface{}{})
+		assert.Equal(t, plugins.InvalidConfigError{Type: plugins.PluginTypeSink, PluginName: "graph"}, err)
+	})
+	t.Run("should return InvalidConfigError on unknown format", func(t *testing.T) {
+		err := graph.New(http.DefaultClient, testUtils.Logger).Init(context.TODO(), map[string]interface{}{
+			"path":   filepath.Join(t.TempDir(), "graph.dot"),
+			"format": "dot",
+		})
+		assert.Equal(t, plugins.InvalidConfigError{Type: plugins.PluginTypeSink, PluginName: "graph"}, err)
+	})
+	t.Run("should return error if both url and path are set", func(t *testing.T) {
+		err := graph.New(http.DefaultClient, testUtils.Logger).Init(context.TODO(), map[string]interface{}{
+			"url":  "http://localhost:7474",
+			"path": filepath.Join(t.TempDir(), "graph.cypher"),
+		})
+		assert.EqualError(t, err, "only one of url and path can be set")
+	})
+}
+
+func TestSink(t *testing.T) {
+	t.Run("should write cypher statements of every batch with nodes before edges", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "graph.cypher")
+		sinkAll(t, map[string]interface{}{"path": path}, table, dashboard)
+
+		b, err := ioutil.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, []string{
+			"MERGE (n:Asset {urn: \"postgres::db/shop/orders\"}) SET n:`Table`, n += {`name`: \"orders\", `service`: \"postgres\", `type`: \"table\", `urn`: \"postgres::db/shop/orders\"}, n.placeholder = false;",
+			"MERGE (n:Asset {urn: \"kafka::broker/orders\"}) ON CREATE SET n:`Topic`, n += {`type`: \"topic\", `urn`: \"kafka::broker/orders\"}, n.placeholder = true;",
+			"MERGE (n:Asset {urn: \"user::jane\"}) ON CREATE SET n:`User`, n += {`type`: \"user\", `urn`: \"user::jane\"}, n.placeholder = true;",
+			"MERGE (n:Asset {urn: \"metabase::host/42\"}) SET n:`Dashboard`, n += {`name`: \"sales \\\"daily\\\"\", `service`: \"metabase\", `type`: \"dashboard\", `urn`: \"metabase::host/42\"}, n.placeholder = false;",
+			"MATCH (a:Asset {urn: \"postgres::db/shop/orders\"}), (b:Asset {urn: \"kafka::broker/orders\"}) MERGE (a)-[r:`UPSTREAM`]->(b) SET r += {};",
+			"MATCH (a:Asset {urn: \"user::jane\"}), (b:Asset {urn: \"postgres::db/shop/orders\"}) MERGE (a)-[r:`OWNS`]->(b) SET r += {`role`: \"owner\"};",
+			"MATCH (a:Asset {urn: \"metabase::host/42\"}), (b:Asset {urn: \"postgres::db/shop/orders\"}) MERGE (a)-[r:`UPSTREAM`]->(b) SET r += {};",
+		}, strings.Split(strings.TrimSpace(string(b)), "\n"))
+	})
+	t.Run("should write graphml with extracted assets replacing placeholders", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "graph.graphml")
+		sinkAll(t, map[string]interface{}{"path": path, "format": "graphml"}, dashboard, table, user)
+
+		b, err := ioutil.ReadFile(path)
+		require.NoError(t, err)
+		var doc struct {
+			Nodes []struct {
+				ID     string `xml:"id,attr"`
+				Labels string `xml:"labels,attr"`
+			} `xml:"graph>node"`
+			Edges []struct {
+				Source string `xml:"source,attr"`
+				Target string `xml:"target,attr"`
+				Label  string `xml:"label,attr"`
+			} `xml:"graph>edge"`
+		}
+		require.NoError(t, xml.Unmarshal(b, &doc))
+
+		labels := make(map[string]string)
+		for _, n := range doc.Nodes {
+			labels[n.ID] = n.Labels
+		}
+		assert.Equal(t, map[string]string{
+			"metabase::host/42":        ":Asset:Dashboard",
+			"postgres::db/shop/orders": ":Asset:Table",
+			"kafka::broker/orders":     ":Asset:Topic",
+			"user::jane":               ":Asset:User",
+			"group::data":              ":Asset:Group",
+		}, labels)
+		assert.Len(t, doc.Edges, 4)
+		assert.Contains(t, string(b), `<data key="node_placeholder">false</data>`)
+		assert.Equal(t, "MEMBER_OF", doc.Edges[3].Label)
+		assert.Equal(t, "group::data", doc.Edges[3].Target)
+	})
+	t.Run("should commit statements of a batch in a transaction", func(t *testing.T) {
+		var statements []map[string]interface{}
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/db/lineage/tx/commit", r.URL.Path)
+			username, password, _ := r.BasicAuth()
+			assert.Equal(t, "neo4j", username)
+			assert.Equal(t, "secret", password)
+
+			var body struct {
+				Statements []map[string]interface{} `json:"statements"`
+			}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			statements = body.Statements
+			w.Write([]byte(`{"results":[],"errors":[]}`))
+		}))
+		defer server.Close()
+
+		sinkAll(t, map[string]interface{}{
+			"url":      server.URL,
+			"database": "lineage",
+			"username": "neo4j",
+			"password": "secret",
+		}, user)
+
+		require.Len(t, statements, 3)
+		assert.Equal(t, map[string]interface{}{
+			"from":  "user::jane",
+			"to":    "group::data",
+			"props": map[string]interface{}{"role": "maintainer"},
+		}, statements[2]["parameters"])
+	})
+	t.Run("should return RetryError on transient errors", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"results":[],"errors":[{"code":"Neo.TransientError.Transaction.DeadlockDetected","message":"deadlock"}]}`))
+		}))
+		defer server.Close()
+
+		sink := graph.New(http.DefaultClient, testUtils.Logger)
+		require.NoError(t, sink.Init(context.TODO(), map[string]interface{}{"url": server.URL}))
+
+		err := sink.Sink(context.TODO(), []models.Record{models.NewRecord(table)})
+		assert.True(t, errors.Is(err, plugins.RetryError{}))
+	})
+	t.Run("should not retry client errors of statements", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"results":[],"errors":[{"code":"Neo.ClientError.Statement.SyntaxError","message":"invalid"}]}`))
+		}))
+		defer server.Close()
+
+		sink := graph.New(http.DefaultClient, testUtils.Logger)
+		require.NoError(t, sink.Init(context.TODO(), map[string]interface{}{"url": server.URL}))
+
+		err := sink.Sink(context.TODO(), []models.Record{models.NewRecord(table)})
+		assert.EqualError(t, err, "neo4j returns Neo.ClientError.Statement.SyntaxError: invalid")
+	})
+}
+
+// sinkAll sinks every asset in its own batch and closes the sink
+func sinkAll(t *testing.T, config map[string]interface{}, assets ...models.Metadata) {
+	sink := graph.New(http.DefaultClient, testUtils.Logger)
+	require.NoError(t, sink.Init(context.TODO(), config))
+	for _, asset := range assets {
+		require.NoError(t, sink.Sink(context.TODO(), []models.Record{models.NewRecord(asset)}))
+	}
+	require.NoError(t, sink.Close())
+}
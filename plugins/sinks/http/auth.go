@@ -0,0 +1,51 @@
+package http
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+)
+
+// Authentication types of the requests
+const (
+	authBearer = "bearer"
+	authBasic  = "basic"
+	authHMAC   = "hmac"
+)
+
+const defaultSignatureHeader = "X-Signature-256"
+
+// AuthConfig holds the authentication of the requests
+type AuthConfig struct {
+	Type     string `mapstructure:"type" validate:"omitempty,oneof=bearer basic hmac"`
+	Token    string `mapstructure:"token" validate:"required_if=Type bearer"`
+	Username string `mapstructure:"username" validate:"required_if=Type basic"`
+	Password string `mapstructure:"password"`
+	Secret   string `mapstructure:"secret" validate:"required_if=Type hmac"`
+	// Header is the header of the HMAC signature
+	Header string `mapstructure:"header"`
+}
+
+// apply authenticates the request, body is the payload of the request
+func (c AuthConfig) apply(req *http.Request, body []byte) {
+	switch c.Type {
+	case authBearer:
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	case authBasic:
+		req.SetBasicAuth(c.Username, c.Password)
+	case authHMAC:
+		header := c.Header
+		if header == "" {
+			header = defaultSignatureHeader
+		}
+		req.Header.Set(header, "sha256="+sign(c.Secret, body))
+	}
+}
+
+// sign returns the hex encoded HMAC-SHA256 of body
+func sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,305 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/odpf/meteor/models"
+	"github.com/odpf/meteor/plugins"
+	"github.com/odpf/meteor/registry"
+	"github.com/odpf/meteor/utils"
+	"github.com/odpf/salt/log"
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+//go:embed README.md
+var summary string
+
+type Config struct {
+	URL              string            `mapstructure:"url" validate:"required"`
+	Method           string            `mapstructure:"method" validate:"oneof=POST PUT PATCH" default:"POST"`
+	Headers          map[string]string `mapstructure:"headers"`
+	Payload          string            `mapstructure:"payload"`
+	PayloadPath      string            `mapstructure:"payload_path"`
+	Batch            bool              `mapstructure:"batch" default:"false"`
+	Auth             AuthConfig        `mapstructure:"auth"`
+	RetryStatusCodes []int             `mapstructure:"retry_status_codes"`
+	TimeoutSeconds   int               `mapstructure:"timeout_seconds" validate:"min=1" default:"10"`
+}
+
+var sampleConfig = `
+# URL of the requests, templated from the record fields
+url: "https://catalog.com/api/{{ assetType . }}s/{{ .Resource.Urn | urlquery }}"
+# HTTP method, POST, PUT or PATCH
+method: PUT
+# Additional HTTP headers, multiple headers value are separated by a comma
+headers:
+  X-Source: meteor
+# Payload of a record templated from the record fields, the asset in JSON is sent if both payload and payload_path are empty
+payload: '{"urn": {{ json .Resource.Urn }}, "name": {{ json .Resource.Name }}}'
+# JSON path of the asset sent as payload instead of a template, e.g. $.resource
+payload_path: ""
+# Send the payloads of a batch with the same URL as a JSON array in a single request
+batch: false
+# Authentication, type is one of bearer, basic or hmac
+auth:
+  type: hmac
+  secret: my-secret
+  header: X-Signature-256
+# Statuses worth retrying, defaults to 429 and 5xx
+retry_status_codes: [429, 502, 503]
+timeout_seconds: 10`
+
+type httpClient interface {
+	Do(*http.Request) (*http.Response, error)
+}
+
+// request is a payload to send to a URL, with the urns of the records it carries
+type request struct {
+	url      string
+	payloads []json.RawMessage
+	urns     []string
+}
+
+type Sink struct {
+	client      httpClient
+	config      Config
+	logger      log.Logger
+	run         plugins.RunInfo
+	urlTmpl     *template.Template
+	payloadTmpl *template.Template
+	retryCodes  map[int]bool
+}
+
+func New(c httpClient, logger log.Logger) plugins.Syncer {
+	return &Sink{client: c, logger: logger}
+}
+
+func (s *Sink) Info() plugins.Info {
+	return plugins.Info{
+		Description:  "Send metadata to any http service",
+		SampleConfig: sampleConfig,
+		Summary:      summary,
+		Tags:         []string{"http", "webhook", "sink"},
+	}
+}
+
+func (s *Sink) Validate(configMap map[string]interface{}) (err error) {
+	return utils.BuildConfig(configMap, &Config{})
+}
+
+func (s *Sink) Init(ctx context.Context, configMap map[string]interface{}) (err error) {
+	if err = utils.BuildConfig(configMap, &s.config); err != nil {
+		return plugins.InvalidConfigError{Type: plugins.PluginTypeSink, PluginName: "http"}
+	}
+	if s.config.Payload != "" && s.config.PayloadPath != "" {
+		return errors.New("only one of payload and payload_path can be set")
+	}
+	s.run = plugins.RunInfoFromContext(ctx)
+
+	funcs := template.FuncMap{
+		"json":      toJSON,
+		"assetType": utils.GetAssetType,
+		"recipe":    func() string { return s.run.RecipeName },
+	}
+	if s.urlTmpl, err = template.New("url").Funcs(funcs).Option("missingkey=error").Parse(s.config.URL); err != nil {
+		return errors.Wrap(err, "invalid url template")
+	}
+	if s.config.Payload != "" {
+		if s.payloadTmpl, err = template.New("payload").Funcs(funcs).Option("missingkey=error").Parse(s.config.Payload); err != nil {
+			return errors.Wrap(err, "invalid payload template")
+		}
+	}
+
+	s.retryCodes = make(map[int]bool)
+	for _, code := range s.config.RetryStatusCodes {
+		s.retryCodes[code] = true
+	}
+
+	return
+}
+
+// Sink sends a request per record, or a request per URL with the payloads of the records as a JSON array if batch is set.
+// Requests are sent one at a time and sending stops at the first failure, the records of the failed and unsent requests
+// are returned in a PartialSinkError so the requests already delivered are not sent again on retry.
+func (s *Sink) Sink(ctx context.Context, batch []models.Record) (err error) {
+	var requests []*request
+	byURL := make(map[string]*request)
+	for _, record := range batch {
+		data := record.Data()
+		url, err := s.render(s.urlTmpl, data)
+		if err != nil {
+			return errors.Wrapf(err, "failed to build url of \"%s\"", data.GetResource().GetUrn())
+		}
+		payload, err := s.buildPayload(data)
+		if err != nil {
+			return errors.Wrapf(err, "failed to build payload of \"%s\"", data.GetResource().GetUrn())
+		}
+
+		urn := data.GetResource().GetUrn()
+		if !s.config.Batch {
+			requests = append(requests, &request{url: url, payloads: []json.RawMessage{payload}, urns: []string{urn}})
+			continue
+		}
+		req, ok := byURL[url]
+		if !ok {
+			req = &request{url: url}
+			byURL[url] = req
+			requests = append(requests, req)
+		}
+		req.payloads = append(req.payloads, payload)
+		req.urns = append(req.urns, urn)
+	}
+
+	for i, req := range requests {
+		if err = s.sendRequest(ctx, req); err == nil {
+			continue
+		}
+
+		var failedURNs []string
+		for _, r := range requests[i:] {
+			failedURNs = append(failedURNs, r.urns...)
+		}
+		partialErr := plugins.PartialSinkError{
+			FailedURNs: failedURNs,
+			Err:        errors.Wrapf(err, "failed to send %d records to \"%s\"", len(req.payloads), req.url),
+		}
+		if errors.Is(err, plugins.RetryError{}) {
+			return plugins.NewRetryError(partialErr)
+		}
+		return partialErr
+	}
+
+	return nil
+}
+
+func (s *Sink) sendRequest(ctx context.Context, req *request) (err error) {
+	body := []byte(req.payloads[0])
+	if s.config.Batch {
+		if body, err = json.Marshal(req.payloads); err != nil {
+			return errors.Wrap(err, "failed to build batch payload")
+		}
+	}
+
+	return s.send(ctx, req.url, body)
+}
+
+func (s *Sink) Close() (err error) { return }
+
+// buildPayload returns the JSON payload of the asset
+func (s *Sink) buildPayload(data models.Metadata) (json.RawMessage, error) {
+	if s.payloadTmpl != nil {
+		payload, err := s.render(s.payloadTmpl, data)
+		if err != nil {
+			return nil, err
+		}
+		if !json.Valid([]byte(payload)) {
+			return nil, fmt.Errorf("payload is not valid JSON: %s", payload)
+		}
+		return json.RawMessage(payload), nil
+	}
+
+	msg, ok := data.(proto.Message)
+	if !ok {
+		return nil, errors.New("asset is not a proto message")
+	}
+	b, err := protojson.MarshalOptions{UseProtoNames: true}.Marshal(msg)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal asset")
+	}
+	if s.config.PayloadPath == "" {
+		return b, nil
+	}
+
+	var asset interface{}
+	if err = json.Unmarshal(b, &asset); err != nil {
+		return nil, err
+	}
+	value, ok := utils.GetJSONPath(asset, s.config.PayloadPath)
+	if !ok {
+		return nil, fmt.Errorf("could not find \"%s\"", s.config.PayloadPath)
+	}
+
+	return json.Marshal(value)
+}
+
+func (s *Sink) send(ctx context.Context, url string, body []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(s.config.TimeoutSeconds)*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, s.config.Method, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for hdrKey, hdrVal := range s.config.Headers {
+		req.Header.Del(hdrKey)
+		for _, val := range strings.Split(hdrVal, ",") {
+			req.Header.Add(hdrKey, strings.TrimSpace(val))
+		}
+	}
+	s.config.Auth.apply(req, body)
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return plugins.NewRetryError(err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= 200 && res.StatusCode < 300 {
+		return nil
+	}
+
+	bodyBytes, _ := ioutil.ReadAll(res.Body)
+	err = fmt.Errorf("%s %s returns %d: %s", s.config.Method, url, res.StatusCode, string(bodyBytes))
+	if s.isRetryable(res.StatusCode) {
+		return plugins.NewRetryError(err)
+	}
+
+	return err
+}
+
+// isRetryable returns true for the retry_status_codes, or for too many requests and server errors if none is configured
+func (s *Sink) isRetryable(code int) bool {
+	if len(s.retryCodes) > 0 {
+		return s.retryCodes[code]
+	}
+	return plugins.IsRetryableStatus(code)
+}
+
+func (s *Sink) render(tmpl *template.Template, data models.Metadata) (string, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+// toJSON encodes the value as JSON to be used in payload templates
+func toJSON(value interface{}) (string, error) {
+	if msg, ok := value.(proto.Message); ok {
+		b, err := protojson.MarshalOptions{UseProtoNames: true}.Marshal(msg)
+		return string(b), err
+	}
+
+	b, err := json.Marshal(value)
+	return string(b), err
+}
+
+func init() {
+	if err := registry.Sinks.Register("http", func() plugins.Syncer {
+		return New(&http.Client{}, plugins.GetLog())
+	}); err != nil {
+		panic(err)
+	}
+}
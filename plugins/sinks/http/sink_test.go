@@ -0,0 +1,170 @@
+package http_test
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/odpf/meteor/models"
+	commonv1beta1 "github.com/odpf/meteor/models/odpf/assets/common/v1beta1"
+	assetsv1beta1 "github.com/odpf/meteor/models/odpf/assets/v1beta1"
+	"github.com/odpf/meteor/plugins"
+	httpSink "github.com/odpf/meteor/plugins/sinks/http"
+	testUtils "github.com/odpf/meteor/test/utils"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var records = []models.Record{
+	models.NewRecord(&assetsv1beta1.Table{
+		Resource: &commonv1beta1.Resource{Urn: "bigquery::p/d/orders", Name: "orders", Service: "bigquery", Type: "table"},
+	}),
+	models.NewRecord(&assetsv1beta1.Table{
+		Resource: &commonv1beta1.Resource{Urn: "bigquery::p/d/payments", Name: "payments", Service: "bigquery", Type: "table"},
+	}),
+	models.NewRecord(&assetsv1beta1.Topic{
+		Resource: &commonv1beta1.Resource{Urn: "kafka::k/orders", Name: "orders", Service: "kafka", Type: "topic"},
+	}),
+}
+
+type received struct {
+	method string
+	path   string
+	header http.Header
+	body   string
+}
+
+func newServer(t *testing.T, status int) (*httptest.Server, *[]received) {
+	var requests []received
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		requests = append(requests, received{method: r.Method, path: r.URL.Path, header: r.Header, body: string(body)})
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+
+	return server, &requests
+}
+
+func TestInit(t *testing.T) {
+	t.Run("should return InvalidConfigError on invalid config", func(t *testing.T) {
+		err := httpSink.New(http.DefaultClient, testUtils.Logger).Init(context.TODO(), map[string]interface{}{
+			"url":  "http://localhost",
+			"auth": map[string]interface{}{"type": "bearer"},
+		})
+		assert.Equal(t, plugins.InvalidConfigError{Type: plugins.PluginTypeSink, PluginName: "http"}, err)
+	})
+	t.Run("should return error if both payload and payload_path are set", func(t *testing.T) {
+		err := httpSink.New(http.DefaultClient, testUtils.Logger).Init(context.TODO(), map[string]interface{}{
+			"url":          "http://localhost",
+			"payload":      "{}",
+			"payload_path": "$.resource",
+		})
+		assert.Error(t, err)
+	})
+}
+
+func TestSink(t *testing.T) {
+	t.Run("should send a templated request per record", func(t *testing.T) {
+		server, requests := newServer(t, http.StatusOK)
+		sink := httpSink.New(http.DefaultClient, testUtils.Logger)
+		require.NoError(t, sink.Init(context.TODO(), map[string]interface{}{
+			"url":     server.URL + "/{{ assetType . }}s/{{ .Resource.Name }}",
+			"method":  "PUT",
+			"payload": `{"urn": {{ json .Resource.Urn }}, "recipe": {{ json recipe }}}`,
+			"headers": map[string]interface{}{"X-Source": "meteor"},
+			"auth":    map[string]interface{}{"type": "bearer", "token": "secret-token"},
+		}))
+
+		require.NoError(t, sink.Sink(context.TODO(), records))
+
+		require.Len(t, *requests, 3)
+		first := (*requests)[0]
+		assert.Equal(t, "PUT", first.method)
+		assert.Equal(t, "/tables/orders", first.path)
+		assert.Equal(t, "Bearer secret-token", first.header.Get("Authorization"))
+		assert.Equal(t, "meteor", first.header.Get("X-Source"))
+		assert.JSONEq(t, `{"urn": "bigquery::p/d/orders", "recipe": ""}`, first.body)
+		assert.Equal(t, "/topics/orders", (*requests)[2].path)
+	})
+	t.Run("should batch payloads of the same url into a JSON array", func(t *testing.T) {
+		server, requests := newServer(t, http.StatusAccepted)
+		sink := httpSink.New(http.DefaultClient, testUtils.Logger)
+		require.NoError(t, sink.Init(context.TODO(), map[string]interface{}{
+			"url":          server.URL + "/{{ assetType . }}",
+			"payload_path": "$.resource.urn",
+			"batch":        true,
+			"auth":         map[string]interface{}{"type": "hmac", "secret": "my-secret"},
+		}))
+
+		require.NoError(t, sink.Sink(context.TODO(), records))
+
+		require.Len(t, *requests, 2)
+		tables := (*requests)[0]
+		assert.Equal(t, "/table", tables.path)
+		assert.JSONEq(t, `["bigquery::p/d/orders", "bigquery::p/d/payments"]`, tables.body)
+
+		mac := hmac.New(sha256.New, []byte("my-secret"))
+		mac.Write([]byte(tables.body))
+		assert.Equal(t, "sha256="+hex.EncodeToString(mac.Sum(nil)), tables.header.Get("X-Signature-256"))
+
+		assert.Equal(t, "/topic", (*requests)[1].path)
+		assert.JSONEq(t, `["kafka::k/orders"]`, (*requests)[1].body)
+	})
+	t.Run("should return the records of the failed and unsent requests", func(t *testing.T) {
+		var calls int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if atomic.AddInt32(&calls, 1) > 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}
+		}))
+		defer server.Close()
+
+		sink := httpSink.New(http.DefaultClient, testUtils.Logger)
+		require.NoError(t, sink.Init(context.TODO(), map[string]interface{}{
+			"url": server.URL + "/{{ .Resource.Name }}",
+		}))
+
+		err := sink.Sink(context.TODO(), records)
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, plugins.RetryError{}))
+		var partialErr plugins.PartialSinkError
+		require.True(t, errors.As(err, &partialErr))
+		assert.Equal(t, []string{"bigquery::p/d/payments", "kafka::k/orders"}, partialErr.FailedURNs)
+		assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+	})
+	t.Run("should map response statuses to retry or permanent failures", func(t *testing.T) {
+		cases := []struct {
+			status     int
+			retryCodes []interface{}
+			retryable  bool
+		}{
+			{status: http.StatusServiceUnavailable, retryable: true},
+			{status: http.StatusTooManyRequests, retryable: true},
+			{status: http.StatusBadRequest, retryable: false},
+			{status: http.StatusConflict, retryCodes: []interface{}{409}, retryable: true},
+			{status: http.StatusInternalServerError, retryCodes: []interface{}{409}, retryable: false},
+		}
+		for _, c := range cases {
+			server, _ := newServer(t, c.status)
+			sink := httpSink.New(http.DefaultClient, testUtils.Logger)
+			require.NoError(t, sink.Init(context.TODO(), map[string]interface{}{
+				"url":                server.URL,
+				"retry_status_codes": c.retryCodes,
+				"auth":               map[string]interface{}{"type": "basic", "username": "meteor"},
+			}))
+
+			err := sink.Sink(context.TODO(), records)
+			require.Error(t, err)
+			assert.Equal(t, c.retryable, errors.Is(err, plugins.RetryError{}), "status %d", c.status)
+		}
+	})
+}
@@ -0,0 +1,125 @@
+package kafka
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/odpf/meteor/plugins"
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// httpClient holds the set of methods require for creating request
+type httpClient interface {
+	Do(*http.Request) (*http.Response, error)
+}
+
+// schemaRegistry looks up schema ids of subjects from a Confluent compatible schema registry
+type schemaRegistry struct {
+	client   httpClient
+	host     string
+	username string
+	password string
+
+	mu  sync.Mutex
+	ids map[string]int
+}
+
+func newSchemaRegistry(host, username, password string) *schemaRegistry {
+	return &schemaRegistry{
+		client:   &http.Client{Timeout: 10 * time.Second},
+		host:     strings.TrimSuffix(host, "/"),
+		username: username,
+		password: password,
+		ids:      make(map[string]int),
+	}
+}
+
+// schemaID returns the id of the latest schema of the subject, ids are cached for the lifetime of the sink
+func (r *schemaRegistry) schemaID(ctx context.Context, subject string) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if id, ok := r.ids[subject]; ok {
+		return id, nil
+	}
+
+	reqURL := fmt.Sprintf("%s/subjects/%s/versions/latest", r.host, url.PathEscape(subject))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Accept", "application/vnd.schemaregistry.v1+json")
+	if r.username != "" {
+		req.SetBasicAuth(r.username, r.password)
+	}
+
+	res, err := r.client.Do(req)
+	if err != nil {
+		return 0, plugins.NewRetryError(err)
+	}
+	defer res.Body.Close()
+
+	bodyBytes, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return 0, plugins.NewRetryError(err)
+	}
+	switch code := res.StatusCode; {
+	case plugins.IsRetryableStatus(code):
+		return 0, plugins.NewRetryError(fmt.Errorf("schema registry returns %d: %v", code, string(bodyBytes)))
+	case code != http.StatusOK:
+		return 0, fmt.Errorf("schema registry returns %d: %v", code, string(bodyBytes))
+	}
+
+	var schema struct {
+		ID int `json:"id"`
+	}
+	if err = json.Unmarshal(bodyBytes, &schema); err != nil {
+		return 0, errors.Wrap(err, "failed to parse schema registry response")
+	}
+	r.ids[subject] = schema.ID
+
+	return schema.ID, nil
+}
+
+// frame prefixes the protobuf payload with the schema registry wire format header:
+// a zero magic byte, the big endian schema id and the message indexes of the message
+// within its file descriptor, a single zero byte references the first message of the file
+func frame(schemaID int, md protoreflect.MessageDescriptor, payload []byte) []byte {
+	framed := make([]byte, 5, 5+binary.MaxVarintLen64+len(payload))
+	binary.BigEndian.PutUint32(framed[1:5], uint32(schemaID))
+	framed = append(framed, messageIndexes(md)...)
+
+	return append(framed, payload...)
+}
+
+// messageIndexes encodes the path of the message within its file descriptor
+// as a zigzag varint count followed by zigzag varint indexes, from the top level message down
+func messageIndexes(md protoreflect.MessageDescriptor) []byte {
+	var indexes []int
+	for d := protoreflect.Descriptor(md); d != nil; d = d.Parent() {
+		if _, ok := d.(protoreflect.MessageDescriptor); !ok {
+			break
+		}
+		indexes = append([]int{d.Index()}, indexes...)
+	}
+	if len(indexes) == 1 && indexes[0] == 0 {
+		return []byte{0}
+	}
+
+	buf := make([]byte, binary.MaxVarintLen64*(len(indexes)+1))
+	n := binary.PutVarint(buf, int64(len(indexes)))
+	for _, index := range indexes {
+		n += binary.PutVarint(buf[n:], int64(index))
+	}
+
+	return buf[:n]
+}
@@ -3,16 +3,18 @@ package kafka
 import (
 	"context"
 	_ "embed"
-	"reflect"
 	"strings"
 
+	"google.golang.org/protobuf/encoding/protojson"
 	"google.golang.org/protobuf/reflect/protoreflect"
 	"google.golang.org/protobuf/types/dynamicpb"
 
 	"github.com/odpf/meteor/models"
 	"github.com/odpf/meteor/plugins"
+	"github.com/odpf/meteor/plugins/kafkautil"
 	"github.com/odpf/meteor/registry"
 	"github.com/odpf/meteor/utils"
+	"github.com/odpf/salt/log"
 	"github.com/pkg/errors"
 	kafka "github.com/segmentio/kafka-go"
 	"google.golang.org/protobuf/proto"
@@ -21,10 +23,32 @@ import (
 //go:embed README.md
 var summary string
 
+// Value encodings supported by the sink
+const (
+	EncodingProtobuf               = "protobuf"
+	EncodingJSON                   = "json"
+	EncodingProtobufSchemaRegistry = "protobuf_schema_registry"
+)
+
+// Headers added to every message
+const (
+	HeaderAssetType  = "asset_type"
+	HeaderRecipeName = "recipe_name"
+)
+
 type Config struct {
-	Brokers string `mapstructure:"brokers" validate:"required"`
-	Topic   string `mapstructure:"topic" validate:"required"`
-	KeyPath string `mapstructure:"key_path"`
+	Brokers                string            `mapstructure:"brokers" validate:"required"`
+	Topic                  string            `mapstructure:"topic" validate:"required_without=Topics"`
+	Topics                 map[string]string `mapstructure:"topics"`
+	KeyPath                string            `mapstructure:"key_path"`
+	Encoding               string            `mapstructure:"encoding" validate:"oneof=protobuf json protobuf_schema_registry" default:"protobuf"`
+	SchemaRegistryURL      string            `mapstructure:"schema_registry_url" validate:"required_if=Encoding protobuf_schema_registry"`
+	SchemaRegistryUsername string            `mapstructure:"schema_registry_username"`
+	SchemaRegistryPassword string            `mapstructure:"schema_registry_password"`
+	Compression            string            `mapstructure:"compression" validate:"oneof=none gzip snappy lz4 zstd" default:"none"`
+	Acks                   string            `mapstructure:"acks" validate:"oneof=none one all" default:"all"`
+	MaxAttempts            int               `mapstructure:"max_attempts" validate:"min=1" default:"10"`
+	kafkautil.AuthConfig   `mapstructure:",squash"`
 }
 
 var sampleConfig = `
@@ -32,20 +56,51 @@ var sampleConfig = `
  brokers: "localhost:9092"
  # The Kafka topic to write to
  topic: sample-topic-name
- # The path to the key field in the payload
- key_path: xxx`
+ # Optional topics per asset type, records of other types are written to topic, or skipped if topic is not set
+ topics:
+   table: sample-tables
+   dashboard: sample-dashboards
+ # The path to the key field in the payload, nested fields are separated by a dot
+ key_path: .Resource.Urn
+ # Encoding of the message value, one of protobuf, json or protobuf_schema_registry
+ encoding: protobuf
+ # Schema registry used to frame protobuf_schema_registry values, the id of the latest "<topic>-value" subject is used
+ schema_registry_url: http://localhost:8081
+ # Compression codec, one of none, gzip, snappy, lz4 or zstd
+ compression: none
+ # Acknowledgements required from brokers, one of none, one or all
+ acks: all
+ # Number of attempts to deliver a batch
+ max_attempts: 10
+ # SASL authentication, mechanism is one of PLAIN, SCRAM-SHA-256 or SCRAM-SHA-512
+ sasl:
+   mechanism: SCRAM-SHA-512
+   username: meteor
+   password: secret
+ # TLS connection to the brokers
+ tls:
+   enabled: true
+   ca_file: /etc/meteor/ca.pem`
 
 type ProtoReflector interface {
 	ProtoReflect() protoreflect.Message
 }
 
+// messageWriter writes messages to kafka, it is satisfied by *kafka.Writer
+type messageWriter interface {
+	WriteMessages(ctx context.Context, msgs ...kafka.Message) error
+	Close() error
+}
+
 type Sink struct {
-	writer *kafka.Writer
-	config Config
+	writer   messageWriter
+	config   Config
+	registry *schemaRegistry
+	logger   log.Logger
 }
 
-func New() plugins.Syncer {
-	return new(Sink)
+func New(logger log.Logger) plugins.Syncer {
+	return &Sink{logger: logger}
 }
 
 func (s *Sink) Info() plugins.Info {
@@ -66,16 +121,46 @@ func (s *Sink) Init(ctx context.Context, configMap map[string]interface{}) (err
 		return err
 	}
 
-	s.writer = createWriter(s.config)
+	if s.writer, err = createWriter(s.config); err != nil {
+		return errors.Wrap(err, "failed to create writer")
+	}
+	if s.config.Encoding == EncodingProtobufSchemaRegistry {
+		s.registry = newSchemaRegistry(s.config.SchemaRegistryURL, s.config.SchemaRegistryUsername, s.config.SchemaRegistryPassword)
+	}
 
 	return
 }
 
+// Sink writes every record of the batch in a single write,
+// records of asset types without a topic are skipped when only topics is configured
 func (s *Sink) Sink(ctx context.Context, batch []models.Record) (err error) {
+	recipeName := plugins.RunInfoFromContext(ctx).RecipeName
+
+	messages := make([]kafka.Message, 0, len(batch))
 	for _, record := range batch {
-		if err := s.push(ctx, record.Data()); err != nil {
+		data := record.Data()
+		assetType := utils.GetAssetType(data)
+		topic := s.topic(assetType)
+		if topic == "" {
+			s.logger.Warn("skipping record without topic for its asset type", "urn", data.GetResource().GetUrn(), "type", assetType)
+			continue
+		}
+		msg, err := s.buildMessage(ctx, data, assetType, topic, recipeName)
+		if err != nil {
 			return err
 		}
+		messages = append(messages, msg)
+	}
+	if len(messages) == 0 {
+		return nil
+	}
+
+	if err = s.writer.WriteMessages(ctx, messages...); err != nil {
+		err = errors.Wrap(err, "failed to write messages")
+		if isRetryable(err) {
+			return plugins.NewRetryError(err)
+		}
+		return err
 	}
 
 	return
@@ -85,120 +170,202 @@ func (s *Sink) Close() (err error) {
 	return s.writer.Close()
 }
 
-func (s *Sink) push(ctx context.Context, payload interface{}) error {
-	kafkaValue, err := s.buildValue(payload)
+func (s *Sink) buildMessage(ctx context.Context, payload models.Metadata, assetType, topic, recipeName string) (kafka.Message, error) {
+	kafkaValue, err := s.buildValue(ctx, topic, payload)
 	if err != nil {
-		return err
+		return kafka.Message{}, err
 	}
 
 	kafkaKey, err := s.buildKey(payload, s.config.KeyPath)
 	if err != nil {
-		return err
+		return kafka.Message{}, err
 	}
 
-	err = s.writer.WriteMessages(ctx,
-		kafka.Message{
-			Key:   kafkaKey,
-			Value: kafkaValue,
-		},
-	)
-	if err != nil {
-		return errors.Wrap(err, "failed to write messages")
+	headers := []kafka.Header{
+		{Key: HeaderAssetType, Value: []byte(assetType)},
+	}
+	if recipeName != "" {
+		headers = append(headers, kafka.Header{Key: HeaderRecipeName, Value: []byte(recipeName)})
 	}
 
-	return nil
+	return kafka.Message{
+		Topic:   topic,
+		Key:     kafkaKey,
+		Value:   kafkaValue,
+		Headers: headers,
+	}, nil
+}
+
+// topic returns the topic configured for the asset type, falling back to the default topic which may be empty
+func (s *Sink) topic(assetType string) string {
+	if topic, ok := s.config.Topics[assetType]; ok {
+		return topic
+	}
+
+	return s.config.Topic
 }
 
-func (s *Sink) buildValue(value interface{}) ([]byte, error) {
-	protoBytes, err := proto.Marshal(value.(proto.Message))
+func (s *Sink) buildValue(ctx context.Context, topic string, value interface{}) ([]byte, error) {
+	msg, ok := value.(proto.Message)
+	if !ok {
+		return nil, errors.New("not a valid proto payload")
+	}
+
+	switch s.config.Encoding {
+	case EncodingJSON:
+		jsonBytes, err := protojson.MarshalOptions{UseProtoNames: true}.Marshal(msg)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to serialize payload as json")
+		}
+		return jsonBytes, nil
+	case EncodingProtobufSchemaRegistry:
+		protoBytes, err := proto.Marshal(msg)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to serialize payload as a protobuf message")
+		}
+		schemaID, err := s.registry.schemaID(ctx, topic+"-value")
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to get schema id")
+		}
+		return frame(schemaID, msg.ProtoReflect().Descriptor(), protoBytes), nil
+	}
+
+	protoBytes, err := proto.Marshal(msg)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to serialize payload as a protobuf message")
 	}
 	return protoBytes, nil
 }
 
-// we can optimize this by caching descriptor and key path
+// buildKey builds a message of the payload type with only the field of the key path set,
+// the message is serialized the same way as the value
 func (s *Sink) buildKey(payload interface{}, keyPath string) ([]byte, error) {
 	if keyPath == "" {
 		return nil, nil
 	}
 
-	// extract key field name and value
-	fieldName, err := s.getTopLevelKeyFromPath(keyPath)
-	if err != nil {
-		return nil, err
-	}
-	keyString, keyJSONName, err := s.extractKeyFromPayload(fieldName, payload)
+	fieldNames, err := s.getKeyFieldsFromPath(keyPath)
 	if err != nil {
 		return nil, err
 	}
 
-	// get descriptor
 	reflector, ok := payload.(ProtoReflector)
 	if !ok {
 		return nil, errors.New("not a valid proto payload")
 	}
-	messageDescriptor := reflector.ProtoReflect().Descriptor()
-	fieldDescriptor := messageDescriptor.Fields().ByJSONName(keyJSONName)
-	if fieldDescriptor == nil {
-		return nil, errors.New("failed to build kafka key")
+	src := reflector.ProtoReflect()
+	dynamicMsgKey := dynamicpb.NewMessage(src.Descriptor())
+	if err := copyKeyField(src, dynamicMsgKey, fieldNames); err != nil {
+		return nil, errors.Wrap(err, "failed to build kafka key")
 	}
 
-	// populate message
-	dynamicMsgKey := dynamicpb.NewMessage(messageDescriptor)
-	dynamicMsgKey.Set(fieldDescriptor, protoreflect.ValueOfString(keyString))
+	if s.config.Encoding == EncodingJSON {
+		return protojson.MarshalOptions{UseProtoNames: true}.Marshal(dynamicMsgKey)
+	}
 	return proto.Marshal(dynamicMsgKey)
 }
 
-func (s *Sink) extractKeyFromPayload(fieldName string, value interface{}) (string, string, error) {
-	valueOf := reflect.ValueOf(value)
-	if valueOf.Kind() == reflect.Ptr {
-		valueOf = valueOf.Elem()
-	}
-	if valueOf.Kind() != reflect.Struct {
-		return "", "", errors.New("invalid data")
-	}
+// copyKeyField copies the string field at the end of fieldNames from src to dst, creating the parent messages on the way
+func copyKeyField(src, dst protoreflect.Message, fieldNames []string) error {
+	for i, fieldName := range fieldNames {
+		fd := findField(src.Descriptor(), fieldName)
+		if fd == nil {
+			return errors.Errorf("invalid path, unknown field \"%s\"", fieldName)
+		}
+		if fd.IsList() || fd.IsMap() {
+			return errors.Errorf("invalid path, \"%s\" is a repeated field", fieldName)
+		}
+		if !src.Has(fd) {
+			return errors.Errorf("invalid path, field \"%s\" is not set", fieldName)
+		}
 
-	structField, ok := valueOf.Type().FieldByName(fieldName)
-	if !ok {
-		return "", "", errors.New("invalid path, unknown field")
-	}
-	jsonName := strings.Split(structField.Tag.Get("json"), ",")[0]
+		if i == len(fieldNames)-1 {
+			if fd.Kind() != protoreflect.StringKind {
+				return errors.Errorf("unsupported key type, should be string found: %s", fd.Kind())
+			}
+			dst.Set(fd, src.Get(fd))
+			return nil
+		}
 
-	fieldVal := valueOf.FieldByName(fieldName)
-	if !fieldVal.IsValid() || fieldVal.IsZero() {
-		return "", "", errors.New("invalid path, unknown field")
+		if fd.Kind() != protoreflect.MessageKind {
+			return errors.Errorf("invalid path, \"%s\" is not a message", fieldName)
+		}
+		src = src.Get(fd).Message()
+		dst = dst.Mutable(fd).Message()
 	}
-	if fieldVal.Type().Kind() != reflect.String {
-		return "", "", errors.Errorf("unsupported key type, should be string found: %s", fieldVal.Type().String())
+
+	return nil
+}
+
+// findField finds a field by its Go, JSON or proto name
+func findField(md protoreflect.MessageDescriptor, name string) protoreflect.FieldDescriptor {
+	fields := md.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		if string(fd.Name()) == name || strings.EqualFold(fd.JSONName(), name) {
+			return fd
+		}
 	}
 
-	return fieldVal.String(), jsonName, nil
+	return nil
 }
 
-func (s *Sink) getTopLevelKeyFromPath(keyPath string) (string, error) {
+func (s *Sink) getKeyFieldsFromPath(keyPath string) ([]string, error) {
 	keyPaths := strings.Split(keyPath, ".")
-	if len(keyPaths) < 2 {
-		return "", errors.New("invalid path, require at least one field name e.g.: .Urn")
+	if len(keyPaths) < 2 || keyPaths[0] != "" {
+		return nil, errors.New("invalid path, require at least one field name e.g.: .Resource.Urn")
 	}
-	if len(keyPaths) > 2 {
-		return "", errors.New("invalid path, doesn't support nested field names yet")
+	for _, p := range keyPaths[1:] {
+		if p == "" {
+			return nil, errors.New("invalid path, empty field name")
+		}
 	}
-	return keyPaths[1], nil
+	return keyPaths[1:], nil
 }
 
-func createWriter(config Config) *kafka.Writer {
+func createWriter(config Config) (*kafka.Writer, error) {
+	transport, err := config.AuthConfig.Transport()
+	if err != nil {
+		return nil, err
+	}
+
 	brokers := strings.Split(config.Brokers, ",")
 	return &kafka.Writer{
-		Addr:     kafka.TCP(brokers...),
-		Topic:    config.Topic,
-		Balancer: &kafka.LeastBytes{},
+		Addr:         kafka.TCP(brokers...),
+		Balancer:     &kafka.Hash{},
+		Compression:  compressions[config.Compression],
+		RequiredAcks: requiredAcks[config.Acks],
+		MaxAttempts:  config.MaxAttempts,
+		Transport:    transport,
+	}, nil
+}
+
+var compressions = map[string]kafka.Compression{
+	"gzip":   kafka.Gzip,
+	"snappy": kafka.Snappy,
+	"lz4":    kafka.Lz4,
+	"zstd":   kafka.Zstd,
+}
+
+var requiredAcks = map[string]kafka.RequiredAcks{
+	"none": kafka.RequireNone,
+	"one":  kafka.RequireOne,
+	"all":  kafka.RequireAll,
+}
+
+// isRetryable returns false for errors returned by the brokers that are not temporary
+func isRetryable(err error) bool {
+	var kafkaErr kafka.Error
+	if errors.As(err, &kafkaErr) {
+		return kafkaErr.Temporary()
 	}
+
+	return true
 }
 
 func init() {
 	if err := registry.Sinks.Register("kafka", func() plugins.Syncer {
-		return &Sink{}
+		return New(plugins.GetLog())
 	}); err != nil {
 		panic(err)
 	}
@@ -0,0 +1,209 @@
+package kafka
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/odpf/meteor/models"
+	commonv1beta1 "github.com/odpf/meteor/models/odpf/assets/common/v1beta1"
+	assetsv1beta1 "github.com/odpf/meteor/models/odpf/assets/v1beta1"
+	"github.com/odpf/meteor/plugins"
+	testUtils "github.com/odpf/meteor/test/utils"
+	kafka "github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestInit(t *testing.T) {
+	t.Run("should return error on invalid config", func(t *testing.T) {
+		invalidConfigs := []map[string]interface{}{
+			{"topic": "assets"},
+			{"brokers": "localhost:9092"},
+			{"brokers": "localhost:9092", "topic": "assets", "encoding": "avro"},
+			{"brokers": "localhost:9092", "topic": "assets", "encoding": "protobuf_schema_registry"},
+			{"brokers": "localhost:9092", "topic": "assets", "sasl": map[string]interface{}{"mechanism": "GSSAPI"}},
+		}
+		for _, config := range invalidConfigs {
+			err := New(testUtils.Logger).Init(context.TODO(), config)
+			assert.Error(t, err)
+		}
+	})
+}
+
+func TestSink(t *testing.T) {
+	table := &assetsv1beta1.Table{
+		Resource: &commonv1beta1.Resource{
+			Urn:     "bigquery::project/dataset/table",
+			Name:    "table",
+			Service: "bigquery",
+		},
+	}
+	topic := &assetsv1beta1.Topic{
+		Resource: &commonv1beta1.Resource{
+			Urn:  "kafka::broker/topic",
+			Name: "topic",
+		},
+	}
+
+	t.Run("should route records by asset type with headers and nested key", func(t *testing.T) {
+		writer := &fakeWriter{}
+		sink := newSink(t, writer, map[string]interface{}{
+			"brokers":  "localhost:9092",
+			"topic":    "assets",
+			"topics":   map[string]string{"table": "tables"},
+			"key_path": ".Resource.Urn",
+			"encoding": "json",
+		})
+		ctx := plugins.ContextWithRunInfo(context.TODO(), plugins.RunInfo{RecipeName: "sample-recipe"})
+
+		err := sink.Sink(ctx, []models.Record{models.NewRecord(table), models.NewRecord(topic)})
+		require.NoError(t, err)
+
+		require.Len(t, writer.messages, 2)
+		assert.Equal(t, "tables", writer.messages[0].Topic)
+		assert.Equal(t, "assets", writer.messages[1].Topic)
+		assert.JSONEq(t, `{"resource":{"urn":"bigquery::project/dataset/table"}}`, string(writer.messages[0].Key))
+		assert.JSONEq(t, `{"resource":{"urn":"bigquery::project/dataset/table","name":"table","service":"bigquery"}}`, string(writer.messages[0].Value))
+		assert.Equal(t, []kafka.Header{
+			{Key: HeaderAssetType, Value: []byte("table")},
+			{Key: HeaderRecipeName, Value: []byte("sample-recipe")},
+		}, writer.messages[0].Headers)
+		assert.Equal(t, []kafka.Header{
+			{Key: HeaderAssetType, Value: []byte("topic")},
+			{Key: HeaderRecipeName, Value: []byte("sample-recipe")},
+		}, writer.messages[1].Headers)
+	})
+
+	t.Run("should skip records of asset types without topic", func(t *testing.T) {
+		writer := &fakeWriter{}
+		sink := newSink(t, writer, map[string]interface{}{
+			"brokers":  "localhost:9092",
+			"topics":   map[string]string{"table": "tables"},
+			"encoding": "json",
+		})
+
+		err := sink.Sink(context.TODO(), []models.Record{models.NewRecord(table), models.NewRecord(topic)})
+		require.NoError(t, err)
+
+		require.Len(t, writer.messages, 1)
+		assert.Equal(t, "tables", writer.messages[0].Topic)
+
+		err = sink.Sink(context.TODO(), []models.Record{models.NewRecord(topic)})
+		require.NoError(t, err)
+		assert.Len(t, writer.messages, 1)
+	})
+	t.Run("should build protobuf key with only the key field", func(t *testing.T) {
+		writer := &fakeWriter{}
+		sink := newSink(t, writer, map[string]interface{}{
+			"brokers":  "localhost:9092",
+			"topic":    "assets",
+			"key_path": ".Resource.Urn",
+		})
+
+		err := sink.Sink(context.TODO(), []models.Record{models.NewRecord(table)})
+		require.NoError(t, err)
+
+		var key assetsv1beta1.Table
+		require.NoError(t, proto.Unmarshal(writer.messages[0].Key, &key))
+		assert.Equal(t, "bigquery::project/dataset/table", key.Resource.Urn)
+		assert.Empty(t, key.Resource.Name)
+	})
+
+	t.Run("should return error on invalid key path", func(t *testing.T) {
+		for _, keyPath := range []string{"Resource.Urn", ".Resource.Unknown", ".Resource", ".Resource..Urn"} {
+			sink := newSink(t, &fakeWriter{}, map[string]interface{}{
+				"brokers":  "localhost:9092",
+				"topic":    "assets",
+				"key_path": keyPath,
+			})
+
+			err := sink.Sink(context.TODO(), []models.Record{models.NewRecord(table)})
+			assert.Error(t, err, keyPath)
+		}
+	})
+
+	t.Run("should frame values with the schema id of the topic subject", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/subjects/assets-value/versions/latest", r.URL.Path)
+			_, _ = w.Write([]byte(`{"subject":"assets-value","version":3,"id":42}`))
+		}))
+		defer server.Close()
+
+		writer := &fakeWriter{}
+		sink := newSink(t, writer, map[string]interface{}{
+			"brokers":             "localhost:9092",
+			"topic":               "assets",
+			"encoding":            "protobuf_schema_registry",
+			"schema_registry_url": server.URL,
+		})
+
+		err := sink.Sink(context.TODO(), []models.Record{models.NewRecord(table)})
+		require.NoError(t, err)
+
+		value := writer.messages[0].Value
+		assert.Equal(t, byte(0), value[0])
+		assert.Equal(t, uint32(42), binary.BigEndian.Uint32(value[1:5]))
+		assert.Equal(t, byte(0), value[5])
+		var decoded assetsv1beta1.Table
+		require.NoError(t, proto.Unmarshal(value[6:], &decoded))
+		assert.True(t, proto.Equal(table, &decoded))
+	})
+
+	t.Run("should frame values with the index of the message within its file", func(t *testing.T) {
+		payload := []byte{1, 2, 3}
+
+		// Table is the first message of its file
+		framed := frame(7, (&assetsv1beta1.Table{}).ProtoReflect().Descriptor(), payload)
+		assert.Equal(t, []byte{0, 0, 0, 0, 7, 0, 1, 2, 3}, framed)
+
+		// Join is the third message of the same file, one index of 2 zigzag encoded
+		framed = frame(7, (&assetsv1beta1.Join{}).ProtoReflect().Descriptor(), payload)
+		assert.Equal(t, []byte{0, 0, 0, 0, 7, 2, 4, 1, 2, 3}, framed)
+	})
+
+	t.Run("should only return RetryError for temporary errors", func(t *testing.T) {
+		config := map[string]interface{}{
+			"brokers": "localhost:9092",
+			"topic":   "assets",
+		}
+
+		sink := newSink(t, &fakeWriter{err: kafka.LeaderNotAvailable}, config)
+		err := sink.Sink(context.TODO(), []models.Record{models.NewRecord(table)})
+		assert.True(t, errors.Is(err, plugins.RetryError{}))
+
+		sink = newSink(t, &fakeWriter{err: kafka.MessageSizeTooLarge}, config)
+		err = sink.Sink(context.TODO(), []models.Record{models.NewRecord(table)})
+		assert.Error(t, err)
+		assert.False(t, errors.Is(err, plugins.RetryError{}))
+	})
+}
+
+func newSink(t *testing.T, writer messageWriter, config map[string]interface{}) *Sink {
+	sink := &Sink{logger: testUtils.Logger}
+	require.NoError(t, sink.Init(context.TODO(), config))
+	sink.writer = writer
+
+	return sink
+}
+
+type fakeWriter struct {
+	messages []kafka.Message
+	err      error
+}
+
+func (w *fakeWriter) WriteMessages(ctx context.Context, msgs ...kafka.Message) error {
+	if w.err != nil {
+		return w.err
+	}
+	w.messages = append(w.messages, msgs...)
+	return nil
+}
+
+func (w *fakeWriter) Close() error {
+	return nil
+}
@@ -0,0 +1,336 @@
+package nats
+
+import (
+	"context"
+	"crypto/tls"
+	_ "embed"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/odpf/meteor/models"
+	"github.com/odpf/meteor/plugins"
+	"github.com/odpf/meteor/registry"
+	"github.com/odpf/meteor/utils"
+	"github.com/odpf/salt/log"
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+//go:embed README.md
+var summary string
+
+// Value encodings supported by the sink
+const (
+	EncodingProtobuf = "protobuf"
+	EncodingJSON     = "json"
+)
+
+// Headers added to every message
+const (
+	HeaderAssetType  = "asset_type"
+	HeaderRecipeName = "recipe_name"
+	// HeaderMsgID lets JetStream drop the messages of a batch delivered again on retry
+	HeaderMsgID = "Nats-Msg-Id"
+)
+
+type Config struct {
+	// Servers are the comma separated urls of the servers, the first reachable one is used
+	Servers  string            `mapstructure:"servers" validate:"required"`
+	Subject  string            `mapstructure:"subject" validate:"required_without=Subjects"`
+	Subjects map[string]string `mapstructure:"subjects"`
+	Encoding string            `mapstructure:"encoding" validate:"oneof=protobuf json" default:"protobuf"`
+	// JetStream waits for every message to be stored by a stream instead of being accepted by the server
+	JetStream      bool            `mapstructure:"jetstream"`
+	Username       string          `mapstructure:"username" validate:"required_with=Password"`
+	Password       string          `mapstructure:"password" validate:"required_with=Username"`
+	Token          string          `mapstructure:"token" validate:"excluded_with=Username"`
+	TimeoutSeconds int             `mapstructure:"timeout_seconds" validate:"min=1" default:"10"`
+	TLS            utils.TLSConfig `mapstructure:"tls"`
+}
+
+var sampleConfig = `
+ # NATS server urls, separated by commas
+ servers: "nats://localhost:4222"
+ # The subject to publish to
+ subject: meteor.assets
+ # Optional subjects per asset type, records of other types are published to subject, or skipped if subject is not set
+ subjects:
+   table: meteor.assets.tables
+   dashboard: meteor.assets.dashboards
+ # Encoding of the message, one of protobuf or json
+ encoding: protobuf
+ # Wait for the messages to be stored by a JetStream stream
+ jetstream: true
+ # Username and password or token to authenticate with
+ username: meteor
+ password: secret
+ # Time to wait for the server to accept or acknowledge a batch
+ timeout_seconds: 10
+ # TLS connection to the servers
+ tls:
+   enabled: true
+   ca_file: /etc/meteor/ca.pem`
+
+type Sink struct {
+	logger    log.Logger
+	config    Config
+	tlsConfig *tls.Config
+	conn      *nats.Conn
+	js        nats.JetStreamContext
+}
+
+func New(logger log.Logger) plugins.Syncer {
+	return &Sink{logger: logger}
+}
+
+func (s *Sink) Info() plugins.Info {
+	return plugins.Info{
+		Description:  "Publish metadata to NATS subjects or JetStream streams",
+		Summary:      summary,
+		SampleConfig: sampleConfig,
+		Tags:         []string{"nats", "jetstream", "sink"},
+	}
+}
+
+func (s *Sink) Validate(configMap map[string]interface{}) (err error) {
+	return utils.BuildConfig(configMap, &Config{})
+}
+
+func (s *Sink) Init(ctx context.Context, configMap map[string]interface{}) (err error) {
+	if err = utils.BuildConfig(configMap, &s.config); err != nil {
+		return plugins.InvalidConfigError{Type: plugins.PluginTypeSink, PluginName: "nats"}
+	}
+	if s.tlsConfig, err = s.config.TLS.Build(); err != nil {
+		return errors.Wrap(err, "failed to build tls config")
+	}
+
+	if err = s.connect(); err != nil {
+		return errors.Wrap(err, "failed to connect")
+	}
+
+	return
+}
+
+// Sink publishes every record of the batch and waits for the batch to be accepted by the server,
+// or acknowledged by JetStream if enabled. Records of asset types without a subject are skipped.
+func (s *Sink) Sink(ctx context.Context, batch []models.Record) (err error) {
+	run := plugins.RunInfoFromContext(ctx)
+
+	// the connection is closed by the client on errors it does not recover from and established again by the next batch
+	if s.conn == nil || s.conn.IsClosed() {
+		if err = s.connect(); err != nil {
+			return plugins.NewRetryError(errors.Wrap(err, "failed to connect"))
+		}
+	}
+
+	msgs := make([]*nats.Msg, 0, len(batch))
+	for _, record := range batch {
+		data := record.Data()
+		assetType := utils.GetAssetType(data)
+		subject := s.subject(assetType)
+		if subject == "" {
+			s.logger.Warn("skipping record without subject for its asset type", "urn", data.GetResource().GetUrn(), "type", assetType)
+			continue
+		}
+		msg, err := s.buildMessage(data, assetType, subject, run)
+		if err != nil {
+			return err
+		}
+		msgs = append(msgs, msg)
+	}
+	if len(msgs) == 0 {
+		return nil
+	}
+
+	for _, msg := range msgs {
+		if max := s.conn.MaxPayload(); max > 0 && int64(len(msg.Data)) > max {
+			return errors.Errorf("message to \"%s\" of %d bytes exceeds the max payload of %d bytes", msg.Subject, len(msg.Data), max)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(s.config.TimeoutSeconds)*time.Second)
+	defer cancel()
+
+	if s.config.JetStream {
+		err = s.request(ctx, msgs)
+	} else {
+		err = s.publish(ctx, msgs)
+	}
+	if err != nil {
+		err = errors.Wrap(err, "failed to publish messages")
+		if isPermanent(err) {
+			return err
+		}
+		return plugins.NewRetryError(err)
+	}
+
+	return
+}
+
+func (s *Sink) Close() (err error) {
+	if s.conn == nil {
+		return nil
+	}
+	s.conn.Close()
+	s.conn = nil
+
+	return
+}
+
+// connect connects to the first reachable server
+func (s *Sink) connect() (err error) {
+	options := []nats.Option{
+		nats.Name("meteor"),
+		nats.Timeout(time.Duration(s.config.TimeoutSeconds) * time.Second),
+		nats.DontRandomize(),
+		nats.ErrorHandler(func(_ *nats.Conn, _ *nats.Subscription, err error) {
+			s.logger.Warn("nats returns error", "error", err)
+		}),
+	}
+	if s.config.Username != "" {
+		options = append(options, nats.UserInfo(s.config.Username, s.config.Password))
+	}
+	if s.config.Token != "" {
+		options = append(options, nats.Token(s.config.Token))
+	}
+	if s.tlsConfig != nil {
+		options = append(options, nats.Secure(s.tlsConfig))
+	}
+
+	if s.conn, err = nats.Connect(s.config.Servers, options...); err != nil {
+		return err
+	}
+	if s.config.JetStream {
+		if s.js, err = s.conn.JetStream(); err != nil {
+			s.conn.Close()
+			return errors.Wrap(err, "failed to create jetstream context")
+		}
+	}
+
+	return
+}
+
+// publish publishes the messages and waits for the server to process them
+func (s *Sink) publish(ctx context.Context, msgs []*nats.Msg) error {
+	lastErr := s.conn.LastError()
+	for _, msg := range msgs {
+		if err := s.conn.PublishMsg(msg); err != nil {
+			return err
+		}
+	}
+	if err := s.conn.FlushWithContext(ctx); err != nil {
+		return err
+	}
+
+	// errors sent by the server, such as permission violations, are only kept as the last error of the connection,
+	// they are processed before the reply to the flush
+	if err := s.conn.LastError(); err != nil && err != lastErr {
+		return serverError{err}
+	}
+
+	return nil
+}
+
+// request publishes the messages to JetStream and waits for every message to be acknowledged
+func (s *Sink) request(ctx context.Context, msgs []*nats.Msg) error {
+	futures := make([]nats.PubAckFuture, 0, len(msgs))
+	for _, msg := range msgs {
+		future, err := s.js.PublishMsgAsync(msg)
+		if err != nil {
+			return err
+		}
+		futures = append(futures, future)
+	}
+
+	for _, future := range futures {
+		select {
+		case <-future.Ok():
+		case err := <-future.Err():
+			return errors.Wrapf(err, "message to \"%s\" is not acknowledged", future.Msg().Subject)
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return nil
+}
+
+func (s *Sink) buildMessage(payload models.Metadata, assetType, subject string, run plugins.RunInfo) (*nats.Msg, error) {
+	data, err := s.buildValue(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := nats.NewMsg(subject)
+	msg.Data = data
+	if !s.conn.HeadersSupported() {
+		return msg, nil
+	}
+
+	msg.Header.Set(HeaderAssetType, assetType)
+	if run.RecipeName != "" {
+		msg.Header.Set(HeaderRecipeName, run.RecipeName)
+	}
+	if s.config.JetStream {
+		id := payload.GetResource().GetUrn()
+		if !run.StartedAt.IsZero() {
+			id += "@" + run.StartedAt.UTC().Format(time.RFC3339Nano)
+		}
+		msg.Header.Set(HeaderMsgID, id)
+	}
+
+	return msg, nil
+}
+
+// subject returns the subject configured for the asset type, falling back to the default subject which may be empty
+func (s *Sink) subject(assetType string) string {
+	if subject, ok := s.config.Subjects[assetType]; ok {
+		return subject
+	}
+
+	return s.config.Subject
+}
+
+func (s *Sink) buildValue(value interface{}) ([]byte, error) {
+	msg, ok := value.(proto.Message)
+	if !ok {
+		return nil, errors.New("not a valid proto payload")
+	}
+
+	if s.config.Encoding == EncodingJSON {
+		jsonBytes, err := protojson.MarshalOptions{UseProtoNames: true}.Marshal(msg)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to serialize payload as json")
+		}
+		return jsonBytes, nil
+	}
+
+	protoBytes, err := proto.Marshal(msg)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to serialize payload as a protobuf message")
+	}
+	return protoBytes, nil
+}
+
+// serverError is an error sent by the server, such as a permission violation
+type serverError struct {
+	error
+}
+
+func (e serverError) Unwrap() error {
+	return e.error
+}
+
+// isPermanent returns true for errors which are not fixed by publishing again
+func isPermanent(err error) bool {
+	return errors.As(err, &serverError{}) || errors.Is(err, nats.ErrMaxPayload) || errors.Is(err, nats.ErrBadSubject)
+}
+
+func init() {
+	if err := registry.Sinks.Register("nats", func() plugins.Syncer {
+		return New(plugins.GetLog())
+	}); err != nil {
+		panic(err)
+	}
+}
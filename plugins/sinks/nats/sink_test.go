@@ -0,0 +1,215 @@
+package nats_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+	natsserver "github.com/nats-io/nats-server/v2/test"
+	natsclient "github.com/nats-io/nats.go"
+	"github.com/odpf/meteor/models"
+	commonv1beta1 "github.com/odpf/meteor/models/odpf/assets/common/v1beta1"
+	assetsv1beta1 "github.com/odpf/meteor/models/odpf/assets/v1beta1"
+	"github.com/odpf/meteor/plugins"
+	"github.com/odpf/meteor/plugins/sinks/nats"
+	testUtils "github.com/odpf/meteor/test/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+)
+
+var (
+	table = &assetsv1beta1.Table{
+		Resource: &commonv1beta1.Resource{Urn: "postgres::db/shop/orders", Name: "orders", Service: "postgres"},
+	}
+	topic = &assetsv1beta1.Topic{
+		Resource: &commonv1beta1.Resource{Urn: "kafka::broker/orders", Name: "orders", Service: "kafka"},
+	}
+)
+
+func TestInit(t *testing.T) {
+	t.Run("should return InvalidConfigError without subject", func(t *testing.T) {
+		err := nats.New(testUtils.Logger).Init(context.TODO(), map[string]interface{}{
+			"servers": "nats://localhost:4222",
+		})
+		assert.Equal(t, plugins.InvalidConfigError{Type: plugins.PluginTypeSink, PluginName: "nats"}, err)
+	})
+	t.Run("should return error if credentials are rejected", func(t *testing.T) {
+		srv := runServer(t)
+		err := nats.New(testUtils.Logger).Init(context.TODO(), map[string]interface{}{
+			"servers":  srv.ClientURL(),
+			"subject":  "meteor.assets",
+			"username": "meteor",
+			"password": "wrong",
+		})
+		assert.EqualError(t, err, "failed to connect: nats: Authorization Violation")
+	})
+}
+
+func TestSink(t *testing.T) {
+	t.Run("should publish records to the subjects of their types", func(t *testing.T) {
+		srv := runServer(t)
+		sub := subscribe(t, srv, "meteor.>")
+		sink := nats.New(testUtils.Logger)
+		require.NoError(t, sink.Init(context.TODO(), map[string]interface{}{
+			"servers":  "nats://127.0.0.1:1," + srv.ClientURL(),
+			"subject":  "meteor.assets",
+			"subjects": map[string]string{"topic": "meteor.topics"},
+			"username": "meteor",
+			"password": "secret",
+		}))
+		defer sink.Close()
+
+		err := sink.Sink(runContext(), []models.Record{models.NewRecord(table), models.NewRecord(topic)})
+		require.NoError(t, err)
+
+		msg, err := sub.NextMsg(time.Second)
+		require.NoError(t, err)
+		assert.Equal(t, "meteor.assets", msg.Subject)
+		assert.Equal(t, "table", msg.Header.Get("asset_type"))
+		assert.Equal(t, "sample", msg.Header.Get("recipe_name"))
+		assert.Empty(t, msg.Header.Get("Nats-Msg-Id"))
+		actual := new(assetsv1beta1.Table)
+		require.NoError(t, proto.Unmarshal(msg.Data, actual))
+		assert.True(t, proto.Equal(table, actual))
+
+		msg, err = sub.NextMsg(time.Second)
+		require.NoError(t, err)
+		assert.Equal(t, "meteor.topics", msg.Subject)
+	})
+	t.Run("should skip records of asset types without subject", func(t *testing.T) {
+		srv := runServer(t)
+		sub := subscribe(t, srv, "meteor.>")
+		sink := nats.New(testUtils.Logger)
+		require.NoError(t, sink.Init(context.TODO(), map[string]interface{}{
+			"servers":  srv.ClientURL(),
+			"subjects": map[string]string{"topic": "meteor.topics"},
+			"username": "meteor",
+			"password": "secret",
+		}))
+		defer sink.Close()
+
+		err := sink.Sink(runContext(), []models.Record{models.NewRecord(table), models.NewRecord(topic)})
+		require.NoError(t, err)
+
+		msg, err := sub.NextMsg(time.Second)
+		require.NoError(t, err)
+		assert.Equal(t, "meteor.topics", msg.Subject)
+		_, err = sub.NextMsg(100 * time.Millisecond)
+		assert.Equal(t, natsclient.ErrTimeout, err)
+	})
+	t.Run("should not return RetryError on permission violations", func(t *testing.T) {
+		srv := runServer(t)
+		sink := nats.New(testUtils.Logger)
+		require.NoError(t, sink.Init(context.TODO(), map[string]interface{}{
+			"servers":  srv.ClientURL(),
+			"subject":  "meteor.assets",
+			"username": "guest",
+			"password": "secret",
+		}))
+		defer sink.Close()
+
+		err := sink.Sink(runContext(), []models.Record{models.NewRecord(table)})
+		require.Error(t, err)
+		assert.False(t, errors.Is(err, plugins.RetryError{}))
+		assert.Contains(t, err.Error(), "Permissions Violation")
+	})
+	t.Run("should wait for jetstream acknowledgments and let streams drop duplicates", func(t *testing.T) {
+		srv := runServer(t)
+		js := jetStream(t, srv)
+		_, err := js.AddStream(&natsclient.StreamConfig{Name: "ASSETS", Subjects: []string{"meteor.assets"}})
+		require.NoError(t, err)
+		sink := nats.New(testUtils.Logger)
+		require.NoError(t, sink.Init(context.TODO(), map[string]interface{}{
+			"servers":   srv.ClientURL(),
+			"subject":   "meteor.assets",
+			"encoding":  "json",
+			"jetstream": true,
+			"username":  "meteor",
+			"password":  "secret",
+		}))
+		defer sink.Close()
+
+		err = sink.Sink(runContext(), []models.Record{models.NewRecord(table), models.NewRecord(table)})
+		require.NoError(t, err)
+
+		info, err := js.StreamInfo("ASSETS")
+		require.NoError(t, err)
+		assert.Equal(t, uint64(1), info.State.Msgs)
+		msg, err := js.GetMsg("ASSETS", 1)
+		require.NoError(t, err)
+		assert.Equal(t, "postgres::db/shop/orders@2022-01-02T15:04:05Z", msg.Header.Get("Nats-Msg-Id"))
+		assert.Contains(t, string(msg.Data), `"urn":"postgres::db/shop/orders"`)
+	})
+	t.Run("should return RetryError if jetstream does not acknowledge", func(t *testing.T) {
+		srv := runServer(t)
+		sink := nats.New(testUtils.Logger)
+		require.NoError(t, sink.Init(context.TODO(), map[string]interface{}{
+			"servers":   srv.ClientURL(),
+			"subject":   "meteor.assets",
+			"jetstream": true,
+			"username":  "meteor",
+			"password":  "secret",
+		}))
+		defer sink.Close()
+
+		err := sink.Sink(runContext(), []models.Record{models.NewRecord(table)})
+		assert.True(t, errors.Is(err, plugins.RetryError{}))
+
+		_, err = jetStream(t, srv).AddStream(&natsclient.StreamConfig{Name: "ASSETS", Subjects: []string{"meteor.assets"}})
+		require.NoError(t, err)
+		assert.NoError(t, sink.Sink(runContext(), []models.Record{models.NewRecord(table)}))
+	})
+}
+
+func runContext() context.Context {
+	return plugins.ContextWithRunInfo(context.TODO(), plugins.RunInfo{
+		RecipeName: "sample",
+		StartedAt:  time.Date(2022, 1, 2, 15, 4, 5, 0, time.UTC),
+	})
+}
+
+// runServer runs a server with JetStream enabled, meteor can publish to any subject
+// while guest can only publish to meteor.other
+func runServer(t *testing.T) *server.Server {
+	opts := natsserver.DefaultTestOptions
+	opts.Port = -1
+	opts.JetStream = true
+	opts.StoreDir = t.TempDir()
+	opts.Users = []*server.User{
+		{Username: "meteor", Password: "secret"},
+		{Username: "guest", Password: "secret", Permissions: &server.Permissions{
+			Publish: &server.SubjectPermission{Allow: []string{"meteor.other"}},
+		}},
+	}
+	srv := natsserver.RunServer(&opts)
+	t.Cleanup(srv.Shutdown)
+
+	return srv
+}
+
+func connect(t *testing.T, srv *server.Server) *natsclient.Conn {
+	nc, err := natsclient.Connect(srv.ClientURL(), natsclient.UserInfo("meteor", "secret"))
+	require.NoError(t, err)
+	t.Cleanup(nc.Close)
+
+	return nc
+}
+
+func subscribe(t *testing.T, srv *server.Server, subject string) *natsclient.Subscription {
+	nc := connect(t, srv)
+	sub, err := nc.SubscribeSync(subject)
+	require.NoError(t, err)
+	require.NoError(t, nc.Flush())
+
+	return sub
+}
+
+func jetStream(t *testing.T, srv *server.Server) natsclient.JetStreamContext {
+	js, err := connect(t, srv).JetStream()
+	require.NoError(t, err)
+
+	return js
+}
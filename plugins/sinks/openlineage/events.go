@@ -0,0 +1,84 @@
+package openlineage
+
+// producer is the producer of the events and facets
+const producer = "https://github.com/odpf/meteor"
+
+// Schema urls of the events and facets
+const (
+	runEventSchemaURL     = "https://openlineage.io/spec/1-0-5/OpenLineage.json#/definitions/RunEvent"
+	datasetEventSchemaURL = "https://openlineage.io/spec/2-0-0/OpenLineage.json#/$defs/DatasetEvent"
+	schemaFacetURL        = "https://openlineage.io/spec/facets/1-0-0/SchemaDatasetFacet.json#/$defs/SchemaDatasetFacet"
+	datasetOwnershipURL   = "https://openlineage.io/spec/facets/1-0-0/OwnershipDatasetFacet.json#/$defs/OwnershipDatasetFacet"
+	jobOwnershipURL       = "https://openlineage.io/spec/facets/1-0-0/OwnershipJobFacet.json#/$defs/OwnershipJobFacet"
+	datasetDocsURL        = "https://openlineage.io/spec/facets/1-0-0/DocumentationDatasetFacet.json#/$defs/DocumentationDatasetFacet"
+	jobDocsURL            = "https://openlineage.io/spec/facets/1-0-0/DocumentationJobFacet.json#/$defs/DocumentationJobFacet"
+)
+
+// RunEvent is the state of a run of a job and the datasets it reads and writes
+type RunEvent struct {
+	EventType string    `json:"eventType"`
+	EventTime string    `json:"eventTime"`
+	Producer  string    `json:"producer"`
+	SchemaURL string    `json:"schemaURL"`
+	Run       Run       `json:"run"`
+	Job       Job       `json:"job"`
+	Inputs    []Dataset `json:"inputs"`
+	Outputs   []Dataset `json:"outputs"`
+}
+
+// DatasetEvent is the metadata of a dataset outside of any run
+type DatasetEvent struct {
+	EventTime string  `json:"eventTime"`
+	Producer  string  `json:"producer"`
+	SchemaURL string  `json:"schemaURL"`
+	Dataset   Dataset `json:"dataset"`
+}
+
+type Run struct {
+	RunID  string                 `json:"runId"`
+	Facets map[string]interface{} `json:"facets,omitempty"`
+}
+
+type Job struct {
+	Namespace string                 `json:"namespace"`
+	Name      string                 `json:"name"`
+	Facets    map[string]interface{} `json:"facets,omitempty"`
+}
+
+type Dataset struct {
+	Namespace string                 `json:"namespace"`
+	Name      string                 `json:"name"`
+	Facets    map[string]interface{} `json:"facets,omitempty"`
+}
+
+// Facet holds the fields common to every facet
+type Facet struct {
+	Producer  string `json:"_producer"`
+	SchemaURL string `json:"_schemaURL"`
+}
+
+type SchemaFacet struct {
+	Facet
+	Fields []SchemaField `json:"fields"`
+}
+
+type SchemaField struct {
+	Name        string `json:"name"`
+	Type        string `json:"type,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+type OwnershipFacet struct {
+	Facet
+	Owners []Owner `json:"owners"`
+}
+
+type Owner struct {
+	Name string `json:"name"`
+	Type string `json:"type,omitempty"`
+}
+
+type DocumentationFacet struct {
+	Facet
+	Description string `json:"description"`
+}
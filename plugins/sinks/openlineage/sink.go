@@ -0,0 +1,346 @@
+package openlineage
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/odpf/meteor/models"
+	commonv1beta1 "github.com/odpf/meteor/models/odpf/assets/common/v1beta1"
+	facetsv1beta1 "github.com/odpf/meteor/models/odpf/assets/facets/v1beta1"
+	assetsv1beta1 "github.com/odpf/meteor/models/odpf/assets/v1beta1"
+	"github.com/odpf/meteor/plugins"
+	"github.com/odpf/meteor/registry"
+	"github.com/odpf/meteor/utils"
+	"github.com/odpf/salt/log"
+	"github.com/pkg/errors"
+)
+
+//go:embed README.md
+var summary string
+
+type Config struct {
+	// URL is the lineage endpoint events are posted to, e.g. http://marquez:5000/api/v1/lineage
+	URL    string `mapstructure:"url" validate:"required_without=Path"`
+	APIKey string `mapstructure:"api_key"`
+	// Path is the file events are appended to as JSON lines instead of being posted
+	Path          string            `mapstructure:"path" validate:"required_without=URL"`
+	EventType     string            `mapstructure:"event_type" validate:"oneof=START RUNNING COMPLETE ABORT FAIL OTHER" default:"COMPLETE"`
+	DatasetEvents bool              `mapstructure:"dataset_events" default:"true"`
+	Namespaces    map[string]string `mapstructure:"namespaces"`
+	// DefaultNamespace is the namespace of the urns without service
+	DefaultNamespace string `mapstructure:"default_namespace" default:"meteor"`
+}
+
+var sampleConfig = `
+# OpenLineage endpoint the events are posted to
+url: http://localhost:5000/api/v1/lineage
+api_key: my-api-key
+# File the events are appended to as JSON lines, instead of url
+# path: ./lineage.ndjson
+# Type of the run events of the jobs
+event_type: COMPLETE
+# Send a dataset event with the schema and ownership of every dataset
+dataset_events: true
+# Namespaces of the datasets and jobs of a service, <service>://<host> by default
+namespaces:
+  bigquery: bigquery
+default_namespace: meteor`
+
+type httpClient interface {
+	Do(*http.Request) (*http.Response, error)
+}
+
+type Sink struct {
+	client httpClient
+	config Config
+	logger log.Logger
+	run    plugins.RunInfo
+	file   *os.File
+}
+
+func New(c httpClient, logger log.Logger) plugins.Syncer {
+	return &Sink{client: c, logger: logger}
+}
+
+func (s *Sink) Info() plugins.Info {
+	return plugins.Info{
+		Description:  "Send lineage of jobs and datasets as OpenLineage events",
+		SampleConfig: sampleConfig,
+		Summary:      summary,
+		Tags:         []string{"http", "lineage", "sink"},
+	}
+}
+
+func (s *Sink) Validate(configMap map[string]interface{}) (err error) {
+	return utils.BuildConfig(configMap, &Config{})
+}
+
+func (s *Sink) Init(ctx context.Context, configMap map[string]interface{}) (err error) {
+	if err = utils.BuildConfig(configMap, &s.config); err != nil {
+		return plugins.InvalidConfigError{Type: plugins.PluginTypeSink, PluginName: "openlineage"}
+	}
+	if s.config.URL != "" && s.config.Path != "" {
+		return errors.New("only one of url and path can be set")
+	}
+
+	s.run = plugins.RunInfoFromContext(ctx)
+	if s.run.StartedAt.IsZero() {
+		s.run.StartedAt = time.Now()
+	}
+
+	if s.config.Path != "" {
+		if s.file, err = os.OpenFile(s.config.Path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644); err != nil {
+			return errors.Wrap(err, "failed to open events file")
+		}
+	}
+
+	return
+}
+
+// Sink sends a run event for every job and every asset with upstreams,
+// and a dataset event for every other asset than users and groups if dataset_events is set
+func (s *Sink) Sink(ctx context.Context, batch []models.Record) (err error) {
+	for _, record := range batch {
+		metadata := record.Data()
+		for _, event := range s.buildEvents(metadata) {
+			if err = s.emit(ctx, event); err != nil {
+				return errors.Wrapf(err, "failed to send event of \"%s\"", metadata.GetResource().GetUrn())
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s *Sink) Close() (err error) {
+	if s.file != nil {
+		return s.file.Close()
+	}
+	return
+}
+
+func (s *Sink) buildEvents(metadata models.Metadata) (events []interface{}) {
+	switch asset := metadata.(type) {
+	case *assetsv1beta1.Job:
+		return []interface{}{s.buildJobEvent(asset)}
+	case *assetsv1beta1.User, *assetsv1beta1.Group:
+		return nil
+	}
+
+	dataset := s.buildDataset(metadata)
+	if s.config.DatasetEvents {
+		events = append(events, DatasetEvent{
+			EventTime: s.eventTime(metadata),
+			Producer:  producer,
+			SchemaURL: datasetEventSchemaURL,
+			Dataset:   dataset,
+		})
+	}
+
+	// lineage of datasets, e.g. the tables of a dashboard, is the run of a job named after the dataset
+	lineage := utils.GetLineage(metadata)
+	if len(lineage.GetUpstreams()) > 0 {
+		job := s.newJob(metadata.GetResource().GetUrn())
+		event := s.newRunEvent(job, metadata)
+		event.Inputs = s.datasetRefs(lineage.GetUpstreams())
+		event.Outputs = []Dataset{dataset}
+		events = append(events, event)
+	}
+
+	return events
+}
+
+func (s *Sink) buildJobEvent(asset *assetsv1beta1.Job) RunEvent {
+	resource := asset.GetResource()
+	job := s.newJob(resource.GetUrn())
+	if resource.GetDescription() != "" {
+		job.Facets["documentation"] = DocumentationFacet{
+			Facet:       Facet{Producer: producer, SchemaURL: jobDocsURL},
+			Description: resource.GetDescription(),
+		}
+	}
+	if owners := buildOwners(asset.GetOwnership()); len(owners) > 0 {
+		job.Facets["ownership"] = OwnershipFacet{
+			Facet:  Facet{Producer: producer, SchemaURL: jobOwnershipURL},
+			Owners: owners,
+		}
+	}
+
+	event := s.newRunEvent(job, asset)
+	event.Inputs = s.datasetRefs(asset.GetLineage().GetUpstreams())
+	event.Outputs = s.datasetRefs(asset.GetLineage().GetDownstreams())
+
+	return event
+}
+
+func (s *Sink) newJob(urn string) Job {
+	namespace, name := s.identify(urn)
+	return Job{Namespace: namespace, Name: name, Facets: make(map[string]interface{})}
+}
+
+func (s *Sink) newRunEvent(job Job, metadata models.Metadata) RunEvent {
+	eventTime := s.eventTime(metadata)
+	return RunEvent{
+		EventType: s.config.EventType,
+		EventTime: eventTime,
+		Producer:  producer,
+		SchemaURL: runEventSchemaURL,
+		Run:       Run{RunID: runID(job.Namespace + "/" + job.Name + "@" + eventTime)},
+		Job:       job,
+		Inputs:    []Dataset{},
+		Outputs:   []Dataset{},
+	}
+}
+
+// buildDataset returns the dataset of the asset with its schema, ownership and documentation facets
+func (s *Sink) buildDataset(metadata models.Metadata) Dataset {
+	resource := metadata.GetResource()
+	namespace, name := s.identify(resource.GetUrn())
+	dataset := Dataset{Namespace: namespace, Name: name, Facets: make(map[string]interface{})}
+
+	if table, ok := metadata.(*assetsv1beta1.Table); ok && len(table.GetSchema().GetColumns()) > 0 {
+		facet := SchemaFacet{Facet: Facet{Producer: producer, SchemaURL: schemaFacetURL}}
+		for _, column := range table.GetSchema().GetColumns() {
+			facet.Fields = append(facet.Fields, SchemaField{
+				Name:        column.GetName(),
+				Type:        column.GetDataType(),
+				Description: column.GetDescription(),
+			})
+		}
+		dataset.Facets["schema"] = facet
+	}
+	if owners := buildOwners(utils.GetOwnership(metadata)); len(owners) > 0 {
+		dataset.Facets["ownership"] = OwnershipFacet{
+			Facet:  Facet{Producer: producer, SchemaURL: datasetOwnershipURL},
+			Owners: owners,
+		}
+	}
+	if resource.GetDescription() != "" {
+		dataset.Facets["documentation"] = DocumentationFacet{
+			Facet:       Facet{Producer: producer, SchemaURL: datasetDocsURL},
+			Description: resource.GetDescription(),
+		}
+	}
+
+	return dataset
+}
+
+func (s *Sink) datasetRefs(resources []*commonv1beta1.Resource) []Dataset {
+	datasets := make([]Dataset, 0, len(resources))
+	for _, resource := range resources {
+		namespace, name := s.identify(resource.GetUrn())
+		datasets = append(datasets, Dataset{Namespace: namespace, Name: name})
+	}
+
+	return datasets
+}
+
+// identify returns the namespace and name of an urn such as bigquery::project/dataset/table,
+// the namespace is <service>://<host> and the name the rest of the path joined with dots
+// unless a namespace is configured for the service, then the name is the whole path
+func (s *Sink) identify(urn string) (namespace, name string) {
+	parts := strings.SplitN(urn, "::", 2)
+	if len(parts) != 2 {
+		return s.config.DefaultNamespace, urn
+	}
+	service, path := parts[0], parts[1]
+
+	if namespace, ok := s.config.Namespaces[service]; ok {
+		return namespace, strings.ReplaceAll(path, "/", ".")
+	}
+	segments := strings.SplitN(path, "/", 2)
+	if len(segments) == 1 {
+		return service, path
+	}
+
+	return fmt.Sprintf("%s://%s", service, segments[0]), strings.ReplaceAll(segments[1], "/", ".")
+}
+
+// eventTime returns the time of the event of the asset, or the time the run started at
+func (s *Sink) eventTime(metadata models.Metadata) string {
+	t := s.run.StartedAt
+	if event, ok := metadata.(interface{ GetEvent() *commonv1beta1.Event }); ok && event.GetEvent().GetTimestamp() != nil {
+		t = event.GetEvent().GetTimestamp().AsTime()
+	}
+
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+func (s *Sink) emit(ctx context.Context, event interface{}) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	if s.file != nil {
+		_, err = s.file.Write(append(body, '\n'))
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.config.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.config.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+s.config.APIKey)
+	}
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return plugins.NewRetryError(err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= 200 && res.StatusCode < 300 {
+		return nil
+	}
+
+	bodyBytes, _ := ioutil.ReadAll(res.Body)
+	err = fmt.Errorf("openlineage returns %d: %s", res.StatusCode, string(bodyBytes))
+	if plugins.IsRetryableStatus(res.StatusCode) {
+		return plugins.NewRetryError(err)
+	}
+
+	return err
+}
+
+// buildOwners returns the owners named after their email, or their urn if they have none
+func buildOwners(ownership *facetsv1beta1.Ownership) (owners []Owner) {
+	for _, owner := range ownership.GetOwners() {
+		name := owner.GetEmail()
+		if name == "" {
+			name = owner.GetUrn()
+		}
+		if name == "" {
+			continue
+		}
+		owners = append(owners, Owner{Name: "user:" + name, Type: owner.GetRole()})
+	}
+
+	return owners
+}
+
+// runID returns a name based uuid of key so that the events of the same run share their id
+func runID(key string) string {
+	sum := sha1.Sum([]byte(key))
+	sum[6] = (sum[6] & 0x0f) | 0x50
+	sum[8] = (sum[8] & 0x3f) | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", sum[0:4], sum[4:6], sum[6:8], sum[8:10], sum[10:16])
+}
+
+func init() {
+	if err := registry.Sinks.Register("openlineage", func() plugins.Syncer {
+		return New(&http.Client{}, plugins.GetLog())
+	}); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,186 @@
+package openlineage_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/odpf/meteor/models"
+	commonv1beta1 "github.com/odpf/meteor/models/odpf/assets/common/v1beta1"
+	facetsv1beta1 "github.com/odpf/meteor/models/odpf/assets/facets/v1beta1"
+	assetsv1beta1 "github.com/odpf/meteor/models/odpf/assets/v1beta1"
+	"github.com/odpf/meteor/plugins"
+	"github.com/odpf/meteor/plugins/sinks/openlineage"
+	testUtils "github.com/odpf/meteor/test/utils"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	startedAt = time.Date(2022, 3, 4, 5, 6, 7, 0, time.UTC)
+	job       = &assetsv1beta1.Job{
+		Resource: &commonv1beta1.Resource{Urn: "optimus::scheduler/project.orders_daily", Name: "orders_daily", Service: "optimus", Description: "daily orders"},
+		Ownership: &facetsv1beta1.Ownership{Owners: []*facetsv1beta1.Owner{
+			{Urn: "u1", Email: "jane@example.com", Role: "maintainer"},
+		}},
+		Lineage: &facetsv1beta1.Lineage{
+			Upstreams:   []*commonv1beta1.Resource{{Urn: "bigquery::p/d/orders"}},
+			Downstreams: []*commonv1beta1.Resource{{Urn: "bigquery::p/d/orders_daily"}},
+		},
+	}
+	table = &assetsv1beta1.Table{
+		Resource: &commonv1beta1.Resource{Urn: "postgres::db:5432/shop/orders", Name: "orders", Service: "postgres"},
+		Schema: &facetsv1beta1.Columns{Columns: []*facetsv1beta1.Column{
+			{Name: "id", DataType: "integer", Description: "order id"},
+		}},
+		Ownership: &facetsv1beta1.Ownership{Owners: []*facetsv1beta1.Owner{{Urn: "u2"}}},
+	}
+	dashboard = &assetsv1beta1.Dashboard{
+		Resource: &commonv1beta1.Resource{Urn: "metabase::host/42", Name: "sales", Service: "metabase"},
+		Lineage: &facetsv1beta1.Lineage{
+			Upstreams: []*commonv1beta1.Resource{{Urn: "postgres::db:5432/shop/orders"}},
+		},
+	}
+)
+
+func TestInit(t *testing.T) {
+	t.Run("should return InvalidConfigError without url and path", func(t *testing.T) {
+		err := openlineage.New(http.DefaultClient, testUtils.Logger).Init(context.TODO(), map[string]interface{}{})
+		assert.Equal(t, plugins.InvalidConfigError{Type: plugins.PluginTypeSink, PluginName: "openlineage"}, err)
+	})
+	t.Run("should return error if both url and path are set", func(t *testing.T) {
+		err := openlineage.New(http.DefaultClient, testUtils.Logger).Init(context.TODO(), map[string]interface{}{
+			"url":  "http://localhost:5000/api/v1/lineage",
+			"path": filepath.Join(t.TempDir(), "events.ndjson"),
+		})
+		assert.EqualError(t, err, "only one of url and path can be set")
+	})
+}
+
+func TestSink(t *testing.T) {
+	t.Run("should convert jobs to run events", func(t *testing.T) {
+		events := sinkToFile(t, map[string]interface{}{
+			"namespaces": map[string]string{"bigquery": "bigquery"},
+		}, job)
+
+		require.Len(t, events, 1)
+		var event openlineage.RunEvent
+		require.NoError(t, json.Unmarshal(events[0], &event))
+
+		assert.Equal(t, "COMPLETE", event.EventType)
+		assert.Equal(t, "2022-03-04T05:06:07Z", event.EventTime)
+		assert.Regexp(t, `^[0-9a-f]{8}-[0-9a-f]{4}-5[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`, event.Run.RunID)
+		assert.Equal(t, "optimus://scheduler", event.Job.Namespace)
+		assert.Equal(t, "project.orders_daily", event.Job.Name)
+		assert.Equal(t, []openlineage.Dataset{{Namespace: "bigquery", Name: "p.d.orders"}}, event.Inputs)
+		assert.Equal(t, []openlineage.Dataset{{Namespace: "bigquery", Name: "p.d.orders_daily"}}, event.Outputs)
+		assert.Equal(t, map[string]interface{}{
+			"_producer":  "https://github.com/odpf/meteor",
+			"_schemaURL": "https://openlineage.io/spec/facets/1-0-0/OwnershipJobFacet.json#/$defs/OwnershipJobFacet",
+			"owners":     []interface{}{map[string]interface{}{"name": "user:jane@example.com", "type": "maintainer"}},
+		}, event.Job.Facets["ownership"])
+		assert.Equal(t, "daily orders", event.Job.Facets["documentation"].(map[string]interface{})["description"])
+	})
+	t.Run("should convert datasets to dataset events with schema and ownership", func(t *testing.T) {
+		events := sinkToFile(t, map[string]interface{}{}, table)
+
+		require.Len(t, events, 1)
+		var event openlineage.DatasetEvent
+		require.NoError(t, json.Unmarshal(events[0], &event))
+
+		assert.Equal(t, "postgres://db:5432", event.Dataset.Namespace)
+		assert.Equal(t, "shop.orders", event.Dataset.Name)
+		assert.Equal(t, []interface{}{
+			map[string]interface{}{"name": "id", "type": "integer", "description": "order id"},
+		}, event.Dataset.Facets["schema"].(map[string]interface{})["fields"])
+		assert.Equal(t, []interface{}{
+			map[string]interface{}{"name": "user:u2"},
+		}, event.Dataset.Facets["ownership"].(map[string]interface{})["owners"])
+	})
+	t.Run("should convert lineage of datasets to run events", func(t *testing.T) {
+		events := sinkToFile(t, map[string]interface{}{"dataset_events": false}, dashboard)
+
+		require.Len(t, events, 1)
+		var event openlineage.RunEvent
+		require.NoError(t, json.Unmarshal(events[0], &event))
+
+		assert.Equal(t, openlineage.Job{Namespace: "metabase://host", Name: "42"}, event.Job)
+		assert.Equal(t, []openlineage.Dataset{{Namespace: "postgres://db:5432", Name: "shop.orders"}}, event.Inputs)
+		assert.Equal(t, []openlineage.Dataset{{Namespace: "metabase://host", Name: "42"}}, event.Outputs)
+	})
+	t.Run("should post events", func(t *testing.T) {
+		var (
+			bodies  []map[string]interface{}
+			headers []string
+		)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var body map[string]interface{}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			bodies = append(bodies, body)
+			headers = append(headers, r.Header.Get("Authorization"))
+			w.WriteHeader(http.StatusCreated)
+		}))
+		defer server.Close()
+
+		sink := openlineage.New(http.DefaultClient, testUtils.Logger)
+		require.NoError(t, sink.Init(runContext(), map[string]interface{}{
+			"url":     server.URL + "/api/v1/lineage",
+			"api_key": "secret",
+		}))
+		require.NoError(t, sink.Sink(context.TODO(), []models.Record{models.NewRecord(job), models.NewRecord(dashboard)}))
+
+		require.Len(t, bodies, 3)
+		assert.Equal(t, "COMPLETE", bodies[0]["eventType"])
+		assert.Contains(t, bodies[1], "dataset")
+		assert.Contains(t, bodies[2], "run")
+		assert.Equal(t, []string{"Bearer secret", "Bearer secret", "Bearer secret"}, headers)
+	})
+	t.Run("should return RetryError on server error", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer server.Close()
+
+		sink := openlineage.New(http.DefaultClient, testUtils.Logger)
+		require.NoError(t, sink.Init(runContext(), map[string]interface{}{"url": server.URL}))
+
+		err := sink.Sink(context.TODO(), []models.Record{models.NewRecord(job)})
+		assert.True(t, errors.Is(err, plugins.RetryError{}))
+	})
+}
+
+func runContext() context.Context {
+	return plugins.ContextWithRunInfo(context.TODO(), plugins.RunInfo{RecipeName: "lineage", StartedAt: startedAt})
+}
+
+// sinkToFile sinks the assets to a file and returns its lines
+func sinkToFile(t *testing.T, config map[string]interface{}, assets ...models.Metadata) (events []json.RawMessage) {
+	path := filepath.Join(t.TempDir(), "events.ndjson")
+	config["path"] = path
+	sink := openlineage.New(http.DefaultClient, testUtils.Logger)
+	require.NoError(t, sink.Init(runContext(), config))
+
+	var records []models.Record
+	for _, asset := range assets {
+		records = append(records, models.NewRecord(asset))
+	}
+	require.NoError(t, sink.Sink(context.TODO(), records))
+	require.NoError(t, sink.Close())
+
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		events = append(events, json.RawMessage(append([]byte{}, scanner.Bytes()...)))
+	}
+
+	return events
+}
@@ -0,0 +1,260 @@
+package parquet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/odpf/meteor/plugins/tabular"
+)
+
+var magic = []byte("PAR1")
+
+// Values of the parquet format enums
+const (
+	typeBoolean   = 0
+	typeInt64     = 2
+	typeByteArray = 6
+
+	convertedUTF8            = 0
+	convertedTimestampMillis = 9
+
+	repetitionOptional = 1
+
+	encodingPlain = 0
+	encodingRLE   = 3
+
+	codecUncompressed = 0
+
+	pageData = 0
+)
+
+// columnLayout is the physical layout of a column type
+type columnLayout struct {
+	physical  int32
+	converted int32
+}
+
+var columnLayouts = map[tabular.ColumnType]columnLayout{
+	tabular.String:    {physical: typeByteArray, converted: convertedUTF8},
+	tabular.Int64:     {physical: typeInt64, converted: -1},
+	tabular.Bool:      {physical: typeBoolean, converted: -1},
+	tabular.Timestamp: {physical: typeInt64, converted: convertedTimestampMillis},
+}
+
+// columnChunk is the location of a written column
+type columnChunk struct {
+	name   string
+	layout columnLayout
+	offset int64
+	size   int64
+}
+
+// NewEncoder returns the encoder writing tables as parquet files
+func NewEncoder() tabular.Encoder {
+	return encoder{}
+}
+
+// encoder writes a table as a parquet file with a single row group,
+// every column is optional, plain encoded and uncompressed
+type encoder struct{}
+
+func (encoder) Extension() string {
+	return ".parquet"
+}
+
+func (encoder) Encode(w io.Writer, table tabular.Table, rows []tabular.Row) error {
+	cw := &countingWriter{w: w}
+	if _, err := cw.Write(magic); err != nil {
+		return err
+	}
+
+	chunks := make([]columnChunk, len(table.Columns))
+	for i, column := range table.Columns {
+		layout, ok := columnLayouts[column.Type]
+		if !ok {
+			return fmt.Errorf("unsupported type of column \"%s\"", column.Name)
+		}
+		page, err := encodePage(column, i, rows)
+		if err != nil {
+			return err
+		}
+
+		chunks[i] = columnChunk{
+			name:   column.Name,
+			layout: layout,
+			offset: cw.n,
+			size:   int64(len(page)),
+		}
+		if _, err = cw.Write(page); err != nil {
+			return err
+		}
+	}
+
+	footer := encodeFileMetadata(table, chunks, int64(len(rows)))
+	if _, err := cw.Write(footer); err != nil {
+		return err
+	}
+	if err := binary.Write(cw, binary.LittleEndian, uint32(len(footer))); err != nil {
+		return err
+	}
+	_, err := cw.Write(magic)
+
+	return err
+}
+
+// encodePage returns a data page with the values of the column, page header included
+func encodePage(column tabular.Column, index int, rows []tabular.Row) ([]byte, error) {
+	levels := make([]bool, len(rows))
+	var (
+		values   bytes.Buffer
+		booleans []bool
+	)
+	for r, row := range rows {
+		value := row[index]
+		if value == nil {
+			continue
+		}
+		levels[r] = true
+
+		switch v := value.(type) {
+		case string:
+			binary.Write(&values, binary.LittleEndian, uint32(len(v)))
+			values.WriteString(v)
+		case int64:
+			binary.Write(&values, binary.LittleEndian, v)
+		case time.Time:
+			binary.Write(&values, binary.LittleEndian, v.UnixNano()/int64(time.Millisecond))
+		case bool:
+			booleans = append(booleans, v)
+		default:
+			return nil, fmt.Errorf("unexpected value %v of column \"%s\"", value, column.Name)
+		}
+	}
+	if column.Type == tabular.Bool {
+		values.Write(packBooleans(booleans))
+	}
+
+	definitions := encodeLevels(levels)
+	data := make([]byte, 0, 4+len(definitions)+values.Len())
+	data = append(data, 0, 0, 0, 0)
+	binary.LittleEndian.PutUint32(data, uint32(len(definitions)))
+	data = append(data, definitions...)
+	data = append(data, values.Bytes()...)
+
+	var header thriftWriter
+	header.i32(1, pageData)
+	header.i32(2, int32(len(data)))
+	header.i32(3, int32(len(data)))
+	header.structField(5)
+	header.i32(1, int32(len(rows)))
+	header.i32(2, encodingPlain)
+	header.i32(3, encodingRLE)
+	header.i32(4, encodingRLE)
+	header.endStruct()
+	header.endStruct()
+
+	return append(header.Bytes(), data...), nil
+}
+
+// encodeLevels encodes the definition levels of an optional column with the RLE hybrid encoding,
+// a level is true if the value is defined
+func encodeLevels(levels []bool) []byte {
+	var w thriftWriter
+	for i := 0; i < len(levels); {
+		j := i
+		for j < len(levels) && levels[j] == levels[i] {
+			j++
+		}
+		w.uvarint(uint64(j-i) << 1)
+		if levels[i] {
+			w.buf.WriteByte(1)
+		} else {
+			w.buf.WriteByte(0)
+		}
+		i = j
+	}
+
+	return w.Bytes()
+}
+
+// packBooleans bit packs the values starting with the least significant bit
+func packBooleans(values []bool) []byte {
+	b := make([]byte, (len(values)+7)/8)
+	for i, v := range values {
+		if v {
+			b[i/8] |= 1 << (i % 8)
+		}
+	}
+	return b
+}
+
+func encodeFileMetadata(table tabular.Table, chunks []columnChunk, numRows int64) []byte {
+	var w thriftWriter
+	w.i32(1, 1)
+
+	w.list(2, thriftStruct, len(table.Columns)+1)
+	w.beginStruct()
+	w.string(4, "schema")
+	w.i32(5, int32(len(table.Columns)))
+	w.endStruct()
+	for _, chunk := range chunks {
+		w.beginStruct()
+		w.i32(1, chunk.layout.physical)
+		w.i32(3, repetitionOptional)
+		w.string(4, chunk.name)
+		if chunk.layout.converted >= 0 {
+			w.i32(6, chunk.layout.converted)
+		}
+		w.endStruct()
+	}
+
+	w.i64(3, numRows)
+
+	var totalSize int64
+	for _, chunk := range chunks {
+		totalSize += chunk.size
+	}
+	w.list(4, thriftStruct, 1)
+	w.beginStruct()
+	w.list(1, thriftStruct, len(chunks))
+	for _, chunk := range chunks {
+		w.beginStruct()
+		w.i64(2, chunk.offset)
+		w.structField(3)
+		w.i32(1, chunk.layout.physical)
+		w.list(2, thriftI32, 2)
+		w.varint(encodingPlain)
+		w.varint(encodingRLE)
+		w.list(3, thriftBinary, 1)
+		w.stringValue(chunk.name)
+		w.i32(4, codecUncompressed)
+		w.i64(5, numRows)
+		w.i64(6, chunk.size)
+		w.i64(7, chunk.size)
+		w.i64(9, chunk.offset)
+		w.endStruct()
+		w.endStruct()
+	}
+	w.i64(2, totalSize)
+	w.i64(3, numRows)
+	w.endStruct()
+
+	w.string(6, "meteor")
+	w.endStruct()
+
+	return w.Bytes()
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(b []byte) (int, error) {
+	n, err := c.w.Write(b)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,130 @@
+package parquet_test
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/odpf/meteor/models"
+	commonv1beta1 "github.com/odpf/meteor/models/odpf/assets/common/v1beta1"
+	facetsv1beta1 "github.com/odpf/meteor/models/odpf/assets/facets/v1beta1"
+	assetsv1beta1 "github.com/odpf/meteor/models/odpf/assets/v1beta1"
+	"github.com/odpf/meteor/plugins"
+	"github.com/odpf/meteor/plugins/sinks/parquet"
+	testUtils "github.com/odpf/meteor/test/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xitongsys/parquet-go-source/local"
+	"github.com/xitongsys/parquet-go/reader"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestInit(t *testing.T) {
+	t.Run("should return InvalidConfigError on invalid config", func(t *testing.T) {
+		err := parquet.New(testUtils.Logger).Init(context.TODO(), map[string]interface{}{})
+		assert.Equal(t, plugins.InvalidConfigError{Type: "sink", PluginName: "parquet"}, err)
+	})
+}
+
+func TestSink(t *testing.T) {
+	dir := t.TempDir()
+	ctx := plugins.ContextWithRunInfo(context.TODO(), plugins.RunInfo{
+		RecipeName: "sample",
+		StartedAt:  time.Date(2022, 1, 2, 15, 4, 5, 0, time.UTC),
+	})
+
+	sink := parquet.New(testUtils.Logger)
+	require.NoError(t, sink.Init(ctx, map[string]interface{}{
+		"path": dir,
+	}))
+	require.NoError(t, sink.Sink(ctx, []models.Record{
+		models.NewRecord(&assetsv1beta1.Table{
+			Resource: &commonv1beta1.Resource{Urn: "bigquery::p/d/orders", Name: "orders", Service: "bigquery", Type: "table"},
+			Schema: &facetsv1beta1.Columns{Columns: []*facetsv1beta1.Column{
+				{Name: "id", DataType: "INT64"},
+				{Name: "name", DataType: "STRING", IsNullable: true, Description: "customer name"},
+			}},
+			Timestamps: &commonv1beta1.Timestamp{
+				CreateTime: timestamppb.New(time.Date(2021, 5, 6, 7, 8, 9, 0, time.UTC)),
+			},
+		}),
+	}))
+	require.NoError(t, sink.Close())
+
+	partition := filepath.Join("recipe=sample", "date=2022-01-02", "part-150405-1.parquet")
+
+	schema, rows := readParquet(t, filepath.Join(dir, "tables", partition))
+	assert.Equal(t, []string{
+		"urn BYTE_ARRAY UTF8",
+		"name BYTE_ARRAY UTF8",
+		"service BYTE_ARRAY UTF8",
+		"type BYTE_ARRAY UTF8",
+		"url BYTE_ARRAY UTF8",
+		"description BYTE_ARRAY UTF8",
+		"owners BYTE_ARRAY UTF8",
+		"tags BYTE_ARRAY UTF8",
+		"labels BYTE_ARRAY UTF8",
+		"attributes BYTE_ARRAY UTF8",
+		"create_time INT64 TIMESTAMP_MILLIS",
+		"update_time INT64 TIMESTAMP_MILLIS",
+		"total_rows INT64",
+		"partition_key BYTE_ARRAY UTF8",
+		"partition_value BYTE_ARRAY UTF8",
+		"usage_count INT64",
+		"column_count INT64",
+	}, schema)
+	assert.JSONEq(t, `[{
+		"Urn": "bigquery::p/d/orders", "Name": "orders", "Service": "bigquery", "Type": "table",
+		"Url": null, "Description": null, "Owners": null, "Tags": null, "Labels": null, "Attributes": null,
+		"Create_time": 1620284889000, "Update_time": null,
+		"Total_rows": null, "Partition_key": null, "Partition_value": null, "Usage_count": null, "Column_count": 2
+	}]`, rows)
+
+	schema, rows = readParquet(t, filepath.Join(dir, "columns", partition))
+	assert.Equal(t, []string{
+		"asset_urn BYTE_ARRAY UTF8",
+		"position INT64",
+		"name BYTE_ARRAY UTF8",
+		"data_type BYTE_ARRAY UTF8",
+		"description BYTE_ARRAY UTF8",
+		"is_nullable BOOLEAN",
+		"length INT64",
+	}, schema)
+	assert.JSONEq(t, `[
+		{"Asset_urn": "bigquery::p/d/orders", "Position": 1, "Name": "id", "Data_type": "INT64", "Description": null, "Is_nullable": false, "Length": null},
+		{"Asset_urn": "bigquery::p/d/orders", "Position": 2, "Name": "name", "Data_type": "STRING", "Description": "customer name", "Is_nullable": true, "Length": null}
+	]`, rows)
+
+	assert.NoDirExists(t, filepath.Join(dir, "lineage"))
+}
+
+// readParquet decodes the file with an independent reader, it returns the optional columns of the schema
+// as "<name> <physical type> [converted type]" and the rows in JSON with nulls
+func readParquet(t *testing.T, path string) (schema []string, rows string) {
+	file, err := local.NewLocalFileReader(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	pr, err := reader.NewParquetReader(file, nil, 1)
+	require.NoError(t, err)
+	defer pr.ReadStop()
+
+	for i, element := range pr.Footer.Schema[1:] {
+		require.Equal(t, "OPTIONAL", element.GetRepetitionType().String())
+		column := []string{pr.SchemaHandler.GetExName(i + 1), element.GetType().String()}
+		if element.IsSetConvertedType() {
+			column = append(column, element.GetConvertedType().String())
+		}
+		schema = append(schema, strings.Join(column, " "))
+	}
+
+	values, err := pr.ReadByNumber(int(pr.GetNumRows()))
+	require.NoError(t, err)
+	b, err := json.Marshal(values)
+	require.NoError(t, err)
+
+	return schema, string(b)
+}
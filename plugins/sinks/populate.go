@@ -3,7 +3,19 @@ package sinks
 import (
 	_ "github.com/odpf/meteor/plugins/sinks/compass"
 	_ "github.com/odpf/meteor/plugins/sinks/console"
+	_ "github.com/odpf/meteor/plugins/sinks/csv"
+	_ "github.com/odpf/meteor/plugins/sinks/datahub"
+	_ "github.com/odpf/meteor/plugins/sinks/elasticsearch"
 	_ "github.com/odpf/meteor/plugins/sinks/file"
+	_ "github.com/odpf/meteor/plugins/sinks/gcs"
+	_ "github.com/odpf/meteor/plugins/sinks/graph"
+	_ "github.com/odpf/meteor/plugins/sinks/http"
 	_ "github.com/odpf/meteor/plugins/sinks/kafka"
+	_ "github.com/odpf/meteor/plugins/sinks/nats"
+	_ "github.com/odpf/meteor/plugins/sinks/openlineage"
+	_ "github.com/odpf/meteor/plugins/sinks/parquet"
+	_ "github.com/odpf/meteor/plugins/sinks/redis"
+	_ "github.com/odpf/meteor/plugins/sinks/s3"
+	_ "github.com/odpf/meteor/plugins/sinks/sql"
 	_ "github.com/odpf/meteor/plugins/sinks/stencil"
 )
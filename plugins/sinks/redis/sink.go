@@ -0,0 +1,260 @@
+package redis
+
+import (
+	"context"
+	_ "embed"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/odpf/meteor/models"
+	"github.com/odpf/meteor/plugins"
+	"github.com/odpf/meteor/registry"
+	"github.com/odpf/meteor/utils"
+	"github.com/odpf/salt/log"
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+//go:embed README.md
+var summary string
+
+// Value encodings supported by the sink
+const (
+	EncodingProtobuf = "protobuf"
+	EncodingJSON     = "json"
+)
+
+// Fields of every stream entry
+const (
+	FieldValue      = "value"
+	FieldURN        = "urn"
+	FieldAssetType  = "asset_type"
+	FieldRecipeName = "recipe_name"
+)
+
+type Config struct {
+	Address  string            `mapstructure:"address" validate:"required"`
+	Stream   string            `mapstructure:"stream" validate:"required_without=Streams"`
+	Streams  map[string]string `mapstructure:"streams"`
+	Encoding string            `mapstructure:"encoding" validate:"oneof=protobuf json" default:"protobuf"`
+	// MaxLen trims the streams to about this many entries, streams are not trimmed if zero
+	MaxLen         int             `mapstructure:"max_len" validate:"min=0"`
+	Username       string          `mapstructure:"username"`
+	Password       string          `mapstructure:"password" validate:"required_with=Username"`
+	DB             int             `mapstructure:"db" validate:"min=0"`
+	TimeoutSeconds int             `mapstructure:"timeout_seconds" validate:"min=1" default:"10"`
+	TLS            utils.TLSConfig `mapstructure:"tls"`
+}
+
+var sampleConfig = `
+ # Redis server address
+ address: "localhost:6379"
+ # The stream to add entries to
+ stream: meteor:assets
+ # Optional streams per asset type, records of other types are added to stream, or skipped if stream is not set
+ streams:
+   table: meteor:tables
+   dashboard: meteor:dashboards
+ # Encoding of the value field, one of protobuf or json
+ encoding: protobuf
+ # Trim the streams to about this many entries
+ max_len: 100000
+ # ACL username and password, or only password for requirepass
+ username: meteor
+ password: secret
+ # Logical database
+ db: 0
+ # Time to wait for a batch to be added
+ timeout_seconds: 10
+ # TLS connection to the server
+ tls:
+   enabled: true
+   ca_file: /etc/meteor/ca.pem`
+
+// retryableReplies are the prefixes of the error replies of servers which are temporarily unable to serve commands
+var retryableReplies = []string{"LOADING", "BUSY", "TRYAGAIN", "MASTERDOWN", "CLUSTERDOWN", "OOM", "READONLY"}
+
+type Sink struct {
+	logger log.Logger
+	config Config
+	client *redis.Client
+}
+
+func New(logger log.Logger) plugins.Syncer {
+	return &Sink{logger: logger}
+}
+
+func (s *Sink) Info() plugins.Info {
+	return plugins.Info{
+		Description:  "Add metadata to Redis streams",
+		Summary:      summary,
+		SampleConfig: sampleConfig,
+		Tags:         []string{"redis", "stream", "sink"},
+	}
+}
+
+func (s *Sink) Validate(configMap map[string]interface{}) (err error) {
+	return utils.BuildConfig(configMap, &Config{})
+}
+
+func (s *Sink) Init(ctx context.Context, configMap map[string]interface{}) (err error) {
+	if err = utils.BuildConfig(configMap, &s.config); err != nil {
+		return plugins.InvalidConfigError{Type: plugins.PluginTypeSink, PluginName: "redis"}
+	}
+	tlsConfig, err := s.config.TLS.Build()
+	if err != nil {
+		return errors.Wrap(err, "failed to build tls config")
+	}
+
+	timeout := time.Duration(s.config.TimeoutSeconds) * time.Second
+	s.client = redis.NewClient(&redis.Options{
+		Addr:         s.config.Address,
+		Username:     s.config.Username,
+		Password:     s.config.Password,
+		DB:           s.config.DB,
+		TLSConfig:    tlsConfig,
+		DialTimeout:  timeout,
+		ReadTimeout:  timeout,
+		WriteTimeout: timeout,
+		// failed batches are retried by the agent
+		MaxRetries: -1,
+	})
+	if err = s.client.Ping(ctx).Err(); err != nil {
+		s.client.Close()
+		return errors.Wrap(err, "failed to connect")
+	}
+
+	return
+}
+
+// Sink adds every record of the batch with XADD, the commands of a batch are sent in a MULTI/EXEC transaction
+// so that the server adds the entries of a batch all at once or none of them. Records of asset types without a stream are skipped.
+func (s *Sink) Sink(ctx context.Context, batch []models.Record) (err error) {
+	recipeName := plugins.RunInfoFromContext(ctx).RecipeName
+
+	args := make([]*redis.XAddArgs, 0, len(batch))
+	for _, record := range batch {
+		data := record.Data()
+		assetType := utils.GetAssetType(data)
+		stream := s.stream(assetType)
+		if stream == "" {
+			s.logger.Warn("skipping record without stream for its asset type", "urn", data.GetResource().GetUrn(), "type", assetType)
+			continue
+		}
+		arg, err := s.buildArgs(data, assetType, stream, recipeName)
+		if err != nil {
+			return err
+		}
+		args = append(args, arg)
+	}
+	if len(args) == 0 {
+		return nil
+	}
+
+	pipe := s.client.TxPipeline()
+	cmds := make([]*redis.StringCmd, len(args))
+	for i, arg := range args {
+		cmds[i] = pipe.XAdd(ctx, arg)
+	}
+	// the error of the first failed command is returned, every command is checked to tell replies from connection failures
+	if _, err = pipe.Exec(ctx); err == nil {
+		return nil
+	}
+	for i, cmd := range cmds {
+		if cmd.Err() == nil {
+			continue
+		}
+		err = errors.Wrapf(cmd.Err(), "failed to add entry to \"%s\"", args[i].Stream)
+		var replyErr redis.Error
+		if errors.As(err, &replyErr) && !isTemporary(replyErr) {
+			return err
+		}
+		return plugins.NewRetryError(err)
+	}
+
+	return plugins.NewRetryError(errors.Wrap(err, "failed to add entries"))
+}
+
+func (s *Sink) Close() (err error) {
+	if s.client == nil {
+		return nil
+	}
+	err = s.client.Close()
+	s.client = nil
+
+	return err
+}
+
+// buildArgs builds the XADD of the record with an id generated by the server
+func (s *Sink) buildArgs(payload models.Metadata, assetType, stream, recipeName string) (*redis.XAddArgs, error) {
+	value, err := s.buildValue(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	values := []interface{}{
+		FieldURN, payload.GetResource().GetUrn(),
+		FieldAssetType, assetType,
+	}
+	if recipeName != "" {
+		values = append(values, FieldRecipeName, recipeName)
+	}
+
+	return &redis.XAddArgs{
+		Stream: stream,
+		MaxLen: int64(s.config.MaxLen),
+		Approx: true,
+		ID:     "*",
+		Values: append(values, FieldValue, string(value)),
+	}, nil
+}
+
+// stream returns the stream configured for the asset type, falling back to the default stream which may be empty
+func (s *Sink) stream(assetType string) string {
+	if stream, ok := s.config.Streams[assetType]; ok {
+		return stream
+	}
+
+	return s.config.Stream
+}
+
+func (s *Sink) buildValue(value interface{}) ([]byte, error) {
+	msg, ok := value.(proto.Message)
+	if !ok {
+		return nil, errors.New("not a valid proto payload")
+	}
+
+	if s.config.Encoding == EncodingJSON {
+		jsonBytes, err := protojson.MarshalOptions{UseProtoNames: true}.Marshal(msg)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to serialize payload as json")
+		}
+		return jsonBytes, nil
+	}
+
+	protoBytes, err := proto.Marshal(msg)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to serialize payload as a protobuf message")
+	}
+	return protoBytes, nil
+}
+
+// isTemporary returns true for replies of servers which are loading, busy or failing over
+func isTemporary(err redis.Error) bool {
+	for _, prefix := range retryableReplies {
+		if strings.HasPrefix(err.Error(), prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func init() {
+	if err := registry.Sinks.Register("redis", func() plugins.Syncer {
+		return New(plugins.GetLog())
+	}); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,165 @@
+package redis_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/odpf/meteor/models"
+	commonv1beta1 "github.com/odpf/meteor/models/odpf/assets/common/v1beta1"
+	assetsv1beta1 "github.com/odpf/meteor/models/odpf/assets/v1beta1"
+	"github.com/odpf/meteor/plugins"
+	"github.com/odpf/meteor/plugins/sinks/redis"
+	testUtils "github.com/odpf/meteor/test/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+)
+
+var (
+	table = &assetsv1beta1.Table{
+		Resource: &commonv1beta1.Resource{Urn: "postgres::db/shop/orders", Name: "orders", Service: "postgres"},
+	}
+	topic = &assetsv1beta1.Topic{
+		Resource: &commonv1beta1.Resource{Urn: "kafka::broker/orders", Name: "orders", Service: "kafka"},
+	}
+)
+
+func TestInit(t *testing.T) {
+	t.Run("should return InvalidConfigError without stream", func(t *testing.T) {
+		err := redis.New(testUtils.Logger).Init(context.TODO(), map[string]interface{}{
+			"address": "localhost:6379",
+		})
+		assert.Equal(t, plugins.InvalidConfigError{Type: plugins.PluginTypeSink, PluginName: "redis"}, err)
+	})
+	t.Run("should return error if password is rejected", func(t *testing.T) {
+		server := miniredis.RunT(t)
+		server.RequireAuth("secret")
+		err := redis.New(testUtils.Logger).Init(context.TODO(), map[string]interface{}{
+			"address":  server.Addr(),
+			"stream":   "meteor:assets",
+			"password": "wrong",
+		})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to connect: WRONGPASS")
+	})
+}
+
+func TestSink(t *testing.T) {
+	t.Run("should add records to the streams of their types", func(t *testing.T) {
+		server := miniredis.RunT(t)
+		server.RequireUserAuth("meteor", "secret")
+		sink := redis.New(testUtils.Logger)
+		require.NoError(t, sink.Init(context.TODO(), map[string]interface{}{
+			"address":  server.Addr(),
+			"stream":   "meteor:assets",
+			"streams":  map[string]string{"topic": "meteor:topics"},
+			"max_len":  1000,
+			"username": "meteor",
+			"password": "secret",
+			"db":       2,
+		}))
+		defer sink.Close()
+
+		ctx := plugins.ContextWithRunInfo(context.TODO(), plugins.RunInfo{RecipeName: "sample"})
+		require.NoError(t, sink.Sink(ctx, []models.Record{models.NewRecord(table), models.NewRecord(topic)}))
+
+		entries, err := server.DB(2).Stream("meteor:assets")
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+		values := entries[0].Values
+		assert.Equal(t, []string{
+			"urn", "postgres::db/shop/orders",
+			"asset_type", "table",
+			"recipe_name", "sample",
+			"value",
+		}, values[:len(values)-1])
+		actual := new(assetsv1beta1.Table)
+		require.NoError(t, proto.Unmarshal([]byte(values[len(values)-1]), actual))
+		assert.True(t, proto.Equal(table, actual))
+
+		entries, err = server.DB(2).Stream("meteor:topics")
+		require.NoError(t, err)
+		assert.Len(t, entries, 1)
+	})
+	t.Run("should skip records of asset types without stream", func(t *testing.T) {
+		server := miniredis.RunT(t)
+		sink := redis.New(testUtils.Logger)
+		require.NoError(t, sink.Init(context.TODO(), map[string]interface{}{
+			"address": server.Addr(),
+			"streams": map[string]string{"topic": "meteor:topics"},
+		}))
+		defer sink.Close()
+
+		require.NoError(t, sink.Sink(context.TODO(), []models.Record{models.NewRecord(table), models.NewRecord(topic)}))
+		require.NoError(t, sink.Sink(context.TODO(), []models.Record{models.NewRecord(table)}))
+
+		assert.Equal(t, []string{"meteor:topics"}, server.Keys())
+		entries, err := server.Stream("meteor:topics")
+		require.NoError(t, err)
+		assert.Len(t, entries, 1)
+	})
+	t.Run("should trim streams to about max_len entries", func(t *testing.T) {
+		server := miniredis.RunT(t)
+		sink := redis.New(testUtils.Logger)
+		require.NoError(t, sink.Init(context.TODO(), map[string]interface{}{
+			"address": server.Addr(),
+			"stream":  "meteor:assets",
+			"max_len": 2,
+		}))
+		defer sink.Close()
+
+		records := []models.Record{models.NewRecord(table), models.NewRecord(table), models.NewRecord(table)}
+		require.NoError(t, sink.Sink(context.TODO(), records))
+
+		entries, err := server.Stream("meteor:assets")
+		require.NoError(t, err)
+		assert.Len(t, entries, 2)
+	})
+	t.Run("should return RetryError if the server is loading", func(t *testing.T) {
+		server := miniredis.RunT(t)
+		sink := redis.New(testUtils.Logger)
+		require.NoError(t, sink.Init(context.TODO(), map[string]interface{}{
+			"address":  server.Addr(),
+			"stream":   "meteor:assets",
+			"encoding": "json",
+		}))
+		defer sink.Close()
+
+		server.SetError("LOADING Redis is loading the dataset in memory")
+		err := sink.Sink(context.TODO(), []models.Record{models.NewRecord(table)})
+		assert.True(t, errors.Is(err, plugins.RetryError{}))
+	})
+	t.Run("should return error if the key is not a stream", func(t *testing.T) {
+		server := miniredis.RunT(t)
+		require.NoError(t, server.Set("meteor:assets", "orders"))
+		sink := redis.New(testUtils.Logger)
+		require.NoError(t, sink.Init(context.TODO(), map[string]interface{}{
+			"address": server.Addr(),
+			"stream":  "meteor:assets",
+		}))
+		defer sink.Close()
+
+		err := sink.Sink(context.TODO(), []models.Record{models.NewRecord(table)})
+		require.Error(t, err)
+		assert.False(t, errors.Is(err, plugins.RetryError{}))
+		assert.Contains(t, err.Error(), "failed to add entry to \"meteor:assets\": WRONGTYPE")
+	})
+	t.Run("should connect again after connection failures", func(t *testing.T) {
+		server := miniredis.RunT(t)
+		sink := redis.New(testUtils.Logger)
+		require.NoError(t, sink.Init(context.TODO(), map[string]interface{}{
+			"address": server.Addr(),
+			"stream":  "meteor:assets",
+		}))
+		defer sink.Close()
+
+		server.Close()
+		err := sink.Sink(context.TODO(), []models.Record{models.NewRecord(table)})
+		assert.True(t, errors.Is(err, plugins.RetryError{}))
+
+		require.NoError(t, server.Restart())
+		assert.NoError(t, sink.Sink(context.TODO(), []models.Record{models.NewRecord(table)}))
+	})
+}
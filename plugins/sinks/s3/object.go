@@ -0,0 +1,50 @@
+package s3
+
+import (
+	"context"
+	"io"
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+// object is an object of the run buffered to a temporary file, it is uploaded once closed
+type object struct {
+	sink *Sink
+	key  string
+	file *os.File
+}
+
+// openObject buffers the object of the key to a new temporary file
+func (s *Sink) openObject(key, _ string) (io.WriteCloser, error) {
+	file, err := os.CreateTemp("", "meteor-s3-*")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create temporary file")
+	}
+	o := &object{sink: s, key: key, file: file}
+	s.objects = append(s.objects, o)
+
+	return o, nil
+}
+
+func (o *object) Write(b []byte) (int, error) {
+	return o.file.Write(b)
+}
+
+// Close rewinds the temporary file and uploads it
+func (o *object) Close() error {
+	if _, err := o.file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if err := o.sink.upload(context.Background(), o.key, o.file, o.sink.config.ContentType(), o.sink.config.ContentEncoding()); err != nil {
+		return errors.Wrap(err, "failed to upload")
+	}
+
+	return nil
+}
+
+// remove closes and removes the temporary file
+func (o *object) remove() {
+	o.file.Close()
+	os.Remove(o.file.Name())
+}
@@ -0,0 +1,243 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"encoding/json"
+	"io"
+	"text/template"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"github.com/odpf/meteor/models"
+	"github.com/odpf/meteor/plugins"
+	"github.com/odpf/meteor/plugins/objectutil"
+	"github.com/odpf/meteor/registry"
+	"github.com/odpf/meteor/utils"
+	"github.com/odpf/salt/log"
+	"github.com/pkg/errors"
+)
+
+//go:embed README.md
+var summary string
+
+const sseKMS = "aws:kms"
+
+type Config struct {
+	objectutil.Config `mapstructure:",squash"`
+	Bucket            string `mapstructure:"bucket" validate:"required"`
+	// Key is the template of the object keys, {{ .Recipe }}/{{ .Date }}/{{ .Type }} with the extension of the format by default
+	Key    string `mapstructure:"key"`
+	Region string `mapstructure:"region" default:"us-east-1"`
+	// Endpoint is the url of S3 compatible stores, e.g. http://localhost:9000 for MinIO
+	Endpoint        string `mapstructure:"endpoint"`
+	ForcePathStyle  bool   `mapstructure:"force_path_style"`
+	AccessKeyID     string `mapstructure:"access_key_id" validate:"required_with=SecretAccessKey"`
+	SecretAccessKey string `mapstructure:"secret_access_key" validate:"required_with=AccessKeyID"`
+	SessionToken    string `mapstructure:"session_token"`
+	// ServerSideEncryption is AES256 for keys managed by S3 or aws:kms for keys managed by KMS
+	ServerSideEncryption string `mapstructure:"server_side_encryption" validate:"omitempty,oneof=AES256 aws:kms"`
+	KMSKeyID             string `mapstructure:"kms_key_id"`
+	// PartSize is the size of the parts of multipart uploads, larger objects are uploaded in parts
+	PartSize    int64  `mapstructure:"part_size" validate:"min=5242880" default:"5242880"`
+	Concurrency int    `mapstructure:"concurrency" validate:"min=1" default:"5"`
+	Manifest    bool   `mapstructure:"manifest" default:"true"`
+	ManifestKey string `mapstructure:"manifest_key" default:"{{ .Recipe }}/{{ .Date }}/manifest.json"`
+}
+
+var sampleConfig = `
+bucket: meteor-archive
+# Template of the object keys, with .Recipe, .Type, .Date and .Time
+key: "{{ .Recipe }}/{{ .Date }}/{{ .Type }}.ndjson"
+# One of ndjson or parquet
+format: ndjson
+# Compression of ndjson objects, one of none or gzip
+compression: gzip
+region: us-east-1
+# Endpoint of S3 compatible stores, such as MinIO
+endpoint: http://localhost:9000
+force_path_style: true
+access_key_id: my-access-key
+secret_access_key: my-secret-key
+# One of AES256 or aws:kms
+server_side_encryption: aws:kms
+kms_key_id: my-kms-key
+# Objects larger than this are uploaded in parts
+part_size: 5242880
+# Upload an object listing the objects of the run once they are uploaded
+manifest: true
+manifest_key: "{{ .Recipe }}/{{ .Date }}/manifest.json"`
+
+type Sink struct {
+	logger       log.Logger
+	config       Config
+	uploader     *s3manager.Uploader
+	manifestTmpl *template.Template
+	run          plugins.RunInfo
+	writer       *objectutil.Writer
+	objects      []*object
+}
+
+func New(logger log.Logger) plugins.Syncer {
+	return &Sink{logger: logger}
+}
+
+func (s *Sink) Info() plugins.Info {
+	return plugins.Info{
+		Description:  "Archive the output of runs to S3 compatible object stores",
+		SampleConfig: sampleConfig,
+		Summary:      summary,
+		Tags:         []string{"s3", "object storage", "sink"},
+	}
+}
+
+func (s *Sink) Validate(configMap map[string]interface{}) (err error) {
+	return utils.BuildConfig(configMap, &Config{})
+}
+
+func (s *Sink) Init(ctx context.Context, configMap map[string]interface{}) (err error) {
+	if err = utils.BuildConfig(configMap, &s.config); err != nil {
+		return plugins.InvalidConfigError{Type: plugins.PluginTypeSink, PluginName: "s3"}
+	}
+	if err = s.config.Validate(); err != nil {
+		return err
+	}
+	if s.config.KMSKeyID != "" && s.config.ServerSideEncryption != sseKMS {
+		return errors.New("kms_key_id requires server_side_encryption to be aws:kms")
+	}
+
+	keyTmpl, err := template.New("key").Option("missingkey=error").Parse(s.config.NameTemplate(s.config.Key))
+	if err != nil {
+		return errors.Wrap(err, "invalid key template")
+	}
+	if s.manifestTmpl, err = template.New("manifest_key").Option("missingkey=error").Parse(s.config.ManifestKey); err != nil {
+		return errors.Wrap(err, "invalid manifest_key template")
+	}
+
+	awsConfig := aws.NewConfig().
+		WithRegion(s.config.Region).
+		WithS3ForcePathStyle(s.config.ForcePathStyle)
+	if s.config.Endpoint != "" {
+		awsConfig = awsConfig.WithEndpoint(s.config.Endpoint)
+	}
+	if s.config.AccessKeyID != "" {
+		awsConfig = awsConfig.WithCredentials(credentials.NewStaticCredentials(
+			s.config.AccessKeyID, s.config.SecretAccessKey, s.config.SessionToken))
+	}
+	sess, err := session.NewSession(awsConfig)
+	if err != nil {
+		return errors.Wrap(err, "failed to create aws session")
+	}
+	s.uploader = s3manager.NewUploaderWithClient(s3.New(sess), func(u *s3manager.Uploader) {
+		u.PartSize = s.config.PartSize
+		u.Concurrency = s.config.Concurrency
+	})
+
+	s.run = plugins.RunInfoFromContext(ctx)
+	if s.run.StartedAt.IsZero() {
+		s.run.StartedAt = time.Now()
+	}
+	s.writer = objectutil.NewWriter(s.config.Config, keyTmpl, s.run, s.openObject, s.logger)
+
+	return
+}
+
+// Sink buffers the records to the objects of their keys, the objects are uploaded once the run is done
+func (s *Sink) Sink(ctx context.Context, batch []models.Record) (err error) {
+	return s.writer.Write(batch)
+}
+
+// Close uploads the objects, and the manifest once every object is uploaded
+func (s *Sink) Close() (err error) {
+	if s.writer == nil {
+		return nil
+	}
+	defer func() {
+		for _, o := range s.objects {
+			o.remove()
+		}
+		s.objects = nil
+	}()
+
+	objects, err := s.writer.Close()
+	if err != nil {
+		return err
+	}
+	if !s.config.Manifest {
+		return nil
+	}
+
+	m := manifest{
+		Recipe:    s.run.RecipeName,
+		StartedAt: s.run.StartedAt.UTC().Format(time.RFC3339),
+		Format:    s.config.Format,
+		Objects:   []manifestObject{},
+	}
+	for _, o := range objects {
+		m.Objects = append(m.Objects, manifestObject{Key: o.Name, Records: o.Records, Size: o.Size})
+		m.Records += o.Records
+	}
+	manifestKey, err := objectutil.Render(s.manifestTmpl, s.run, "")
+	if err != nil {
+		return errors.Wrap(err, "failed to build manifest key")
+	}
+	b, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err = s.upload(context.Background(), manifestKey, bytes.NewReader(b), "application/json", ""); err != nil {
+		return errors.Wrapf(err, "failed to upload manifest \"%s\"", manifestKey)
+	}
+
+	return nil
+}
+
+// manifest lists the objects uploaded by a run
+type manifest struct {
+	Recipe    string           `json:"recipe"`
+	StartedAt string           `json:"started_at"`
+	Format    string           `json:"format"`
+	Records   int              `json:"records"`
+	Objects   []manifestObject `json:"objects"`
+}
+
+type manifestObject struct {
+	Key     string `json:"key"`
+	Records int    `json:"records"`
+	Size    int64  `json:"size"`
+}
+
+// upload uploads the body in parts if it is larger than the part size
+func (s *Sink) upload(ctx context.Context, key string, body io.ReadSeeker, contentType, contentEncoding string) error {
+	input := &s3manager.UploadInput{
+		Bucket:      aws.String(s.config.Bucket),
+		Key:         aws.String(key),
+		Body:        body,
+		ContentType: aws.String(contentType),
+	}
+	if contentEncoding != "" {
+		input.ContentEncoding = aws.String(contentEncoding)
+	}
+	if s.config.ServerSideEncryption != "" {
+		input.ServerSideEncryption = aws.String(s.config.ServerSideEncryption)
+	}
+	if s.config.KMSKeyID != "" {
+		input.SSEKMSKeyId = aws.String(s.config.KMSKeyID)
+	}
+
+	_, err := s.uploader.UploadWithContext(ctx, input)
+	return err
+}
+
+func init() {
+	if err := registry.Sinks.Register("s3", func() plugins.Syncer {
+		return New(plugins.GetLog())
+	}); err != nil {
+		panic(err)
+	}
+}
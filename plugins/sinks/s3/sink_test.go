@@ -0,0 +1,269 @@
+package s3_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/odpf/meteor/models"
+	commonv1beta1 "github.com/odpf/meteor/models/odpf/assets/common/v1beta1"
+	assetsv1beta1 "github.com/odpf/meteor/models/odpf/assets/v1beta1"
+	"github.com/odpf/meteor/plugins"
+	"github.com/odpf/meteor/plugins/sinks/s3"
+	testUtils "github.com/odpf/meteor/test/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	table = &assetsv1beta1.Table{
+		Resource: &commonv1beta1.Resource{Urn: "postgres::db/shop/orders", Name: "orders", Service: "postgres"},
+	}
+	topic = &assetsv1beta1.Topic{
+		Resource: &commonv1beta1.Resource{Urn: "kafka::broker/orders", Name: "orders", Service: "kafka"},
+	}
+)
+
+func TestInit(t *testing.T) {
+	t.Run("should return InvalidConfigError without bucket", func(t *testing.T) {
+		err := s3.New(testUtils.Logger).Init(context.TODO(), map[string]interface{}{})
+		assert.Equal(t, plugins.InvalidConfigError{Type: plugins.PluginTypeSink, PluginName: "s3"}, err)
+	})
+	t.Run("should return InvalidConfigError if part size is less than 5MiB", func(t *testing.T) {
+		err := s3.New(testUtils.Logger).Init(context.TODO(), map[string]interface{}{
+			"bucket":    "meteor-archive",
+			"part_size": 1024,
+		})
+		assert.Equal(t, plugins.InvalidConfigError{Type: plugins.PluginTypeSink, PluginName: "s3"}, err)
+	})
+	t.Run("should return error on compressed parquet", func(t *testing.T) {
+		err := s3.New(testUtils.Logger).Init(context.TODO(), map[string]interface{}{
+			"bucket":      "meteor-archive",
+			"format":      "parquet",
+			"compression": "gzip",
+		})
+		assert.EqualError(t, err, "compression is not supported for parquet")
+	})
+	t.Run("should return error on kms key without kms encryption", func(t *testing.T) {
+		err := s3.New(testUtils.Logger).Init(context.TODO(), map[string]interface{}{
+			"bucket":                 "meteor-archive",
+			"server_side_encryption": "AES256",
+			"kms_key_id":             "my-key",
+		})
+		assert.EqualError(t, err, "kms_key_id requires server_side_encryption to be aws:kms")
+	})
+}
+
+func TestSink(t *testing.T) {
+	t.Run("should upload compressed ndjson objects by type and a manifest", func(t *testing.T) {
+		store := newFakeS3(t)
+		sinkAll(t, store, map[string]interface{}{
+			"compression":            "gzip",
+			"server_side_encryption": "AES256",
+		}, table, topic, table)
+
+		assert.Equal(t, []string{
+			"meteor-archive/sample/2022-01-02/manifest.json",
+			"meteor-archive/sample/2022-01-02/table.ndjson.gz",
+			"meteor-archive/sample/2022-01-02/topic.ndjson.gz",
+		}, store.keys())
+
+		lines := strings.Split(strings.TrimSpace(gunzip(t, store.objects["meteor-archive/sample/2022-01-02/table.ndjson.gz"])), "\n")
+		require.Len(t, lines, 2)
+		assert.Contains(t, lines[0], `"urn":"postgres::db/shop/orders"`)
+
+		header := store.headers["meteor-archive/sample/2022-01-02/table.ndjson.gz"]
+		assert.Equal(t, "gzip", header.Get("Content-Encoding"))
+		assert.Equal(t, "application/x-ndjson", header.Get("Content-Type"))
+		assert.Equal(t, "AES256", header.Get("X-Amz-Server-Side-Encryption"))
+
+		var manifest struct {
+			Recipe    string `json:"recipe"`
+			StartedAt string `json:"started_at"`
+			Records   int    `json:"records"`
+			Objects   []struct {
+				Key     string `json:"key"`
+				Records int    `json:"records"`
+			} `json:"objects"`
+		}
+		require.NoError(t, json.Unmarshal(store.objects["meteor-archive/sample/2022-01-02/manifest.json"], &manifest))
+		assert.Equal(t, "sample", manifest.Recipe)
+		assert.Equal(t, "2022-01-02T15:04:05Z", manifest.StartedAt)
+		assert.Equal(t, 3, manifest.Records)
+		require.Len(t, manifest.Objects, 2)
+		assert.Equal(t, "sample/2022-01-02/table.ndjson.gz", manifest.Objects[0].Key)
+		assert.Equal(t, 2, manifest.Objects[0].Records)
+	})
+	t.Run("should upload parquet objects with templated keys", func(t *testing.T) {
+		store := newFakeS3(t)
+		sinkAll(t, store, map[string]interface{}{
+			"format":   "parquet",
+			"key":      `archive/{{ .Time.Format "2006/01" }}/{{ .Type }}.parquet`,
+			"manifest": false,
+		}, table, topic)
+
+		assert.Equal(t, []string{
+			"meteor-archive/archive/2022/01/table.parquet",
+			"meteor-archive/archive/2022/01/topic.parquet",
+		}, store.keys())
+		object := store.objects["meteor-archive/archive/2022/01/table.parquet"]
+		assert.Equal(t, "PAR1", string(object[:4]))
+		assert.Equal(t, "PAR1", string(object[len(object)-4:]))
+	})
+	t.Run("should upload large objects in parts", func(t *testing.T) {
+		large := &assetsv1beta1.Table{
+			Resource: &commonv1beta1.Resource{
+				Urn:         "postgres::db/shop/large",
+				Description: strings.Repeat("x", 6<<20),
+			},
+		}
+		store := newFakeS3(t)
+		sinkAll(t, store, map[string]interface{}{
+			"server_side_encryption": "aws:kms",
+			"kms_key_id":             "my-key",
+			"manifest":               false,
+		}, large)
+
+		key := "meteor-archive/sample/2022-01-02/table.ndjson"
+		assert.Equal(t, []string{key}, store.multipart)
+		assert.Contains(t, string(store.objects[key]), `"urn":"postgres::db/shop/large"`)
+		assert.Greater(t, len(store.objects[key]), 6<<20)
+		assert.Equal(t, "aws:kms", store.headers[key].Get("X-Amz-Server-Side-Encryption"))
+		assert.Equal(t, "my-key", store.headers[key].Get("X-Amz-Server-Side-Encryption-Aws-Kms-Key-Id"))
+	})
+	t.Run("should return error if parquet tables have the same key", func(t *testing.T) {
+		store := newFakeS3(t)
+		sink := s3.New(testUtils.Logger)
+		require.NoError(t, sink.Init(runContext(), store.config(map[string]interface{}{
+			"format": "parquet",
+			"key":    "{{ .Recipe }}.parquet",
+		})))
+
+		err := sink.Sink(context.TODO(), []models.Record{models.NewRecord(table), models.NewRecord(topic)})
+		assert.EqualError(t, err, "tables \"tables\" and \"topics\" have the same name \"sample.parquet\", parquet names should contain the type")
+		require.NoError(t, sink.Close())
+	})
+}
+
+func runContext() context.Context {
+	return plugins.ContextWithRunInfo(context.TODO(), plugins.RunInfo{
+		RecipeName: "sample",
+		StartedAt:  time.Date(2022, 1, 2, 15, 4, 5, 0, time.UTC),
+	})
+}
+
+// sinkAll sinks every asset in its own batch to the store and closes the sink
+func sinkAll(t *testing.T, store *fakeS3, config map[string]interface{}, assets ...models.Metadata) {
+	sink := s3.New(testUtils.Logger)
+	require.NoError(t, sink.Init(runContext(), store.config(config)))
+	for _, asset := range assets {
+		require.NoError(t, sink.Sink(context.TODO(), []models.Record{models.NewRecord(asset)}))
+	}
+	require.NoError(t, sink.Close())
+}
+
+func gunzip(t *testing.T, b []byte) string {
+	r, err := gzip.NewReader(bytes.NewReader(b))
+	require.NoError(t, err)
+	out, err := ioutil.ReadAll(r)
+	require.NoError(t, err)
+
+	return string(out)
+}
+
+// fakeS3 is an in-memory S3 compatible store serving path style requests,
+// single part and multipart uploads are supported
+type fakeS3 struct {
+	t         *testing.T
+	server    *httptest.Server
+	mu        sync.Mutex
+	objects   map[string][]byte
+	headers   map[string]http.Header
+	uploads   map[string]map[int][]byte
+	multipart []string
+}
+
+func newFakeS3(t *testing.T) *fakeS3 {
+	f := &fakeS3{
+		t:       t,
+		objects: make(map[string][]byte),
+		headers: make(map[string]http.Header),
+		uploads: make(map[string]map[int][]byte),
+	}
+	f.server = httptest.NewServer(f)
+	t.Cleanup(f.server.Close)
+
+	return f
+}
+
+func (f *fakeS3) config(config map[string]interface{}) map[string]interface{} {
+	config["bucket"] = "meteor-archive"
+	config["endpoint"] = f.server.URL
+	config["force_path_style"] = true
+	config["access_key_id"] = "access-key"
+	config["secret_access_key"] = "secret-key"
+	return config
+}
+
+func (f *fakeS3) keys() []string {
+	keys := make([]string, 0, len(f.objects))
+	for key := range f.objects {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	key := strings.TrimPrefix(r.URL.Path, "/")
+	query := r.URL.Query()
+	body, err := ioutil.ReadAll(r.Body)
+	require.NoError(f.t, err)
+
+	_, initiate := query["uploads"]
+	uploadID := query.Get("uploadId")
+	switch {
+	case r.Method == http.MethodPost && initiate:
+		uploadID = strconv.Itoa(len(f.uploads) + 1)
+		f.uploads[uploadID] = make(map[int][]byte)
+		f.headers[key] = r.Header
+		fmt.Fprintf(w, `<InitiateMultipartUploadResult><Bucket>meteor-archive</Bucket><Key>%s</Key><UploadId>%s</UploadId></InitiateMultipartUploadResult>`, key, uploadID)
+	case r.Method == http.MethodPut && uploadID != "":
+		part, err := strconv.Atoi(query.Get("partNumber"))
+		require.NoError(f.t, err)
+		f.uploads[uploadID][part] = body
+		w.Header().Set("ETag", fmt.Sprintf(`"part-%d"`, part))
+	case r.Method == http.MethodPost && uploadID != "":
+		parts := f.uploads[uploadID]
+		var object []byte
+		for i := 1; i <= len(parts); i++ {
+			object = append(object, parts[i]...)
+		}
+		f.objects[key] = object
+		f.multipart = append(f.multipart, key)
+		fmt.Fprintf(w, `<CompleteMultipartUploadResult><Location>%s</Location><Bucket>meteor-archive</Bucket><Key>%s</Key><ETag>"object"</ETag></CompleteMultipartUploadResult>`, key, key)
+	case r.Method == http.MethodPut:
+		f.objects[key] = body
+		f.headers[key] = r.Header
+		w.Header().Set("ETag", `"object"`)
+	case r.Method == http.MethodDelete:
+		delete(f.uploads, uploadID)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusNotImplemented)
+	}
+}
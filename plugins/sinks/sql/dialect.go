@@ -0,0 +1,80 @@
+package sql
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Drivers supported by the sink
+const (
+	driverPostgres = "postgres"
+	driverMySQL    = "mysql"
+	driverSQLite   = "sqlite3"
+)
+
+// dialect holds the differences between the SQL of the supported drivers
+type dialect struct {
+	driver string
+	// sqlDriver is the name the driver is registered with in database/sql
+	sqlDriver string
+	// longText is the type of columns holding whole assets
+	longText string
+	// timestamp is the type of timestamp columns
+	timestamp string
+}
+
+var dialects = map[string]dialect{
+	driverPostgres: {driver: driverPostgres, sqlDriver: "postgres", longText: "TEXT", timestamp: "TIMESTAMPTZ"},
+	driverMySQL:    {driver: driverMySQL, sqlDriver: "mysql", longText: "LONGTEXT", timestamp: "DATETIME(3)"},
+	// the pure go driver is used so that sqlite works in builds without cgo
+	driverSQLite: {driver: driverSQLite, sqlDriver: "sqlite", longText: "TEXT", timestamp: "TIMESTAMP"},
+}
+
+// rebind replaces the ? placeholders of query by the placeholders of the driver
+func (d dialect) rebind(query string) string {
+	if d.driver != driverPostgres {
+		return query
+	}
+
+	var (
+		b strings.Builder
+		n int
+	)
+	for _, r := range query {
+		if r != '?' {
+			b.WriteRune(r)
+			continue
+		}
+		n++
+		fmt.Fprintf(&b, "$%d", n)
+	}
+
+	return b.String()
+}
+
+// upsert returns a query inserting a row into table, the row is updated if a row with the same keys exists
+func (d dialect) upsert(table string, keys, columns []string) string {
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ")
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", table, strings.Join(columns, ", "), placeholders)
+
+	isKey := make(map[string]bool)
+	for _, key := range keys {
+		isKey[key] = true
+	}
+	var updates []string
+	for _, column := range columns {
+		if isKey[column] {
+			continue
+		}
+		if d.driver == driverMySQL {
+			updates = append(updates, fmt.Sprintf("%s = VALUES(%s)", column, column))
+		} else {
+			updates = append(updates, fmt.Sprintf("%s = excluded.%s", column, column))
+		}
+	}
+
+	if d.driver == driverMySQL {
+		return fmt.Sprintf("%s ON DUPLICATE KEY UPDATE %s", query, strings.Join(updates, ", "))
+	}
+	return fmt.Sprintf("%s ON CONFLICT (%s) DO UPDATE SET %s", query, strings.Join(keys, ", "), strings.Join(updates, ", "))
+}
@@ -0,0 +1,125 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// migration is a version of the schema, statements are built for the dialect and the prefix of the tables
+type migration struct {
+	version    int
+	statements func(d dialect, prefix string) []string
+}
+
+// migrations are applied in order, applied migrations must never change
+var migrations = []migration{
+	{
+		version: 1,
+		statements: func(d dialect, p string) []string {
+			return []string{
+				fmt.Sprintf(`CREATE TABLE %sassets (
+	urn VARCHAR(384) NOT NULL PRIMARY KEY,
+	type VARCHAR(64) NOT NULL,
+	name VARCHAR(512) NOT NULL,
+	service VARCHAR(128) NOT NULL,
+	url TEXT,
+	description TEXT,
+	tags TEXT,
+	data %s NOT NULL,
+	create_time %s NULL,
+	update_time %s NULL,
+	synced_at %s NOT NULL
+)`, p, d.longText, d.timestamp, d.timestamp, d.timestamp),
+				fmt.Sprintf(`CREATE TABLE %scolumns (
+	asset_urn VARCHAR(384) NOT NULL,
+	position INTEGER NOT NULL,
+	name VARCHAR(512) NOT NULL,
+	data_type VARCHAR(256),
+	description TEXT,
+	is_nullable BOOLEAN NOT NULL,
+	length BIGINT,
+	PRIMARY KEY (asset_urn, position)
+)`, p),
+				fmt.Sprintf(`CREATE TABLE %slineage (
+	asset_urn VARCHAR(384) NOT NULL,
+	source_urn VARCHAR(384) NOT NULL,
+	target_urn VARCHAR(384) NOT NULL
+)`, p),
+				fmt.Sprintf("CREATE INDEX %[1]slineage_asset_urn ON %[1]slineage (asset_urn)", p),
+				fmt.Sprintf("CREATE INDEX %[1]slineage_source_urn ON %[1]slineage (source_urn)", p),
+				fmt.Sprintf("CREATE INDEX %[1]slineage_target_urn ON %[1]slineage (target_urn)", p),
+				fmt.Sprintf(`CREATE TABLE %sowners (
+	asset_urn VARCHAR(384) NOT NULL,
+	position INTEGER NOT NULL,
+	urn VARCHAR(384),
+	name VARCHAR(512),
+	role VARCHAR(128),
+	email VARCHAR(320),
+	PRIMARY KEY (asset_urn, position)
+)`, p),
+				fmt.Sprintf(`CREATE TABLE %slabels (
+	asset_urn VARCHAR(384) NOT NULL,
+	name VARCHAR(255) NOT NULL,
+	value TEXT,
+	PRIMARY KEY (asset_urn, name)
+)`, p),
+			}
+		},
+	},
+}
+
+// migrate creates the migrations table if needed and applies the migrations that are not applied yet
+func migrate(ctx context.Context, db *sql.DB, d dialect, prefix string) error {
+	table := prefix + "schema_migrations"
+	if _, err := db.ExecContext(ctx, fmt.Sprintf(
+		"CREATE TABLE IF NOT EXISTS %s (version INTEGER NOT NULL PRIMARY KEY, applied_at %s NOT NULL)", table, d.timestamp,
+	)); err != nil {
+		return errors.Wrap(err, "failed to create migrations table")
+	}
+
+	var current sql.NullInt64
+	if err := db.QueryRowContext(ctx, fmt.Sprintf("SELECT MAX(version) FROM %s", table)).Scan(&current); err != nil {
+		return errors.Wrap(err, "failed to read schema version")
+	}
+
+	for _, m := range migrations {
+		if int64(m.version) <= current.Int64 {
+			continue
+		}
+		if err := applyMigration(ctx, db, d, prefix, m); err != nil {
+			return errors.Wrapf(err, "failed to apply migration %d", m.version)
+		}
+	}
+
+	return nil
+}
+
+// applyMigration runs the statements of the migration in a transaction,
+// MySQL commits schema changes implicitly so a failed migration has to be cleaned up manually there
+func applyMigration(ctx context.Context, db *sql.DB, d dialect, prefix string, m migration) (err error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	for _, statement := range m.statements(d, prefix) {
+		if _, err = tx.ExecContext(ctx, statement); err != nil {
+			return err
+		}
+	}
+	query := d.rebind(fmt.Sprintf("INSERT INTO %sschema_migrations (version, applied_at) VALUES (?, ?)", prefix))
+	if _, err = tx.ExecContext(ctx, query, m.version, time.Now().UTC()); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
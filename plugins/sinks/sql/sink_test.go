@@ -0,0 +1,109 @@
+package sql_test
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/odpf/meteor/models"
+	commonv1beta1 "github.com/odpf/meteor/models/odpf/assets/common/v1beta1"
+	facetsv1beta1 "github.com/odpf/meteor/models/odpf/assets/facets/v1beta1"
+	assetsv1beta1 "github.com/odpf/meteor/models/odpf/assets/v1beta1"
+	"github.com/odpf/meteor/plugins"
+	sqlSink "github.com/odpf/meteor/plugins/sinks/sql"
+	testUtils "github.com/odpf/meteor/test/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInit(t *testing.T) {
+	t.Run("should return InvalidConfigError on invalid config", func(t *testing.T) {
+		err := sqlSink.New(testUtils.Logger).Init(context.TODO(), map[string]interface{}{
+			"driver": "oracle",
+			"dsn":    "oracle://localhost",
+		})
+		assert.Equal(t, plugins.InvalidConfigError{Type: plugins.PluginTypeSink, PluginName: "sql"}, err)
+	})
+	t.Run("should migrate an existing database once", func(t *testing.T) {
+		config := map[string]interface{}{
+			"driver": "sqlite3",
+			"dsn":    filepath.Join(t.TempDir(), "meteor.db"),
+		}
+		for i := 0; i < 2; i++ {
+			sink := sqlSink.New(testUtils.Logger)
+			require.NoError(t, sink.Init(context.TODO(), config))
+			require.NoError(t, sink.Close())
+		}
+
+		db := openDB(t, config["dsn"].(string))
+		assert.Equal(t, 1, count(t, db, "SELECT COUNT(*) FROM meteor_schema_migrations"))
+	})
+}
+
+func TestSink(t *testing.T) {
+	dsn := filepath.Join(t.TempDir(), "meteor.db")
+	sink := sqlSink.New(testUtils.Logger)
+	require.NoError(t, sink.Init(context.TODO(), map[string]interface{}{
+		"driver": "sqlite3",
+		"dsn":    dsn,
+	}))
+	defer sink.Close()
+
+	table := &assetsv1beta1.Table{
+		Resource: &commonv1beta1.Resource{Urn: "bigquery::p/d/orders", Name: "orders", Service: "bigquery", Type: "table"},
+		Schema: &facetsv1beta1.Columns{Columns: []*facetsv1beta1.Column{
+			{Name: "id", DataType: "INT64"},
+			{Name: "item", DataType: "STRING", IsNullable: true},
+		}},
+		Ownership: &facetsv1beta1.Ownership{Owners: []*facetsv1beta1.Owner{
+			{Urn: "user:jane", Email: "jane@example.com", Role: "owner"},
+		}},
+		Lineage: &facetsv1beta1.Lineage{
+			Upstreams: []*commonv1beta1.Resource{{Urn: "kafka::k/orders"}},
+		},
+		Properties: &facetsv1beta1.Properties{
+			Labels: map[string]string{"team": "sales", "tier": "gold"},
+		},
+	}
+	topic := &assetsv1beta1.Topic{
+		Resource: &commonv1beta1.Resource{Urn: "kafka::k/orders", Name: "orders", Service: "kafka", Type: "topic"},
+	}
+	require.NoError(t, sink.Sink(context.TODO(), []models.Record{models.NewRecord(table), models.NewRecord(topic)}))
+
+	// syncing the asset again replaces its rows
+	table.Resource.Description = "orders of the shop"
+	table.Schema.Columns = table.Schema.Columns[:1]
+	table.Properties.Labels = map[string]string{"team": "growth"}
+	require.NoError(t, sink.Sink(context.TODO(), []models.Record{models.NewRecord(table)}))
+
+	db := openDB(t, dsn)
+	assert.Equal(t, 2, count(t, db, "SELECT COUNT(*) FROM meteor_assets"))
+
+	var assetType, description string
+	require.NoError(t, db.QueryRow("SELECT type, description FROM meteor_assets WHERE urn = ?", "bigquery::p/d/orders").Scan(&assetType, &description))
+	assert.Equal(t, "table", assetType)
+	assert.Equal(t, "orders of the shop", description)
+
+	assert.Equal(t, 1, count(t, db, "SELECT COUNT(*) FROM meteor_columns WHERE asset_urn = 'bigquery::p/d/orders'"))
+	assert.Equal(t, 1, count(t, db, "SELECT COUNT(*) FROM meteor_owners WHERE email = 'jane@example.com'"))
+	assert.Equal(t, 1, count(t, db, "SELECT COUNT(*) FROM meteor_lineage WHERE source_urn = 'kafka::k/orders' AND target_urn = 'bigquery::p/d/orders'"))
+
+	var team string
+	require.NoError(t, db.QueryRow("SELECT value FROM meteor_labels WHERE name = 'team'").Scan(&team))
+	assert.Equal(t, "growth", team)
+	assert.Equal(t, 1, count(t, db, "SELECT COUNT(*) FROM meteor_labels"))
+}
+
+func openDB(t *testing.T, dsn string) *sql.DB {
+	db, err := sql.Open("sqlite", dsn)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	return db
+}
+
+func count(t *testing.T, db *sql.DB, query string) (n int) {
+	require.NoError(t, db.QueryRow(query).Scan(&n))
+	return n
+}
@@ -0,0 +1,143 @@
+package stencil
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	assetsv1beta1 "github.com/odpf/meteor/models/odpf/assets/v1beta1"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// wellKnownTypes are the messages of the kinds without a scalar type
+var wellKnownTypes = map[kind]protoreflect.MessageDescriptor{
+	kindTimestamp: (&timestamppb.Timestamp{}).ProtoReflect().Descriptor(),
+	kindRecord:    (&structpb.Struct{}).ProtoReflect().Descriptor(),
+}
+
+var protoScalarTypes = map[kind]descriptorpb.FieldDescriptorProto_Type{
+	kindString:  descriptorpb.FieldDescriptorProto_TYPE_STRING,
+	kindInt:     descriptorpb.FieldDescriptorProto_TYPE_INT32,
+	kindLong:    descriptorpb.FieldDescriptorProto_TYPE_INT64,
+	kindFloat:   descriptorpb.FieldDescriptorProto_TYPE_FLOAT,
+	kindDouble:  descriptorpb.FieldDescriptorProto_TYPE_DOUBLE,
+	kindBoolean: descriptorpb.FieldDescriptorProto_TYPE_BOOL,
+	kindBytes:   descriptorpb.FieldDescriptorProto_TYPE_BYTES,
+}
+
+// buildDescriptorSet builds a proto3 file with a message of the columns of the table,
+// the set also holds the files of the well known types used by the fields so that it is self contained
+func buildDescriptorSet(namespace, schemaID string, table *assetsv1beta1.Table) (*descriptorpb.FileDescriptorSet, error) {
+	service := table.GetResource().GetService()
+	message := &descriptorpb.DescriptorProto{Name: proto.String(messageName(table.GetResource().GetName()))}
+
+	var deps []protoreflect.FileDescriptor
+	names := make(map[string]bool)
+	for i, column := range table.GetSchema().GetColumns() {
+		t := resolveType(service, column)
+		field := &descriptorpb.FieldDescriptorProto{
+			Name:     proto.String(fieldName(column.GetName(), names)),
+			Number:   proto.Int32(int32(i + 1)),
+			Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+			JsonName: proto.String(column.GetName()),
+		}
+		if t.repeated {
+			field.Label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+		}
+		if msg, ok := wellKnownTypes[t.kind]; ok {
+			field.Type = descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum()
+			field.TypeName = proto.String("." + string(msg.FullName()))
+			deps = appendFile(deps, msg.ParentFile())
+		} else {
+			field.Type = protoScalarTypes[t.kind].Enum()
+		}
+		message.Field = append(message.Field, field)
+	}
+
+	file := &descriptorpb.FileDescriptorProto{
+		Name:        proto.String(schemaID + ".proto"),
+		Package:     proto.String(packageName(namespace)),
+		Syntax:      proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{message},
+	}
+	set := &descriptorpb.FileDescriptorSet{}
+	for _, dep := range deps {
+		file.Dependency = append(file.Dependency, dep.Path())
+		set.File = append(set.File, protodesc.ToFileDescriptorProto(dep))
+	}
+	set.File = append(set.File, file)
+
+	if _, err := protodesc.NewFiles(set); err != nil {
+		return nil, fmt.Errorf("invalid descriptor of \"%s\": %w", table.GetResource().GetUrn(), err)
+	}
+
+	return set, nil
+}
+
+func appendFile(files []protoreflect.FileDescriptor, file protoreflect.FileDescriptor) []protoreflect.FileDescriptor {
+	for _, f := range files {
+		if f.Path() == file.Path() {
+			return files
+		}
+	}
+	return append(files, file)
+}
+
+// messageName returns the name in camel case, e.g. OrderItems for order_items
+func messageName(name string) string {
+	var b strings.Builder
+	for _, word := range strings.FieldsFunc(name, func(r rune) bool { return !isIdentRune(r) || r == '_' }) {
+		runes := []rune(word)
+		b.WriteRune(unicode.ToUpper(runes[0]))
+		b.WriteString(string(runes[1:]))
+	}
+
+	return identifier(b.String())
+}
+
+// fieldName returns the name of the column as a field name unique among names,
+// proto3 names conflict if they are equal once lower cased without underscores
+func fieldName(column string, names map[string]bool) string {
+	name := identifier(strings.Map(func(r rune) rune {
+		if isIdentRune(r) {
+			return r
+		}
+		return '_'
+	}, column))
+
+	key := func(name string) string { return strings.ReplaceAll(strings.ToLower(name), "_", "") }
+	unique := name
+	for i := 2; names[key(unique)]; i++ {
+		unique = fmt.Sprintf("%s_%d", name, i)
+	}
+	names[key(unique)] = true
+
+	return unique
+}
+
+// packageName returns the namespace as a package name, e.g. odpf.assets for odpf-assets
+func packageName(namespace string) string {
+	var parts []string
+	for _, part := range strings.FieldsFunc(strings.ToLower(namespace), func(r rune) bool { return !isIdentRune(r) }) {
+		parts = append(parts, identifier(part))
+	}
+
+	return strings.Join(parts, ".")
+}
+
+// identifier prefixes the names which are empty or starting with a digit
+func identifier(name string) string {
+	if name == "" || unicode.IsDigit([]rune(name)[0]) {
+		return "_" + name
+	}
+	return name
+}
+
+func isIdentRune(r rune) bool {
+	return r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_')
+}
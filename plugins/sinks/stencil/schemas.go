@@ -35,6 +35,14 @@ type JsonSchema struct {
 type JsonProperty struct {
 	Type        []JsonType `json:"type"`
 	Description string     `json:"description"`
+	Format      string     `json:"format,omitempty"`
+	Items       *JsonItems `json:"items,omitempty"`
+}
+
+// JsonItems is the schema of the elements of an array property
+type JsonItems struct {
+	Type   JsonType `json:"type"`
+	Format string   `json:"format,omitempty"`
 }
 
 type AvroSchema struct {
@@ -48,3 +56,15 @@ type AvroFields struct {
 	Name string      `json:"name"`
 	Type interface{} `json:"type"`
 }
+
+// AvroArray is the avro type of repeated fields
+type AvroArray struct {
+	Type  AvroType    `json:"type"`
+	Items interface{} `json:"items"`
+}
+
+// AvroLogicalType annotates a primitive type, e.g. a long holding timestamps
+type AvroLogicalType struct {
+	Type        AvroType `json:"type"`
+	LogicalType string   `json:"logicalType"`
+}
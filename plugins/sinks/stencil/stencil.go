@@ -17,16 +17,37 @@ import (
 	"github.com/odpf/meteor/utils"
 	"github.com/odpf/salt/log"
 	"github.com/pkg/errors"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/types/descriptorpb"
 )
 
 //go:embed README.md
 var summary string
 
+// Schema formats of the payloads
+const (
+	formatJSON     = "json"
+	formatAvro     = "avro"
+	formatProtobuf = "protobuf"
+)
+
+// stencilFormats are the values of the format header of the schema formats
+var stencilFormats = map[string]string{
+	formatJSON:     "FORMAT_JSON",
+	formatAvro:     "FORMAT_AVRO",
+	formatProtobuf: "FORMAT_PROTOBUF",
+}
+
 // Config holds the set of configuration options for the sink
 type Config struct {
-	Host        string `mapstructure:"host" validate:"required"`
-	NamespaceID string `mapstructure:"namespace_id" validate:"required"`
-	Format      string `mapstructure:"format" validate:"oneof=json avro" default:"json"`
+	Host             string `mapstructure:"host" validate:"required"`
+	NamespaceID      string `mapstructure:"namespace_id" validate:"required"`
+	Format           string `mapstructure:"format" validate:"oneof=json avro protobuf" default:"json"`
+	SendFormatHeader bool   `mapstructure:"send_format_header" default:"false"`
+	// CheckCompatibility checks a schema against the latest version of the schema before uploading it
+	CheckCompatibility bool   `mapstructure:"check_compatibility" default:"false"`
+	Compatibility      string `mapstructure:"compatibility" validate:"omitempty,oneof=COMPATIBILITY_UNSPECIFIED COMPATIBILITY_BACKWARD COMPATIBILITY_BACKWARD_TRANSITIVE COMPATIBILITY_FORWARD COMPATIBILITY_FORWARD_TRANSITIVE COMPATIBILITY_FULL COMPATIBILITY_FULL_TRANSITIVE"`
 }
 
 var sampleConfig = `
@@ -34,8 +55,14 @@ var sampleConfig = `
 host: https://stencil.com
 # The namespace ID of the stencil service
 namespace_id: myNamespace
-# The schema format in which data will sink to stencil
+# The schema format in which tables will sink to stencil, one of json, avro or protobuf
 format: avro
+# Send the format of the schemas, schemas of topics are always sent with their format
+send_format_header: false
+# Check the schema against the latest version before uploading it
+check_compatibility: true
+# The compatibility rule of the check and the upload, the rule of the schema in stencil is used if empty
+compatibility: COMPATIBILITY_BACKWARD
 `
 
 // httpClient holds the set of methods require for creating request
@@ -43,6 +70,14 @@ type httpClient interface {
 	Do(*http.Request) (*http.Response, error)
 }
 
+// schema is a schema to upload to stencil
+type schema struct {
+	id         string
+	format     string
+	data       []byte
+	sendFormat bool
+}
+
 // Sink manages the sinking of data to Stencil
 type Sink struct {
 	client httpClient
@@ -76,38 +111,44 @@ func (s *Sink) Init(_ context.Context, configMap map[string]interface{}) (err er
 	if err = utils.BuildConfig(configMap, &s.config); err != nil {
 		return plugins.InvalidConfigError{Type: plugins.PluginTypeSink}
 	}
+	s.config.Host = strings.TrimSuffix(s.config.Host, "/")
 
 	return
 }
 
-// Sink helps to sink record to stencil
-func (s *Sink) Sink(_ context.Context, batch []models.Record) (err error) {
-	var stencilPayload interface{}
-
+// Sink helps to sink record to stencil, tables are sent in the configured format
+// and topics with the schema of their schema url
+func (s *Sink) Sink(ctx context.Context, batch []models.Record) (err error) {
 	for _, record := range batch {
-		metadata := record.Data()
-
-		table, ok := metadata.(*assetsv1beta1.Table)
-		if !ok {
+		var sch *schema
+		switch asset := record.Data().(type) {
+		case *assetsv1beta1.Table:
+			sch, err = s.buildTableSchema(asset)
+		case *assetsv1beta1.Topic:
+			sch, err = s.buildTopicSchema(ctx, asset)
+		default:
 			continue
 		}
-		s.logger.Info("sinking record to stencil", "record", table.GetResource().Urn)
-
-		switch s.config.Format {
-		case "avro":
-			stencilPayload, err = s.buildAvroStencilPayload(table)
-		case "json":
-			stencilPayload, err = s.buildJsonStencilPayload(table)
-		}
-
 		if err != nil {
 			return errors.Wrap(err, "failed to build stencil payload")
 		}
-		if err = s.send(table.Resource.Urn, stencilPayload); err != nil {
+		if sch == nil {
+			continue
+		}
+
+		urn := record.Data().GetResource().GetUrn()
+		s.logger.Info("sinking record to stencil", "record", urn)
+
+		if s.config.CheckCompatibility {
+			if err = s.checkCompatibility(ctx, sch); err != nil {
+				return errors.Wrap(err, "error checking compatibility")
+			}
+		}
+		if err = s.send(ctx, sch); err != nil {
 			return errors.Wrap(err, "error sending data")
 		}
 
-		s.logger.Info("successfully sinked record to stencil", "record", table.GetResource().Urn)
+		s.logger.Info("successfully sinked record to stencil", "record", urn)
 	}
 
 	return
@@ -116,6 +157,81 @@ func (s *Sink) Sink(_ context.Context, batch []models.Record) (err error) {
 // Close will be called once after everything is done
 func (s *Sink) Close() (err error) { return }
 
+// buildTableSchema builds the schema of the columns of the table in the configured format
+func (s *Sink) buildTableSchema(table *assetsv1beta1.Table) (*schema, error) {
+	sch := &schema{
+		id:         schemaID(table.GetResource().GetUrn()),
+		format:     s.config.Format,
+		sendFormat: s.config.SendFormatHeader,
+	}
+
+	var err error
+	switch s.config.Format {
+	case formatAvro:
+		var payload AvroSchema
+		if payload, err = s.buildAvroStencilPayload(table); err == nil {
+			sch.data, err = json.Marshal(payload)
+		}
+	case formatJSON:
+		var payload JsonSchema
+		if payload, err = s.buildJsonStencilPayload(table); err == nil {
+			sch.data, err = json.Marshal(payload)
+		}
+	case formatProtobuf:
+		var set *descriptorpb.FileDescriptorSet
+		if set, err = buildDescriptorSet(s.config.NamespaceID, sch.id, table); err == nil {
+			sch.data, err = proto.Marshal(set)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return sch, nil
+}
+
+// buildTopicSchema returns the schema fetched from the schema url of the topic,
+// or the json schema sampled by the extractor for json topics without schema url.
+// Nil is returned for topics without schema or with a protobuf schema which is not a descriptor set.
+func (s *Sink) buildTopicSchema(ctx context.Context, topic *assetsv1beta1.Topic) (*schema, error) {
+	urn := topic.GetResource().GetUrn()
+	format := strings.ToLower(topic.GetSchema().GetFormat())
+	if _, ok := stencilFormats[format]; !ok {
+		s.logger.Debug("skipping topic without schema", "record", urn, "format", format)
+		return nil, nil
+	}
+	sch := &schema{id: schemaID(urn), format: format, sendFormat: true}
+
+	var err error
+	if url := topic.GetSchema().GetSchemaUrl(); url != "" {
+		if sch.data, err = s.fetchSchema(ctx, url); err != nil {
+			return nil, errors.Wrapf(err, "failed to fetch schema of \"%s\"", urn)
+		}
+	} else if format == formatJSON {
+		sample, ok := utils.GetCustomProperties(topic)["json_schema"].(map[string]interface{})
+		if !ok {
+			s.logger.Debug("skipping json topic without sampled schema", "record", urn)
+			return nil, nil
+		}
+		sample["$id"] = urn + ".json"
+		sample["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+		sample["title"] = topic.GetResource().GetName()
+		if sch.data, err = json.Marshal(sample); err != nil {
+			return nil, err
+		}
+	} else {
+		s.logger.Debug("skipping topic without schema url", "record", urn, "format", format)
+		return nil, nil
+	}
+
+	if format == formatProtobuf && !isDescriptorSet(sch.data) {
+		s.logger.Warn("skipping topic with a protobuf schema which is not a descriptor set", "record", urn)
+		return nil, nil
+	}
+
+	return sch, nil
+}
+
 // buildJsonStencilPayload build json stencil payload
 func (s *Sink) buildJsonStencilPayload(table *assetsv1beta1.Table) (JsonSchema, error) {
 	resource := table.GetResource()
@@ -147,46 +263,136 @@ func (s *Sink) buildAvroStencilPayload(table *assetsv1beta1.Table) (AvroSchema,
 	return record, nil
 }
 
+// fetchSchema returns the schema at url, the schema of schema registry responses is unwrapped
+func (s *Sink) fetchSchema(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	body, err := s.do(req, http.StatusOK)
+	if err != nil {
+		return nil, err
+	}
+
+	var registryRes struct {
+		Schema *string `json:"schema"`
+	}
+	if err = json.Unmarshal(body, &registryRes); err == nil && registryRes.Schema != nil {
+		return []byte(*registryRes.Schema), nil
+	}
+
+	return body, nil
+}
+
+// checkCompatibility checks the schema against the latest version of the schema in stencil,
+// there is nothing to check against for new schemas. Only a bad request reply means the schema is not compatible,
+// other failures such as a denied request are returned as they are.
+func (s *Sink) checkCompatibility(ctx context.Context, sch *schema) error {
+	req, err := s.newRequest(ctx, s.schemaURL(sch.id)+"/check", sch)
+	if err != nil {
+		return err
+	}
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return plugins.NewRetryError(err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode == http.StatusNotFound || (res.StatusCode >= 200 && res.StatusCode < 300) {
+		return nil
+	}
+
+	bodyBytes, _ := ioutil.ReadAll(res.Body)
+	err = fmt.Errorf("stencil returns %d: %v", res.StatusCode, string(bodyBytes))
+	switch {
+	case res.StatusCode == http.StatusBadRequest:
+		return errors.Wrapf(err, "schema \"%s\" is not compatible with its latest version", sch.id)
+	case plugins.IsRetryableStatus(res.StatusCode):
+		return plugins.NewRetryError(err)
+	}
+
+	return err
+}
+
 // send helps to pass data to stencil
-func (s *Sink) send(tableURN string, record interface{}) (err error) {
-	payloadBytes, err := json.Marshal(record)
+func (s *Sink) send(ctx context.Context, sch *schema) (err error) {
+	req, err := s.newRequest(ctx, s.schemaURL(sch.id), sch)
 	if err != nil {
 		return
 	}
+	if s.config.Compatibility == "" && sch.format == formatJSON {
+		req.Header.Set("X-Compatibility", "COMPATIBILITY_UNSPECIFIED")
+	}
+
+	_, err = s.do(req, http.StatusCreated)
+	return
+}
 
-	// send request
-	schemaID := strings.ReplaceAll(tableURN, "/", ".")
-	url := fmt.Sprintf("%s/v1beta1/namespaces/%s/schemas/%s", s.config.Host, s.config.NamespaceID, schemaID)
-	req, err := http.NewRequest(http.MethodPost, url, bytes.NewBuffer(payloadBytes))
+// newRequest returns a request posting the schema to url with the format and compatibility headers
+func (s *Sink) newRequest(ctx context.Context, url string, sch *schema) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(sch.data))
 	if err != nil {
-		return
+		return nil, err
 	}
 
-	if s.config.Format == "json" {
-		req.Header.Add("X-Compatibility", "COMPATIBILITY_UNSPECIFIED")
+	if sch.format == formatProtobuf {
+		req.Header.Set("Content-Type", "application/octet-stream")
+	} else {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if sch.sendFormat {
+		req.Header.Set("X-Format", stencilFormats[sch.format])
+	}
+	if s.config.Compatibility != "" {
+		req.Header.Set("X-Compatibility", s.config.Compatibility)
 	}
 
+	return req, nil
+}
+
+// do sends the request and returns the body of the response if its status is expected,
+// a RetryError is returned for connection errors, too many requests and server errors
+func (s *Sink) do(req *http.Request, expected int) ([]byte, error) {
 	res, err := s.client.Do(req)
 	if err != nil {
-		return
-	}
-	if res.StatusCode == http.StatusCreated {
-		return
+		return nil, plugins.NewRetryError(err)
 	}
+	defer res.Body.Close()
 
-	var bodyBytes []byte
-	bodyBytes, err = ioutil.ReadAll(res.Body)
+	bodyBytes, err := ioutil.ReadAll(res.Body)
 	if err != nil {
-		return
+		return nil, err
+	}
+	if res.StatusCode == expected {
+		return bodyBytes, nil
 	}
 	err = fmt.Errorf("stencil returns %d: %v", res.StatusCode, string(bodyBytes))
 
 	switch code := res.StatusCode; {
-	case code >= 500:
-		return plugins.NewRetryError(err)
+	case plugins.IsRetryableStatus(code):
+		return nil, plugins.NewRetryError(err)
 	default:
-		return err
+		return nil, err
+	}
+}
+
+func (s *Sink) schemaURL(id string) string {
+	return fmt.Sprintf("%s/v1beta1/namespaces/%s/schemas/%s", s.config.Host, s.config.NamespaceID, id)
+}
+
+// schemaID returns the id of the schema of an asset
+func schemaID(urn string) string {
+	return strings.ReplaceAll(urn, "/", ".")
+}
+
+// isDescriptorSet returns true if data is a valid protobuf FileDescriptorSet
+func isDescriptorSet(data []byte) bool {
+	var set descriptorpb.FileDescriptorSet
+	if err := proto.Unmarshal(data, &set); err != nil || len(set.File) == 0 {
+		return false
 	}
+	_, err := protodesc.NewFiles(&set)
+	return err == nil
 }
 
 // buildJsonProperties builds the json schema properties
@@ -203,57 +409,45 @@ func buildJsonProperties(table *assetsv1beta1.Table) map[string]JsonProperty {
 	}
 
 	for _, column := range columns {
-		dataType := typeToJsonSchemaType(service, column.DataType)
-		columnType := []JsonType{dataType}
+		t := resolveType(service, column)
+		dataType, format := kindToJsonSchemaType(t.kind)
+		property := JsonProperty{
+			Type:        []JsonType{dataType},
+			Description: column.GetDescription(),
+			Format:      format,
+		}
+		if t.repeated {
+			property.Type = []JsonType{JsonTypeArray}
+			property.Format = ""
+			property.Items = &JsonItems{Type: dataType, Format: format}
+		}
 
 		if column.IsNullable {
-			columnType = append(columnType, JsonTypeNull)
+			property.Type = append(property.Type, JsonTypeNull)
 		}
 
-		columnRecord[column.Name] = JsonProperty{
-			Type:        columnType,
-			Description: column.GetDescription(),
-		}
+		columnRecord[column.Name] = property
 	}
 
 	return columnRecord
 }
 
-// typeToJsonSchemaType converts particular service type to Json type
-func typeToJsonSchemaType(service string, columnType string) (dataType JsonType) {
-
-	if service == "bigquery" {
-		switch columnType {
-		case "STRING", "DATE", "DATETIME", "TIME", "TIMESTAMP", "GEOGRAPHY":
-			dataType = JsonTypeString
-		case "INT64", "NUMERIC", "FLOAT64", "INT", "FLOAT", "BIGNUMERIC":
-			dataType = JsonTypeNumber
-		case "BYTES":
-			dataType = JsonTypeArray
-		case "BOOLEAN":
-			dataType = JsonTypeBoolean
-		case "RECORD":
-			dataType = JsonTypeObject
-		default:
-			dataType = JsonTypeString
-		}
-	}
-	if service == "postgres" {
-		switch columnType {
-		case "uuid", "integer", "decimal", "smallint", "bigint", "bit", "bit varying", "numeric", "real", "double precision", "cidr", "inet", "macaddr", "serial", "bigserial", "money":
-			dataType = JsonTypeNumber
-		case "varchar", "text", "character", "character varying", "date", "time", "timestamp", "interval", "point", "line", "path":
-			dataType = JsonTypeString
-		case "boolean":
-			dataType = JsonTypeBoolean
-		case "bytea", "integer[]", "character[]", "text[]":
-			dataType = JsonTypeArray
-		default:
-			dataType = JsonTypeString
-		}
+// kindToJsonSchemaType converts the kind of a column to Json type and format
+func kindToJsonSchemaType(k kind) (dataType JsonType, format string) {
+	switch k {
+	case kindInt, kindLong, kindFloat, kindDouble:
+		return JsonTypeNumber, ""
+	case kindBoolean:
+		return JsonTypeBoolean, ""
+	case kindBytes:
+		return JsonTypeArray, ""
+	case kindRecord:
+		return JsonTypeObject, ""
+	case kindTimestamp:
+		return JsonTypeString, "date-time"
+	default:
+		return JsonTypeString, ""
 	}
-
-	return
 }
 
 // buildAvroFields builds the avro schema fields
@@ -269,11 +463,15 @@ func buildAvroFields(table *assetsv1beta1.Table) (fields []AvroFields) {
 	}
 
 	for _, column := range columns {
-		dataType := typeToAvroSchemaType(service, column.DataType)
-		columnType := []AvroType{dataType}
+		t := resolveType(service, column)
+		dataType := kindToAvroSchemaType(t.kind)
+		if t.repeated {
+			dataType = AvroArray{Type: AvroTypeArray, Items: dataType}
+		}
+		columnType := []interface{}{dataType}
 
 		if column.IsNullable {
-			columnType = []AvroType{dataType, AvroTypeNull}
+			columnType = []interface{}{dataType, AvroTypeNull}
 		}
 
 		fields = append(fields, AvroFields{
@@ -285,43 +483,28 @@ func buildAvroFields(table *assetsv1beta1.Table) (fields []AvroFields) {
 	return fields
 }
 
-// typeToAvroSchemaType converts particular service type to avro type
-func typeToAvroSchemaType(service string, columnType string) (dataType AvroType) {
-
-	if service == "bigquery" {
-		switch columnType {
-		case "STRING", "DATE", "DATETIME", "TIME", "TIMESTAMP", "GEOGRAPHY":
-			dataType = AvroTypeString
-		case "INT64", "NUMERIC", "INT", "BIGNUMERIC":
-			dataType = AvroTypeInteger
-		case "FLOAT64", "FLOAT":
-			dataType = AvroTypeFloat
-		case "BYTES":
-			dataType = AvroTypeBytes
-		case "BOOLEAN":
-			dataType = AvroTypeBoolean
-		case "RECORD":
-			dataType = AvroTypeRecord
-		default:
-			dataType = AvroTypeString
-		}
-	}
-	if service == "postgres" {
-		switch columnType {
-		case "uuid", "integer", "decimal", "smallint", "bigint", "bit", "bit varying", "numeric", "real", "double precision", "cidr", "inet", "macaddr", "serial", "bigserial", "money":
-			dataType = AvroTypeInteger
-		case "varchar", "text", "character", "character varying", "date", "time", "timestamp", "interval", "point", "line", "path":
-			dataType = AvroTypeString
-		case "boolean":
-			dataType = AvroTypeBoolean
-		case "bytea", "integer[]", "character[]", "text[]":
-			dataType = AvroTypeArray
-		default:
-			dataType = AvroTypeString
-		}
+// kindToAvroSchemaType converts the kind of a column to avro type
+func kindToAvroSchemaType(k kind) interface{} {
+	switch k {
+	case kindInt:
+		return AvroTypeInteger
+	case kindLong:
+		return AvroTypeLong
+	case kindFloat:
+		return AvroTypeFloat
+	case kindDouble:
+		return AvroTypeDouble
+	case kindBoolean:
+		return AvroTypeBoolean
+	case kindBytes:
+		return AvroTypeBytes
+	case kindRecord:
+		return AvroTypeRecord
+	case kindTimestamp:
+		return AvroLogicalType{Type: AvroTypeLong, LogicalType: "timestamp-micros"}
+	default:
+		return AvroTypeString
 	}
-
-	return
 }
 
 // init register the sink to the catalog
@@ -8,6 +8,8 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/odpf/meteor/models"
@@ -18,7 +20,13 @@ import (
 
 	"github.com/odpf/meteor/plugins"
 	testUtils "github.com/odpf/meteor/test/utils"
+	"github.com/odpf/meteor/utils"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
 )
 
 var (
@@ -436,7 +444,7 @@ func TestSink(t *testing.T) {
 				Fields: []stencil.AvroFields{
 					{
 						Name: "id",
-						Type: []stencil.AvroType{stencil.AvroTypeInteger, stencil.AvroTypeNull},
+						Type: []stencil.AvroType{stencil.AvroTypeLong, stencil.AvroTypeNull},
 					},
 					{
 						Name: "user_id",
@@ -448,7 +456,7 @@ func TestSink(t *testing.T) {
 					},
 					{
 						Name: "distance",
-						Type: []stencil.AvroType{stencil.AvroTypeFloat, stencil.AvroTypeNull},
+						Type: []stencil.AvroType{stencil.AvroTypeDouble, stencil.AvroTypeNull},
 					},
 					{
 						Name: "is_active",
@@ -537,7 +545,7 @@ func TestSink(t *testing.T) {
 					},
 					{
 						Name: "range",
-						Type: []stencil.AvroType{stencil.AvroTypeArray},
+						Type: []stencil.AvroType{stencil.AvroTypeBytes},
 					},
 				},
 			},
@@ -571,6 +579,275 @@ func TestSink(t *testing.T) {
 	}
 }
 
+func TestSinkTypes(t *testing.T) {
+	table := &assetsv1beta1.Table{
+		Resource: &commonv1beta1.Resource{Urn: tableURN, Name: "order_items", Service: "bigquery"},
+		Schema: &facetsv1beta1.Columns{Columns: []*facetsv1beta1.Column{
+			{Name: "id", DataType: "INTEGER"},
+			{Name: "tags", DataType: "STRING", Properties: &facetsv1beta1.Properties{
+				Attributes: utils.TryParseMapToProto(map[string]interface{}{"mode": "REPEATED"}),
+			}},
+			{Name: "created_at", DataType: "TIMESTAMP", IsNullable: true},
+			{Name: "address", DataType: "STRUCT<city STRING>"},
+			{Name: "scores", DataType: "ARRAY<FLOAT64>"},
+		}},
+	}
+
+	t.Run("should map repeated and timestamp columns to json schema", func(t *testing.T) {
+		fake := newFakeStencil(t)
+		sink := initSink(t, fake, map[string]interface{}{"format": "json"})
+
+		require.NoError(t, sink.Sink(context.TODO(), []models.Record{models.NewRecord(table)}))
+
+		var payload stencil.JsonSchema
+		require.NoError(t, json.Unmarshal(fake.uploads[tableURN], &payload))
+		assert.Equal(t, map[string]stencil.JsonProperty{
+			"id":         {Type: []stencil.JsonType{stencil.JsonTypeNumber}},
+			"tags":       {Type: []stencil.JsonType{stencil.JsonTypeArray}, Items: &stencil.JsonItems{Type: stencil.JsonTypeString}},
+			"created_at": {Type: []stencil.JsonType{stencil.JsonTypeString, stencil.JsonTypeNull}, Format: "date-time"},
+			"address":    {Type: []stencil.JsonType{stencil.JsonTypeObject}},
+			"scores":     {Type: []stencil.JsonType{stencil.JsonTypeArray}, Items: &stencil.JsonItems{Type: stencil.JsonTypeNumber}},
+		}, payload.Properties)
+	})
+	t.Run("should map repeated and timestamp columns to avro schema", func(t *testing.T) {
+		fake := newFakeStencil(t)
+		sink := initSink(t, fake, map[string]interface{}{"format": "avro"})
+
+		require.NoError(t, sink.Sink(context.TODO(), []models.Record{models.NewRecord(table)}))
+
+		assert.JSONEq(t, `{
+			"type": "record",
+			"namespace": "test-namespace",
+			"name": "order_items",
+			"fields": [
+				{"name": "id", "type": ["long"]},
+				{"name": "tags", "type": [{"type": "array", "items": "string"}]},
+				{"name": "created_at", "type": [{"type": "long", "logicalType": "timestamp-micros"}, "null"]},
+				{"name": "address", "type": ["record"]},
+				{"name": "scores", "type": [{"type": "array", "items": "double"}]}
+			]
+		}`, string(fake.uploads[tableURN]))
+	})
+	t.Run("should build protobuf descriptor set", func(t *testing.T) {
+		fake := newFakeStencil(t)
+		sink := initSink(t, fake, map[string]interface{}{"format": "protobuf", "send_format_header": true})
+
+		require.NoError(t, sink.Sink(context.TODO(), []models.Record{models.NewRecord(table)}))
+		assert.Equal(t, "FORMAT_PROTOBUF", fake.formats[tableURN])
+
+		var set descriptorpb.FileDescriptorSet
+		require.NoError(t, proto.Unmarshal(fake.uploads[tableURN], &set))
+		files, err := protodesc.NewFiles(&set)
+		require.NoError(t, err)
+		desc, err := files.FindDescriptorByName("test.namespace.OrderItems")
+		require.NoError(t, err)
+
+		fields := desc.(protoreflect.MessageDescriptor).Fields()
+		assert.Equal(t, protoreflect.Int64Kind, fields.ByName("id").Kind())
+		assert.Equal(t, protoreflect.StringKind, fields.ByName("tags").Kind())
+		assert.True(t, fields.ByName("tags").IsList())
+		assert.Equal(t, protoreflect.FullName("google.protobuf.Timestamp"), fields.ByName("created_at").Message().FullName())
+		assert.Equal(t, protoreflect.FullName("google.protobuf.Struct"), fields.ByName("address").Message().FullName())
+		assert.Equal(t, protoreflect.DoubleKind, fields.ByName("scores").Kind())
+		assert.True(t, fields.ByName("scores").IsList())
+	})
+	t.Run("should map postgres arrays", func(t *testing.T) {
+		fake := newFakeStencil(t)
+		sink := initSink(t, fake, map[string]interface{}{"format": "avro"})
+
+		err := sink.Sink(context.TODO(), []models.Record{models.NewRecord(&assetsv1beta1.Table{
+			Resource: &commonv1beta1.Resource{Urn: tableURN, Name: "users", Service: "postgres"},
+			Schema: &facetsv1beta1.Columns{Columns: []*facetsv1beta1.Column{
+				{Name: "ids", DataType: "bigint[]"},
+				{Name: "roles", DataType: "_varchar"},
+				{Name: "name", DataType: "character varying(255)"},
+			}},
+		})})
+		require.NoError(t, err)
+
+		assert.JSONEq(t, `[
+			{"name": "ids", "type": [{"type": "array", "items": "long"}]},
+			{"name": "roles", "type": [{"type": "array", "items": "string"}]},
+			{"name": "name", "type": ["string"]}
+		]`, string(mustGetPath(t, fake.uploads[tableURN], "fields")))
+	})
+}
+
+func TestSinkTopic(t *testing.T) {
+	t.Run("should upload schema from the schema url of the topic", func(t *testing.T) {
+		fake := newFakeStencil(t)
+		registry := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"schema": "{\"type\":\"record\",\"name\":\"order\",\"fields\":[]}"}`))
+		}))
+		defer registry.Close()
+		sink := initSink(t, fake, map[string]interface{}{"format": "json"})
+
+		err := sink.Sink(context.TODO(), []models.Record{models.NewRecord(&assetsv1beta1.Topic{
+			Resource: &commonv1beta1.Resource{Urn: "kafka::broker/orders", Name: "orders", Service: "kafka"},
+			Schema:   &facetsv1beta1.TopicSchema{Format: "avro", SchemaUrl: registry.URL + "/schemas/ids/7"},
+		})})
+		require.NoError(t, err)
+
+		assert.JSONEq(t, `{"type":"record","name":"order","fields":[]}`, string(fake.uploads["kafka::broker.orders"]))
+		assert.Equal(t, "FORMAT_AVRO", fake.formats["kafka::broker.orders"])
+	})
+	t.Run("should upload sampled json schema of json topics", func(t *testing.T) {
+		fake := newFakeStencil(t)
+		sink := initSink(t, fake, map[string]interface{}{})
+
+		topic := &assetsv1beta1.Topic{
+			Resource: &commonv1beta1.Resource{Urn: "kafka::broker/orders", Name: "orders", Service: "kafka"},
+			Schema:   &facetsv1beta1.TopicSchema{Format: "json"},
+		}
+		_, err := utils.SetCustomProperties(topic, map[string]interface{}{
+			"json_schema": map[string]interface{}{"type": "object"},
+		})
+		require.NoError(t, err)
+
+		require.NoError(t, sink.Sink(context.TODO(), []models.Record{models.NewRecord(topic)}))
+
+		assert.JSONEq(t, `{
+			"$id": "kafka::broker/orders.json",
+			"$schema": "https://json-schema.org/draft/2020-12/schema",
+			"title": "orders",
+			"type": "object"
+		}`, string(fake.uploads["kafka::broker.orders"]))
+	})
+	t.Run("should skip topics without schema", func(t *testing.T) {
+		fake := newFakeStencil(t)
+		sink := initSink(t, fake, map[string]interface{}{})
+
+		err := sink.Sink(context.TODO(), []models.Record{models.NewRecord(&assetsv1beta1.Topic{
+			Resource: &commonv1beta1.Resource{Urn: "kafka::broker/orders", Name: "orders", Service: "kafka"},
+		})})
+		require.NoError(t, err)
+		assert.Empty(t, fake.uploads)
+	})
+}
+
+func TestSinkCompatibility(t *testing.T) {
+	table := &assetsv1beta1.Table{
+		Resource: &commonv1beta1.Resource{Urn: tableURN, Name: "table-name", Service: "bigquery"},
+	}
+
+	t.Run("should check compatibility before uploading", func(t *testing.T) {
+		fake := newFakeStencil(t)
+		fake.existing[tableURN] = true
+		sink := initSink(t, fake, map[string]interface{}{"check_compatibility": true, "compatibility": "COMPATIBILITY_FULL"})
+
+		require.NoError(t, sink.Sink(context.TODO(), []models.Record{models.NewRecord(table)}))
+		assert.Equal(t, []string{tableURN}, fake.checks)
+		assert.Equal(t, "COMPATIBILITY_FULL", fake.compatibilities[tableURN])
+		assert.Contains(t, fake.uploads, tableURN)
+	})
+	t.Run("should not upload incompatible schema", func(t *testing.T) {
+		fake := newFakeStencil(t)
+		fake.existing[tableURN] = true
+		fake.incompatible = true
+		sink := initSink(t, fake, map[string]interface{}{"check_compatibility": true})
+
+		err := sink.Sink(context.TODO(), []models.Record{models.NewRecord(table)})
+		require.Error(t, err)
+		assert.False(t, errors.Is(err, plugins.RetryError{}))
+		assert.Contains(t, err.Error(), "is not compatible")
+		assert.Empty(t, fake.uploads)
+	})
+	t.Run("should not report denied checks as incompatible", func(t *testing.T) {
+		fake := newFakeStencil(t)
+		fake.existing[tableURN] = true
+		fake.checkStatus = http.StatusForbidden
+		sink := initSink(t, fake, map[string]interface{}{"check_compatibility": true})
+
+		err := sink.Sink(context.TODO(), []models.Record{models.NewRecord(table)})
+		require.Error(t, err)
+		assert.False(t, errors.Is(err, plugins.RetryError{}))
+		assert.NotContains(t, err.Error(), "is not compatible")
+		assert.Contains(t, err.Error(), "stencil returns 403")
+		assert.Empty(t, fake.uploads)
+	})
+	t.Run("should skip check by default", func(t *testing.T) {
+		fake := newFakeStencil(t)
+		fake.existing[tableURN] = true
+		fake.incompatible = true
+		sink := initSink(t, fake, map[string]interface{}{})
+
+		require.NoError(t, sink.Sink(context.TODO(), []models.Record{models.NewRecord(table)}))
+		assert.Empty(t, fake.checks)
+		assert.Contains(t, fake.uploads, tableURN)
+	})
+}
+
+func initSink(t *testing.T, fake *fakeStencil, config map[string]interface{}) plugins.Syncer {
+	config["host"] = fake.server.URL
+	config["namespace_id"] = namespaceID
+	sink := stencil.New(http.DefaultClient, testUtils.Logger)
+	require.NoError(t, sink.Init(context.TODO(), config))
+
+	return sink
+}
+
+func mustGetPath(t *testing.T, data []byte, field string) json.RawMessage {
+	var object map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal(data, &object))
+	return object[field]
+}
+
+// fakeStencil is a stand-in of the schema apis of a namespace, existing holds the schemas with a version
+// and checkStatus overrides the status of compatibility checks
+type fakeStencil struct {
+	server          *httptest.Server
+	existing        map[string]bool
+	incompatible    bool
+	checkStatus     int
+	checks          []string
+	uploads         map[string][]byte
+	formats         map[string]string
+	compatibilities map[string]string
+}
+
+func newFakeStencil(t *testing.T) *fakeStencil {
+	fake := &fakeStencil{
+		existing:        make(map[string]bool),
+		uploads:         make(map[string][]byte),
+		formats:         make(map[string]string),
+		compatibilities: make(map[string]string),
+	}
+	fake.server = httptest.NewServer(http.HandlerFunc(fake.handle))
+	t.Cleanup(fake.server.Close)
+
+	return fake
+}
+
+func (f *fakeStencil) handle(w http.ResponseWriter, r *http.Request) {
+	prefix := fmt.Sprintf("/v1beta1/namespaces/%s/schemas/", namespaceID)
+	if r.Method != http.MethodPost || !strings.HasPrefix(r.URL.Path, prefix) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	body, _ := ioutil.ReadAll(r.Body)
+	id := strings.TrimPrefix(r.URL.Path, prefix)
+
+	if strings.HasSuffix(id, "/check") {
+		id = strings.TrimSuffix(id, "/check")
+		f.checks = append(f.checks, id)
+		switch {
+		case f.checkStatus != 0:
+			w.WriteHeader(f.checkStatus)
+		case !f.existing[id]:
+			w.WriteHeader(http.StatusNotFound)
+		case f.incompatible:
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"message":"field id: type changed"}`))
+		}
+		return
+	}
+
+	f.uploads[id] = body
+	f.formats[id] = r.Header.Get("X-Format")
+	f.compatibilities[id] = r.Header.Get("X-Compatibility")
+	w.WriteHeader(http.StatusCreated)
+}
+
 type mockHTTPClient struct {
 	URL            string
 	Method         string
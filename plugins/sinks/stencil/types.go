@@ -0,0 +1,168 @@
+package stencil
+
+import (
+	"strings"
+
+	facetsv1beta1 "github.com/odpf/meteor/models/odpf/assets/facets/v1beta1"
+)
+
+// kind is the kind of value held by a column, independent of the service and the schema format
+type kind int
+
+const (
+	kindString kind = iota
+	kindInt
+	kindLong
+	kindFloat
+	kindDouble
+	kindBoolean
+	kindBytes
+	kindTimestamp
+	kindRecord
+)
+
+// columnType is the type of a column resolved from its data type
+type columnType struct {
+	kind     kind
+	repeated bool
+}
+
+var bigqueryTypes = map[string]kind{
+	"STRING":     kindString,
+	"DATE":       kindString,
+	"DATETIME":   kindString,
+	"TIME":       kindString,
+	"GEOGRAPHY":  kindString,
+	"JSON":       kindString,
+	"INTERVAL":   kindString,
+	"TIMESTAMP":  kindTimestamp,
+	"INT":        kindLong,
+	"INT64":      kindLong,
+	"INTEGER":    kindLong,
+	"SMALLINT":   kindLong,
+	"BIGINT":     kindLong,
+	"TINYINT":    kindLong,
+	"BYTEINT":    kindLong,
+	"NUMERIC":    kindDouble,
+	"BIGNUMERIC": kindDouble,
+	"DECIMAL":    kindDouble,
+	"BIGDECIMAL": kindDouble,
+	"FLOAT":      kindDouble,
+	"FLOAT64":    kindDouble,
+	"BOOL":       kindBoolean,
+	"BOOLEAN":    kindBoolean,
+	"BYTES":      kindBytes,
+	"RECORD":     kindRecord,
+	"STRUCT":     kindRecord,
+}
+
+// postgresTypes holds both the data types and the internal names of the types, e.g. int4 for integer
+var postgresTypes = map[string]kind{
+	"smallint":                    kindInt,
+	"integer":                     kindInt,
+	"int":                         kindInt,
+	"int2":                        kindInt,
+	"int4":                        kindInt,
+	"smallserial":                 kindInt,
+	"serial":                      kindInt,
+	"bigint":                      kindLong,
+	"int8":                        kindLong,
+	"bigserial":                   kindLong,
+	"real":                        kindFloat,
+	"float4":                      kindFloat,
+	"double precision":            kindDouble,
+	"float8":                      kindDouble,
+	"numeric":                     kindDouble,
+	"decimal":                     kindDouble,
+	"money":                       kindDouble,
+	"boolean":                     kindBoolean,
+	"bool":                        kindBoolean,
+	"bytea":                       kindBytes,
+	"timestamp":                   kindTimestamp,
+	"timestamp without time zone": kindTimestamp,
+	"timestamp with time zone":    kindTimestamp,
+	"timestamptz":                 kindTimestamp,
+}
+
+var mysqlTypes = map[string]kind{
+	"tinyint":    kindInt,
+	"smallint":   kindInt,
+	"mediumint":  kindInt,
+	"int":        kindInt,
+	"integer":    kindInt,
+	"bigint":     kindLong,
+	"float":      kindFloat,
+	"double":     kindDouble,
+	"real":       kindDouble,
+	"decimal":    kindDouble,
+	"numeric":    kindDouble,
+	"bool":       kindBoolean,
+	"boolean":    kindBoolean,
+	"binary":     kindBytes,
+	"varbinary":  kindBytes,
+	"blob":       kindBytes,
+	"tinyblob":   kindBytes,
+	"mediumblob": kindBytes,
+	"longblob":   kindBytes,
+	"datetime":   kindTimestamp,
+	"timestamp":  kindTimestamp,
+}
+
+// typeMappings maps the data types of a service to kinds, data types missing are strings
+var typeMappings = map[string]map[string]kind{
+	"bigquery": bigqueryTypes,
+	"postgres": postgresTypes,
+	"mysql":    mysqlTypes,
+	"mariadb":  mysqlTypes,
+}
+
+// resolveType returns the type of the column from its data type in the service,
+// repeated columns are the arrays of the data type or the bigquery columns in REPEATED mode
+func resolveType(service string, column *facetsv1beta1.Column) columnType {
+	t := parseType(service, strings.TrimSpace(column.GetDataType()))
+	if service == "bigquery" && columnMode(column) == "REPEATED" {
+		t.repeated = true
+	}
+
+	return t
+}
+
+func parseType(service, dataType string) columnType {
+	upper := strings.ToUpper(dataType)
+	switch {
+	case strings.HasPrefix(upper, "ARRAY<") && strings.HasSuffix(upper, ">"):
+		t := parseType(service, strings.TrimSpace(dataType[len("ARRAY<"):len(dataType)-1]))
+		t.repeated = true
+		return t
+	case strings.HasSuffix(dataType, "[]"):
+		t := parseType(service, strings.TrimSpace(strings.TrimSuffix(dataType, "[]")))
+		t.repeated = true
+		return t
+	case strings.HasPrefix(upper, "STRUCT<"):
+		return columnType{kind: kindRecord}
+	case service == "postgres" && upper == "ARRAY":
+		// information_schema does not tell the type of the elements
+		return columnType{kind: kindString, repeated: true}
+	case service == "postgres" && strings.HasPrefix(dataType, "_"):
+		// internal name of the arrays, e.g. _int4
+		t := parseType(service, dataType[1:])
+		t.repeated = true
+		return t
+	}
+
+	// drop the length or precision, e.g. varchar(255) or numeric(10,2)
+	if i := strings.IndexByte(dataType, '('); i > 0 {
+		dataType = strings.TrimSpace(dataType[:i])
+	}
+	key := strings.ToLower(dataType)
+	if service == "bigquery" {
+		key = strings.ToUpper(dataType)
+	}
+
+	return columnType{kind: typeMappings[service][key]}
+}
+
+// columnMode returns the mode attribute of a bigquery column
+func columnMode(column *facetsv1beta1.Column) string {
+	return column.GetProperties().GetAttributes().GetFields()["mode"].GetStringValue()
+}
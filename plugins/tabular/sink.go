@@ -0,0 +1,220 @@
+package tabular
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/odpf/meteor/models"
+	"github.com/odpf/meteor/plugins"
+	"github.com/odpf/meteor/utils"
+	"github.com/odpf/salt/log"
+	"github.com/pkg/errors"
+)
+
+// Encoder writes the rows of a table in a file format
+type Encoder interface {
+	// Extension returns the extension of the written files, e.g. ".csv"
+	Extension() string
+	// Encode writes the rows of the table as a whole file to w
+	Encode(w io.Writer, table Table, rows []Row) error
+}
+
+// Config holds the configuration shared by the tabular sinks
+type Config struct {
+	Path           string `mapstructure:"path" validate:"required"`
+	MaxRowsPerFile int    `mapstructure:"max_rows_per_file" validate:"min=1" default:"100000"`
+}
+
+// buffer holds the rows of a table until they are written to a file
+type buffer struct {
+	table Table
+	rows  []Row
+	files int
+}
+
+// Sink flattens assets into an asset table per type, a columns table and a lineage table.
+// Tables are written to the directory of the path partitioned by recipe and run date:
+// <path>/<table>/recipe=<recipe>/date=<date>/part-<time>-<n><ext>
+type Sink struct {
+	name    string
+	info    plugins.Info
+	encoder Encoder
+	logger  log.Logger
+	config  Config
+	run     plugins.RunInfo
+	buffers map[string]*buffer
+	edges   map[string]bool
+}
+
+// NewSink returns a sink writing tables with the encoder, name and info are the ones of the sink plugin
+func NewSink(name string, info plugins.Info, encoder Encoder, logger log.Logger) *Sink {
+	return &Sink{
+		name:    name,
+		info:    info,
+		encoder: encoder,
+		logger:  logger,
+	}
+}
+
+func (s *Sink) Info() plugins.Info {
+	return s.info
+}
+
+func (s *Sink) Validate(configMap map[string]interface{}) (err error) {
+	return utils.BuildConfig(configMap, &Config{})
+}
+
+func (s *Sink) Init(ctx context.Context, config map[string]interface{}) (err error) {
+	if err := utils.BuildConfig(config, &s.config); err != nil {
+		return plugins.InvalidConfigError{Type: "sink", PluginName: s.name}
+	}
+	if err = os.MkdirAll(s.config.Path, 0755); err != nil {
+		return errors.Wrapf(err, "failed to create directory \"%s\"", s.config.Path)
+	}
+
+	s.run = plugins.RunInfoFromContext(ctx)
+	if s.run.StartedAt.IsZero() {
+		s.run.StartedAt = time.Now()
+	}
+	s.buffers = make(map[string]*buffer)
+	s.edges = make(map[string]bool)
+
+	return
+}
+
+func (s *Sink) Sink(ctx context.Context, batch []models.Record) (err error) {
+	for _, record := range batch {
+		data := record.Data()
+		assetType := utils.GetAssetType(data)
+		table, ok := AssetTable(assetType)
+		if !ok {
+			s.logger.Warn("skipping asset of unsupported type", "urn", data.GetResource().GetUrn(), "type", assetType)
+			continue
+		}
+
+		if err = s.add(table, AssetRow(data)); err != nil {
+			return err
+		}
+		for _, row := range columnRows(data) {
+			if err = s.add(columnsTable, row); err != nil {
+				return err
+			}
+		}
+		for _, row := range lineageRows(data) {
+			// an edge is usually reported by both of its assets
+			key := fmt.Sprintf("%s\x00%s", row[0], row[2])
+			if s.edges[key] {
+				continue
+			}
+			s.edges[key] = true
+			if err = s.add(lineageTable, row); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Close writes the buffered rows of every table
+func (s *Sink) Close() (err error) {
+	names := make([]string, 0, len(s.buffers))
+	for name := range s.buffers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if flushErr := s.flush(s.buffers[name]); flushErr != nil && err == nil {
+			err = flushErr
+		}
+	}
+
+	return err
+}
+
+// add buffers the row, the table is written once it reaches max_rows_per_file
+func (s *Sink) add(table Table, row Row) error {
+	b, ok := s.buffers[table.Name]
+	if !ok {
+		b = &buffer{table: table}
+		s.buffers[table.Name] = b
+	}
+
+	b.rows = append(b.rows, row)
+	if len(b.rows) < s.config.MaxRowsPerFile {
+		return nil
+	}
+
+	return s.flush(b)
+}
+
+// flush writes the buffered rows to a new file of the partition of the run
+func (s *Sink) flush(b *buffer) error {
+	if len(b.rows) == 0 {
+		return nil
+	}
+
+	path := s.filePath(b.table.Name, b.files)
+	if err := writeFile(path, func(w io.Writer) error {
+		return s.encoder.Encode(w, b.table, b.rows)
+	}); err != nil {
+		return errors.Wrapf(err, "failed to write table \"%s\" to \"%s\"", b.table.Name, path)
+	}
+	b.rows = nil
+	b.files++
+
+	return nil
+}
+
+func (s *Sink) filePath(table string, index int) string {
+	recipe := s.run.RecipeName
+	if recipe == "" {
+		recipe = "unknown"
+	}
+
+	return filepath.Join(
+		s.config.Path,
+		table,
+		"recipe="+url.PathEscape(recipe),
+		"date="+s.run.StartedAt.Format("2006-01-02"),
+		fmt.Sprintf("part-%s-%d%s", s.run.StartedAt.Format("150405"), index+1, s.encoder.Extension()),
+	)
+}
+
+// writeFile writes to a temporary file next to path and renames it to path once write succeeds,
+// so a half written file is never observed at path
+func writeFile(path string, write func(w io.Writer) error) (err error) {
+	dir := filepath.Dir(path)
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	if err = write(tmp); err != nil {
+		return err
+	}
+	if err = tmp.Chmod(0644); err != nil {
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
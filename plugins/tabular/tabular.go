@@ -0,0 +1,331 @@
+package tabular
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/odpf/meteor/models"
+	commonv1beta1 "github.com/odpf/meteor/models/odpf/assets/common/v1beta1"
+	facetsv1beta1 "github.com/odpf/meteor/models/odpf/assets/facets/v1beta1"
+	assetsv1beta1 "github.com/odpf/meteor/models/odpf/assets/v1beta1"
+	"github.com/odpf/meteor/utils"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// ColumnType is the type of the values of a column
+type ColumnType int
+
+// Column types, every column is nullable
+const (
+	// String values are of type string
+	String ColumnType = iota
+	// Int64 values are of type int64
+	Int64
+	// Bool values are of type bool
+	Bool
+	// Timestamp values are of type time.Time
+	Timestamp
+)
+
+// Column is a column of a table
+type Column struct {
+	Name string
+	Type ColumnType
+}
+
+// Table is the layout of a table, values of a row are ordered as its columns
+type Table struct {
+	Name    string
+	Columns []Column
+}
+
+// Row holds the values of a row, nil values are null
+type Row []interface{}
+
+var resourceColumns = []Column{
+	{Name: "urn", Type: String},
+	{Name: "name", Type: String},
+	{Name: "service", Type: String},
+	{Name: "type", Type: String},
+	{Name: "url", Type: String},
+	{Name: "description", Type: String},
+	{Name: "owners", Type: String},
+	{Name: "tags", Type: String},
+	{Name: "labels", Type: String},
+	{Name: "attributes", Type: String},
+	{Name: "create_time", Type: Timestamp},
+	{Name: "update_time", Type: Timestamp},
+}
+
+// assetTables holds the table of every asset type, their columns follow the resource columns
+var assetTables = map[string]Table{
+	utils.AssetTypeTable: newAssetTable("tables",
+		Column{Name: "total_rows", Type: Int64},
+		Column{Name: "partition_key", Type: String},
+		Column{Name: "partition_value", Type: String},
+		Column{Name: "usage_count", Type: Int64},
+		Column{Name: "column_count", Type: Int64},
+	),
+	utils.AssetTypeTopic: newAssetTable("topics",
+		Column{Name: "number_of_partitions", Type: Int64},
+		Column{Name: "throughput", Type: String},
+		Column{Name: "schema_format", Type: String},
+		Column{Name: "schema_url", Type: String},
+	),
+	utils.AssetTypeDashboard: newAssetTable("dashboards",
+		Column{Name: "chart_count", Type: Int64},
+	),
+	utils.AssetTypeBucket: newAssetTable("buckets",
+		Column{Name: "location", Type: String},
+		Column{Name: "storage_type", Type: String},
+		Column{Name: "blob_count", Type: Int64},
+	),
+	utils.AssetTypeJob: newAssetTable("jobs"),
+	utils.AssetTypeUser: newAssetTable("users",
+		Column{Name: "email", Type: String},
+		Column{Name: "username", Type: String},
+		Column{Name: "full_name", Type: String},
+		Column{Name: "display_name", Type: String},
+		Column{Name: "title", Type: String},
+		Column{Name: "status", Type: String},
+		Column{Name: "manager_email", Type: String},
+	),
+	utils.AssetTypeGroup: newAssetTable("groups",
+		Column{Name: "email", Type: String},
+		Column{Name: "member_count", Type: Int64},
+	),
+}
+
+var columnsTable = Table{
+	Name: "columns",
+	Columns: []Column{
+		{Name: "asset_urn", Type: String},
+		{Name: "position", Type: Int64},
+		{Name: "name", Type: String},
+		{Name: "data_type", Type: String},
+		{Name: "description", Type: String},
+		{Name: "is_nullable", Type: Bool},
+		{Name: "length", Type: Int64},
+	},
+}
+
+var lineageTable = Table{
+	Name: "lineage",
+	Columns: []Column{
+		{Name: "source_urn", Type: String},
+		{Name: "source_type", Type: String},
+		{Name: "target_urn", Type: String},
+		{Name: "target_type", Type: String},
+	},
+}
+
+func newAssetTable(name string, columns ...Column) Table {
+	return Table{
+		Name:    name,
+		Columns: append(append([]Column{}, resourceColumns...), columns...),
+	}
+}
+
+// AssetTable returns the table of the asset type, false is returned for unsupported types
+func AssetTable(assetType string) (Table, bool) {
+	table, ok := assetTables[assetType]
+	return table, ok
+}
+
+// AssetRow flattens the asset into a row of its asset table
+func AssetRow(data models.Metadata) Row {
+	resource := data.GetResource()
+	properties := data.GetProperties()
+
+	description := resource.GetDescription()
+	if bucket, ok := data.(*assetsv1beta1.Bucket); ok && description == "" {
+		description = bucket.GetDescription()
+	}
+
+	row := Row{
+		resource.GetUrn(),
+		resource.GetName(),
+		resource.GetService(),
+		resource.GetType(),
+		nullString(resource.GetUrl()),
+		nullString(description),
+		ownersValue(utils.GetOwnership(data)),
+		jsonValue(properties.GetTags()),
+		jsonValue(properties.GetLabels()),
+		jsonValue(utils.GetCustomProperties(data)),
+	}
+	timestamps := getTimestamps(data)
+	row = append(row, timeValue(timestamps.GetCreateTime()), timeValue(timestamps.GetUpdateTime()))
+
+	switch asset := data.(type) {
+	case *assetsv1beta1.Table:
+		profile := asset.GetProfile()
+		row = append(row,
+			nullInt(profile.GetTotalRows(), profile != nil),
+			nullString(profile.GetPartitionKey()),
+			nullString(profile.GetPartitionValue()),
+			nullInt(profile.GetUsageCount(), profile != nil),
+			int64(len(asset.GetSchema().GetColumns())),
+		)
+	case *assetsv1beta1.Topic:
+		profile := asset.GetProfile()
+		row = append(row,
+			nullInt(profile.GetNumberOfPartitions(), profile != nil),
+			nullString(profile.GetThroughput()),
+			nullString(asset.GetSchema().GetFormat()),
+			nullString(asset.GetSchema().GetSchemaUrl()),
+		)
+	case *assetsv1beta1.Dashboard:
+		row = append(row, int64(len(asset.GetCharts())))
+	case *assetsv1beta1.Bucket:
+		row = append(row,
+			nullString(asset.GetLocation()),
+			nullString(asset.GetStorageType()),
+			int64(len(asset.GetBlobs())),
+		)
+	case *assetsv1beta1.User:
+		row = append(row,
+			nullString(asset.GetEmail()),
+			nullString(asset.GetUsername()),
+			nullString(asset.GetFullName()),
+			nullString(asset.GetDisplayName()),
+			nullString(asset.GetTitle()),
+			nullString(asset.GetStatus()),
+			nullString(asset.GetManagerEmail()),
+		)
+	case *assetsv1beta1.Group:
+		row = append(row,
+			nullString(asset.GetEmail()),
+			int64(len(asset.GetMembers())),
+		)
+	}
+
+	return row
+}
+
+// columnRows flattens the columns of a table asset into rows of the columns table
+func columnRows(data models.Metadata) []Row {
+	table, ok := data.(*assetsv1beta1.Table)
+	if !ok {
+		return nil
+	}
+
+	var rows []Row
+	for i, column := range table.GetSchema().GetColumns() {
+		rows = append(rows, Row{
+			table.GetResource().GetUrn(),
+			int64(i + 1),
+			column.GetName(),
+			nullString(column.GetDataType()),
+			nullString(column.GetDescription()),
+			column.GetIsNullable(),
+			nullInt(column.GetLength(), column.GetLength() > 0),
+		})
+	}
+
+	return rows
+}
+
+// lineageRows flattens the lineage of the asset into rows of the lineage table,
+// upstreams are sources of the asset and downstreams are targets of the asset
+func lineageRows(data models.Metadata) []Row {
+	lineage := utils.GetLineage(data)
+	if lineage == nil {
+		return nil
+	}
+
+	resource := data.GetResource()
+	var rows []Row
+	for _, upstream := range lineage.GetUpstreams() {
+		rows = append(rows, edgeRow(upstream, resource))
+	}
+	for _, downstream := range lineage.GetDownstreams() {
+		rows = append(rows, edgeRow(resource, downstream))
+	}
+
+	return rows
+}
+
+func edgeRow(source, target *commonv1beta1.Resource) Row {
+	return Row{
+		source.GetUrn(),
+		nullString(source.GetType()),
+		target.GetUrn(),
+		nullString(target.GetType()),
+	}
+}
+
+type timestampsMetadata interface {
+	GetTimestamps() *commonv1beta1.Timestamp
+}
+
+func getTimestamps(data models.Metadata) *commonv1beta1.Timestamp {
+	if t, ok := data.(timestampsMetadata); ok {
+		return t.GetTimestamps()
+	}
+	return nil
+}
+
+// ownersValue returns the urns of the owners separated by commas
+func ownersValue(ownership *facetsv1beta1.Ownership) interface{} {
+	var urns []string
+	for _, owner := range ownership.GetOwners() {
+		urn := owner.GetUrn()
+		if urn == "" {
+			urn = owner.GetEmail()
+		}
+		urns = append(urns, urn)
+	}
+	if len(urns) == 0 {
+		return nil
+	}
+	sort.Strings(urns)
+
+	return strings.Join(urns, ",")
+}
+
+// jsonValue returns the value encoded as JSON, nil is returned for empty values
+func jsonValue(v interface{}) interface{} {
+	switch value := v.(type) {
+	case []string:
+		if len(value) == 0 {
+			return nil
+		}
+	case map[string]string:
+		if len(value) == 0 {
+			return nil
+		}
+	case map[string]interface{}:
+		if len(value) == 0 {
+			return nil
+		}
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	return string(b)
+}
+
+func nullString(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+func nullInt(i int64, valid bool) interface{} {
+	if !valid {
+		return nil
+	}
+	return i
+}
+
+func timeValue(t *timestamppb.Timestamp) interface{} {
+	if t == nil {
+		return nil
+	}
+	return t.AsTime()
+}
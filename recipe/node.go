@@ -18,9 +18,54 @@ type RecipeNode struct {
 // PluginNode contains the json data for a recipe node that is being used for
 // generating the plugins code for a recipe.
 type PluginNode struct {
-	Name   yaml.Node            `json:"name" yaml:"name"`
-	Type   yaml.Node            `json:"type" yaml:"type"`
-	Config map[string]yaml.Node `json:"config" yaml:"config"`
+	Name        yaml.Node            `json:"name" yaml:"name"`
+	Type        yaml.Node            `json:"type" yaml:"type"`
+	Config      map[string]yaml.Node `json:"config" yaml:"config"`
+	BatchSize   yaml.Node            `json:"batch_size" yaml:"batch_size"`
+	When        yaml.Node            `json:"when" yaml:"when"`
+	Match       yaml.Node            `json:"match" yaml:"match"`
+	OnError     yaml.Node            `json:"on_error" yaml:"on_error"`
+	RateLimit   yaml.Node            `json:"rate_limit" yaml:"rate_limit"`
+	MaxInFlight yaml.Node            `json:"max_in_flight" yaml:"max_in_flight"`
+	BufferSize  yaml.Node            `json:"buffer_size" yaml:"buffer_size"`
+	Retry       yaml.Node            `json:"retry" yaml:"retry"`
+}
+
+// decodeLimits decodes the rate limit and the max in flight of the plugin, zero values are returned if not set
+func (plug PluginNode) decodeLimits() (rateLimit float64, maxInFlight int, err error) {
+	if !plug.RateLimit.IsZero() {
+		if err = plug.RateLimit.Decode(&rateLimit); err != nil {
+			return 0, 0, fmt.Errorf("error decoding rate_limit :%w", err)
+		}
+	}
+	if !plug.MaxInFlight.IsZero() {
+		if err = plug.MaxInFlight.Decode(&maxInFlight); err != nil {
+			return 0, 0, fmt.Errorf("error decoding max_in_flight :%w", err)
+		}
+	}
+
+	return rateLimit, maxInFlight, nil
+}
+
+// decodeWhen decodes the clause selecting the records of a sink,
+// it supports both tags `when` and `match`
+func (plug PluginNode) decodeWhen() (*Match, error) {
+	when := plug.When
+	switch {
+	case !when.IsZero() && !plug.Match.IsZero():
+		return nil, fmt.Errorf("only one of when and match can be set")
+	case when.IsZero():
+		when = plug.Match
+	}
+	if when.IsZero() {
+		return nil, nil
+	}
+
+	var match Match
+	if err := when.Decode(&match); err != nil {
+		return nil, err
+	}
+	return &match, nil
 }
 
 // decodeConfig decodes the plugins config
@@ -50,6 +95,18 @@ func (node RecipeNode) toRecipe() (recipe Recipe, err error) {
 		err = fmt.Errorf("error decoding source config :%w", err)
 		return
 	}
+	sourceRateLimit, sourceMaxInFlight, err := node.Source.decodeLimits()
+	if err != nil {
+		err = fmt.Errorf("error decoding source limits :%w", err)
+		return
+	}
+	var sourceBufferSize int
+	if !node.Source.BufferSize.IsZero() {
+		if err = node.Source.BufferSize.Decode(&sourceBufferSize); err != nil {
+			err = fmt.Errorf("error decoding source buffer_size :%w", err)
+			return
+		}
+	}
 	processors, err := node.toProcessors()
 	if err != nil {
 		err = fmt.Errorf("error building processors :%w", err)
@@ -64,9 +121,12 @@ func (node RecipeNode) toRecipe() (recipe Recipe, err error) {
 		Name:    node.Name.Value,
 		Version: node.Version.Value,
 		Source: PluginRecipe{
-			Name:   node.Source.Name.Value,
-			Config: sourceConfig,
-			Node:   node.Source,
+			Name:        node.Source.Name.Value,
+			Config:      sourceConfig,
+			RateLimit:   sourceRateLimit,
+			MaxInFlight: sourceMaxInFlight,
+			BufferSize:  sourceBufferSize,
+			Node:        node.Source,
 		},
 		Sinks:      sinks,
 		Processors: processors,
@@ -101,10 +161,41 @@ func (node RecipeNode) toSinks() (sinks []PluginRecipe, err error) {
 			err = fmt.Errorf("error decoding sink config :%w", cfgErr)
 			return
 		}
+		var batchSize int
+		if !sink.BatchSize.IsZero() {
+			if bsErr := sink.BatchSize.Decode(&batchSize); bsErr != nil {
+				err = fmt.Errorf("error decoding sink batch_size :%w", bsErr)
+				return
+			}
+		}
+		when, whenErr := sink.decodeWhen()
+		if whenErr != nil {
+			err = fmt.Errorf("error decoding sink when :%w", whenErr)
+			return
+		}
+		rateLimit, maxInFlight, limitsErr := sink.decodeLimits()
+		if limitsErr != nil {
+			err = fmt.Errorf("error decoding sink limits :%w", limitsErr)
+			return
+		}
+		var retry *RetryPolicy
+		if !sink.Retry.IsZero() {
+			retry = new(RetryPolicy)
+			if retryErr := sink.Retry.Decode(retry); retryErr != nil {
+				err = fmt.Errorf("error decoding sink retry :%w", retryErr)
+				return
+			}
+		}
 		sinks = append(sinks, PluginRecipe{
-			Name:   sink.Name.Value,
-			Config: sinkConfig,
-			Node:   sink,
+			Name:        sink.Name.Value,
+			Config:      sinkConfig,
+			BatchSize:   batchSize,
+			When:        when,
+			OnError:     sink.OnError.Value,
+			RateLimit:   rateLimit,
+			MaxInFlight: maxInFlight,
+			Retry:       retry,
+			Node:        sink,
 		})
 	}
 	return
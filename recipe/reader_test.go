@@ -136,6 +136,86 @@ func TestReaderRead(t *testing.T) {
 		}
 	})
 
+	t.Run("should read the batch sizes of sinks", func(t *testing.T) {
+		reader := recipe.NewReader(testLog, emptyConfigPath)
+		recipes, err := reader.Read("./testdata/sinks-batch-size-recipe.yaml")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.Len(t, recipes, 1)
+		sinks := recipes[0].Sinks
+		assert.Len(t, sinks, 2)
+		assert.Zero(t, sinks[0].BatchSize)
+		assert.Equal(t, 50, sinks[1].BatchSize)
+	})
+
+	t.Run("should read the when clauses of sinks", func(t *testing.T) {
+		reader := recipe.NewReader(testLog, emptyConfigPath)
+		recipes, err := reader.Read("./testdata/sinks-when-recipe.yaml")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.Len(t, recipes, 1)
+		sinks := recipes[0].Sinks
+		assert.Len(t, sinks, 3)
+		assert.Nil(t, sinks[0].When)
+		assert.Equal(t, &recipe.Match{Types: []string{"table"}}, sinks[1].When)
+		assert.Equal(t, &recipe.Match{
+			Services: []string{"bigquery"},
+			URNs:     []string{"bigquery::project/*"},
+			Tags:     []string{"pii"},
+			Labels:   map[string]string{"team": "*"},
+		}, sinks[2].When)
+	})
+
+	t.Run("should return error if a sink has both when and match", func(t *testing.T) {
+		reader := recipe.NewReader(testLog, emptyConfigPath)
+		_, err := reader.Read("./testdata/sinks-when-match-recipe.yaml")
+		assert.EqualError(t, err, "error building sinks :error decoding sink when :only one of when and match can be set")
+	})
+
+	t.Run("should read the rate limits, buffer size and max in flight of plugins", func(t *testing.T) {
+		reader := recipe.NewReader(testLog, emptyConfigPath)
+		recipes, err := reader.Read("./testdata/limits-recipe.yaml")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.Len(t, recipes, 1)
+		source := recipes[0].Source
+		assert.Equal(t, 50.0, source.RateLimit)
+		assert.Equal(t, 200, source.BufferSize)
+		assert.Zero(t, source.MaxInFlight)
+		sinks := recipes[0].Sinks
+		assert.Len(t, sinks, 2)
+		assert.Zero(t, sinks[0].RateLimit)
+		assert.Zero(t, sinks[0].MaxInFlight)
+		assert.Equal(t, 2.5, sinks[1].RateLimit)
+		assert.Equal(t, 4, sinks[1].MaxInFlight)
+	})
+
+	t.Run("should read the retry policies of sinks", func(t *testing.T) {
+		reader := recipe.NewReader(testLog, emptyConfigPath)
+		recipes, err := reader.Read("./testdata/sinks-retry-recipe.yaml")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.Len(t, recipes, 1)
+		sinks := recipes[0].Sinks
+		assert.Len(t, sinks, 2)
+		assert.Nil(t, sinks[0].Retry)
+		assert.Equal(t, &recipe.RetryPolicy{
+			MaxRetries:             intPtr(8),
+			InitialIntervalSeconds: intPtr(1),
+			MaxIntervalSeconds:     intPtr(30),
+			Multiplier:             floatPtr(2),
+			Jitter:                 floatPtr(0),
+		}, sinks[1].Retry)
+	})
+
 	t.Run("should return error if directory is not found", func(t *testing.T) {
 		reader := recipe.NewReader(testLog, emptyConfigPath)
 		_, err := reader.Read("./testdata/wrong-dir")
@@ -314,3 +394,7 @@ func compareRecipes(t *testing.T, expected, actual recipe.Recipe) {
 		assert.Equal(t, expected.Processors[i].Config, actual.Processors[i].Config)
 	}
 }
+
+func intPtr(i int) *int { return &i }
+
+func floatPtr(f float64) *float64 { return &f }
@@ -13,7 +13,54 @@ type Recipe struct {
 // PluginRecipe contains the json data for a recipe that is being used for
 // generating the plugins code for a recipe.
 type PluginRecipe struct {
-	Name   string                 `json:"name" yaml:"name" validate:"required"`
-	Config map[string]interface{} `json:"config" yaml:"config"`
-	Node   PluginNode
+	Name      string                 `json:"name" yaml:"name" validate:"required"`
+	Config    map[string]interface{} `json:"config" yaml:"config"`
+	BatchSize int                    `json:"batch_size" yaml:"batch_size"`
+	// When selects the records sent to a sink, every record is sent if not set
+	When *Match `json:"when" yaml:"when"`
+	// OnError overrides the sink error policy of the agent for a sink, one of continue or stop
+	OnError string `json:"on_error" yaml:"on_error"`
+	// RateLimit is the number of records per second an extractor emits, or of batches per second sent to a sink,
+	// there is no limit if not set
+	RateLimit float64 `json:"rate_limit" yaml:"rate_limit"`
+	// MaxInFlight is the number of batches a sink is sent at the same time, it does not apply to sources
+	MaxInFlight int `json:"max_in_flight" yaml:"max_in_flight"`
+	// BufferSize is the number of records an extractor emits ahead of each sink, the batch size of the sink if not set
+	BufferSize int `json:"buffer_size" yaml:"buffer_size"`
+	// Retry overrides the retries of the agent for a sink
+	Retry *RetryPolicy `json:"retry" yaml:"retry"`
+	Node  PluginNode
+}
+
+// Match selects records by their asset, a record is selected if it matches every criterion that is set
+type Match struct {
+	// Types are the asset types, such as table or dashboard, the record should be one of
+	Types []string `json:"types" yaml:"types"`
+	// Services are the services, such as bigquery, the record should be from one of
+	Services []string `json:"services" yaml:"services"`
+	// URNs are patterns the urn should match one of, * matches any sequence of characters and ? any single character
+	URNs []string `json:"urns" yaml:"urns"`
+	// Tags are the tags the asset should have at least one of
+	Tags []string `json:"tags" yaml:"tags"`
+	// Labels are the labels the asset should have all of, a value of * matches any value
+	Labels map[string]string `json:"labels" yaml:"labels"`
+}
+
+// RetryPolicy contains the retries of a sink failing with retryable errors,
+// the settings of the agent are used for the unset keys, zero values are kept,
+// e.g. a max_retries of 0 disables retries.
+type RetryPolicy struct {
+	// MaxRetries is the number of retries of a batch
+	MaxRetries *int `json:"max_retries" yaml:"max_retries"`
+	// InitialIntervalSeconds is the time waited before the first retry
+	InitialIntervalSeconds *int `json:"initial_interval_seconds" yaml:"initial_interval_seconds"`
+	// MaxIntervalSeconds caps the time waited between retries
+	MaxIntervalSeconds *int `json:"max_interval_seconds" yaml:"max_interval_seconds"`
+	// Multiplier grows the time waited after every retry, should be at least 1
+	Multiplier *float64 `json:"multiplier" yaml:"multiplier"`
+	// Jitter randomizes the time waited by up to this fraction of it, between 0 and 1
+	Jitter *float64 `json:"jitter" yaml:"jitter"`
+	// DeadlineSeconds is the time after the first attempt past which a batch is not retried anymore,
+	// 0 retries without deadline
+	DeadlineSeconds *int `json:"deadline_seconds" yaml:"deadline_seconds"`
 }
@@ -0,0 +1,40 @@
+package utils
+
+import (
+	"github.com/odpf/meteor/models"
+	assetsv1beta1 "github.com/odpf/meteor/models/odpf/assets/v1beta1"
+)
+
+// Asset types of the supported assets
+const (
+	AssetTypeTable     = "table"
+	AssetTypeTopic     = "topic"
+	AssetTypeDashboard = "dashboard"
+	AssetTypeBucket    = "bucket"
+	AssetTypeJob       = "job"
+	AssetTypeUser      = "user"
+	AssetTypeGroup     = "group"
+)
+
+// GetAssetType returns the type of the given asset,
+// the resource type is returned for assets that are not known
+func GetAssetType(metadata models.Metadata) string {
+	switch metadata.(type) {
+	case *assetsv1beta1.Table:
+		return AssetTypeTable
+	case *assetsv1beta1.Topic:
+		return AssetTypeTopic
+	case *assetsv1beta1.Dashboard:
+		return AssetTypeDashboard
+	case *assetsv1beta1.Bucket:
+		return AssetTypeBucket
+	case *assetsv1beta1.Job:
+		return AssetTypeJob
+	case *assetsv1beta1.User:
+		return AssetTypeUser
+	case *assetsv1beta1.Group:
+		return AssetTypeGroup
+	}
+
+	return metadata.GetResource().GetType()
+}
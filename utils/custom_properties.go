@@ -48,6 +48,28 @@ func SetCustomProperties(metadata models.Metadata, customFields map[string]inter
 	return metadata, nil
 }
 
+// SetProperties sets properties of the given asset
+func SetProperties(metadata models.Metadata, properties *facetsv1beta1.Properties) models.Metadata {
+	switch metadata := metadata.(type) {
+	case *assetsv1beta1.Table:
+		metadata.Properties = properties
+	case *assetsv1beta1.Topic:
+		metadata.Properties = properties
+	case *assetsv1beta1.Dashboard:
+		metadata.Properties = properties
+	case *assetsv1beta1.Bucket:
+		metadata.Properties = properties
+	case *assetsv1beta1.Group:
+		metadata.Properties = properties
+	case *assetsv1beta1.Job:
+		metadata.Properties = properties
+	case *assetsv1beta1.User:
+		metadata.Properties = properties
+	}
+
+	return metadata
+}
+
 func appendCustomFields(metadata models.Metadata, customFields map[string]interface{}) (*facetsv1beta1.Properties, error) {
 	properties := metadata.GetProperties()
 	if properties == nil {
@@ -0,0 +1,33 @@
+package utils
+
+import (
+	"github.com/odpf/meteor/models"
+	facetsv1beta1 "github.com/odpf/meteor/models/odpf/assets/facets/v1beta1"
+	assetsv1beta1 "github.com/odpf/meteor/models/odpf/assets/v1beta1"
+)
+
+// GetLineage returns lineage of the given asset, nil is returned if the asset has no lineage
+func GetLineage(metadata models.Metadata) *facetsv1beta1.Lineage {
+	lm, ok := metadata.(models.LineageMetadata)
+	if !ok {
+		return nil
+	}
+
+	return lm.GetLineage()
+}
+
+// SetLineage sets lineage of the given asset, assets that do not support lineage are returned as is
+func SetLineage(metadata models.Metadata, lineage *facetsv1beta1.Lineage) models.Metadata {
+	switch metadata := metadata.(type) {
+	case *assetsv1beta1.Table:
+		metadata.Lineage = lineage
+	case *assetsv1beta1.Topic:
+		metadata.Lineage = lineage
+	case *assetsv1beta1.Dashboard:
+		metadata.Lineage = lineage
+	case *assetsv1beta1.Job:
+		metadata.Lineage = lineage
+	}
+
+	return metadata
+}
@@ -0,0 +1,35 @@
+package utils
+
+import (
+	"github.com/odpf/meteor/models"
+	facetsv1beta1 "github.com/odpf/meteor/models/odpf/assets/facets/v1beta1"
+	assetsv1beta1 "github.com/odpf/meteor/models/odpf/assets/v1beta1"
+)
+
+// GetOwnership returns ownership of the given asset, nil is returned if the asset has no ownership
+func GetOwnership(metadata models.Metadata) *facetsv1beta1.Ownership {
+	om, ok := metadata.(models.OwnershipMetadata)
+	if !ok {
+		return nil
+	}
+
+	return om.GetOwnership()
+}
+
+// SetOwnership sets ownership of the given asset, assets that do not support ownership are returned as is
+func SetOwnership(metadata models.Metadata, ownership *facetsv1beta1.Ownership) models.Metadata {
+	switch metadata := metadata.(type) {
+	case *assetsv1beta1.Table:
+		metadata.Ownership = ownership
+	case *assetsv1beta1.Topic:
+		metadata.Ownership = ownership
+	case *assetsv1beta1.Dashboard:
+		metadata.Ownership = ownership
+	case *assetsv1beta1.Bucket:
+		metadata.Ownership = ownership
+	case *assetsv1beta1.Job:
+		metadata.Ownership = ownership
+	}
+
+	return metadata
+}
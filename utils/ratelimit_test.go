@@ -0,0 +1,41 @@
+package utils_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/odpf/meteor/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimiter(t *testing.T) {
+	t.Run("should return nil limiter which never blocks if rate is not positive", func(t *testing.T) {
+		limiter := utils.NewRateLimiter(0)
+		assert.Nil(t, limiter)
+
+		for i := 0; i < 100; i++ {
+			assert.NoError(t, limiter.Wait(context.TODO()))
+		}
+	})
+
+	t.Run("should space out calls at the configured rate", func(t *testing.T) {
+		limiter := utils.NewRateLimiter(50)
+
+		start := time.Now()
+		for i := 0; i < 4; i++ {
+			assert.NoError(t, limiter.Wait(context.TODO()))
+		}
+		// the first call is not delayed, the next three wait 20ms each
+		assert.GreaterOrEqual(t, int64(time.Since(start)), int64(60*time.Millisecond))
+	})
+
+	t.Run("should return error once the context is done", func(t *testing.T) {
+		limiter := utils.NewRateLimiter(0.1)
+		assert.NoError(t, limiter.Wait(context.TODO()))
+
+		ctx, cancel := context.WithTimeout(context.TODO(), 10*time.Millisecond)
+		defer cancel()
+		assert.ErrorIs(t, limiter.Wait(ctx), context.DeadlineExceeded)
+	})
+}